@@ -0,0 +1,211 @@
+package main
+
+import "fmt"
+
+// Hospital admission queue.
+//
+// By default hospital overload is modeled in aggregate: when ward or ICU
+// demand exceeds the staffed beds, every Severe or Critical patient's daily
+// mortality is scaled up by the overload ratio (see computeC). With
+// hospitalQueue enabled, beds are assigned to individuals instead. Each day,
+// patients who died or recovered are discharged. New Severe (ward) and
+// Critical (ICU) cases join the back of a queue for their bed type. Patients
+// are then admitted first come, first served while staffed beds are free.
+// Admitted patients get the base mortality and the recovery boost of
+// medicalCareLevel; patients still waiting get the mortality multiplied by the
+// waiting multiplier for their bed type, and recover without the care boost.
+// Admission criteria can deny some patients a bed altogether (see triage.go).
+
+// HospitalQueueConfig configures individual bed assignment.
+type HospitalQueueConfig struct {
+	enabled         bool
+	wardWaitingMult float64 // mortality multiplier while waiting for a ward bed
+	icuWaitingMult  float64 // mortality multiplier while waiting for an ICU bed
+	criteria        AdmissionCriteria
+}
+
+// hospitalState holds who occupies and who is waiting for each kind of bed.
+type hospitalState struct {
+	ward, icu           []*Individual // admitted patients
+	wardQueue, icuQueue []*Individual // waiting patients, longest wait first
+	denied              []*Individual // patients denied care by the admission criteria
+
+	admissions         int     // patients admitted over the run
+	waitDays           int     // total days admitted patients spent waiting
+	deathsWaiting      int     // deaths among patients waiting for a bed
+	attributableDeaths float64 // expected share of those deaths due to the wait
+	byAge              [ageBands]careTally
+}
+
+// needsBed reports whether ind currently needs a ward or ICU bed, and which.
+func needsBed(ind *Individual) (need bool, icu bool) {
+	if ind == nil || ind.healthStatus != Infected || ind.infection == nil {
+		return false, false
+	}
+	switch ind.infection.severity {
+	case Severe:
+		return true, false
+	case Critical:
+		return true, true
+	}
+	return false, false
+}
+
+// stillNeeding filters list down to individuals who still need a bed, clearing
+// the hospital flags of those who no longer do (discharged or died waiting).
+func stillNeeding(list []*Individual) []*Individual {
+	kept := list[:0]
+	for _, ind := range list {
+		if need, _ := needsBed(ind); need {
+			kept = append(kept, ind)
+			continue
+		}
+		ind.inHospital = false
+		ind.waitingForBed = false
+		ind.deniedCare = false
+	}
+	clear(list[len(kept):])
+	return kept
+}
+
+// admitPatients runs one day of discharges, queueing and admissions.
+func admitPatients(env *Environment) {
+	if !env.hospitalQueue.enabled {
+		return
+	}
+	h := &env.hospital
+	h.ward = stillNeeding(h.ward)
+	h.icu = stillNeeding(h.icu)
+	h.wardQueue = stillNeeding(h.wardQueue)
+	h.icuQueue = stillNeeding(h.icuQueue)
+	h.denied = stillNeeding(h.denied)
+
+	// New cases join the back of their queue, unless the criteria deny them care
+	criteria := env.hospitalQueue.criteria
+	for _, ind := range env.population {
+		if ind == nil || ind.inHospital || ind.waitingForBed || ind.deniedCare {
+			continue
+		}
+		need, icu := needsBed(ind)
+		if !need {
+			continue
+		}
+		tally := &h.byAge[ageBand(ind.age)]
+		tally.needed++
+		ind.waitingSince = env.day
+		if !admissible(criteria, ind, icu) {
+			ind.deniedCare = true
+			h.denied = append(h.denied, ind)
+			tally.denied++
+			continue
+		}
+		ind.waitingForBed = true
+		if icu {
+			h.icuQueue = append(h.icuQueue, ind)
+		} else {
+			h.wardQueue = append(h.wardQueue, ind)
+		}
+	}
+
+	total, icuBeds := effectiveCapacity(env)
+	wardBeds := max(total-icuBeds, 0)
+	before := h.admissions
+	orderICUQueue(criteria, h.icuQueue)
+	h.ward, h.wardQueue = h.admitFromQueue(h.ward, h.wardQueue, wardBeds, env.day)
+	h.icu, h.icuQueue = h.admitFromQueue(h.icu, h.icuQueue, icuBeds, env.day)
+	env.transitions.newAdmissions += h.admissions - before
+}
+
+// countAdmission counts ind as today's hospital admission if it has just
+// become infectious with a case that needs a bed. With hospitalQueue,
+// admissions are counted when a bed is assigned instead.
+func countAdmission(env *Environment, ind *Individual) {
+	if env == nil || env.hospitalQueue.enabled {
+		return
+	}
+	if need, _ := needsBed(ind); need {
+		env.transitions.newAdmissions++
+	}
+}
+
+// admitFromQueue moves patients from the front of queue into beds until all
+// beds are taken. Patients already admitted keep their beds even if staffed
+// capacity has dropped below occupancy.
+func (h *hospitalState) admitFromQueue(admitted, queue []*Individual, beds, day int) ([]*Individual, []*Individual) {
+	n := min(max(beds-len(admitted), 0), len(queue))
+	for _, ind := range queue[:n] {
+		ind.waitingForBed = false
+		ind.inHospital = true
+		admitted = append(admitted, ind)
+		h.admissions++
+		h.waitDays += day - ind.waitingSince
+		h.byAge[ageBand(ind.age)].admitted++
+	}
+	rest := queue[n:]
+	// copy down so the queue's backing array does not grow without bound
+	kept := append(queue[:0], rest...)
+	clear(queue[len(kept):])
+	return admitted, kept
+}
+
+// waitingMortalityMult returns the mortality multiplier for ind under the
+// queue model: the waiting multiplier for its bed type while it waits, 1 otherwise.
+func waitingMortalityMult(env *Environment, ind *Individual) float64 {
+	if !waitingForCare(env, ind) || ind.infection == nil {
+		return 1.0
+	}
+	if ind.infection.severity == Critical {
+		return env.hospitalQueue.icuWaitingMult
+	}
+	return env.hospitalQueue.wardWaitingMult
+}
+
+// waitingForCare reports whether ind needs a bed but has not been admitted yet,
+// or was denied one, under the queue model, and so gets no hospital care.
+func waitingForCare(env *Environment, ind *Individual) bool {
+	return env.hospitalQueue.enabled && (ind.waitingForBed || ind.deniedCare)
+}
+
+// recordWaitingDeath counts the death of a patient who was still waiting for
+// a bed; call it before the infection record is dropped. A share 1 - 1/mult of the death risk was due to the wait, so that
+// share of the death is attributed to the capacity shortfall.
+func recordWaitingDeath(env *Environment, ind *Individual) {
+	if !env.hospitalQueue.enabled {
+		return
+	}
+	if ind.deniedCare {
+		env.hospital.byAge[ageBand(ind.age)].diedDenied++
+		return
+	}
+	if !ind.waitingForBed {
+		return
+	}
+	env.hospital.byAge[ageBand(ind.age)].diedWaiting++
+	env.hospital.deathsWaiting++
+	if mult := waitingMortalityMult(env, ind); mult > 1 {
+		env.hospital.attributableDeaths += 1 - 1/mult
+	}
+}
+
+// printHospitalQueueSummary reports deaths among patients waiting for beds.
+func printHospitalQueueSummary(env *Environment) {
+	if !env.hospitalQueue.enabled {
+		return
+	}
+	h := env.hospital
+	meanWait := 0.0
+	if h.admissions > 0 {
+		meanWait = float64(h.waitDays) / float64(h.admissions)
+	}
+	fmt.Fprintf(msgOut, "Hospital queue: %d admissions (mean wait %.1f days), %d deaths while waiting for a bed, ~%.1f attributable to the capacity shortfall\n",
+		h.admissions, meanWait, h.deathsWaiting, h.attributableDeaths)
+	if env.hospitalQueue.criteria.restricts() {
+		denied, died := 0, 0
+		for _, t := range h.byAge {
+			denied += t.denied
+			died += t.diedDenied
+		}
+		fmt.Fprintf(msgOut, "  Admission criteria denied care to %d patients, %d of whom died\n", denied, died)
+	}
+	printCareByAge(env)
+}
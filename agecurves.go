@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Age curves.
+//
+// The model's age effects are compiled-in three-bucket switches (under 40,
+// 40-60, over 60). ageCurvesFile replaces them with curves read from a CSV
+// file, interpolated linearly between the listed ages and held flat beyond
+// the first and last one. The header names the columns; the first is the age
+// and the others are any of
+//
+//	susceptibility  multiplier on the chance of catching the infection
+//	contacts        relative contact rate outside the household; a contact
+//	                between two neighbors is scaled by both of their rates,
+//	                one on a train or flight by the passenger's own
+//	severity        multiplier on severeFraction and criticalFraction
+//	mortality       multiplier on mortalityRate
+//	recovery        multiplier on recoveryRate
+//	ifr             infection fatality ratio, used like ifrByAge (see ifr.go)
+//
+// For example
+//
+//	age, susceptibility, severity, ifr
+//	0,   0.4,            0.1,      0.00002
+//	20,  0.8,            0.3,      0.0003
+//	50,  1.0,            1.0,      0.006
+//	80,  1.2,            3.0,      0.08
+//
+// A column that is left out keeps the built-in behavior: mortality, recovery
+// and severity use the buckets, susceptibility and contacts are 1. Commas,
+// spaces or tabs separate the values, and '#' starts a comment.
+
+// ageColumn is a column of the age curves file.
+type ageColumn int
+
+const (
+	AgeSusceptibility ageColumn = iota
+	AgeContacts
+	AgeSeverity
+	AgeMortality
+	AgeRecovery
+	AgeIFR
+	numAgeColumns
+)
+
+var ageColumnNames = [numAgeColumns]string{"susceptibility", "contacts", "severity", "mortality", "recovery", "ifr"}
+
+// ageCurve is one column: values at increasing ages.
+type ageCurve struct {
+	ages   []int
+	values []float64
+}
+
+// at interpolates the curve linearly at age, holding the end values outside
+// the listed range.
+func (c *ageCurve) at(age int) float64 {
+	if age <= c.ages[0] {
+		return c.values[0]
+	}
+	for i := 1; i < len(c.ages); i++ {
+		if age <= c.ages[i] {
+			t := float64(age-c.ages[i-1]) / float64(c.ages[i]-c.ages[i-1])
+			return c.values[i-1] + t*(c.values[i]-c.values[i-1])
+		}
+	}
+	return c.values[len(c.values)-1]
+}
+
+// ageCurves holds the columns of an age curves file; a nil column was not
+// given.
+type ageCurves struct {
+	ages   []int // the listed ages, shared by all columns
+	curves [numAgeColumns]*ageCurve
+}
+
+// has reports whether the column was given.
+func (a *ageCurves) has(col ageColumn) bool {
+	return a != nil && a.curves[col] != nil
+}
+
+// names lists the columns given, in ageColumnNames order.
+func (a *ageCurves) names() []string {
+	var out []string
+	for col, c := range a.curves {
+		if c != nil {
+			out = append(out, ageColumnNames[col])
+		}
+	}
+	return out
+}
+
+// ageFactor returns the column's value at ind's age, or def without that
+// column.
+func ageFactor(env *Environment, col ageColumn, ind *Individual, def float64) float64 {
+	if env == nil || !env.ageCurves.has(col) {
+		return def
+	}
+	return env.ageCurves.curves[col].at(ind.age)
+}
+
+// loadAgeCurves reads an age curves file.
+func loadAgeCurves(path string) (*ageCurves, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cols []ageColumn // column of each field after the age
+	var ages []int
+	var rows [][]float64
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			continue
+		}
+		if cols == nil {
+			if len(fields) < 2 || !strings.EqualFold(fields[0], "age") {
+				return nil, fmt.Errorf("%s line %d: expected a header \"age, column, ...\"", path, lineNum)
+			}
+			seen := map[ageColumn]bool{}
+			for _, name := range fields[1:] {
+				col := ageColumn(-1)
+				for c, n := range ageColumnNames {
+					if strings.EqualFold(name, n) {
+						col = ageColumn(c)
+					}
+				}
+				if col < 0 {
+					return nil, fmt.Errorf("%s line %d: unknown column %q (expected %s)", path, lineNum, name, strings.Join(ageColumnNames[:], ", "))
+				}
+				if seen[col] {
+					return nil, fmt.Errorf("%s line %d: column %s listed twice", path, lineNum, name)
+				}
+				seen[col] = true
+				cols = append(cols, col)
+			}
+			continue
+		}
+		if len(fields) != len(cols)+1 {
+			return nil, fmt.Errorf("%s line %d: expected %d values, got %d", path, lineNum, len(cols)+1, len(fields))
+		}
+		age, err := strconv.Atoi(fields[0])
+		if err != nil || age < 0 || age > 120 {
+			return nil, fmt.Errorf("%s line %d: invalid age %q (expected 0-120)", path, lineNum, fields[0])
+		}
+		if len(ages) > 0 && age <= ages[len(ages)-1] {
+			return nil, fmt.Errorf("%s line %d: ages must increase (%d after %d)", path, lineNum, age, ages[len(ages)-1])
+		}
+		row := make([]float64, len(cols))
+		for i, s := range fields[1:] {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid number %q", path, lineNum, s)
+			}
+			limit := 100.0
+			if cols[i] == AgeIFR {
+				limit = 1
+			}
+			if v < 0 || v > limit {
+				return nil, fmt.Errorf("%s line %d: %s %g outside [0, %g]", path, lineNum, ageColumnNames[cols[i]], v, limit)
+			}
+			row[i] = v
+		}
+		ages = append(ages, age)
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ages) == 0 {
+		return nil, fmt.Errorf("%s: no entries", path)
+	}
+
+	a := &ageCurves{ages: ages}
+	for i, col := range cols {
+		c := &ageCurve{ages: ages, values: make([]float64, len(rows))}
+		for k, row := range rows {
+			c.values[k] = row[i]
+		}
+		a.curves[col] = c
+	}
+	return a, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+)
+
+// Age summary figure. With ageSummary = true, a static PNG is written at the
+// end of the run with three bar charts sharing the same 10-year age bands as
+// rows: the population pyramid (males left, females right), the attack rate
+// (share ever infected) and the death rate (share dead of the disease) in
+// each band.
+
+const (
+	ageBandWidth = 10
+	ageBands     = 9 // 0-9 ... 80+ (ages go up to 90)
+
+	agePanelWidth = 300
+	ageRowHeight  = 32
+	ageTop        = 40 // room for panel titles
+	ageLabelWidth = 50 // room for the band labels
+)
+
+// ageBandStats counts one age band.
+type ageBandStats struct {
+	male, female int
+	infected     int // ever infected
+	dead         int
+}
+
+func (b ageBandStats) total() int { return b.male + b.female }
+
+// ageBand returns the band index of an age.
+func ageBand(age int) int {
+	return max(0, min(age/ageBandWidth, ageBands-1))
+}
+
+// ageBandLabel returns e.g. "20-29", or "80+" for the last band.
+func ageBandLabel(i int) string {
+	if i == ageBands-1 {
+		return fmt.Sprintf("%d+", i*ageBandWidth)
+	}
+	return fmt.Sprintf("%d-%d", i*ageBandWidth, (i+1)*ageBandWidth-1)
+}
+
+// collectAgeBands tallies the whole population (living and dead) by age band.
+func collectAgeBands(env *Environment) []ageBandStats {
+	bands := make([]ageBandStats, ageBands)
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		b := &bands[ageBand(ind.age)]
+		if ind.gender == "Male" {
+			b.male++
+		} else {
+			b.female++
+		}
+		if ind.timesInfected > 0 {
+			b.infected++
+		}
+		if ind.healthStatus == Dead && !ind.backgroundDeath {
+			b.dead++
+		}
+	}
+	return bands
+}
+
+// DrawAgeSummary renders the age pyramid, attack rates and death rates.
+func DrawAgeSummary(env *Environment) image.Image {
+	bands := collectAgeBands(env)
+	width := 3 * agePanelWidth
+	height := ageTop + ageBands*ageRowHeight + 10
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	maxCount, maxDeathRate := 1, 0.0
+	for _, b := range bands {
+		maxCount = max(maxCount, b.male, b.female)
+		if b.total() > 0 {
+			maxDeathRate = max(maxDeathRate, float64(b.dead)/float64(b.total()))
+		}
+	}
+	if maxDeathRate == 0 {
+		maxDeathRate = 1
+	}
+
+	drawLabel(img, 10, 20, color.White, "Population (M | F)")
+	drawLabel(img, agePanelWidth+10, 20, color.White, "Attack rate")
+	drawLabel(img, 2*agePanelWidth+10, 20, color.White, "Death rate")
+
+	male := color.RGBA{70, 130, 220, 255}
+	female := color.RGBA{220, 100, 160, 255}
+	attack := color.RGBA{200, 0, 0, 255}
+	death := color.RGBA{160, 160, 160, 255}
+	barSpan := agePanelWidth - ageLabelWidth - 50 // leave room for the value label
+
+	// Oldest band at the top, as in a pyramid
+	for i, b := range bands {
+		y := ageTop + (ageBands-1-i)*ageRowHeight
+		bar := func(x0, length int, col color.Color) {
+			r := image.Rect(x0, y+4, x0+length, y+ageRowHeight-4)
+			draw.Draw(img, r.Canon(), &image.Uniform{col}, image.Point{}, draw.Src)
+		}
+		for panel := 0; panel < 3; panel++ {
+			drawLabel(img, panel*agePanelWidth+6, y+ageRowHeight/2+4, color.White, ageBandLabel(i))
+		}
+
+		// Pyramid: bars grow outwards from the center of the panel
+		half := (agePanelWidth - ageLabelWidth) / 2
+		center := ageLabelWidth + half
+		bar(center, -b.male*(half-4)/maxCount, male)
+		bar(center, b.female*(half-4)/maxCount, female)
+
+		if b.total() == 0 {
+			continue
+		}
+		attackRate := float64(b.infected) / float64(b.total())
+		x0 := agePanelWidth + ageLabelWidth
+		bar(x0, int(attackRate*float64(barSpan)), attack)
+		drawLabel(img, x0+int(attackRate*float64(barSpan))+4, y+ageRowHeight/2+4, color.White,
+			fmt.Sprintf("%.0f%%", 100*attackRate))
+
+		deathRate := float64(b.dead) / float64(b.total())
+		x0 = 2*agePanelWidth + ageLabelWidth
+		bar(x0, int(deathRate/maxDeathRate*float64(barSpan)), death)
+		drawLabel(img, x0+int(deathRate/maxDeathRate*float64(barSpan))+4, y+ageRowHeight/2+4, color.White,
+			fmt.Sprintf("%.1f%%", 100*deathRate))
+	}
+	return img
+}
+
+// SaveAgeSummary writes the age summary figure to filename as a PNG.
+func SaveAgeSummary(filename string, env *Environment) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, DrawAgeSummary(env)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scenario annotations: free-text notes attached to days in the config
+// (e.g. annotation.45 = schools reopen). They are written to the stats output
+// on their day and shown on every frame from their day on, so figures explain
+// themselves.
+
+// annotationHeader returns the extra CSV header column for annotations.
+func annotationHeader(env *Environment) string {
+	if len(env.annotations) == 0 {
+		return ""
+	}
+	return ", Annotation"
+}
+
+// annotationColumn formats a row's annotation as a quoted CSV field, matching annotationHeader.
+func annotationColumn(env *Environment, text string) string {
+	if len(env.annotations) == 0 {
+		return ""
+	}
+	if text == "" {
+		return ", "
+	}
+	return `, "` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// latestAnnotation returns the most recent annotation on or before day.
+func latestAnnotation(env *Environment, day int) (int, string, bool) {
+	best, found := -1, false
+	for d := range env.annotations {
+		if d <= day && d > best {
+			best, found = d, true
+		}
+	}
+	if !found {
+		return 0, "", false
+	}
+	return best, env.annotations[best], true
+}
+
+// annotationLabel returns the frame label line for the current day's latest
+// annotation, e.g. "Day 45: schools reopen", or "" if there is none yet.
+func annotationLabel(env *Environment) string {
+	day, text, ok := latestAnnotation(env, env.day)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("Day %d: %s", day, text)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Assimilation of an observed incidence curve.
+//
+// An incidence file gives the real number of new cases per day, one
+// "day, cases" line each (commas or spaces; '#' starts a comment; the day is
+// a simulation day number, or a date with startDate). For the first
+// assimilationDays days (default: up to the last listed day) the model's new
+// infections are forced to match it, each count multiplied by incidenceScale
+// (e.g. the model's population over the real one) and rounded. Afterwards the
+// model runs freely, so the run is a forecast from the observed start.
+//
+// On an assimilated day the infection probabilities are computed as usual,
+// then exactly the observed number of infections is drawn among the
+// Susceptible individuals, each chosen with weight its probability, so the
+// model decides who is infected and the data how many. If too few have any
+// chance of infection, the rest are picked at random among the Healthy and
+// Susceptible, like imported cases. Day 0, and days within the period that
+// are not listed, are left to the model.
+
+// Assimilation holds the observed curve and how the model fitted it.
+type Assimilation struct {
+	observed map[int]int // day -> infections to impose, already scaled
+	until    int         // last assimilated day
+
+	target   int     // infections to impose today
+	active   bool    // today is assimilated
+	days     int     // days assimilated so far
+	imposed  int     // infections imposed
+	expected float64 // sum of the Susceptible's infection probabilities on those days
+	seeded   int     // infections picked at random for lack of candidates
+}
+
+// loadIncidence reads an incidence file. startDate (zero if no calendar) is
+// needed to resolve dates; scale multiplies every count; days is the length
+// of the assimilation period, 0 = up to the last listed day.
+func loadIncidence(path string, startDate time.Time, scale float64, days int) (*Assimilation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	a := &Assimilation{observed: map[int]int{}, until: days}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	first := true
+	last := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s line %d: expected day, cases", path, lineNum)
+		}
+		cases, numErr := strconv.ParseFloat(fields[1], 64)
+		header := first && numErr != nil
+		first = false
+		day, err := parseWeatherDay(fields[0], startDate)
+		if err != nil {
+			if header {
+				continue // e.g. "day, cases"
+			}
+			return nil, fmt.Errorf("%s line %d: %v", path, lineNum, err)
+		}
+		if numErr != nil {
+			return nil, fmt.Errorf("%s line %d: invalid number %q", path, lineNum, fields[1])
+		}
+		if cases < 0 || math.IsInf(cases, 0) || math.IsNaN(cases) {
+			return nil, fmt.Errorf("%s line %d: invalid case count %g", path, lineNum, cases)
+		}
+		if _, ok := a.observed[day]; ok {
+			return nil, fmt.Errorf("%s line %d: day %d listed twice", path, lineNum, day)
+		}
+		a.observed[day] = int(math.Round(cases * scale))
+		last = max(last, day)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(a.observed) == 0 {
+		return nil, fmt.Errorf("%s: no entries", path)
+	}
+	if a.until == 0 {
+		a.until = last
+	}
+	return a, nil
+}
+
+// constrainInfections fixes who is infected today on an assimilated day: the
+// target number of Susceptible individuals, drawn with weight b, get b = 1
+// and all others b = 0. It must run between computing and applying ps.
+func constrainInfections(env *Environment, ps []transitionProbs, rng *rand.Rand) {
+	a := env.assimilation
+	if a == nil {
+		return
+	}
+	a.target, a.active = a.observed[env.day]
+	a.active = a.active && env.day >= 1 && env.day <= a.until
+	if !a.active {
+		return
+	}
+	a.days++
+	a.imposed += a.target
+
+	// Weighted sampling without replacement: the smallest keys -ln(U)/b win
+	var candidates []int
+	keys := make([]float64, len(env.population))
+	for i, ind := range env.population {
+		if ind == nil || ind.healthStatus != Susceptible {
+			continue
+		}
+		a.expected += ps[i].b
+		if ps[i].b > 0 {
+			candidates = append(candidates, i)
+			keys[i] = -math.Log(1-rng.Float64()) / ps[i].b
+		}
+		ps[i].b = 0
+	}
+	sort.SliceStable(candidates, func(x, y int) bool {
+		return keys[candidates[x]] < keys[candidates[y]]
+	})
+	for _, i := range candidates[:min(a.target, len(candidates))] {
+		ps[i].b = 1
+	}
+}
+
+// fillInfections makes up today's shortfall, if too few had any chance of
+// infection, with infections picked at random among the Healthy and
+// Susceptible. It must run after the day's health updates.
+func fillInfections(env *Environment, rng *rand.Rand) {
+	a := env.assimilation
+	if a == nil || !a.active {
+		return
+	}
+	short := a.target - env.transitions.newInfections
+	if short <= 0 {
+		return
+	}
+	var pool []*Individual
+	for _, ind := range env.population {
+		if ind != nil && (ind.healthStatus == Healthy || ind.healthStatus == Susceptible) {
+			pool = append(pool, ind)
+		}
+	}
+	for k := 0; k < short && k < len(pool); k++ {
+		j := k + rng.Intn(len(pool)-k)
+		pool[k], pool[j] = pool[j], pool[k]
+		startInfection(env, pool[k], rng)
+		a.seeded++
+	}
+}
+
+// printAssimilationSummary reports how the model fitted the observed curve.
+func printAssimilationSummary(env *Environment) {
+	a := env.assimilation
+	if a == nil || a.days == 0 {
+		return
+	}
+	fmt.Fprintf(msgOut, "Assimilated %d days up to day %d: %d infections imposed, the model alone expected %.1f",
+		a.days, a.until, a.imposed, a.expected)
+	if a.imposed > 0 {
+		fmt.Fprintf(msgOut, " (%.2fx)", a.expected/float64(a.imposed))
+	}
+	fmt.Fprintln(msgOut)
+	if a.seeded > 0 {
+		fmt.Fprintf(msgOut, "  %d of them were seeded at random for lack of exposed candidates\n", a.seeded)
+	}
+}
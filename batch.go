@@ -0,0 +1,216 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// Batched transmission. computeA and computeB find each healthy or
+// susceptible individual's infected neighbors through the spatial index,
+// which walks every individual in the nearby cells (or the whole population
+// without an index) and follows a pointer per candidate to check its health.
+// With batchTransmission = true, the day's infectious individuals are instead
+// copied once into flat float64 slices (x, y and a per-source weight),
+// grouped by grid cell, and the queries run tight loops over those slices.
+// computeA only asks whether anyone infected is close enough. computeB takes
+// the distances of the sources in reach first, then their kernel weights,
+// then the product of escape probabilities. The loops touch only contiguous
+// float64s of infected individuals, which is what the compiler can vectorize
+// and the cache likes, and at low prevalence most of the population is never
+// looked at.
+//
+// The model is the same, and computeA gives the same answers, but computeB
+// multiplies the contacts in another order, so its probabilities differ in
+// the last bits and a seeded run does not reproduce the scalar one exactly.
+// The NPI report needs computeB's scalar path, so it keeps it. The
+// bench-transmission subcommand (see bench.go) measures the speedup on a
+// given config.
+
+// infectedBatch is the day's infectious individuals as flat slices, grouped
+// by grid cell.
+type infectedBatch struct {
+	side   int       // cells per side
+	cell   float64   // side length of a cell
+	reach  float64   // contact radius of the kernel
+	start  []int32   // sources of cell c are start[c]:start[c+1]
+	xs, ys []float64 // positions
+	weight []float64 // per-source factor: isolation, age contact rate and infectiousness
+	region []int32   // region of each source
+	id     []int32   // population index of each source
+}
+
+// batchScratch holds a query's candidates; one is pooled per goroutine.
+type batchScratch struct {
+	ds []float64 // distances, then kernel weights
+	js []int32   // source index in the batch
+}
+
+var batchScratchPool = sync.Pool{
+	New: func() any { return &batchScratch{ds: make([]float64, 0, 64), js: make([]int32, 0, 64)} },
+}
+
+// prepareBatch builds today's batch before the probabilities are computed,
+// or clears it when the batch is off or no one is infectious.
+func prepareBatch(env *Environment) {
+	env.batch = nil
+	if !env.batchTransmission || env.fastForward.quiet || env.disease == nil {
+		return
+	}
+	D0 := env.disease.transmissionDistance
+	if D0 <= 0 {
+		D0 = 1.0
+	}
+	env.batch = buildInfectedBatch(env, transmissionKernel(env).reach(D0))
+}
+
+// buildInfectedBatch copies the infectious individuals of env into a batch
+// with cells no smaller than the kernel's reach (a counting sort, like
+// hashGrid).
+func buildInfectedBatch(env *Environment, reach float64) *infectedBatch {
+	n := 0
+	for _, ind := range env.population {
+		if ind != nil && ind.healthStatus == Infected {
+			n++
+		}
+	}
+	side := int(math.Ceil(math.Sqrt(float64(n) / gridOccupancy)))
+	if reach > 0 {
+		side = min(side, int(env.areaSize/reach))
+	}
+	side = max(1, min(side, maxGridSide))
+	b := &infectedBatch{side: side, cell: env.areaSize / float64(side), reach: reach}
+	if b.cell <= 0 {
+		b.cell = 1
+	}
+
+	cellOf := make([]int32, 0, n)
+	b.start = make([]int32, side*side+1)
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus != Infected {
+			continue
+		}
+		c := b.cellIndex(ind.position.y)*side + b.cellIndex(ind.position.x)
+		cellOf = append(cellOf, int32(c))
+		b.start[c+1]++
+	}
+	for c := 1; c <= side*side; c++ {
+		b.start[c] += b.start[c-1]
+	}
+	next := make([]int32, side*side)
+	copy(next, b.start[:side*side])
+	b.xs = make([]float64, n)
+	b.ys = make([]float64, n)
+	b.weight = make([]float64, n)
+	b.region = make([]int32, n)
+	b.id = make([]int32, n)
+	k := 0
+	for i, ind := range env.population {
+		if ind == nil || ind.healthStatus != Infected {
+			continue
+		}
+		j := next[cellOf[k]]
+		next[cellOf[k]]++
+		k++
+		b.xs[j], b.ys[j] = ind.position.x, ind.position.y
+		b.weight[j] = quarantineTransmission(env, ind) * ageFactor(env, AgeContacts, ind, 1) * infectiousness(ind.infection)
+		b.region[j] = int32(ind.region)
+		b.id[j] = int32(i)
+	}
+	return b
+}
+
+// cellIndex returns the cell row or column of coordinate v, clamped to the grid.
+func (b *infectedBatch) cellIndex(v float64) int {
+	return max(0, min(b.side-1, int(v/b.cell)))
+}
+
+// anyInfectedWithin reports whether an infectious individual is within r of
+// ind (and in its region), from the batch if there is one.
+func anyInfectedWithin(env *Environment, ind *Individual, r float64) bool {
+	if env.batch != nil {
+		return env.batch.anyWithin(ind, r)
+	}
+	buf := getNeighborBuf()
+	defer putNeighborBuf(buf)
+	*buf = appendInfectedNeighbors(*buf, env, ind, r)
+	return len(*buf) > 0
+}
+
+// anyWithin reports whether an infectious individual in ind's region is
+// within r of it.
+func (b *infectedBatch) anyWithin(ind *Individual, r float64) bool {
+	x, y := ind.position.x, ind.position.y
+	region := int32(ind.region)
+	x0, x1 := b.cellIndex(x-r), b.cellIndex(x+r)
+	for cy := b.cellIndex(y - r); cy <= b.cellIndex(y+r); cy++ {
+		lo, hi := b.start[cy*b.side+x0], b.start[cy*b.side+x1+1]
+		xs, ys, regions := b.xs[lo:hi], b.ys[lo:hi], b.region[lo:hi]
+		for k := range xs {
+			dx, dy := xs[k]-x, ys[k]-y
+			if math.Sqrt(dx*dx+dy*dy) <= r && regions[k] == region {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// escape returns the probability that ind escapes infection by every source
+// within reach, where a source j at distance d infects it with probability
+// clamp01(common * kernel(d) * weight[j]). Each contact is offered to source.
+func (b *infectedBatch) escape(env *Environment, ind *Individual, common float64, kernel Kernel, D0 float64, source *sourcePicker) float64 {
+	s := batchScratchPool.Get().(*batchScratch)
+	defer batchScratchPool.Put(s)
+	ds, js := s.ds[:0], s.js[:0]
+
+	// Candidates in reach, from the cells overlapping the query square
+	x, y, r := ind.position.x, ind.position.y, b.reach
+	region := int32(ind.region)
+	x0, x1 := b.cellIndex(x-r), b.cellIndex(x+r)
+	for cy := b.cellIndex(y - r); cy <= b.cellIndex(y+r); cy++ {
+		lo, hi := b.start[cy*b.side+x0], b.start[cy*b.side+x1+1]
+		xs, ys, regions := b.xs[lo:hi], b.ys[lo:hi], b.region[lo:hi]
+		for k := range xs {
+			dx, dy := xs[k]-x, ys[k]-y
+			if d := math.Sqrt(dx*dx + dy*dy); d <= r && regions[k] == region {
+				ds = append(ds, d)
+				js = append(js, lo+int32(k))
+			}
+		}
+	}
+
+	kernelWeights(kernel, ds, D0)
+	fail := 1.0
+	for k, w := range ds {
+		pi := clamp01(common * w * b.weight[js[k]])
+		fail *= 1 - pi
+		if source != nil {
+			source.offer(env.population[b.id[js[k]]].infection, pi)
+		}
+	}
+	s.ds, s.js = ds, js
+	return fail
+}
+
+// kernelWeights replaces each distance in ds by its kernel weight, with a
+// loop per built-in kernel so the call is not made through the interface.
+func kernelWeights(kernel Kernel, ds []float64, D0 float64) {
+	switch kernel.(type) {
+	case exponentialKernel:
+		for i, d := range ds {
+			ds[i] = math.Exp(-d / D0)
+		}
+	case gaussianKernel:
+		for i, d := range ds {
+			ds[i] = math.Exp(-d * d / (2 * D0 * D0))
+		}
+	case inverseSquareKernel:
+		for i, d := range ds {
+			ds[i] = 1 / (1 + (d/D0)*(d/D0))
+		}
+	default:
+		for i, d := range ds {
+			ds[i] = kernel.weight(d, D0)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Initial behavior. Each individual starts with a hygieneLevel and a
+// socialDistanceCompliance in [0, 1]. By default both are drawn uniformly,
+// but population-level results are sensitive to this starting point, so each
+// can instead be drawn from a Beta(a, b) distribution (e.g. a = 8, b = 2 for a
+// mostly compliant population) or fixed to a constant.
+
+// behaviorDistributionKind selects how an initial behavior level is drawn.
+type behaviorDistributionKind string
+
+const (
+	BehaviorUniform  behaviorDistributionKind = "uniform"  // Uniform(0, 1)
+	BehaviorBeta     behaviorDistributionKind = "beta"     // Beta(a, b)
+	BehaviorConstant behaviorDistributionKind = "constant" // always value
+)
+
+var behaviorDistributionChoices = []string{string(BehaviorUniform), string(BehaviorBeta), string(BehaviorConstant)}
+
+// behaviorDistribution is the initial distribution of one behavior level.
+// The zero value is Uniform(0, 1).
+type behaviorDistribution struct {
+	kind  behaviorDistributionKind
+	a, b  float64 // Beta shape parameters
+	value float64 // constant level
+}
+
+// draw returns one initial level in [0, 1].
+func (d behaviorDistribution) draw(rng *rand.Rand) float64 {
+	switch d.kind {
+	case BehaviorBeta:
+		return betaSample(d.a, d.b, rng)
+	case BehaviorConstant:
+		return d.value
+	default:
+		return rng.Float64()
+	}
+}
+
+// betaSample draws from Beta(a, b) as X/(X+Y) with X ~ Gamma(a), Y ~ Gamma(b).
+func betaSample(a, b float64, rng *rand.Rand) float64 {
+	x := gammaSample(a, rng)
+	y := gammaSample(b, rng)
+	if x+y == 0 {
+		return a / (a + b)
+	}
+	return x / (x + y)
+}
+
+// gammaSample draws from Gamma(k, 1) with the Marsaglia-Tsang method. Shapes
+// below 1 are boosted to k+1 and scaled back by U^(1/k).
+func gammaSample(k float64, rng *rand.Rand) float64 {
+	if k < 1 {
+		return gammaSample(k+1, rng) * math.Pow(rng.Float64(), 1/k)
+	}
+	d := k - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
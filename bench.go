@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// bench-transmission subcommand.
+//
+//	go run . bench-transmission -config my.txt [-seed 1] [-days 20] [-reps 5]
+//
+// Simulates the config for -days days (like verify-determinism, without road
+// network, weather file or outputs), then times the next day's exposure
+// probabilities of everyone healthy or susceptible (computeA and computeB),
+// on one goroutine: first through the spatial index, as by default, then
+// with batchTransmission (see batch.go), including building the batch. Each
+// is repeated -reps times and the fastest is reported, with the largest
+// difference between the two paths' probabilities.
+
+// runBenchTransmission implements the subcommand; args exclude its name.
+func runBenchTransmission(args []string) {
+	fs := flag.NewFlagSet("bench-transmission", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to configuration file")
+	preset := fs.String("preset", "", "Use a built-in example config instead of -config")
+	seed := fs.Int64("seed", 0, "Seed of the run (default: the config's randomSeed, or 1)")
+	days := fs.Int("days", 20, "Days to simulate before timing, so the epidemic has spread")
+	reps := fs.Int("reps", 5, "Times each path is timed; the fastest counts")
+	fs.Parse(args)
+
+	config, err := loadRunConfig(*configFile, *preset)
+	if err != nil {
+		fmt.Fprintln(msgOut, "Error:", err)
+		os.Exit(2)
+	}
+	if *seed == 0 {
+		*seed = config.randomSeed
+	}
+	if *seed == 0 {
+		*seed = 1
+	}
+
+	id := rngStream{alg: config.rngAlgorithm, seed: *seed}
+	rng := id.newRand()
+	env := environmentFromConfig(config, rng)
+	env.streams = id.splitter()
+	env.disease = diseaseFromConfig(config)
+	env.batchTransmission = false
+	seedInfections(env, config, rng)
+	for day := 1; day <= *days; day++ {
+		env.day = day
+		if _, err := stepDay(env, rng); err != nil {
+			fmt.Fprintf(msgOut, "Error on day %d: %v\n", day, err)
+			os.Exit(1)
+		}
+	}
+	rebuildSpatialIndex(env)
+
+	var targets []*Individual
+	infected, susceptible := 0, 0
+	for _, ind := range env.population {
+		switch {
+		case ind == nil:
+		case ind.healthStatus == Healthy:
+			targets = append(targets, ind)
+		case ind.healthStatus == Susceptible:
+			targets = append(targets, ind)
+			susceptible++
+		case ind.healthStatus == Infected:
+			infected++
+		}
+	}
+	fmt.Fprintf(msgOut, "Day %d: %d individuals, %d infected, %d healthy, %d susceptible (spatialIndex = %s)\n",
+		env.day, len(env.population), infected, len(targets)-susceptible, susceptible, env.spatialIndex)
+	if len(targets) == 0 || infected == 0 {
+		fmt.Fprintln(msgOut, "Nothing to time: no one is healthy or susceptible, or no one is infected")
+		return
+	}
+
+	D0 := env.disease.transmissionDistance
+	if D0 <= 0 {
+		D0 = 1.0
+	}
+	reach := transmissionKernel(env).reach(D0)
+	scalar := make([]float64, len(targets))
+	batched := make([]float64, len(targets))
+	timeIt := func(probs []float64, batch bool) time.Duration {
+		best := time.Duration(math.MaxInt64)
+		for r := 0; r < max(*reps, 1); r++ {
+			start := time.Now()
+			if batch {
+				env.batch = buildInfectedBatch(env, reach)
+			}
+			for i, ind := range targets {
+				probs[i] = computeA(env, ind) + computeB(env, ind, nil) // one of them is 0
+			}
+			best = min(best, time.Since(start))
+			env.batch = nil
+		}
+		return best
+	}
+	tScalar := timeIt(scalar, false)
+	tBatch := timeIt(batched, true)
+
+	maxDiff := 0.0
+	for i := range scalar {
+		maxDiff = max(maxDiff, math.Abs(scalar[i]-batched[i]))
+	}
+	perInd := func(t time.Duration) float64 { return float64(t.Nanoseconds()) / float64(len(targets)) }
+	fmt.Fprintf(msgOut, "  spatial index: %10v (%.0f ns per individual)\n", tScalar, perInd(tScalar))
+	fmt.Fprintf(msgOut, "  batch:         %10v (%.0f ns per individual, building included)\n", tBatch, perInd(tBatch))
+	fmt.Fprintf(msgOut, "  speedup %.2fx, largest probability difference %.2g\n", float64(tScalar)/float64(tBatch), maxDiff)
+}
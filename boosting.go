@@ -0,0 +1,60 @@
+package main
+
+import "math"
+
+// Natural immunity boosting. In endemic settings people are exposed again and
+// again; an exposure that does not cause infection still primes the immune
+// system. With boosting enabled, every such exposure raises an individual's
+// immunityBoost by perExposure (up to max). The boost scales down both the
+// chance of infection (computeB) and the chance of losing post-recovery
+// immunity (computeE) by (1 - boost), and halves every halfLife days without
+// new exposure.
+//
+// An individual counts as exposed on a day if it had a non-zero chance of
+// becoming susceptible or infected (a or b > 0), or, while Recovered, had an
+// infected individual within transmission distance.
+
+// ImmunityBoosting configures natural boosting from exposures that do not infect.
+type ImmunityBoosting struct {
+	enabled     bool
+	perExposure float64 // boost added per non-infecting exposure
+	max         float64 // upper bound on the boost
+	halfLife    float64 // days for the boost to halve without exposure
+}
+
+// boostProtection returns the factor (1 - boost) applied to infection and
+// immunity-loss probabilities.
+func boostProtection(ind *Individual) float64 {
+	return 1 - ind.immunityBoost
+}
+
+// markExposure records whether ind was exposed today, given the probabilities
+// computed for it. It runs in the read-only phase of the daily update.
+func markExposure(env *Environment, ind *Individual, p transitionProbs) {
+	if !env.boosting.enabled {
+		return
+	}
+	ind.exposed = p.a > 0 || p.b > 0
+	if ind.healthStatus == Recovered && env.disease != nil && !env.fastForward.quiet {
+		buf := getNeighborBuf()
+		*buf = appendInfectedNeighbors(*buf, env, ind, env.disease.transmissionDistance)
+		ind.exposed = len(*buf) > 0
+		putNeighborBuf(buf)
+	}
+}
+
+// updateImmunityBoost lets the boost wane for one day, then adds today's
+// exposure if it did not lead to infection.
+func updateImmunityBoost(env *Environment, ind *Individual) {
+	b := env.boosting
+	if !b.enabled {
+		return
+	}
+	if ind.immunityBoost > 0 && b.halfLife > 0 {
+		ind.immunityBoost *= math.Pow(0.5, 1/b.halfLife)
+	}
+	if ind.exposed && ind.healthStatus != Infected {
+		ind.immunityBoost = min(ind.immunityBoost+b.perExposure, b.max)
+	}
+	ind.exposed = false
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// dateLayout is the format of the startDate config key and of dates in the stats output.
+const dateLayout = "2006-01-02"
+
+// hasCalendar reports whether a startDate was configured.
+func (env *Environment) hasCalendar() bool {
+	return env != nil && !env.startDate.IsZero()
+}
+
+// dateForDay returns the calendar date of a simulated day (day 0 = startDate).
+func (env *Environment) dateForDay(day int) time.Time {
+	return env.startDate.AddDate(0, 0, day)
+}
+
+// calendarHeader returns the extra CSV header columns for calendar dates.
+func calendarHeader(env *Environment) string {
+	if !env.hasCalendar() {
+		return ""
+	}
+	return ", Date, Weekday"
+}
+
+// calendarColumns returns the date and weekday of a day, matching calendarHeader.
+func calendarColumns(env *Environment, day int) string {
+	if !env.hasCalendar() {
+		return ""
+	}
+	date := env.dateForDay(day)
+	return fmt.Sprintf(", %s, %s", date.Format(dateLayout), date.Weekday().String()[:3])
+}
+
+// dayLabel returns the frame label for the environment's current day,
+// e.g. "Day 12" or "Day 12 2020-03-13 Fri" when a calendar is configured.
+func dayLabel(env *Environment) string {
+	if !env.hasCalendar() {
+		return fmt.Sprintf("Day %d", env.day)
+	}
+	date := env.dateForDay(env.day)
+	return fmt.Sprintf("Day %d %s %s", env.day, date.Format(dateLayout), date.Weekday().String()[:3])
+}
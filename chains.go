@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Transmission chains. With chainStats = true, every infection with the main
+// disease is logged with the infection that caused it. computeB knows every
+// infectious contact of a Susceptible individual and its transmission
+// probability; one of them is picked as the likely source, with probability
+// proportional to its transmission probability. The pick uses a hash of the
+// day, the individual and the contact rather than the run's generator, so
+// logging never changes the course of a seeded run. Seeded infections (and
+// those imposed by assimilation without a contact) start a new chain.
+//
+// At the end of the run the log gives the transmission tree statistics
+// epidemiologists use to characterize outbreaks: the deepest chain in
+// generations, the distribution of secondary cases per infection with the
+// share of transmission caused by the top 10% of infectors, and how many
+// chains died out (terminal chains) and how many are still active. Only
+// completed infections enter the secondary case distribution, since ongoing
+// ones may still infect others. The log itself is written to
+// output_gif/transmissions.csv.
+
+// transmissionsFileName is the name of the log file in the output directory.
+const transmissionsFileName = "transmissions.csv"
+
+// transmission is one infection in the log.
+type transmission struct {
+	day        int
+	infectee   int // individual ID
+	parent     int // log index of the infection that caused it, -1 if seeded
+	generation int // 0 if seeded, else the parent's plus one
+	offspring  int // infections it caused so far
+}
+
+// chainLog is the transmission log of a run.
+type chainLog struct {
+	events []transmission
+}
+
+// sourcePicker picks the likely source of an infection among the contacts
+// computeB considers, in proportion to their transmission probabilities.
+type sourcePicker struct {
+	day, id int
+	n       uint64
+	total   float64
+	pick    *Infection
+}
+
+// newSourcePicker returns a picker for ind's contacts today, or nil without
+// a transmission log. All methods accept a nil picker.
+func newSourcePicker(env *Environment, ind *Individual) *sourcePicker {
+	if env.chains == nil {
+		return nil
+	}
+	return &sourcePicker{day: env.day, id: ind.id}
+}
+
+// offer considers the infection of a contact with transmission probability p.
+func (s *sourcePicker) offer(inf *Infection, p float64) {
+	if s == nil || inf == nil || p <= 0 {
+		return
+	}
+	s.n++
+	s.total += p
+	// weighted reservoir sampling: keep this contact with probability p/total
+	if chainUniform(s.day, s.id, s.n) < p/s.total {
+		s.pick = inf
+	}
+}
+
+// done stores the pick as ind's source if ind is infected today.
+func (s *sourcePicker) done(ind *Individual) {
+	if s != nil {
+		ind.exposedBy = s.pick
+	}
+}
+
+// chainUniform returns a uniform in [0, 1) determined by the day, the
+// individual and the contact number (splitmix64, as in points.go).
+func chainUniform(day, id int, n uint64) float64 {
+	z := uint64(day)*0x9e3779b97f4a7c15 ^ uint64(id)*0xc2b2ae3d27d4eb4f ^ n*0x165667b19e3779f9
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11) / (1 << 53)
+}
+
+// recordTransmission logs the new infection of ind, whose source is the one
+// computeB picked today, if any.
+func recordTransmission(env *Environment, ind *Individual) {
+	c := env.chains
+	src := ind.exposedBy
+	ind.exposedBy = nil
+	if c == nil || ind.infection == nil {
+		return
+	}
+	t := transmission{day: env.day, infectee: ind.id, parent: -1}
+	if src != nil && src.chain >= 0 && src.chain < len(c.events) {
+		t.parent = src.chain
+		t.generation = c.events[src.chain].generation + 1
+		c.events[src.chain].offspring++
+	}
+	ind.infection.chain = len(c.events)
+	c.events = append(c.events, t)
+}
+
+// chainSummary holds the transmission tree statistics of a run.
+type chainSummary struct {
+	infections, seeded int
+	maxDepth           int
+	completed          int     // infections that have ended
+	meanOffspring      float64 // secondary cases per completed infection
+	deadEnds           int     // completed infections that infected no one
+	top10Share         float64 // share of the secondary cases of completed infections caused by the top 10% of them
+	histogram          [6]int  // completed infections with 0, 1, 2, 3-5, 6-10 and more than 10 secondary cases
+	chains, active     int     // chains started, and those with an ongoing infection
+	largest            int     // infections in the largest chain
+}
+
+// summarizeChains computes the statistics of env's log.
+func summarizeChains(env *Environment) chainSummary {
+	events := env.chains.events
+	s := chainSummary{infections: len(events)}
+	ongoing := make([]bool, len(events))
+	for _, ind := range env.population {
+		if ind == nil || ind.infection == nil || ind.healthStatus == Dead {
+			continue
+		}
+		if k := ind.infection.chain; k >= 0 && k < len(events) {
+			ongoing[k] = true
+		}
+	}
+	root := make([]int, len(events))
+	size := map[int]int{}
+	active := map[int]bool{}
+	var offspring []int
+	for k, t := range events {
+		root[k] = k
+		if t.parent >= 0 {
+			root[k] = root[t.parent]
+		} else {
+			s.seeded++
+		}
+		size[root[k]]++
+		s.maxDepth = max(s.maxDepth, t.generation)
+		if ongoing[k] {
+			active[root[k]] = true
+			continue
+		}
+		offspring = append(offspring, t.offspring)
+	}
+	s.chains, s.active = len(size), len(active)
+	for _, n := range size {
+		s.largest = max(s.largest, n)
+	}
+
+	s.completed = len(offspring)
+	if s.completed == 0 {
+		return s
+	}
+	total := 0
+	for _, n := range offspring {
+		total += n
+		switch {
+		case n <= 2:
+			s.histogram[n]++
+		case n <= 5:
+			s.histogram[3]++
+		case n <= 10:
+			s.histogram[4]++
+		default:
+			s.histogram[5]++
+		}
+	}
+	s.deadEnds = s.histogram[0]
+	s.meanOffspring = float64(total) / float64(s.completed)
+	if total > 0 {
+		slices.Sort(offspring)
+		slices.Reverse(offspring)
+		top := 0
+		for _, n := range offspring[:int(math.Ceil(0.1*float64(s.completed)))] {
+			top += n
+		}
+		s.top10Share = float64(top) / float64(total)
+	}
+	return s
+}
+
+// printChainSummary reports the transmission tree statistics.
+func printChainSummary(env *Environment) {
+	if !env.chainStats {
+		return
+	}
+	s := summarizeChains(env)
+	fmt.Fprintf(msgOut, "Transmission chains: %d infections (%d seeded), deepest chain %d generations\n",
+		s.infections, s.seeded, s.maxDepth)
+	if s.completed > 0 {
+		fmt.Fprintf(msgOut, "  Secondary cases per completed infection: mean %.2f, %.1f%% caused none, top 10%% of infectors caused %.1f%% of transmission\n",
+			s.meanOffspring, 100*float64(s.deadEnds)/float64(s.completed), 100*s.top10Share)
+		h := s.histogram
+		fmt.Fprintf(msgOut, "  Distribution: 0: %d, 1: %d, 2: %d, 3-5: %d, 6-10: %d, >10: %d\n", h[0], h[1], h[2], h[3], h[4], h[5])
+	}
+	fmt.Fprintf(msgOut, "  Chains: %d started, %d died out (terminal), %d still active, largest %d infections\n",
+		s.chains, s.chains-s.active, s.active, s.largest)
+}
+
+// SaveTransmissions writes the log, one row per infection.
+func SaveTransmissions(path string, env *Environment) error {
+	w, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "Day,Infectee,Infector,Generation,SecondaryCases")
+	for _, t := range env.chains.events {
+		infector := ""
+		if t.parent >= 0 {
+			infector = fmt.Sprint(env.chains.events[t.parent].infectee)
+		}
+		fmt.Fprintf(w, "%d,%d,%s,%d,%d\n", t.day, t.infectee, infector, t.generation, t.offspring)
+	}
+	return w.Close()
+}
@@ -0,0 +1,819 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+// Saving and resuming a run.
+//
+// With checkpointInterval = N, every N days the full state of the run is
+// saved to output_gif/state_day<N>.gob: every individual (position, status,
+// infection, timers, contact log, household), the event queue, the hospital
+// queues, stocks and counters, the daily stats so far, and the position of
+// the random number generator. Running the same config again with
+//
+//	go run . -config my.txt -resume output_gif/state_day60.gob
+//
+// continues from the day after the saved one, exactly as the uninterrupted
+// run would have. The config is not saved: it must be the one the state was
+// saved with, except that numDays may be raised to extend the run. The stats
+// file, epidemic curve, flow diagram and end-of-run summaries cover the whole run; the
+// console rows, GIFs and other per-day outputs start at the resumed day.
+//
+// The generator's position is saved as its stream (see rng.go) and the
+// number of values drawn so far, and is restored by drawing them again, which
+// takes about a second per hundred million draws.
+
+// stateVersion is written in every state file; files of another version are
+// refused.
+const stateVersion = 14
+
+// countingSource is the run's random source. It counts its draws so its
+// position can be saved and restored.
+type countingSource struct {
+	src   rand.Source64
+	id    rngStream
+	draws uint64
+}
+
+func newCountingSource(id rngStream) *countingSource {
+	return &countingSource{src: id.source(), id: id}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.id, s.draws = rngStream{alg: s.id.alg, seed: seed}, 0
+}
+
+// skip advances the source by n draws.
+func (s *countingSource) skip(n uint64) {
+	for range n {
+		s.src.Uint64()
+	}
+	s.draws += n
+}
+
+// savedState is the content of a state file.
+type savedState struct {
+	Version     int
+	Algorithm   rngAlgorithm
+	Seed        int64
+	Stream      int
+	Draws       uint64
+	Split       uint64 // chunk streams taken, with a jumpable generator
+	Day         int
+	Rows        []DayStats // day 0 first
+	Individuals []savedIndividual
+	Households  []savedHousehold
+	Events      []savedEvent
+	NextEvent   uint64 // the event queue's next sequence number
+	Env         savedEnvironment
+}
+
+// savedIndividual is the state of one individual. Other individuals and
+// households are referred to by index, -1 for none.
+type savedIndividual struct {
+	Missing              bool // nil slot in the population
+	Gender               string
+	Age                  int
+	Status               HealthStatus
+	Infection            *savedInfection
+	DaysSinceRecovery    int
+	DaysSinceVaccination int
+	Vaccinated           bool
+	Hygiene              float64
+	Compliance           float64
+	HasMovement          bool
+	MoveType             moveType
+	MoveRadius           float64
+	X, Y                 float64
+	InHospital           bool
+	Tags                 []string
+	ContactIDs           []int32
+	ContactCounts        []uint16
+	ContactHead          int
+	RoadFrom, RoadTo     int
+	RoadT                float64
+	DeathDay             int
+	TimesInfected        int
+	ImmunityBoost        float64
+	Exposed              bool
+	ExposureRisk         float64
+	RiskDays             int
+	WaitingForBed        bool
+	WaitingSince         int
+	DeniedCare           bool
+	SideEffectsUntil     int
+	QuarantinedUntil     int
+	BackgroundDeath      bool
+	ShockedUntil         int
+	Pathogens            []pathogenRecord
+	Home                 int
+	Region               int
+	Profile              string // behavior profile name, empty for none
+}
+
+// savedInfection is an individual's current infection.
+type savedInfection struct {
+	DaysInfected   int
+	Severity       Severity
+	LatencyOver    bool
+	MustResolve    bool
+	Detected       bool
+	Chain          int
+	Infectiousness float64
+	Treated        bool
+}
+
+// pathogenRecord is an individual's state for one pathogen.
+type pathogenRecord struct {
+	Status HealthStatus
+	Days   int
+}
+
+// savedHousehold is a household and its members.
+type savedHousehold struct {
+	X, Y      float64
+	Region    int
+	Members   []int
+	Attitudes []float64
+}
+
+// savedCell is the policy state of a policy cell.
+type savedCell struct {
+	SDThreshold                 float64
+	Locked                      bool
+	Since, Lockdowns, LockedFor int
+	Pending                     []float64
+}
+
+// Values of savedEvent.Infection.
+const (
+	eventNoInfection    = iota // the event belongs to no infection
+	eventInfection             // it belongs to the individual's current infection
+	eventStaleInfection        // it belongs to an infection that has ended
+)
+
+// savedEvent is a pending event.
+type savedEvent struct {
+	Time      float64
+	Seq       uint64
+	Kind      eventKind
+	Ind       int
+	Infection int
+}
+
+// savedEnvironment is the state of the environment that changes during a run.
+type savedEnvironment struct {
+	SocialDistanceThreshold float64
+	HygieneLevel            float64
+	MobilityRate            float64
+	VaccinationRate         float64
+	MedicalCareLevel        float64
+
+	BurdenDeaths      int
+	BurdenYLL         float64
+	BurdenIllnessDays map[Severity]int
+	BurdenRecoveries  int
+
+	NPIPressure []float64
+	NPIFactors  [3]float64
+	NPIEvals    int
+
+	FastForwardQuiet bool
+	FastForwardDays  int
+
+	DoseLots         [][2]int // doses, expires
+	DosesWasted      int
+	DosesGiven       int
+	RestockedThrough int
+
+	SideEffectEpisodes int
+
+	Stockpile []int // doses given, courses used, depleted day; nil without a stockpile
+
+	HygieneStock        float64
+	HygieneRatio        float64
+	HygieneShortageDays int
+
+	Detected           int
+	Quarantined        int
+	QuarantineDays     int
+	Tested             int
+	TestedInfected     int
+	FalseNegatives     int
+	DetectionToday     int
+	DetectedToday      int
+	TestedToday        int
+	TraceNotified      int
+	TraceIsolated      int
+	TraceInfected      int
+	TraceToday         int
+	TraceNotifiedToday int
+	TraceIsolatedToday int
+
+	RegionInfections []int
+
+	LockdownActive bool
+	LockdownSince  int
+	Lockdowns      int
+	LockdownDays   int
+	Cells          []savedCell
+	ReportsPending []float64
+
+	TransmissionLog bool
+	Transmissions   [][5]int // day, infectee, parent, generation, offspring
+
+	PathogenInfections []int
+	PathogenDeaths     []int
+
+	Ward, ICU, WardQueue, ICUQueue []int
+	Denied                         []int
+	Admissions                     int
+	WaitDays                       int
+	DeathsWaiting                  int
+	AttributableDeaths             float64
+	CareByAge                      [][5]int // needed, admitted, denied, died denied, died waiting
+
+	AssimilatedDays int
+	Imposed         int
+	Expected        float64
+	Seeded          int
+
+	FlowStart        [numFlowNodes]int
+	FlowMoves        [numFlowNodes][numFlowNodes]int
+	FlowReinfections [numFlowNodes][numFlowNodes]int
+}
+
+// stateFileName returns the name of the state file saved at the end of day.
+func stateFileName(day int) string {
+	return fmt.Sprintf("state_day%d.gob", day)
+}
+
+// SaveState writes the state of the run at the end of env.day to filename:
+// env, the position of src (which must be the run's only generator) and
+// rows, the daily stats so far.
+func SaveState(filename string, env *Environment, src *countingSource, rows []DayStats) error {
+	st := savedState{
+		Version:   stateVersion,
+		Algorithm: src.id.alg,
+		Seed:      src.id.seed,
+		Stream:    src.id.stream,
+		Draws:     src.draws,
+		Day:       env.day,
+		Rows:      rows,
+		Env:       saveEnvironment(env),
+	}
+	if env.streams != nil {
+		st.Split = env.streams.used
+	}
+	index := func(ind *Individual) int {
+		if ind == nil {
+			return -1
+		}
+		return ind.id
+	}
+	indices := func(inds []*Individual) []int {
+		out := make([]int, len(inds))
+		for i, ind := range inds {
+			out[i] = index(ind)
+		}
+		return out
+	}
+	st.Env.Ward, st.Env.ICU = indices(env.hospital.ward), indices(env.hospital.icu)
+	st.Env.WardQueue, st.Env.ICUQueue = indices(env.hospital.wardQueue), indices(env.hospital.icuQueue)
+	st.Env.Denied = indices(env.hospital.denied)
+
+	homes := map[*Household]int{}
+	for _, ind := range env.population {
+		if ind == nil || ind.home == nil {
+			continue
+		}
+		if _, ok := homes[ind.home]; ok {
+			continue
+		}
+		h := ind.home
+		homes[h] = len(st.Households)
+		st.Households = append(st.Households, savedHousehold{
+			X: h.position.x, Y: h.position.y, Region: h.region, Members: indices(h.members), Attitudes: h.attitudes[:],
+		})
+	}
+
+	st.Individuals = make([]savedIndividual, len(env.population))
+	for i, ind := range env.population {
+		if ind == nil {
+			st.Individuals[i].Missing = true
+			continue
+		}
+		s := savedIndividual{
+			Gender:               ind.gender,
+			Age:                  ind.age,
+			Status:               ind.healthStatus,
+			DaysSinceRecovery:    ind.daysSinceRecovery,
+			DaysSinceVaccination: ind.daysSinceVacination,
+			Vaccinated:           ind.vaccinated,
+			Hygiene:              ind.hygieneLevel,
+			Compliance:           ind.socialDistanceCompliance,
+			X:                    ind.position.x,
+			Y:                    ind.position.y,
+			InHospital:           ind.inHospital,
+			Tags:                 ind.tags,
+			RoadFrom:             ind.road.from,
+			RoadTo:               ind.road.to,
+			RoadT:                ind.road.t,
+			DeathDay:             ind.deathDay,
+			TimesInfected:        ind.timesInfected,
+			ImmunityBoost:        ind.immunityBoost,
+			Exposed:              ind.exposed,
+			ExposureRisk:         ind.exposureRisk,
+			RiskDays:             ind.riskDays,
+			WaitingForBed:        ind.waitingForBed,
+			WaitingSince:         ind.waitingSince,
+			DeniedCare:           ind.deniedCare,
+			SideEffectsUntil:     ind.sideEffectsUntil,
+			QuarantinedUntil:     ind.quarantinedUntil,
+			BackgroundDeath:      ind.backgroundDeath,
+			ShockedUntil:         ind.shockedUntil,
+			Home:                 -1,
+			Region:               ind.region,
+		}
+		if ind.profile != nil {
+			s.Profile = ind.profile.name
+		}
+		if inf := ind.infection; inf != nil {
+			s.Infection = &savedInfection{inf.daysInfected, inf.severity, inf.latencyOver, inf.mustResolve, inf.detected, inf.chain, inf.infectiousness, inf.treated}
+		}
+		if mp := ind.movementPattern; mp != nil {
+			s.HasMovement, s.MoveType, s.MoveRadius = true, mp.moveType, mp.moveRadius
+		}
+		if l := ind.contacts; l != nil {
+			s.ContactIDs, s.ContactCounts, s.ContactHead = l.ids, l.counts, l.head
+		}
+		for _, p := range ind.pathogens {
+			s.Pathogens = append(s.Pathogens, pathogenRecord{p.status, p.days})
+		}
+		if ind.home != nil {
+			s.Home = homes[ind.home]
+		}
+		st.Individuals[i] = s
+	}
+
+	if q := env.events; q != nil {
+		st.NextEvent = q.nextSeq
+		for _, ev := range q.items {
+			s := savedEvent{Time: ev.time, Seq: ev.seq, Kind: ev.kind, Ind: index(ev.ind)}
+			switch {
+			case ev.infection == nil:
+				s.Infection = eventNoInfection
+			case ev.ind != nil && ev.ind.infection == ev.infection:
+				s.Infection = eventInfection
+			default:
+				s.Infection = eventStaleInfection
+			}
+			st.Events = append(st.Events, s)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	w, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(w).Encode(&st); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// saveEnvironment returns the run-dependent state of env, except the
+// hospital's patients.
+func saveEnvironment(env *Environment) savedEnvironment {
+	e := savedEnvironment{
+		SocialDistanceThreshold: env.socialDistanceThreshold,
+		HygieneLevel:            env.hygieneLevel,
+		MobilityRate:            env.mobilityRate,
+		VaccinationRate:         env.vaccinationRate,
+		MedicalCareLevel:        env.medicalCareLevel,
+
+		BurdenDeaths:      env.burden.deaths,
+		BurdenYLL:         env.burden.yll,
+		BurdenIllnessDays: env.burden.illnessDays,
+		BurdenRecoveries:  env.burden.recoveries,
+
+		NPIPressure: env.npi.pressure[:],
+		NPIFactors:  [3]float64{env.npi.factors.vax, env.npi.factors.hygiene, env.npi.factors.compliance},
+		NPIEvals:    env.npi.evals,
+
+		FastForwardQuiet: env.fastForward.quiet,
+		FastForwardDays:  env.fastForward.days,
+
+		DosesWasted:      env.vaccineStock.wasted,
+		DosesGiven:       env.vaccineStock.administered,
+		RestockedThrough: env.vaccineStock.restockedThrough,
+
+		SideEffectEpisodes: env.sideEffects.episodes,
+
+		HygieneStock:        env.hygieneSupply.stock,
+		HygieneRatio:        env.hygieneSupply.ratio,
+		HygieneShortageDays: env.hygieneSupply.shortageDays,
+
+		Detected:           env.quarantine.detected,
+		Quarantined:        env.quarantine.quarantined,
+		QuarantineDays:     env.quarantine.personDays,
+		Tested:             env.quarantine.tested,
+		TestedInfected:     env.quarantine.testedInfected,
+		FalseNegatives:     env.quarantine.falseNegatives,
+		DetectionToday:     env.quarantine.today,
+		DetectedToday:      env.quarantine.detectedToday,
+		TestedToday:        env.quarantine.testedToday,
+		TraceNotified:      env.tracing.notified,
+		TraceIsolated:      env.tracing.isolated,
+		TraceInfected:      env.tracing.infectedAtNotice,
+		TraceToday:         env.tracing.today,
+		TraceNotifiedToday: env.tracing.notifiedToday,
+		TraceIsolatedToday: env.tracing.isolatedToday,
+
+		RegionInfections: env.regionInfections,
+
+		LockdownActive: env.lockdown.active,
+		LockdownSince:  env.lockdown.since,
+		Lockdowns:      env.lockdown.count,
+		LockdownDays:   env.lockdown.days,
+		ReportsPending: env.information.pending,
+
+		Admissions:         env.hospital.admissions,
+		WaitDays:           env.hospital.waitDays,
+		DeathsWaiting:      env.hospital.deathsWaiting,
+		AttributableDeaths: env.hospital.attributableDeaths,
+	}
+	for _, c := range env.cells {
+		l := c.lockdown
+		e.Cells = append(e.Cells, savedCell{SDThreshold: c.sdThreshold, Locked: l.active, Since: l.since, Lockdowns: l.count, LockedFor: l.days, Pending: c.pending})
+	}
+	if c := env.chains; c != nil {
+		e.TransmissionLog = true
+		for _, t := range c.events {
+			e.Transmissions = append(e.Transmissions, [5]int{t.day, t.infectee, t.parent, t.generation, t.offspring})
+		}
+	}
+	if s := env.stockpile; s != nil {
+		e.Stockpile = []int{s.dosesGiven, s.coursesUsed, s.depletedDay}
+	}
+	for _, lot := range env.vaccineStock.lots {
+		e.DoseLots = append(e.DoseLots, [2]int{lot.doses, lot.expires})
+	}
+	for _, t := range env.hospital.byAge {
+		e.CareByAge = append(e.CareByAge, [5]int{t.needed, t.admitted, t.denied, t.diedDenied, t.diedWaiting})
+	}
+	for _, t := range env.pathogenTotals {
+		e.PathogenInfections = append(e.PathogenInfections, t.infections)
+		e.PathogenDeaths = append(e.PathogenDeaths, t.deaths)
+	}
+	if a := env.assimilation; a != nil {
+		e.AssimilatedDays, e.Imposed, e.Expected, e.Seeded = a.days, a.imposed, a.expected, a.seeded
+	}
+	if t := env.flows; t != nil {
+		e.FlowStart, e.FlowMoves, e.FlowReinfections = t.start, t.moves, t.reinfections
+	}
+	return e
+}
+
+// LoadState restores the state saved in filename into env, which must have
+// been built from the config the state was saved with (before its initial
+// infections), with env.disease set. It returns the run's random source,
+// positioned where the saved run left it, and the daily stats so far.
+func LoadState(filename string, env *Environment, alg rngAlgorithm) (*countingSource, []DayStats, error) {
+	f, err := openInput(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	var st savedState
+	if err := gob.NewDecoder(f).Decode(&st); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if st.Version != stateVersion {
+		return nil, nil, fmt.Errorf("%s: state file version %d, expected %d", filename, st.Version, stateVersion)
+	}
+	if st.Algorithm != alg {
+		return nil, nil, fmt.Errorf("%s: saved with rngAlgorithm %s, the config has %s", filename, st.Algorithm, alg)
+	}
+	if err := restoreState(env, &st); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	id := rngStream{alg: st.Algorithm, seed: st.Seed, stream: st.Stream}
+	src := newCountingSource(id)
+	src.skip(st.Draws)
+	if env.streams = id.splitter(); env.streams != nil {
+		env.streams.skip(st.Split)
+	}
+	return src, st.Rows, nil
+}
+
+// restoreState replaces the population and run-dependent state of env with
+// st, after checking that st fits the config env was built from.
+func restoreState(env *Environment, st *savedState) error {
+	n := len(st.Individuals)
+	if n != len(env.population) {
+		return fmt.Errorf("saved with %d individuals, the config has %d", n, len(env.population))
+	}
+	e := st.Env
+	if len(e.PathogenDeaths) != len(env.pathogens) {
+		return fmt.Errorf("saved with %d pathogens, the config has %d", len(e.PathogenDeaths), len(env.pathogens))
+	}
+	if len(e.RegionInfections) != len(env.regions) {
+		return fmt.Errorf("saved with %d regions, the config has %d", len(e.RegionInfections), len(env.regions))
+	}
+	if len(e.Cells) != len(env.cells) {
+		return fmt.Errorf("saved with %d policy cells, the config has %d", len(e.Cells), len(env.cells))
+	}
+	if e.TransmissionLog != (env.chains != nil) {
+		return fmt.Errorf("the transmission log (chainStats, reportReff, infectiousnessDispersion) does not match the config")
+	}
+	if (len(e.Stockpile) == 3) != (env.stockpile != nil) {
+		return fmt.Errorf("the stockpile does not match the config")
+	}
+	if (len(st.Households) > 0) != env.households.enabled() {
+		return fmt.Errorf("households do not match the config")
+	}
+	at := func(i int) (*Individual, error) {
+		if i == -1 {
+			return nil, nil
+		}
+		if i < 0 || i >= n || st.Individuals[i].Missing {
+			return nil, fmt.Errorf("invalid individual %d", i)
+		}
+		return env.population[i], nil
+	}
+	all := func(ids []int) ([]*Individual, error) {
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		out := make([]*Individual, len(ids))
+		for k, i := range ids {
+			ind, err := at(i)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = ind
+		}
+		return out, nil
+	}
+
+	population := make([]*Individual, n)
+	for i, s := range st.Individuals {
+		if s.Missing {
+			continue
+		}
+		if s.Region < 0 || s.Region >= max(len(env.regions), 1) {
+			return fmt.Errorf("individual %d: invalid region %d", i, s.Region)
+		}
+		ind := &Individual{
+			id:                       i,
+			gender:                   s.Gender,
+			age:                      s.Age,
+			healthStatus:             s.Status,
+			daysSinceRecovery:        s.DaysSinceRecovery,
+			daysSinceVacination:      s.DaysSinceVaccination,
+			vaccinated:               s.Vaccinated,
+			hygieneLevel:             s.Hygiene,
+			socialDistanceCompliance: s.Compliance,
+			position:                 OrderedPair{s.X, s.Y},
+			inHospital:               s.InHospital,
+			tags:                     s.Tags,
+			road:                     roadPosition{from: s.RoadFrom, to: s.RoadTo, t: s.RoadT},
+			deathDay:                 s.DeathDay,
+			timesInfected:            s.TimesInfected,
+			immunityBoost:            s.ImmunityBoost,
+			exposed:                  s.Exposed,
+			exposureRisk:             s.ExposureRisk,
+			riskDays:                 s.RiskDays,
+			waitingForBed:            s.WaitingForBed,
+			waitingSince:             s.WaitingSince,
+			deniedCare:               s.DeniedCare,
+			sideEffectsUntil:         s.SideEffectsUntil,
+			quarantinedUntil:         s.QuarantinedUntil,
+			backgroundDeath:          s.BackgroundDeath,
+			shockedUntil:             s.ShockedUntil,
+			region:                   s.Region,
+		}
+		if s.Profile != "" {
+			if ind.profile = env.profileByName(s.Profile); ind.profile == nil {
+				return fmt.Errorf("individual %d: profile %q is not in the config", i, s.Profile)
+			}
+		}
+		if inf := s.Infection; inf != nil {
+			ind.infection = &Infection{
+				disease:      env.disease,
+				daysInfected: inf.DaysInfected,
+				severity:     inf.Severity,
+				latencyOver:  inf.LatencyOver,
+				mustResolve:  inf.MustResolve,
+				detected:     inf.Detected,
+				chain:        inf.Chain,
+
+				infectiousness: inf.Infectiousness,
+				treated:        inf.Treated,
+			}
+		}
+		if s.HasMovement {
+			ind.movementPattern = &MovementPattern{moveType: s.MoveType, moveRadius: s.MoveRadius}
+		}
+		if env.contactMemoryDays > 0 {
+			l := newContactLog(env.contactMemoryDays, env.contactsPerDay)
+			if len(s.ContactIDs) != len(l.ids) || len(s.ContactCounts) != len(l.counts) {
+				return fmt.Errorf("contact memory does not match the config")
+			}
+			copy(l.ids, s.ContactIDs)
+			copy(l.counts, s.ContactCounts)
+			l.head = s.ContactHead
+			ind.contacts = l
+		}
+		if len(s.Pathogens) != len(env.pathogens) {
+			return fmt.Errorf("individual %d: %d pathogen states, expected %d", i, len(s.Pathogens), len(env.pathogens))
+		}
+		if len(s.Pathogens) > 0 {
+			ind.pathogens = make([]pathogenState, len(s.Pathogens))
+			for k, p := range s.Pathogens {
+				ind.pathogens[k] = pathogenState{p.Status, p.Days}
+			}
+		}
+		population[i] = ind
+	}
+	env.population = population
+
+	env.households.count = len(st.Households)
+	homes := make([]*Household, len(st.Households))
+	for k, sh := range st.Households {
+		h := &Household{position: OrderedPair{sh.X, sh.Y}, region: sh.Region}
+		copy(h.attitudes[:], sh.Attitudes)
+		members, err := all(sh.Members)
+		if err != nil {
+			return err
+		}
+		h.members = members
+		homes[k] = h
+	}
+	for i, s := range st.Individuals {
+		if s.Missing || s.Home == -1 {
+			continue
+		}
+		if s.Home < 0 || s.Home >= len(homes) {
+			return fmt.Errorf("individual %d: invalid household %d", i, s.Home)
+		}
+		env.population[i].home = homes[s.Home]
+	}
+
+	env.events = &eventQueue{nextSeq: st.NextEvent}
+	for _, s := range st.Events {
+		ind, err := at(s.Ind)
+		if err != nil {
+			return err
+		}
+		ev := &scheduledEvent{time: s.Time, seq: s.Seq, kind: s.Kind, ind: ind}
+		switch s.Infection {
+		case eventInfection:
+			if ind == nil || ind.infection == nil {
+				return fmt.Errorf("event for individual %d: no current infection", s.Ind)
+			}
+			ev.infection = ind.infection
+		case eventStaleInfection:
+			ev.infection = &Infection{disease: env.disease, chain: -1}
+		}
+		// Saved in heap order, so the slice is already a valid heap
+		env.events.items = append(env.events.items, ev)
+	}
+
+	env.day = st.Day
+	env.socialDistanceThreshold = e.SocialDistanceThreshold
+	env.hygieneLevel = e.HygieneLevel
+	env.mobilityRate = e.MobilityRate
+	env.vaccinationRate = e.VaccinationRate
+	env.medicalCareLevel = e.MedicalCareLevel
+
+	env.burden = diseaseBurden{
+		deaths:      e.BurdenDeaths,
+		yll:         e.BurdenYLL,
+		illnessDays: e.BurdenIllnessDays,
+		recoveries:  e.BurdenRecoveries,
+	}
+	env.npi = npiTally{evals: e.NPIEvals}
+	copy(env.npi.pressure[:], e.NPIPressure)
+	env.npi.factors = npiFactors{e.NPIFactors[0], e.NPIFactors[1], e.NPIFactors[2]}
+	env.fastForward.quiet, env.fastForward.days = e.FastForwardQuiet, e.FastForwardDays
+
+	env.vaccineStock = vaccineStock{
+		wasted:           e.DosesWasted,
+		administered:     e.DosesGiven,
+		restockedThrough: e.RestockedThrough,
+	}
+	for _, lot := range e.DoseLots {
+		env.vaccineStock.lots = append(env.vaccineStock.lots, doseLot{doses: lot[0], expires: lot[1]})
+	}
+	env.sideEffects.episodes = e.SideEffectEpisodes
+	if s := env.stockpile; s != nil {
+		s.dosesGiven, s.coursesUsed, s.depletedDay = e.Stockpile[0], e.Stockpile[1], e.Stockpile[2]
+	}
+	env.hygieneSupply.stock = e.HygieneStock
+	env.hygieneSupply.ratio = e.HygieneRatio
+	env.hygieneSupply.shortageDays = e.HygieneShortageDays
+
+	env.quarantine.detected = e.Detected
+	env.quarantine.quarantined = e.Quarantined
+	env.quarantine.personDays = e.QuarantineDays
+	env.quarantine.tested = e.Tested
+	env.quarantine.testedInfected = e.TestedInfected
+	env.quarantine.falseNegatives = e.FalseNegatives
+	env.quarantine.today = e.DetectionToday
+	env.quarantine.detectedToday = e.DetectedToday
+	env.quarantine.testedToday = e.TestedToday
+	env.tracing.notified = e.TraceNotified
+	env.tracing.isolated = e.TraceIsolated
+	env.tracing.infectedAtNotice = e.TraceInfected
+	env.tracing.today = e.TraceToday
+	env.tracing.notifiedToday = e.TraceNotifiedToday
+	env.tracing.isolatedToday = e.TraceIsolatedToday
+	env.regionInfections = e.RegionInfections
+	env.lockdown.active = e.LockdownActive
+	env.lockdown.since = e.LockdownSince
+	env.lockdown.count = e.Lockdowns
+	env.lockdown.days = e.LockdownDays
+	env.information.pending = e.ReportsPending
+	for k, c := range e.Cells {
+		p := &env.cells[k]
+		p.sdThreshold = c.SDThreshold
+		p.lockdown.active, p.lockdown.since, p.lockdown.count, p.lockdown.days = c.Locked, c.Since, c.Lockdowns, c.LockedFor
+		p.pending = c.Pending
+	}
+	if c := env.chains; c != nil {
+		c.events = c.events[:0]
+		for _, t := range e.Transmissions {
+			c.events = append(c.events, transmission{day: t[0], infectee: t[1], parent: t[2], generation: t[3], offspring: t[4]})
+		}
+	}
+
+	env.pathogenTotals = make([]pathogenTotals, len(e.PathogenDeaths))
+	for k := range env.pathogenTotals {
+		env.pathogenTotals[k] = pathogenTotals{infections: e.PathogenInfections[k], deaths: e.PathogenDeaths[k]}
+	}
+
+	h := &env.hospital
+	var err error
+	if h.ward, err = all(e.Ward); err != nil {
+		return err
+	}
+	if h.icu, err = all(e.ICU); err != nil {
+		return err
+	}
+	if h.wardQueue, err = all(e.WardQueue); err != nil {
+		return err
+	}
+	if h.icuQueue, err = all(e.ICUQueue); err != nil {
+		return err
+	}
+	if h.denied, err = all(e.Denied); err != nil {
+		return err
+	}
+	for i, t := range e.CareByAge {
+		if i < len(h.byAge) {
+			h.byAge[i] = careTally{t[0], t[1], t[2], t[3], t[4]}
+		}
+	}
+	h.admissions, h.waitDays = e.Admissions, e.WaitDays
+	h.deathsWaiting, h.attributableDeaths = e.DeathsWaiting, e.AttributableDeaths
+
+	if a := env.assimilation; a != nil {
+		a.days, a.imposed, a.expected, a.seeded = e.AssimilatedDays, e.Imposed, e.Expected, e.Seeded
+	}
+	if t := env.flows; t != nil {
+		if e.FlowStart == ([numFlowNodes]int{}) {
+			t.begin(env) // saved without flowDiagram: the flows start here
+		} else {
+			t.start, t.moves, t.reinfections = e.FlowStart, e.FlowMoves, e.FlowReinfections
+			t.remember(env)
+		}
+	}
+
+	// Derived from the old population
+	env.vehicles = env.vehicles[:0]
+	env.index = nil
+	env.renderSet = nil
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import "math"
+
+// clusterSampleSize caps how many infected individuals the clustering
+// statistics measure in largePopulation mode; the nearest neighbor of each
+// sampled individual is still searched among all infected.
+const clusterSampleSize = 10000
+
+// infectionClustering measures how spatially clustered the given infected
+// individuals (today's infections) are.
+// Returns:
+//
+//	meanNN: mean distance from each infected individual to the nearest other infected individual
+//	clarkEvans: meanNN divided by its expectation for the same number of points placed
+//	            uniformly at random over the area (0.5*sqrt(area/n)). Values < 1 mean
+//	            infections are clustered, ~1 random, > 1 dispersed.
+//
+// Both are 0 when fewer than two individuals are infected.
+//
+// Nearest neighbors are found with a k-d tree over the infected. In
+// largePopulation mode the mean is estimated from an evenly spaced sample of
+// at most clusterSampleSize of them. With regions, nearest neighbors are
+// searched within each region (those alone in their region are not
+// measured), and the area is that of all regions.
+func infectionClustering(env *Environment, infected []*Individual) (meanNN float64, clarkEvans float64) {
+	n := len(infected)
+	if n < 2 {
+		return 0, 0
+	}
+
+	groups := [][]*Individual{infected}
+	if len(env.regions) > 1 {
+		groups = make([][]*Individual, len(env.regions))
+		for _, ind := range infected {
+			groups[ind.region] = append(groups[ind.region], ind)
+		}
+	}
+	step := 1
+	if env.largePopulation && n > clusterSampleSize {
+		step = (n + clusterSampleSize - 1) / clusterSampleSize
+	}
+	sum, measured := 0.0, 0
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		tree := buildKDTree(group)
+		for i := 0; i < len(group); i += step {
+			sum += tree.nearest(group[i].position, group[i])
+			measured++
+		}
+	}
+	if measured == 0 {
+		return 0, 0
+	}
+	meanNN = sum / float64(measured)
+
+	area := env.areaSize * env.areaSize * float64(max(len(env.regions), 1))
+	expected := 0.5 * math.Sqrt(area/float64(n))
+	if expected > 0 {
+		clarkEvans = meanNN / expected
+	}
+	return meanNN, clarkEvans
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// Output compression. Agent-level outputs of a big run (the transmission log,
+// tracked agents, exposure risk, snapshots, saved states) easily reach
+// gigabytes. Any of them whose file name ends in .gz is written through gzip:
+// statsFilename = stats.csv.gz compresses the stats file, and with
+// compressOutputs = true the outputs with fixed names get the suffix too
+// (transmissions.csv.gz, snapshots.gob.gz, state_day60.gob.gz, ...). The
+// render subcommand and -resume read compressed files the same way.
+
+// gzipSuffix marks a compressed file.
+const gzipSuffix = ".gz"
+
+// outputName returns the name a fixed-name output is written under: name,
+// with gzipSuffix if env compresses its outputs.
+func (env *Environment) outputName(name string) string {
+	if env.compressOutputs && !strings.HasSuffix(name, gzipSuffix) {
+		return name + gzipSuffix
+	}
+	return name
+}
+
+// outputFile is a buffered output file, gzip-compressed if its name ends in
+// gzipSuffix.
+type outputFile struct {
+	*bufio.Writer
+	f  *os.File
+	gz *gzip.Writer
+}
+
+// createOutput creates path for writing.
+func createOutput(path string) (*outputFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	o := &outputFile{f: f}
+	if strings.HasSuffix(path, gzipSuffix) {
+		o.gz = gzip.NewWriter(f)
+		o.Writer = bufio.NewWriter(o.gz)
+	} else {
+		o.Writer = bufio.NewWriter(f)
+	}
+	return o, nil
+}
+
+// Flush writes out the buffered data. A compressed file is flushed too, so
+// what was written so far can be decompressed if the run dies.
+func (o *outputFile) Flush() error {
+	if err := o.Writer.Flush(); err != nil {
+		return err
+	}
+	if o.gz != nil {
+		return o.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes the data, ends the compressed stream and closes the file,
+// returning the first error.
+func (o *outputFile) Close() error {
+	err := o.Writer.Flush()
+	if o.gz != nil {
+		if gerr := o.gz.Close(); err == nil {
+			err = gerr
+		}
+	}
+	if cerr := o.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// inputFile is a file opened by openInput.
+type inputFile struct {
+	io.Reader
+	f  *os.File
+	gz *gzip.Reader
+}
+
+// openInput opens path for buffered reading, decompressing it if its name
+// ends in gzipSuffix.
+func openInput(path string) (*inputFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	in := &inputFile{f: f}
+	if !strings.HasSuffix(path, gzipSuffix) {
+		in.Reader = bufio.NewReader(f)
+		return in, nil
+	}
+	in.gz, err = gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	in.Reader = in.gz
+	return in, nil
+}
+
+// Close closes the file.
+func (in *inputFile) Close() error {
+	if in.gz != nil {
+		in.gz.Close()
+	}
+	return in.f.Close()
+}
@@ -0,0 +1,992 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config schema.
+//
+// Every config parameter is described once here: its name, type, units, valid
+// range and description. The schema drives validation in loadConfigFromFile
+// and generates the -help-config text, and -config-schema prints it as JSON,
+// so a new parameter only needs one entry to be parsed, checked and documented.
+// Checks that involve several parameters stay in loadConfigFromFile.
+
+// paramKind is the value type of a config parameter.
+type paramKind string
+
+const (
+	KindFloat    paramKind = "float64"
+	KindInt      paramKind = "int"
+	KindBool     paramKind = "bool"
+	KindString   paramKind = "string"
+	KindChoice   paramKind = "choice" // one of Choices, case-insensitive
+	KindDate     paramKind = "date"   // YYYY-MM-DD
+	KindFilename paramKind = "filename"
+)
+
+// ParamSpec describes one config parameter.
+//
+// If Suffix is set, Name is a prefix and the parameter is written as
+// Name.SUFFIX (e.g. tag.NAME, annotation.DAY); the suffix is checked
+// according to its kind: "NAME" (letters, digits, '_'), "DAY" (0 - 10,000)
+// or "AGE" (0 - 120).
+//
+// If Block is set, the parameter is written inside a [Block.NAME] section
+// (e.g. [disease.flu]) and NAME is passed to set as the suffix. A block
+// parameter with Suffix "REGION" is written Name.REGION inside the block
+// (e.g. travel.south) and "NAME.REGION" is passed instead.
+type ParamSpec struct {
+	Name        string    `json:"name"`
+	Suffix      string    `json:"suffix,omitempty"`
+	Block       string    `json:"block,omitempty"`
+	Section     string    `json:"section"`
+	Kind        paramKind `json:"type"`
+	Units       string    `json:"units,omitempty"`
+	Min         float64   `json:"min"`
+	Max         float64   `json:"max"`
+	Exclusive   bool      `json:"exclusive,omitempty"` // float range excludes both ends
+	MaxLen      int       `json:"maxLength,omitempty"` // strings
+	Choices     []string  `json:"choices,omitempty"`
+	Default     string    `json:"default,omitempty"`
+	Description string    `json:"description"`
+
+	// set stores a parsed value. Its type matches Kind: func(*Config, float64),
+	// func(*Config, int), func(*Config, bool), func(*Config, string) or
+	// func(*Config, time.Time). Parameters with a Suffix take the suffix first:
+	// func(*Config, string, float64), etc.
+	set any
+}
+
+// configSections lists the help sections in display order.
+var configSections = []string{
+	"DISEASE",
+	"[disease.NAME] BLOCK",
+	"[region.NAME] BLOCK",
+	"POPULATION",
+	"ENVIRONMENT",
+	"POLICY",
+	"MOVEMENT",
+	"TAG",
+	"PROFILE",
+	"SIMULATION",
+	"VACCINATION CAMPAIGN",
+	"STOCKPILE",
+	"ANNOTATIONS",
+	"HEALTH ECONOMICS",
+	"VISUALIZATION",
+}
+
+var stepDistributionChoices = []string{string(StepDisk), string(StepUniform), string(StepExponential), string(StepLevy)}
+
+// stepDistributionSetter stores the step distribution for one move type.
+func stepDistributionSetter(mt moveType) func(*Config, string) {
+	return func(c *Config, v string) { c.stepDistributions[mt] = stepDistribution(v) }
+}
+
+// configSchema is the list of all config parameters.
+var configSchema = []*ParamSpec{
+	// Disease
+	{Name: "diseaseName", Section: "DISEASE", Kind: KindString, MaxLen: 100, Default: "DemoDisease",
+		Description: "Name of the disease",
+		set:         func(c *Config, v string) { c.diseaseName = v }},
+	{Name: "transmissionRate", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0.8",
+		Description: "Chance of transmission per contact at distance 0",
+		set:         func(c *Config, v float64) { c.transmissionRate = v }},
+	{Name: "transmissionDistance", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 100, Exclusive: true, Units: "units", Default: "2.0",
+		Description: "Distance scale of transmission; must not exceed areaSize",
+		set:         func(c *Config, v float64) { c.transmissionDistance = v }},
+	{Name: "transmissionKernel", Section: "DISEASE", Kind: KindChoice, Default: string(KernelExponential),
+		Choices:     []string{string(KernelExponential), string(KernelGaussian), string(KernelInverseSquare), string(KernelStep)},
+		Description: "Distance decay of transmission, with transmissionDistance as its scale; applies to every pathogen",
+		set:         func(c *Config, v string) { c.transmissionKernel = kernelKind(v) }},
+	{Name: "recoveryRate", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "probability per day", Default: "0.05",
+		Description: "Base daily chance of recovery",
+		set:         func(c *Config, v float64) { c.recoveryRate = v }},
+	{Name: "mortalityRate", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0.01",
+		Description: "Base daily chance of death while infected",
+		set:         func(c *Config, v float64) { c.mortalityRate = v }},
+	{Name: "latentPeriod", Section: "DISEASE", Kind: KindInt, Min: 0, Max: 365, Units: "days", Default: "3",
+		Description: "Days from exposure to infectiousness; only used with seir = true",
+		set:         func(c *Config, v int) { c.latentPeriod = v }},
+	{Name: "seir", Section: "DISEASE", Kind: KindBool, Default: "false",
+		Description: "New infections are Exposed (infected but not yet infectious) for latentPeriod days before becoming Infected",
+		set:         func(c *Config, v bool) { c.seir = v }},
+	{Name: "immunityWaning", Section: "DISEASE", Kind: KindChoice, Default: string(WaningHealthy),
+		Choices:     []string{string(WaningHealthy), string(WaningSusceptible)},
+		Description: "State Recovered individuals return to when immunity wanes: healthy (must be exposed again) or susceptible (SIRS)",
+		set:         func(c *Config, v string) { c.immunityWaning = immunityWaning(v) }},
+	{Name: "ifrByAge", Section: "DISEASE", Kind: KindBool, Default: "false",
+		Description: "Derive the daily death probability from an age-banded infection fatality ratio (ifr.AGE) instead of mortalityRate",
+		set:         func(c *Config, v bool) { c.ifrByAge = v }},
+	{Name: "ifr", Suffix: "AGE", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "share of infections",
+		Description: "With ifrByAge, the IFR from AGE up to the next listed age; any entry replaces the default COVID-19 table",
+		set:         func(c *Config, age string, v float64) { c.ifr[mustAtoi(age)] = v }},
+	{Name: "ageCurvesFile", Section: "DISEASE", Kind: KindString, MaxLen: 500,
+		Description: `CSV with an "age, ..." header and columns susceptibility, contacts, severity, mortality, recovery and/or ifr; interpolated by age in place of the built-in age buckets`,
+		set:         func(c *Config, v string) { c.ageCurvesFile = v }},
+	{Name: "infectiousPeriod", Section: "DISEASE", Kind: KindInt, Min: 1, Max: 365, Units: "days", Default: "10",
+		Description: "Days an individual remains infectious",
+		set:         func(c *Config, v int) { c.infectiousPeriod = v }},
+	{Name: "immunityDuration", Section: "DISEASE", Kind: KindInt, Min: 0, Max: 3650, Units: "days", Default: "90",
+		Description: "Days immunity lasts after recovery, 0 = no immunity",
+		set:         func(c *Config, v int) { c.immunityDuration = v }},
+	{Name: "severeFraction", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "share of cases", Default: "0.15",
+		Description: "Cases needing a ward bed; severeFraction + criticalFraction <= 1.0",
+		set:         func(c *Config, v float64) { c.severeFraction = v }},
+	{Name: "criticalFraction", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "share of cases", Default: "0.05",
+		Description: "Cases needing an ICU bed",
+		set:         func(c *Config, v float64) { c.criticalFraction = v }},
+	{Name: "maxInfectionDays", Section: "DISEASE", Kind: KindInt, Min: 0, Max: 3650, Units: "days", Default: "365",
+		Description: "Infections still unresolved by then end in recovery or death, 0 = no cap",
+		set:         func(c *Config, v int) { c.maxInfectionDays = v }},
+	{Name: "infectiousnessDispersion", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 100, Default: "0",
+		Description: "Shape k of a gamma-distributed infectiousness per infection (mean 1); small k concentrates transmission in superspreaders, 0 = everyone alike",
+		set:         func(c *Config, v float64) { c.dispersion = v }},
+	{Name: "immunityBoosting", Section: "DISEASE", Kind: KindBool, Default: "false",
+		Description: "Exposures that do not infect boost immunity against infection and against losing post-recovery immunity",
+		set:         func(c *Config, v bool) { c.boosting.enabled = v }},
+	{Name: "boostPerExposure", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "protection", Default: "0.02",
+		Description: "Boost added per non-infecting exposure",
+		set:         func(c *Config, v float64) { c.boosting.perExposure = v }},
+	{Name: "boostMax", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 1, Units: "protection", Default: "0.5",
+		Description: "Highest boost an individual can reach",
+		set:         func(c *Config, v float64) { c.boosting.max = v }},
+	{Name: "boostHalfLife", Section: "DISEASE", Kind: KindFloat, Min: 0, Max: 3650, Exclusive: true, Units: "days", Default: "60",
+		Description: "Days for the boost to halve without new exposure",
+		set:         func(c *Config, v float64) { c.boosting.halfLife = v }},
+
+	// [disease.NAME] blocks: co-circulating pathogens
+	{Name: "transmissionRate", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0.8",
+		Description: "Chance of transmission per contact at distance 0",
+		set:         func(c *Config, name string, v float64) { c.pathogen(name).disease.transmissionRate = v }},
+	{Name: "transmissionDistance", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindFloat, Min: 0, Max: 100, Exclusive: true, Units: "units", Default: "2.0",
+		Description: "Distance scale of transmission; must not exceed areaSize",
+		set:         func(c *Config, name string, v float64) { c.pathogen(name).disease.transmissionDistance = v }},
+	{Name: "recoveryRate", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindFloat, Min: 0, Max: 1, Units: "probability per day", Default: "0.05",
+		Description: "Base daily chance of recovery",
+		set:         func(c *Config, name string, v float64) { c.pathogen(name).disease.recoveryRate = v }},
+	{Name: "mortalityRate", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0.01",
+		Description: "Base daily chance of death while infected",
+		set:         func(c *Config, name string, v float64) { c.pathogen(name).disease.mortalityRate = v }},
+	{Name: "infectiousPeriod", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindInt, Min: 1, Max: 365, Units: "days", Default: "10",
+		Description: "Infections still unresolved after this many days end in recovery",
+		set:         func(c *Config, name string, v int) { c.pathogen(name).disease.infectiousPeriod = v }},
+	{Name: "immunityDuration", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindInt, Min: 0, Max: 3650, Units: "days", Default: "90",
+		Description: "Days immunity lasts after recovery, 0 = no immunity",
+		set:         func(c *Config, name string, v int) { c.pathogen(name).disease.immunityDuration = v }},
+	{Name: "initialInfected", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindInt, Min: 0, Max: maxLargePopulation, Units: "individuals", Default: "10",
+		Description: "Individuals infected with this pathogen on startDay; must not exceed popSize",
+		set:         func(c *Config, name string, v int) { c.pathogen(name).initialInfected = v }},
+	{Name: "startDay", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindInt, Min: 0, Max: 100000, Units: "days", Default: "0",
+		Description: "Day the pathogen is introduced into the running epidemic; must not exceed numDays",
+		set:         func(c *Config, name string, v int) { c.pathogen(name).startDay = v }},
+	{Name: "crossImmunity", Block: "disease", Suffix: "NAME", Section: "[disease.NAME] BLOCK", Kind: KindFloat, Min: 0, Max: 1, Units: "protection", Default: "0",
+		Description: "Protection against this pathogen of individuals ever infected with the main disease, e.g. for a variant",
+		set:         func(c *Config, name string, v float64) { c.pathogen(name).crossImmunity = v }},
+
+	// [region.NAME] blocks
+	{Name: "population", Block: "region", Suffix: "NAME", Section: "[region.NAME] BLOCK", Kind: KindFloat, Min: 0, Max: 1000000, Units: "relative weight", Default: "1",
+		Description: "Size of the region's population relative to the other regions; popSize is split over the regions in proportion",
+		set:         func(c *Config, name string, v float64) { c.region(name).weight = v }},
+	{Name: "initialInfected", Block: "region", Suffix: "NAME", Section: "[region.NAME] BLOCK", Kind: KindInt, Min: 0, Max: maxLargePopulation, Units: "individuals", Default: "0",
+		Description: "Infections seeded among the region's residents at the start, on top of the global initialInfected",
+		set:         func(c *Config, name string, v int) { c.region(name).initialInfected = v }},
+	{Name: "travel", Block: "region", Suffix: "REGION", Section: "[region.NAME] BLOCK", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0",
+		Description: "Chance that a train or flight trip from this region ends in region REGION",
+		set:         func(c *Config, names string, v float64) { c.setTravel(names, v) }},
+
+	// Population
+	{Name: "popSize", Section: "POPULATION", Kind: KindInt, Min: 1, Max: maxLargePopulation, Units: "individuals", Default: "1000",
+		Description: "Population size; above 1,000,000 requires largePopulation = true",
+		set:         func(c *Config, v int) { c.popSize = v }},
+	{Name: "initialInfected", Section: "POPULATION", Kind: KindInt, Min: 0, Max: maxLargePopulation, Units: "individuals", Default: "10",
+		Description: "Infections seeded on day 0; must not exceed popSize",
+		set:         func(c *Config, v int) { c.initialInfected = v }},
+	{Name: "hygieneDistribution", Section: "POPULATION", Kind: KindChoice, Choices: behaviorDistributionChoices, Default: string(BehaviorUniform),
+		Description: "How each individual's initial hygiene level is drawn: uniform on [0, 1], beta (hygieneBetaA, hygieneBetaB) or constant (hygieneConstant)",
+		set:         func(c *Config, v string) { c.hygieneInit.kind = behaviorDistributionKind(v) }},
+	{Name: "hygieneBetaA", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 1000, Exclusive: true, Default: "2",
+		Description: "First Beta shape parameter of the initial hygiene level",
+		set:         func(c *Config, v float64) { c.hygieneInit.a = v }},
+	{Name: "hygieneBetaB", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 1000, Exclusive: true, Default: "2",
+		Description: "Second Beta shape parameter of the initial hygiene level",
+		set:         func(c *Config, v float64) { c.hygieneInit.b = v }},
+	{Name: "hygieneConstant", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 1, Units: "level", Default: "0.5",
+		Description: "Initial hygiene level of everyone when hygieneDistribution = constant",
+		set:         func(c *Config, v float64) { c.hygieneInit.value = v }},
+	{Name: "complianceDistribution", Section: "POPULATION", Kind: KindChoice, Choices: behaviorDistributionChoices, Default: string(BehaviorUniform),
+		Description: "How each individual's initial social distance compliance is drawn: uniform on [0, 1], beta (complianceBetaA, complianceBetaB) or constant (complianceConstant)",
+		set:         func(c *Config, v string) { c.complianceInit.kind = behaviorDistributionKind(v) }},
+	{Name: "complianceBetaA", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 1000, Exclusive: true, Default: "2",
+		Description: "First Beta shape parameter of the initial compliance",
+		set:         func(c *Config, v float64) { c.complianceInit.a = v }},
+	{Name: "complianceBetaB", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 1000, Exclusive: true, Default: "2",
+		Description: "Second Beta shape parameter of the initial compliance",
+		set:         func(c *Config, v float64) { c.complianceInit.b = v }},
+	{Name: "complianceConstant", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 1, Units: "level", Default: "0.5",
+		Description: "Initial compliance of everyone when complianceDistribution = constant",
+		set:         func(c *Config, v float64) { c.complianceInit.value = v }},
+	{Name: "householdSizeMean", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 20, Units: "people", Default: "0",
+		Description: "Mean household size; individuals live in households and set out from home every day, 0 = no households",
+		set:         func(c *Config, v float64) { c.households.sizeMean = v }},
+	{Name: "householdTransmissionMultiplier", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 100, Default: "2",
+		Description: "Per-contact transmission multiplier between housemates, who meet every night",
+		set:         func(c *Config, v float64) { c.households.transmission = v }},
+	{Name: "householdCorrelation", Section: "POPULATION", Kind: KindFloat, Min: 0, Max: 1, Units: "correlation", Default: "0",
+		Description: "Correlation of housemates' vaccine acceptance and quarantine compliance (household random effect), 0 = independent",
+		set:         func(c *Config, v float64) { c.households.correlation = v }},
+
+	// Environment
+	{Name: "areaSize", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 10000, Exclusive: true, Units: "units", Default: "100.0",
+		Description: "Side length of the square simulation area",
+		set:         func(c *Config, v float64) { c.areaSize = v }},
+	{Name: "socialDistanceThreshold", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 100, Units: "units", Default: "2.0",
+		Description: "Initial social distance requirement; must not exceed areaSize",
+		set:         func(c *Config, v float64) { c.socialDistanceThreshold = v }},
+	{Name: "hygieneLevel", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 1, Default: "0.1",
+		Description: "Initial environmental hygiene level",
+		set:         func(c *Config, v float64) { c.hygieneLevel = v }},
+	{Name: "hygieneSupply", Section: "ENVIRONMENT", Kind: KindBool, Default: "false",
+		Description: "Hygiene uses up a finite supply stock; during shortages individual hygiene gains are scaled down",
+		set:         func(c *Config, v bool) { c.hygieneSupply.enabled = v }},
+	{Name: "hygieneStockDays", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 3650, Units: "days of full use", Default: "30",
+		Description: "With hygieneSupply, initial stock: days the whole population could practice full hygiene",
+		set:         func(c *Config, v float64) { c.hygieneSupply.stockDays = v }},
+	{Name: "hygieneRestockShare", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 10, Units: "share of full daily use", Default: "0.3",
+		Description: "With hygieneSupply, daily delivery relative to the whole population practicing full hygiene",
+		set:         func(c *Config, v float64) { c.hygieneSupply.restockShare = v }},
+	{Name: "mobilityRate", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 10, Default: "1.0",
+		Description: "Population mobility",
+		set:         func(c *Config, v float64) { c.mobilityRate = v }},
+	{Name: "vaccinationRate", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 1, Units: "share of population", Default: "0.2",
+		Description: "Vaccination coverage target",
+		set:         func(c *Config, v float64) { c.vaccinationRate = v }},
+	{Name: "medicalCareLevel", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 1, Default: "0.7",
+		Description: "Quality of medical care",
+		set:         func(c *Config, v float64) { c.medicalCareLevel = v }},
+	{Name: "medicalCapacity", Section: "ENVIRONMENT", Kind: KindInt, Min: 0, Max: maxLargePopulation, Units: "beds", Default: "0",
+		Description: "Total hospital beds (ward + ICU), 0 = 10% of popSize; must not exceed popSize",
+		set:         func(c *Config, v int) { c.medicalCapacity = v }},
+	{Name: "icuCapacity", Section: "ENVIRONMENT", Kind: KindInt, Min: -1, Max: maxLargePopulation, Units: "beds", Default: "-1",
+		Description: "ICU beds out of medicalCapacity, -1 = 20% of beds, 0 = no ICU",
+		set:         func(c *Config, v int) { c.icuCapacity = v }},
+	{Name: "hospitalQueue", Section: "ENVIRONMENT", Kind: KindBool, Default: "false",
+		Description: "Assign beds to patients first come, first served; only those still waiting get higher mortality",
+		set:         func(c *Config, v bool) { c.hospitalQueue.enabled = v }},
+	{Name: "wardWaitingMortality", Section: "ENVIRONMENT", Kind: KindFloat, Min: 1, Max: 20, Units: "multiplier", Default: "2.0",
+		Description: "With hospitalQueue, daily mortality multiplier while waiting for a ward bed",
+		set:         func(c *Config, v float64) { c.hospitalQueue.wardWaitingMult = v }},
+	{Name: "icuWaitingMortality", Section: "ENVIRONMENT", Kind: KindFloat, Min: 1, Max: 20, Units: "multiplier", Default: "3.0",
+		Description: "With hospitalQueue, daily mortality multiplier while waiting for an ICU bed",
+		set:         func(c *Config, v float64) { c.hospitalQueue.icuWaitingMult = v }},
+	{Name: "admissionSeverity", Section: "ENVIRONMENT", Kind: KindChoice, Default: "severe",
+		Choices:     []string{"severe", "critical"},
+		Description: "With hospitalQueue, least severe case given a bed; critical = Severe cases get no ward bed",
+		set:         func(c *Config, v string) { c.hospitalQueue.criteria.minSeverity = admissionSeverities[v] }},
+	{Name: "admissionMaxAge", Section: "ENVIRONMENT", Kind: KindInt, Min: 0, Max: 120, Units: "years", Default: "0",
+		Description: "With hospitalQueue, patients older than this get no bed, 0 = no limit",
+		set:         func(c *Config, v int) { c.hospitalQueue.criteria.maxAge = v }},
+	{Name: "icuMaxAge", Section: "ENVIRONMENT", Kind: KindInt, Min: 0, Max: 120, Units: "years", Default: "0",
+		Description: "With hospitalQueue, patients older than this get no ICU bed, 0 = no limit",
+		set:         func(c *Config, v int) { c.hospitalQueue.criteria.icuMaxAge = v }},
+	{Name: "comorbidityTag", Section: "ENVIRONMENT", Kind: KindString, MaxLen: 100,
+		Description: "Population tag marking individuals with comorbidities, for icuExcludeComorbid",
+		set:         func(c *Config, v string) { c.hospitalQueue.criteria.comorbidityTag = v }},
+	{Name: "icuExcludeComorbid", Section: "ENVIRONMENT", Kind: KindBool, Default: "false",
+		Description: "With hospitalQueue, patients carrying comorbidityTag get no ICU bed",
+		set:         func(c *Config, v bool) { c.hospitalQueue.criteria.icuExcludeComorbid = v }},
+	{Name: "icuAllocation", Section: "ENVIRONMENT", Kind: KindChoice, Default: string(ICUFirstCome),
+		Choices:     []string{string(ICUFirstCome), string(ICUYoungest)},
+		Description: "With hospitalQueue, order of free ICU beds: longest waiting first, or youngest first",
+		set:         func(c *Config, v string) { c.hospitalQueue.criteria.icuAllocation = icuAllocation(v) }},
+	{Name: "backgroundMortality", Section: "ENVIRONMENT", Kind: KindBool, Default: "false",
+		Description: "Also let people die of other causes (Gompertz law by age), and report excess mortality against a no-disease baseline",
+		set:         func(c *Config, v bool) { c.backgroundMortality.enabled = v }},
+	{Name: "backgroundMortalityA", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 1, Units: "per year", Default: "0.00005",
+		Description: "Yearly hazard of dying of other causes at age 0",
+		set:         func(c *Config, v float64) { c.backgroundMortality.a = v }},
+	{Name: "backgroundMortalityB", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 1, Units: "per year of age", Default: "0.085",
+		Description: "Growth rate of the background hazard with age (the hazard doubles every ln2/B years)",
+		set:         func(c *Config, v float64) { c.backgroundMortality.b = v }},
+	{Name: "weatherFile", Section: "ENVIRONMENT", Kind: KindString, MaxLen: 500,
+		Description: `CSV with "day, transmission, activity" per line (day number, or YYYY-MM-DD with startDate); scales transmissionRate and the daily chance of going out`,
+		set:         func(c *Config, v string) { c.weatherFile = v }},
+	{Name: "incidenceFile", Section: "ENVIRONMENT", Kind: KindString, MaxLen: 500,
+		Description: `CSV with "day, cases" per line (day number, or YYYY-MM-DD with startDate); new infections are forced to match it for the first assimilationDays days`,
+		set:         func(c *Config, v string) { c.incidenceFile = v }},
+	{Name: "incidenceScale", Section: "ENVIRONMENT", Kind: KindFloat, Min: 0, Max: 1e6, Units: "infections per case", Default: "1",
+		Description: "Multiplies every count in incidenceFile, e.g. popSize over the real population, divided by the share of infections reported",
+		set:         func(c *Config, v float64) { c.incidenceScale = v }},
+	{Name: "assimilationDays", Section: "ENVIRONMENT", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "Days that follow incidenceFile before the model runs freely, 0 = up to the last listed day",
+		set:         func(c *Config, v int) { c.assimilationDays = v }},
+
+	// Policy
+	{Name: "distancingTrigger", Section: "POLICY", Kind: KindChoice, Default: string(TriggerPrevalence),
+		Choices:     []string{string(TriggerPrevalence), string(TriggerHospital), string(TriggerICU)},
+		Description: "Signal driving the social distance policy",
+		set:         func(c *Config, v string) { c.distancingTrigger = triggerMetric(v) }},
+	{Name: "informationSource", Section: "POLICY", Kind: KindChoice, Default: string(InformationTrue),
+		Choices:     []string{string(InformationTrue), string(InformationReported), string(InformationDetected)},
+		Description: "Infected fraction policy reacts to: true, reported (true x ascertainmentRate) or detected (detectionRate/testingRate finds)",
+		set:         func(c *Config, v string) { c.information.source = informationSource(v) }},
+	{Name: "ascertainmentRate", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "fraction", Default: "1",
+		Description: "Share of infections in the case counts, with informationSource = reported",
+		set:         func(c *Config, v float64) { c.information.ascertainment = v }},
+	{Name: "reportingDelay", Section: "POLICY", Kind: KindInt, Min: 0, Max: 60, Units: "days", Default: "0",
+		Description: "Days before reported or detected counts reach policy",
+		set:         func(c *Config, v int) { c.information.delay = v }},
+
+	// Movement
+	{Name: "walkStepDistribution", Section: "MOVEMENT", Kind: KindChoice, Choices: stepDistributionChoices, Default: string(StepDisk),
+		Description: "Step length law for Walk moves",
+		set:         stepDistributionSetter(Walk)},
+	{Name: "trainStepDistribution", Section: "MOVEMENT", Kind: KindChoice, Choices: stepDistributionChoices, Default: string(StepDisk),
+		Description: "Step length law for Train moves",
+		set:         stepDistributionSetter(Train)},
+	{Name: "flightStepDistribution", Section: "MOVEMENT", Kind: KindChoice, Choices: stepDistributionChoices, Default: string(StepDisk),
+		Description: "Step length law for Flight moves",
+		set:         stepDistributionSetter(Flight)},
+	{Name: "levyExponent", Section: "MOVEMENT", Kind: KindFloat, Min: 0, Max: 5, Exclusive: true, Default: "1.5",
+		Description: "Tail exponent of the levy step law",
+		set:         func(c *Config, v float64) { c.levyExponent = v }},
+	{Name: "roadNetworkFile", Section: "MOVEMENT", Kind: KindString, MaxLen: 500,
+		Description: `Edge list with "x1, y1, x2, y2" per line (coordinates within areaSize); if set, everyone lives on and moves along the roads`,
+		set:         func(c *Config, v string) { c.roadNetworkFile = v }},
+	{Name: "transitVehicles", Section: "MOVEMENT", Kind: KindBool, Default: "false",
+		Description: "Group train/flight travelers into vehicles whose passengers are all in contact",
+		set:         func(c *Config, v bool) { c.transit.enabled = v }},
+	{Name: "trainCapacity", Section: "MOVEMENT", Kind: KindInt, Min: 1, Max: 10000, Units: "passengers", Default: "100",
+		Description: "Passengers per train",
+		set:         func(c *Config, v int) { c.transit.trainCapacity = v }},
+	{Name: "flightCapacity", Section: "MOVEMENT", Kind: KindInt, Min: 1, Max: 1000, Units: "passengers", Default: "150",
+		Description: "Passengers per flight",
+		set:         func(c *Config, v int) { c.transit.flightCapacity = v }},
+	{Name: "vehicleContactFactor", Section: "MOVEMENT", Kind: KindFloat, Min: 0, Max: 1, Default: "0.05",
+		Description: "On-board per-contact transmission, relative to a contact at distance 0",
+		set:         func(c *Config, v float64) { c.transit.contactFactor = v }},
+
+	// Tags
+	{Name: "tag", Suffix: "NAME", Section: "TAG", Kind: KindFloat, Min: 0, Max: 1, Units: "share of population",
+		Description: "Fraction of the population carrying tag NAME (e.g. tag.healthcare_worker)",
+		set:         func(c *Config, name string, v float64) { c.tagSpec(name).fraction = v }},
+	{Name: "tagExposure", Suffix: "NAME", Section: "TAG", Kind: KindFloat, Min: 0, Max: 10, Default: "1.0",
+		Description: "Exposure multiplier for tag NAME",
+		set:         func(c *Config, name string, v float64) { c.tagSpec(name).exposureMult = v }},
+	{Name: "tagVaccinationPriority", Section: "TAG", Suffix: "NAME", Kind: KindFloat, Min: 0, Max: 100, Default: "1.0",
+		Description: "Vaccine rollout weight for tag NAME",
+		set:         func(c *Config, name string, v float64) { c.tagSpec(name).vaccinationPriority = v }},
+	{Name: "stratifyByTag", Section: "TAG", Kind: KindBool, Default: "false",
+		Description: "Append per-tag counts to the stats",
+		set:         func(c *Config, v bool) { c.stratifyByTag = v }},
+
+	// Behavior profiles
+	{Name: "profile", Suffix: "NAME", Section: "PROFILE", Kind: KindFloat, Min: 0, Max: 1, Units: "share of population",
+		Description: "Fraction of the population with behavior profile NAME (e.g. profile.cautious); profiles are exclusive",
+		set:         func(c *Config, name string, v float64) { c.profileSpec(name).fraction = v }},
+	{Name: "profileHygiene", Suffix: "NAME", Section: "PROFILE", Kind: KindFloat, Min: 0, Max: 1,
+		Description: "Hygiene baseline of profile NAME (default: the hygieneDistribution draw)",
+		set:         func(c *Config, name string, v float64) { c.profileSpec(name).hygiene = v }},
+	{Name: "profileCompliance", Suffix: "NAME", Section: "PROFILE", Kind: KindFloat, Min: 0, Max: 1,
+		Description: "Distancing compliance baseline of profile NAME (default: the complianceDistribution draw)",
+		set:         func(c *Config, name string, v float64) { c.profileSpec(name).compliance = v }},
+	{Name: "profileMobility", Suffix: "NAME", Section: "PROFILE", Kind: KindFloat, Min: 0, Max: 10, Default: "1.0",
+		Description: "Multiplier on the step lengths and train/flight chances of profile NAME",
+		set:         func(c *Config, name string, v float64) { c.profileSpec(name).mobility = v }},
+	{Name: "profileVaccineAcceptance", Suffix: "NAME", Section: "PROFILE", Kind: KindFloat, Min: 0, Max: 1, Default: "0.55",
+		Description: "Base vaccine acceptance of profile NAME, before the age, behavior and health modifiers",
+		set:         func(c *Config, name string, v float64) { c.profileSpec(name).vaccineAcceptance = v }},
+
+	// Simulation
+	{Name: "numDays", Section: "SIMULATION", Kind: KindInt, Min: 1, Max: 10000, Units: "days", Default: "200",
+		Description: "Days to simulate",
+		set:         func(c *Config, v int) { c.numDays = v }},
+	{Name: "errorPolicy", Section: "SIMULATION", Kind: KindChoice, Default: string(PolicyAbort),
+		Choices:     []string{string(PolicyAbort), string(PolicySkipDay), string(PolicyCheckpoint)},
+		Description: "On a failed day: stop, skip the day, or dump agent state and stop; outputs gathered so far are always saved",
+		set:         func(c *Config, v string) { c.errorPolicy = errorPolicy(v) }},
+	{Name: "warmupDays", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "Infection-free days before day 0",
+		set:         func(c *Config, v int) { c.warmupDays = v }},
+	{Name: "startDate", Section: "SIMULATION", Kind: KindDate,
+		Description: "Calendar date of day 0 (labels stats and frames)",
+		set:         func(c *Config, v time.Time) { c.startDate = v }},
+	{Name: "statsPer100k", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Print counts per 100,000 population",
+		set:         func(c *Config, v bool) { c.statsPer100k = v }},
+	{Name: "reportIncidence", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Add NewInfections, NewDeaths, NewRecoveries and NewAdmissions per day columns",
+		set:         func(c *Config, v bool) { c.reportIncidence = v }},
+	{Name: "statsWindowDays", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "0 = off; else only the last N days are kept at full detail, older days weekly",
+		set:         func(c *Config, v int) { c.statsWindowDays = v }},
+	{Name: "flushEveryDays", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "0 = off; else stats rows, tracked-agent lines and frames are written to disk every K days so a crash keeps them",
+		set:         func(c *Config, v int) { c.flushEveryDays = v }},
+	{Name: "statsFilename", Section: "SIMULATION", Kind: KindString, MaxLen: 100,
+		Description: "If set, every day's stats are also written to output_gif/<statsFilename> at the end of the run",
+		set:         func(c *Config, v string) { c.statsFilename = v }},
+	{Name: "compressOutputs", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Gzip the outputs with fixed names (transmissions, tracked agents, exposure risk, snapshots, saved states), adding .gz; statsFilename is gzipped if it ends in .gz",
+		set:         func(c *Config, v bool) { c.compressOutputs = v }},
+	{Name: "sweepHeatmap", Section: "SIMULATION", Kind: KindChoice, Default: SweepHeatmapNone, Choices: sweepHeatmapChoices,
+		Description: "With two or more parameters swept as start:step:end ranges, draw output_gif/sweep_heatmap.png of this outcome over the first two: peak, deaths, duration or attack (rate)",
+		set:         func(c *Config, v string) { c.sweepHeatmap = v }},
+	{Name: "exposureRisk", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Write output_gif/exposure_risk.csv: each individual's summed daily infection probability and traits",
+		set:         func(c *Config, v bool) { c.exposureRisk = v }},
+	{Name: "npiReport", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Report how much the vaccine, hygiene and compliance factors each reduced transmission over the run",
+		set:         func(c *Config, v bool) { c.npiReport = v }},
+	{Name: "chainStats", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Log who infected whom to output_gif/transmissions.csv and report chain depth, secondary cases and terminal chains",
+		set:         func(c *Config, v bool) { c.chainStats = v }},
+	{Name: "reportReff", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Print R_effective (mean secondary cases) by date of infection at the end and add a Reff column to the stats file",
+		set:         func(c *Config, v bool) { c.reportReff = v }},
+	{Name: "statsFormat", Section: "SIMULATION", Kind: KindChoice, Default: string(StatsCSV),
+		Choices:     []string{string(StatsCSV), string(StatsJSON)},
+		Description: "Format of the stats file: csv (the console columns) or json (one object per day)",
+		set:         func(c *Config, v string) { c.statsFormat = statsFormat(v) }},
+	{Name: "waveProminence", Section: "SIMULATION", Kind: KindFloat, Min: 0.01, Max: 1, Units: "share of peak", Default: "0.2",
+		Description: "How far the infected curve must fall and rise again to separate two waves",
+		set:         func(c *Config, v float64) { c.waveProminence = v }},
+	{Name: "randomSeed", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 9e18, Default: "0",
+		Description: "Seed of the random number generator; the same seed and config give the same run. 0 = a new seed every run (printed at the start)",
+		set:         func(c *Config, v int) { c.randomSeed = int64(v) }},
+	{Name: "rngAlgorithm", Section: "SIMULATION", Kind: KindChoice, Default: string(RNGMathRand),
+		Choices:     rngAlgorithmChoices,
+		Description: "Random number generator: mathrand, or xoshiro (xoshiro256**) whose runs, replicates and parallel chunks get provably non-overlapping streams",
+		set:         func(c *Config, v string) { c.rngAlgorithm = rngAlgorithm(v) }},
+	{Name: "sanityCheckDays", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 365, Units: "days", Default: "10",
+		Description: "Length of a pre-run burn-in that estimates R0 and doubling time and warns about implausible parameters, 0 = off",
+		set:         func(c *Config, v int) { c.sanityCheckDays = v }},
+	{Name: "largePopulation", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Multi-million mode: slab allocation, k-d tree neighbor search, sampled clustering stats and rendering",
+		set:         func(c *Config, v bool) { c.largePopulation = v }},
+	{Name: "spatialIndex", Section: "SIMULATION", Kind: KindChoice, Default: string(IndexScan),
+		Choices:     []string{string(IndexScan), string(IndexKDTree), string(IndexGrid)},
+		Description: "How neighbors are found; kdtree and grid are much faster for large populations, kdtree copes better with uneven spreads",
+		set:         func(c *Config, v string) { c.spatialIndex = spatialIndexKind(v) }},
+	{Name: "numWorkers", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 1024, Units: "goroutines", Default: "0",
+		Description: "Goroutines for the per-day probability and movement updates, 0 = one per CPU; results do not depend on it",
+		set:         func(c *Config, v int) { c.numWorkers = v }},
+	{Name: "batchTransmission", Section: "SIMULATION", Kind: KindBool, Default: "false",
+		Description: "Compute infection probabilities from flat arrays of the infected, faster for large populations; same model, but rounding differs from the default path",
+		set:         func(c *Config, v bool) { c.batchTransmission = v }},
+	{Name: "fastForward", Section: "SIMULATION", Kind: KindBool, Default: "true",
+		Description: "Skip the transmission neighbor searches on days with no one infectious; results are unchanged",
+		set:         func(c *Config, v bool) { c.fastForward = v }},
+	{Name: "numReplicates", Section: "SIMULATION", Kind: KindInt, Min: 1, Max: 10000, Units: "runs", Default: "1",
+		Description: "Runs of the config with seeds seed, seed+1, ...; above 1, every daily stat's mean, median and 95% interval are written to output_gif/replicates.csv",
+		set:         func(c *Config, v int) { c.numReplicates = v }},
+	{Name: "checkpointInterval", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "Days between saves of the full run state to output_gif/state_day<N>.gob, for -resume; 0 = off",
+		set:         func(c *Config, v int) { c.checkpointDays = v }},
+	{Name: "contactMemoryDays", Section: "SIMULATION", Kind: KindInt, Min: 0, Max: 60, Units: "days", Default: "0",
+		Description: "Days of contacts remembered per individual, 0 = off",
+		set:         func(c *Config, v int) { c.contactMemoryDays = v }},
+	{Name: "contactsPerDay", Section: "SIMULATION", Kind: KindInt, Min: 1, Max: 1000, Units: "contacts", Default: "20",
+		Description: "Contacts remembered per individual per day; memory ~ contactMemoryDays*contactsPerDay*4 bytes per individual",
+		set:         func(c *Config, v int) { c.contactsPerDay = v }},
+
+	// Vaccination campaign
+	{Name: "doseDelivery", Suffix: "DAY", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 0, Max: 100000000, Units: "doses",
+		Description: "Doses arriving on DAY; if any are given, only doses in stock can be used",
+		set:         func(c *Config, day string, v int) { c.vaccineSupply.deliveries[mustAtoi(day)] += v }},
+	{Name: "dailyDoseCapacity", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 0, Max: 1000000, Units: "doses per day", Default: "0",
+		Description: "Doses given per day, 0 = 2% of the population",
+		set:         func(c *Config, v int) { c.vaccineSupply.dailyCapacity = v }},
+	{Name: "doseShelfLife", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 0, Max: 3650, Units: "days", Default: "0",
+		Description: "Days before delivered doses expire, 0 = never; expired doses are counted as wasted",
+		set:         func(c *Config, v int) { c.vaccineSupply.shelfLife = v }},
+	{Name: "vaccinationStartDay", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 0, Max: 10000, Units: "day", Default: "0",
+		Description: "First day of the campaign",
+		set:         func(c *Config, v int) { c.vaccineSupply.startDay = v }},
+	{Name: "vaccinationEndDay", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 0, Max: 10000, Units: "day", Default: "0",
+		Description: "Last day of the campaign, 0 = until the end of the run",
+		set:         func(c *Config, v int) { c.vaccineSupply.endDay = v }},
+	{Name: "vaccinateRecovered", Section: "VACCINATION CAMPAIGN", Kind: KindBool, Default: "true",
+		Description: "Offer vaccines to Recovered individuals",
+		set:         func(c *Config, v bool) { c.vaccineSupply.excludeRecovered = !v }},
+	{Name: "recoveredCountTowardCoverage", Section: "VACCINATION CAMPAIGN", Kind: KindBool, Default: "true",
+		Description: "Recovered count in the coverage target; false = coverage is measured among everyone else",
+		set:         func(c *Config, v bool) { c.vaccineSupply.recoveredOutsideTarget = !v }},
+	{Name: "vaccinationPriority", Section: "VACCINATION CAMPAIGN", Kind: KindChoice, Default: string(PriorityRandom),
+		Choices:     []string{string(PriorityRandom), string(PriorityElderly), string(PriorityHealthcare)},
+		Description: "Who is offered vaccines first: random, elderly (oldest first) or healthcare (healthcare_worker tag, then oldest first)",
+		set:         func(c *Config, v string) { c.vaccineSupply.priority = vaccinationPriority(v) }},
+	{Name: "vaccineRollout", Section: "VACCINATION CAMPAIGN", Kind: KindChoice, Default: string(RolloutRandom),
+		Choices:     []string{string(RolloutRandom), string(RolloutRows), string(RolloutCenter), string(RolloutCustom)},
+		Description: "Order vaccines reach the map: random (everywhere at once), or cell by cell on a rolloutGrid grid by rows, from the center outwards, or in the custom rolloutCells order",
+		set:         func(c *Config, v string) { c.rollout.order = rolloutOrder(v) }},
+	{Name: "rolloutGrid", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 1, Max: 100, Units: "cells per side", Default: "4",
+		Description: "Grid the map is split into for a geographic rollout",
+		set:         func(c *Config, v int) { c.rollout.grid = v }},
+	{Name: "rolloutCells", Section: "VACCINATION CAMPAIGN", Kind: KindString, MaxLen: 1000,
+		Description: "Comma-separated cell order for vaccineRollout = custom; cells are numbered row by row from 0 at the top-left, unlisted cells come last",
+		set:         func(c *Config, v string) { c.rollout.cellsText = v }},
+	{Name: "rolloutDaysPerCell", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "With a geographic rollout, open one more cell every N days of the campaign; 0 = all cells open, served in order",
+		set:         func(c *Config, v int) { c.rollout.daysPerCell = v }},
+	{Name: "sideEffects", Section: "VACCINATION CAMPAIGN", Kind: KindBool, Default: "false",
+		Description: "Some newly vaccinated individuals move less for a day or two while recovering from side effects",
+		set:         func(c *Config, v bool) { c.sideEffects.enabled = v }},
+	{Name: "sideEffectRate", Section: "VACCINATION CAMPAIGN", Kind: KindFloat, Min: 0, Max: 1, Units: "share of doses", Default: "0.3",
+		Description: "With sideEffects, chance that a dose causes side effects",
+		set:         func(c *Config, v float64) { c.sideEffects.rate = v }},
+	{Name: "sideEffectMaxDays", Section: "VACCINATION CAMPAIGN", Kind: KindInt, Min: 1, Max: 30, Units: "days", Default: "2",
+		Description: "With sideEffects, episodes last between 1 and this many days",
+		set:         func(c *Config, v int) { c.sideEffects.maxDays = v }},
+	{Name: "sideEffectMobility", Section: "VACCINATION CAMPAIGN", Kind: KindFloat, Min: 0, Max: 1, Units: "multiplier", Default: "0.2",
+		Description: "With sideEffects, step length multiplier while side effects last",
+		set:         func(c *Config, v float64) { c.sideEffects.mobility = v }},
+
+	// Preparedness stockpile
+	{Name: "stockpileTarget", Section: "STOCKPILE", Kind: KindChoice, Default: string(StockpileRandom),
+		Choices:     []string{string(StockpileRandom), string(StockpileElderly), string(StockpileHealthcare)},
+		Description: "Group the stockpile is for: random (everyone), elderly (stockpileElderlyAge and over) or healthcare (healthcare_worker tag)",
+		set:         func(c *Config, v string) { c.stockpile.target = stockpileTarget(v) }},
+	{Name: "stockpileElderlyAge", Section: "STOCKPILE", Kind: KindInt, Min: 0, Max: 120, Units: "years", Default: "65",
+		Description: "With stockpileTarget = elderly, the youngest age in the group",
+		set:         func(c *Config, v int) { c.stockpile.elderlyAge = v }},
+	{Name: "prophylaxisDoses", Section: "STOCKPILE", Kind: KindInt, Min: 0, Max: 100000000, Units: "doses", Default: "0",
+		Description: "Prophylactic doses handed out by lottery within the target group at day 0; recipients count as vaccinated",
+		set:         func(c *Config, v int) { c.stockpile.prophylaxis = v }},
+	{Name: "antiviralCourses", Section: "STOCKPILE", Kind: KindInt, Min: 0, Max: 100000000, Units: "courses", Default: "0",
+		Description: "Antiviral courses given to infected members of the target group until none are left",
+		set:         func(c *Config, v int) { c.stockpile.antivirals = v }},
+	{Name: "antiviralMortality", Section: "STOCKPILE", Kind: KindFloat, Min: 0, Max: 1, Units: "multiplier", Default: "0.5",
+		Description: "Multiplier on the daily mortality of an infection treated with antivirals",
+		set:         func(c *Config, v float64) { c.stockpile.mortality = v }},
+	{Name: "antiviralRecovery", Section: "STOCKPILE", Kind: KindFloat, Min: 1, Max: 10, Units: "multiplier", Default: "1.5",
+		Description: "Multiplier on the daily recovery rate of an infection treated with antivirals",
+		set:         func(c *Config, v float64) { c.stockpile.recovery = v }},
+
+	// Shocks
+	{Name: "shock", Suffix: "DAY", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "share of population",
+		Description: "A holiday or mass gathering on DAY: this share of the population travels further and keeps less distance for shockDays days",
+		set:         func(c *Config, day string, v float64) { c.shocks.shares[mustAtoi(day)] = v }},
+	{Name: "shockDays", Section: "POLICY", Kind: KindInt, Min: 1, Max: 365, Units: "days", Default: "3",
+		Description: "How long each shock lasts",
+		set:         func(c *Config, v int) { c.shocks.days = v }},
+	{Name: "shockMobility", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 100, Units: "multiplier", Default: "5",
+		Description: "Step length multiplier for shocked individuals",
+		set:         func(c *Config, v float64) { c.shocks.mobility = v }},
+	{Name: "shockCompliance", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "multiplier", Default: "0.2",
+		Description: "Multiplier on the social distance compliance of shocked individuals (0 = none at all)",
+		set:         func(c *Config, v float64) { c.shocks.compliance = v }},
+	{Name: "lockdownThreshold", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "fraction", Default: "0",
+		Description: "Infected fraction above which a lockdown starts, 0 = no lockdowns",
+		set:         func(c *Config, v float64) { c.lockdown.threshold = v }},
+	{Name: "lockdownRelease", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "fraction", Default: "0",
+		Description: "Infected fraction below which a lockdown is lifted, 0 = half of lockdownThreshold",
+		set:         func(c *Config, v float64) { c.lockdown.release = v }},
+	{Name: "lockdownMobility", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "multiplier", Default: "0.2",
+		Description: "Multiplier on step lengths and on the chances of taking a train or a flight during a lockdown",
+		set:         func(c *Config, v float64) { c.lockdown.mobility = v }},
+	{Name: "lockdownMinDays", Section: "POLICY", Kind: KindInt, Min: 0, Max: 365, Units: "days", Default: "14",
+		Description: "Shortest lockdown: it is not lifted before this many days",
+		set:         func(c *Config, v int) { c.lockdown.minDays = v }},
+	{Name: "policyCells", Section: "POLICY", Kind: KindInt, Min: 0, Max: 50, Units: "cells per side", Default: "0",
+		Description: "Split the area into N x N cells, each with its own distancing threshold and lockdowns reacting to local prevalence, 0 = one central policy",
+		set:         func(c *Config, v int) { c.policyCells = v }},
+
+	// Quarantine
+	{Name: "detectionRate", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0",
+		Description: "Probability that an infection is detected, 0 = no detection or quarantine",
+		set:         func(c *Config, v float64) { c.quarantine.detectionRate = v }},
+	{Name: "detectionDelay", Section: "POLICY", Kind: KindInt, Min: 1, Max: 365, Units: "days", Default: "2",
+		Description: "Days from the start of an infection to its detection",
+		set:         func(c *Config, v int) { c.quarantine.detectionDelay = v }},
+	{Name: "quarantineComplianceRate", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0.8",
+		Description: "Probability that a detected individual goes into quarantine",
+		set:         func(c *Config, v float64) { c.quarantine.complianceRate = v }},
+	{Name: "quarantineDays", Section: "POLICY", Kind: KindInt, Min: 1, Max: 365, Units: "days", Default: "14",
+		Description: "How long a quarantine lasts",
+		set:         func(c *Config, v int) { c.quarantine.days = v }},
+	{Name: "quarantineTransmission", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "multiplier", Default: "0.1",
+		Description: "Multiplier on the per-contact transmission probability from individuals in quarantine",
+		set:         func(c *Config, v float64) { c.quarantine.transmission = v }},
+	{Name: "testingRate", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "probability per day", Default: "0",
+		Description: "Share of the living population tested each day, 0 = no testing; positive tests detect infections like detectionRate",
+		set:         func(c *Config, v float64) { c.quarantine.testRate = v }},
+	{Name: "testSensitivity", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0.8",
+		Description: "Probability that a test of an infected individual is positive (the rest are false negatives)",
+		set:         func(c *Config, v float64) { c.quarantine.sensitivity = v }},
+	{Name: "tracingFraction", Section: "POLICY", Kind: KindFloat, Min: 0, Max: 1, Units: "probability", Default: "0",
+		Description: "Share of a detected case's recent contacts who are traced, notified and asked to quarantine, 0 = no contact tracing",
+		set:         func(c *Config, v float64) { c.tracing.fraction = v }},
+	{Name: "tracingDays", Section: "POLICY", Kind: KindInt, Min: 1, Max: 60, Units: "days", Default: "7",
+		Description: "Days of contacts traced back from a detection (contact memory is kept at least this long)",
+		set:         func(c *Config, v int) { c.tracing.days = v }},
+	{Name: "tracingDelay", Section: "POLICY", Kind: KindInt, Min: 0, Max: 60, Units: "days", Default: "2",
+		Description: "Days from a detection until its traced contacts are notified",
+		set:         func(c *Config, v int) { c.tracing.delay = v }},
+
+	// Annotations
+	{Name: "annotation", Suffix: "DAY", Section: "ANNOTATIONS", Kind: KindString, MaxLen: 100,
+		Description: "Note for day DAY (e.g. annotation.45 = schools reopen), written to the stats and shown on frames from that day on; DAY must not exceed numDays",
+		set:         func(c *Config, day string, v string) { c.annotations[mustAtoi(day)] = v }},
+
+	// Health economics
+	{Name: "lifeExpectancy", Suffix: "AGE", Section: "HEALTH ECONOMICS", Kind: KindFloat, Min: 0, Max: 120, Units: "years",
+		Description: "Remaining life expectancy at AGE, interpolated between ages (default: standard table)",
+		set:         func(c *Config, age string, v float64) { c.lifeExpectancy[mustAtoi(age)] = v }},
+	{Name: "qalyReport", Section: "HEALTH ECONOMICS", Kind: KindBool, Default: "false",
+		Description: "Add QALY losses to the final summary",
+		set:         func(c *Config, v bool) { c.qaly.enabled = v }},
+	{Name: "illnessDisutility", Section: "HEALTH ECONOMICS", Kind: KindFloat, Min: 0, Max: 1, Default: "0.2",
+		Description: "Quality lost per day of mild illness (x2.5 severe, x4 critical)",
+		set:         func(c *Config, v float64) { c.qaly.illnessDisutility = v }},
+	{Name: "sequelaeFraction", Section: "HEALTH ECONOMICS", Kind: KindFloat, Min: 0, Max: 1, Units: "share of recoveries", Default: "0.1",
+		Description: "Recoveries left with sequelae",
+		set:         func(c *Config, v float64) { c.qaly.sequelaeFraction = v }},
+	{Name: "sequelaeDisutility", Section: "HEALTH ECONOMICS", Kind: KindFloat, Min: 0, Max: 1, Default: "0.05",
+		Description: "Quality lost per year while living with sequelae",
+		set:         func(c *Config, v float64) { c.qaly.sequelaeDisutility = v }},
+	{Name: "sequelaeYears", Section: "HEALTH ECONOMICS", Kind: KindFloat, Min: 0, Max: 100, Units: "years", Default: "1.0",
+		Description: "How long sequelae last",
+		set:         func(c *Config, v float64) { c.qaly.sequelaeYears = v }},
+
+	// Visualization
+	{Name: "canvasWidth", Section: "VISUALIZATION", Kind: KindInt, Min: 100, Max: 4096, Units: "pixels", Default: "800",
+		Description: "Output image width",
+		set:         func(c *Config, v int) { c.canvasWidth = v }},
+	{Name: "pointRadius", Section: "VISUALIZATION", Kind: KindFloat, Min: 0.5, Max: 50, Units: "pixels", Default: "3.0",
+		Description: "Size of individual dots",
+		set:         func(c *Config, v float64) { c.pointRadius = v }},
+	{Name: "pointAlpha", Section: "VISUALIZATION", Kind: KindFloat, Min: 0.05, Max: 1, Units: "opacity", Default: "1",
+		Description: "Opacity of individual dots; below 1 overlapping dots blend, which keeps dense maps readable",
+		set:         func(c *Config, v float64) { c.points.alpha = v }},
+	{Name: "densityPointSize", Section: "VISUALIZATION", Kind: KindBool, Default: "false",
+		Description: "Shrink dots where the map is crowded, down to half a pixel",
+		set:         func(c *Config, v bool) { c.points.density = v }},
+	{Name: "pointJitter", Section: "VISUALIZATION", Kind: KindFloat, Min: 0, Max: 20, Units: "pixels", Default: "0",
+		Description: "Move each dot by up to this many pixels (fixed per individual), so individuals at the same spot stay visible",
+		set:         func(c *Config, v float64) { c.points.jitter = v }},
+	{Name: "frameFrequency", Section: "VISUALIZATION", Kind: KindInt, Min: 1, Max: 10000, Units: "days", Default: "2",
+		Description: "Capture a frame every N days; must not exceed numDays",
+		set:         func(c *Config, v int) { c.frameFrequency = v }},
+	{Name: "gifDelay", Section: "VISUALIZATION", Kind: KindInt, Min: 1, Max: 1000, Units: "centiseconds", Default: "5",
+		Description: "Delay between GIF frames",
+		set:         func(c *Config, v int) { c.gifDelay = v }},
+	{Name: "gifFilename", Section: "VISUALIZATION", Kind: KindFilename, Default: "env_sim.gif",
+		Description: "Output GIF name; must end with .gif, no path or special characters",
+		set:         func(c *Config, v string) { c.gifFilename = v }},
+	{Name: "deadRendering", Section: "VISUALIZATION", Kind: KindChoice, Default: string(DeadGrey),
+		Choices:     []string{string(DeadGrey), string(DeadFade), string(DeadCross), string(DeadRemove)},
+		Description: "How dead individuals are drawn on the map; all but grey draw them beneath the living",
+		set:         func(c *Config, v string) { c.deadRendering = deadRenderMode(v) }},
+	{Name: "deadFadeFrames", Section: "VISUALIZATION", Kind: KindInt, Min: 1, Max: 1000, Units: "frames", Default: "5",
+		Description: "With deadRendering = fade, frames until a dead individual disappears",
+		set:         func(c *Config, v int) { c.deadFadeFrames = v }},
+	{Name: "deathCounter", Section: "VISUALIZATION", Kind: KindBool, Default: "false",
+		Description: "Show the cumulative death count in the corner of the map",
+		set:         func(c *Config, v bool) { c.deathCounter = v }},
+	{Name: "renderSample", Section: "VISUALIZATION", Kind: KindInt, Min: 1000, Max: 10000000, Units: "individuals", Default: "200000",
+		Description: "With largePopulation, the most individuals drawn on the spatial map",
+		set:         func(c *Config, v int) { c.renderSample = v }},
+	{Name: "coverageMapEvery", Section: "VISUALIZATION", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "0 = off; else draw a vaccination coverage map (ever vaccinated and current protection per grid cell) every N days",
+		set:         func(c *Config, v int) { c.coverageMapEvery = v }},
+	{Name: "coverageGrid", Section: "VISUALIZATION", Kind: KindInt, Min: 1, Max: 200, Units: "cells per side", Default: "10",
+		Description: "Grid resolution of the coverage map",
+		set:         func(c *Config, v int) { c.coverageGrid = v }},
+	{Name: "snapshotEvery", Section: "VISUALIZATION", Kind: KindInt, Min: 0, Max: 10000, Units: "days", Default: "0",
+		Description: "0 = off; else save the state every N days to output_gif/snapshots.gob, to redraw GIFs later with the render subcommand",
+		set:         func(c *Config, v int) { c.snapshotEvery = v }},
+	{Name: "palette", Section: "VISUALIZATION", Kind: KindChoice, Choices: paletteNames, Default: "default",
+		Description: "Colors of the health states on the map and pie chart: default or colorblind (Okabe-Ito)",
+		set:         func(c *Config, v string) { c.palette = v }},
+	{Name: "ageSummary", Section: "VISUALIZATION", Kind: KindBool, Default: "false",
+		Description: "Write output_gif/age_summary.png: age pyramid, attack rate and death rate by 10-year age band",
+		set:         func(c *Config, v bool) { c.ageSummary = v }},
+	{Name: "epidemicCurve", Section: "VISUALIZATION", Kind: KindBool, Default: "false",
+		Description: "Write a line chart of Infected, Recovered and Dead over time to output_gif/epidemic_curve.png, and as a growing GIF to output_gif/curve_<gifFilename>",
+		set:         func(c *Config, v bool) { c.epidemicCurve = v }},
+	{Name: "flowDiagram", Section: "VISUALIZATION", Kind: KindBool, Default: "false",
+		Description: "Write the moves between health states over the run to output_gif/flows.json, and as a Sankey diagram to output_gif/flows.png",
+		set:         func(c *Config, v bool) { c.flowDiagram = v }},
+	{Name: "report", Section: "VISUALIZATION", Kind: KindChoice, Default: string(ReportNone),
+		Choices:     []string{string(ReportNone), string(ReportMarkdown), string(ReportHTML)},
+		Description: "Write output_gif/report.md or report.html: key figures, the summary, charts, GIFs and settings in one self-contained document",
+		set:         func(c *Config, v string) { c.report = reportFormat(v) }},
+	{Name: "burstFrames", Section: "VISUALIZATION", Kind: KindInt, Min: 0, Max: 365, Units: "days", Default: "0",
+		Description: "0 = off; else spatial and pie frames are captured every day for N days after the policy tightens",
+		set:         func(c *Config, v int) { c.burstFrames = v }},
+	{Name: "finalFrame", Section: "VISUALIZATION", Kind: KindBool, Default: "false",
+		Description: "Always capture the last simulated day, even if it is not a multiple of frameFrequency",
+		set:         func(c *Config, v bool) { c.finalFrame = v }},
+}
+
+// mustAtoi converts a suffix that lookupParam already validated as an integer.
+func mustAtoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// lookupParam finds the schema entry for a config key, returning the suffix
+// for Name.SUFFIX parameters. It returns nil if the key is unknown.
+func lookupParam(key string) (*ParamSpec, string) {
+	prefix, suffix, hasSuffix := strings.Cut(key, ".")
+	for _, p := range configSchema {
+		if p.Block != "" {
+			continue
+		}
+		if p.Suffix == "" && p.Name == key {
+			return p, ""
+		}
+		if p.Suffix != "" && hasSuffix && p.Name == prefix {
+			return p, suffix
+		}
+	}
+	return nil, ""
+}
+
+// blockParams lists the schema entries of each kind of block. It is filled
+// in init, since the entries' setters create blocks with applyBlockDefaults.
+var blockParams = map[string][]*ParamSpec{}
+
+func init() {
+	for _, p := range configSchema {
+		if p.Block != "" && p.Suffix == "NAME" {
+			blockParams[p.Block] = append(blockParams[p.Block], p)
+		}
+	}
+}
+
+// applyBlockDefaults sets the parameters of the new [block.NAME] section name
+// to their schema defaults.
+func applyBlockDefaults(config *Config, block, name string) {
+	for _, p := range blockParams[block] {
+		p.apply(config, NewConfigValidator(), p.Name, name, p.Default)
+	}
+}
+
+// lookupBlockParam finds the schema entry for a key inside a [block.NAME]
+// section, returning the suffix for Name.REGION parameters. It returns nil
+// if the key is unknown.
+func lookupBlockParam(block, key string) (*ParamSpec, string) {
+	prefix, suffix, hasSuffix := strings.Cut(key, ".")
+	for _, p := range configSchema {
+		if p.Block != block {
+			continue
+		}
+		if p.Suffix == "NAME" && p.Name == key {
+			return p, ""
+		}
+		if p.Suffix != "NAME" && hasSuffix && p.Name == prefix {
+			return p, suffix
+		}
+	}
+	return nil, ""
+}
+
+// checkSuffix validates the SUFFIX part of a Name.SUFFIX key.
+func (p *ParamSpec) checkSuffix(v *ConfigValidator, key, value, suffix string) bool {
+	switch p.Suffix {
+	case "REGION":
+		if _, to, _ := strings.Cut(suffix, "."); !validTagName(to) {
+			v.AddError(key, value, "region name must be non-empty and contain only letters, digits and '_'")
+			return false
+		}
+	case "NAME":
+		if !validTagName(suffix) {
+			v.AddError(key, value, "name must be non-empty and contain only letters, digits and '_'")
+			return false
+		}
+	case "DAY", "AGE":
+		limit := 10000
+		if p.Suffix == "AGE" {
+			limit = 120
+		}
+		n, err := strconv.Atoi(suffix)
+		if err != nil || n < 0 || n > limit {
+			v.AddError(key, value, fmt.Sprintf("%s must be an integer between 0 and %d", strings.ToLower(p.Suffix), limit))
+			return false
+		}
+	}
+	return true
+}
+
+// apply validates value against the spec and stores it in config.
+func (p *ParamSpec) apply(config *Config, v *ConfigValidator, key, suffix, value string) {
+	if p.Suffix != "" && !p.checkSuffix(v, key, value, suffix) {
+		return
+	}
+
+	var parsed any
+	ok := false
+	switch p.Kind {
+	case KindFloat:
+		parsed, ok = v.parseAndValidateFloat(key, value, p.Min, p.Max, !p.Exclusive)
+	case KindInt:
+		parsed, ok = v.parseAndValidateInt(key, value, int(p.Min), int(p.Max))
+	case KindBool:
+		parsed, ok = v.parseAndValidateBool(key, value)
+	case KindString:
+		parsed, ok = v.parseAndValidateString(key, value, p.MaxLen)
+	case KindChoice:
+		parsed, ok = v.parseAndValidateChoice(key, value, p.Choices)
+	case KindDate:
+		parsed, ok = v.parseAndValidateDate(key, value)
+	case KindFilename:
+		parsed, ok = v.parseAndValidateFilename(key, value)
+	}
+	if !ok {
+		return
+	}
+
+	switch set := p.set.(type) {
+	case func(*Config, float64):
+		set(config, parsed.(float64))
+	case func(*Config, int):
+		set(config, parsed.(int))
+	case func(*Config, bool):
+		set(config, parsed.(bool))
+	case func(*Config, string):
+		set(config, parsed.(string))
+	case func(*Config, time.Time):
+		set(config, parsed.(time.Time))
+	case func(*Config, string, float64):
+		set(config, suffix, parsed.(float64))
+	case func(*Config, string, int):
+		set(config, suffix, parsed.(int))
+	case func(*Config, string, string):
+		set(config, suffix, parsed.(string))
+	default:
+		panic(fmt.Sprintf("config schema: %s has no setter for %s", p.Name, p.Kind))
+	}
+}
+
+// parseAndValidateChoice checks that value is one of choices (case-insensitive)
+// and returns it in lower case.
+func (v *ConfigValidator) parseAndValidateChoice(key, value string, choices []string) (string, bool) {
+	lower := strings.ToLower(value)
+	for _, c := range choices {
+		if lower == c {
+			return c, true
+		}
+	}
+	v.AddError(key, value, "must be one of "+strings.Join(choices, ", "))
+	return "", false
+}
+
+// key returns the parameter as written in a config file, e.g. "tag.NAME".
+func (p *ParamSpec) key() string {
+	if p.Suffix == "" || p.Block != "" && p.Suffix == "NAME" {
+		return p.Name
+	}
+	return p.Name + "." + p.Suffix
+}
+
+// rangeText describes the valid values of a parameter for the help output.
+func (p *ParamSpec) rangeText() string {
+	switch p.Kind {
+	case KindFloat:
+		if p.Exclusive {
+			return fmt.Sprintf("> %s, < %s", formatNumber(p.Min, true), formatNumber(p.Max, true))
+		}
+		return fmt.Sprintf("%s - %s", formatNumber(p.Min, true), formatNumber(p.Max, true))
+	case KindInt:
+		return fmt.Sprintf("%s - %s", formatNumber(p.Min, false), formatNumber(p.Max, false))
+	case KindBool:
+		return "true/false"
+	case KindString:
+		return fmt.Sprintf("non-empty, max %d characters", p.MaxLen)
+	case KindChoice:
+		return strings.Join(p.Choices, " | ")
+	case KindDate:
+		return "YYYY-MM-DD"
+	case KindFilename:
+		return "ends with .gif"
+	}
+	return ""
+}
+
+// formatNumber prints a range bound with thousands separators; floats keep one decimal.
+func formatNumber(x float64, float bool) string {
+	if float && x != math.Trunc(x) {
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	s := strconv.FormatInt(int64(x), 10)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	if float {
+		s += ".0"
+	}
+	return s
+}
+
+// writeConfigHelp prints the schema as the -help-config text.
+func writeConfigHelp(w io.Writer) {
+	const (
+		nameWidth = 28
+		kindWidth = 9
+		lineWidth = 100
+	)
+	indent := strings.Repeat(" ", 2+nameWidth+1+kindWidth+1)
+
+	fmt.Fprintln(w, "\n=== Configuration Parameter Validation Rules ===")
+	for _, section := range configSections {
+		fmt.Fprintf(w, "\n%s PARAMETERS:\n", section)
+		for _, p := range configSchema {
+			if p.Section != section {
+				continue
+			}
+			text := p.rangeText()
+			if p.Units != "" {
+				text += " (" + p.Units + ")"
+			}
+			if p.Default != "" {
+				text += ", default " + p.Default
+			}
+			text += ". " + p.Description
+
+			kind := string(p.Kind)
+			if p.Kind == KindChoice || p.Kind == KindFilename {
+				kind = "string"
+			}
+			lines := wrapWords(text, lineWidth-len(indent))
+			fmt.Fprintf(w, "  %-*s %-*s %s\n", nameWidth, p.key(), kindWidth, kind, lines[0])
+			for _, l := range lines[1:] {
+				fmt.Fprintf(w, "%s%s\n", indent, l)
+			}
+		}
+	}
+	fmt.Fprintln(w, "\n================================================")
+}
+
+// wrapWords splits text into lines of at most width characters, breaking at spaces.
+func wrapWords(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+// writeConfigSchemaJSON prints the schema as JSON for tools and editors.
+func writeConfigSchemaJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(configSchema)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestICUCapacity checks that only -1 derives the ICU beds from
+// medicalCapacity, and that 0 leaves the hospital without an ICU.
+func TestICUCapacity(t *testing.T) {
+	for _, tc := range []struct{ set, want int }{{-1, 20}, {0, 0}, {5, 5}} {
+		config := getDefaultConfig()
+		config.medicalCapacity = 100
+		config.icuCapacity = tc.set
+		config, err := validateConfig(config, NewConfigValidator())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.icuCapacity != tc.want {
+			t.Errorf("icuCapacity = %d gives %d ICU beds, want %d", tc.set, config.icuCapacity, tc.want)
+		}
+	}
+}
+
+// TestPathogenDefaults checks that a [disease.NAME] block starts from the
+// defaults its schema entries document.
+func TestPathogenDefaults(t *testing.T) {
+	p := getDefaultConfig().pathogen("flu")
+	d := p.disease
+	if d.name != "flu" || d.transmissionRate != 0.8 || d.transmissionDistance != 2 || d.recoveryRate != 0.05 ||
+		d.mortalityRate != 0.01 || d.infectiousPeriod != 10 || d.immunityDuration != 90 {
+		t.Errorf("flu starts as %+v", *d)
+	}
+	if p.initialInfected != 10 || p.startDay != 0 || p.crossImmunity != 0 {
+		t.Errorf("flu starts with %d infected on day %d and cross-immunity %g", p.initialInfected, p.startDay, p.crossImmunity)
+	}
+}
+
+// TestConfigDocumentSections checks that configDocument has a field for every
+// help section that is not a parameter, and that no field hides a parameter.
+func TestConfigDocumentSections(t *testing.T) {
+	fields := map[string]bool{}
+	typ := reflect.TypeFor[configDocument]()
+	for i := range typ.NumField() {
+		key, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		fields[key] = true
+	}
+	params := map[string]bool{}
+	for _, p := range configSchema {
+		if p.Block == "" {
+			params[p.Name] = true
+		}
+	}
+	for _, section := range configSections {
+		if strings.HasSuffix(section, " BLOCK") {
+			continue
+		}
+		words := strings.Fields(strings.ToLower(section))
+		for i := 1; i < len(words); i++ {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+		key := strings.Join(words, "")
+		if !fields[key] && !params[key] {
+			t.Errorf("section %s has no %q field in configDocument", section, key)
+		}
+	}
+	for key := range fields {
+		if params[key] {
+			t.Errorf("configDocument field %q hides the parameter of that name", key)
+		}
+	}
+}
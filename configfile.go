@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML and JSON configs.
+//
+// A config file ending in .yaml, .yml or .json is read as a tree instead of
+// key = value lines. The keys are the same parameters, checked by the same
+// schema (see config_schema.go):
+//
+//	disease:
+//	  diseaseName: flu
+//	  transmissionRate: 0.3
+//	population:
+//	  popSize: 5000
+//	tag:
+//	  elderly: 0.2
+//	tagExposure:
+//	  elderly: 1.5
+//	diseases:
+//	  rsv:
+//	    transmissionRate: 0.4
+//	    startDay: 20
+//	regions:
+//	  north:
+//	    population: 0.6
+//	    travel:
+//	      south: 0.01
+//
+// A parameter may sit at the top level or under its help section, named in
+// lower camel case (disease, population, environment, policy, movement,
+// simulation, vaccinationCampaign, stockpile, annotations, healthEconomics,
+// visualization). A nested mapping joins its keys with dots, so
+// tag: {elderly: 0.2} is tag.elderly = 0.2 and annotation: {30: lockdown} is
+// annotation.30 = lockdown; tag and profile are parameters, not sections.
+// diseases and regions hold the [disease.NAME] and [region.NAME] blocks. A
+// list of values is joined with commas. A JSON file is read the same way, as
+// the subset of YAML it is.
+//
+// The file is decoded into a configDocument, then each of its values goes
+// through setParam like a key = value line, so every format gets the same
+// range checks and errors.
+
+// configBlockKeys maps the keys holding blocks to their block names.
+var configBlockKeys = map[string]string{"diseases": "disease", "regions": "region"}
+
+// configDocument is the layout of a YAML or JSON config: a field for each
+// help section and each kind of block, and the top-level parameters in
+// Params. The values are kept as nodes until they are applied, in file
+// order, through the schema.
+type configDocument struct {
+	Disease             yaml.Node `yaml:"disease"`
+	Population          yaml.Node `yaml:"population"`
+	Environment         yaml.Node `yaml:"environment"`
+	Policy              yaml.Node `yaml:"policy"`
+	Movement            yaml.Node `yaml:"movement"`
+	Simulation          yaml.Node `yaml:"simulation"`
+	VaccinationCampaign yaml.Node `yaml:"vaccinationCampaign"`
+	Stockpile           yaml.Node `yaml:"stockpile"`
+	Annotations         yaml.Node `yaml:"annotations"`
+	HealthEconomics     yaml.Node `yaml:"healthEconomics"`
+	Visualization       yaml.Node `yaml:"visualization"`
+
+	Diseases yaml.Node `yaml:"diseases"` // [disease.NAME] blocks by name
+	Regions  yaml.Node `yaml:"regions"`  // [region.NAME] blocks by name
+
+	Params map[string]yaml.Node `yaml:",inline"`
+}
+
+// configEntry is a top-level key of a configDocument and its value.
+type configEntry struct {
+	key     string
+	value   *yaml.Node
+	section bool // a help section, not a parameter
+}
+
+// entries returns the keys set in d in the order they appear in the file.
+func (d *configDocument) entries() []configEntry {
+	var entries []configEntry
+	v := reflect.ValueOf(d).Elem()
+	for i := range v.NumField() {
+		n, ok := v.Field(i).Addr().Interface().(*yaml.Node)
+		if !ok || n.Kind == 0 {
+			continue
+		}
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		entries = append(entries, configEntry{key, n, configBlockKeys[key] == ""})
+	}
+	for key := range d.Params {
+		n := d.Params[key]
+		entries = append(entries, configEntry{key, &n, false})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].value, entries[j].value
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return entries
+}
+
+// loadStructuredConfig reads and validates a YAML or JSON config from r.
+func loadStructuredConfig(r io.Reader) (*Config, error) {
+	config := getDefaultConfig()
+	validator := NewConfigValidator()
+
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return validateConfig(config, validator) // an empty file keeps the defaults
+	}
+	root := resolveAlias(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of parameter names to values", root.Line)
+	}
+	var fields configDocument
+	if err := root.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	set := func(block, blockName string) func(key, value string, line int) {
+		return func(key, value string, line int) {
+			if config.setParam(validator, block, blockName, key, value) {
+				return
+			}
+			if block != "" {
+				fmt.Fprintf(msgOut, "Warning: unknown parameter '%s' in %ss.%s on line %d\n", key, block, blockName, line)
+			} else {
+				fmt.Fprintf(msgOut, "Warning: unknown parameter '%s' on line %d\n", key, line)
+			}
+		}
+	}
+	for _, e := range fields.entries() {
+		key, value := e.key, resolveAlias(e.value)
+		switch {
+		case configBlockKeys[key] != "":
+			block := configBlockKeys[key]
+			if value.Kind != yaml.MappingNode {
+				validator.AddError(key, value.Value, "must map each "+block+" name to its parameters")
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name := value.Content[j].Value
+				if config.startBlock(validator, key+"."+name, block, name) {
+					flattenConfig("", value.Content[j+1], set(block, name))
+				}
+			}
+		case e.section && value.Kind == yaml.MappingNode:
+			flattenConfig("", value, set("", ""))
+		default:
+			flattenConfig(key, value, set("", ""))
+		}
+	}
+	return validateConfig(config, validator)
+}
+
+// flattenConfig passes every value under n to set, with its key: prefix, and
+// the keys of nested mappings joined with dots.
+func flattenConfig(prefix string, n *yaml.Node, set func(key, value string, line int)) {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenConfig(key, n.Content[i+1], set)
+		}
+	case yaml.SequenceNode:
+		values := make([]string, len(n.Content))
+		for i, item := range n.Content {
+			if item = resolveAlias(item); item.Kind != yaml.ScalarNode {
+				fmt.Fprintf(msgOut, "Warning: skipping '%s' on line %d: a list may only hold values\n", prefix, n.Line)
+				return
+			}
+			values[i] = item.Value
+		}
+		set(prefix, strings.Join(values, ", "), n.Line)
+	default:
+		value := n.Value
+		if n.Tag == "!!null" {
+			value = ""
+		}
+		set(prefix, value, n.Line)
+	}
+}
+
+// resolveAlias returns the node an alias refers to, or n itself.
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
@@ -0,0 +1,139 @@
+package main
+
+// Recent-contact memory.
+//
+// Each individual can keep the IDs of the people it was in contact with over
+// the last contactMemoryDays days, for contact tracing and exposure
+// notification. The log is a fixed-size ring of day slots, each holding up to
+// contactsPerDay IDs, so memory is bounded at
+// contactMemoryDays*contactsPerDay*4 + contactMemoryDays*2 bytes per individual
+// no matter how long the run is. The oldest day is overwritten as a new one starts.
+
+// contactLog is one individual's ring buffer of recent contacts.
+type contactLog struct {
+	ids    []int32  // days*perDay slots; day k uses ids[k*perDay : (k+1)*perDay]
+	counts []uint16 // contacts stored in each day slot
+	perDay int
+	head   int // day slot being filled today
+}
+
+func newContactLog(days, perDay int) *contactLog {
+	return &contactLog{
+		ids:    make([]int32, days*perDay),
+		counts: make([]uint16, days),
+		perDay: perDay,
+	}
+}
+
+// days returns how many days the log remembers.
+func (l *contactLog) days() int { return len(l.counts) }
+
+// startDay advances the ring to a fresh day slot, forgetting the oldest day.
+func (l *contactLog) startDay() {
+	l.head = (l.head + 1) % l.days()
+	l.counts[l.head] = 0
+}
+
+// add records a contact for today. Contacts beyond perDay are dropped.
+func (l *contactLog) add(id int) {
+	n := int(l.counts[l.head])
+	if n >= l.perDay {
+		return
+	}
+	l.ids[l.head*l.perDay+n] = int32(id)
+	l.counts[l.head]++
+}
+
+// appendRecent appends the IDs recorded over the last `days` days (today first) to dst.
+// An ID met on several days appears once per day.
+func (l *contactLog) appendRecent(dst []int, days int) []int {
+	if days > l.days() {
+		days = l.days()
+	}
+	for k := 0; k < days; k++ {
+		slot := (l.head - k + l.days()) % l.days()
+		base := slot * l.perDay
+		for _, id := range l.ids[base : base+int(l.counts[slot])] {
+			dst = append(dst, int(id))
+		}
+	}
+	return dst
+}
+
+// enableContactMemory gives every individual a contact log of the given size.
+// days <= 0 disables contact memory.
+func enableContactMemory(env *Environment, days, perDay int) {
+	env.contactMemoryDays = days
+	env.contactsPerDay = perDay
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		ind.contacts = nil
+		if days > 0 {
+			ind.contacts = newContactLog(days, perDay)
+		}
+	}
+}
+
+// recordContacts starts a new day in every contact log and records today's
+// contacts: living individuals within the disease's transmission distance,
+// plus co-passengers on the same train or flight and housemates. Does nothing unless
+// contact memory is enabled.
+func recordContacts(env *Environment) {
+	if env == nil || env.contactMemoryDays <= 0 || env.disease == nil {
+		return
+	}
+	buf := getIndividualBuf()
+	defer putIndividualBuf(buf)
+
+	for _, ind := range env.population {
+		if ind == nil || ind.contacts == nil {
+			continue
+		}
+		ind.contacts.startDay()
+		if ind.healthStatus == Dead {
+			continue
+		}
+
+		*buf = appendNeighborsWithin((*buf)[:0], env, ind, env.disease.transmissionDistance)
+		for _, other := range *buf {
+			if other.healthStatus != Dead {
+				ind.contacts.add(other.id)
+			}
+		}
+		if ind.vehicle != nil {
+			for _, p := range ind.vehicle.passengers {
+				if p != ind && p.healthStatus != Dead {
+					ind.contacts.add(p.id)
+				}
+			}
+		}
+		if ind.home != nil {
+			for _, m := range ind.home.members {
+				if m != ind && m.healthStatus != Dead {
+					ind.contacts.add(m.id)
+				}
+			}
+		}
+	}
+}
+
+// recentContacts returns the individuals ind met over the last `days` days,
+// each listed once. Returns nil if contact memory is disabled.
+func recentContacts(env *Environment, ind *Individual, days int) []*Individual {
+	if env == nil || ind == nil || ind.contacts == nil {
+		return nil
+	}
+	ids := ind.contacts.appendRecent(nil, days)
+	seen := make(map[int]bool, len(ids))
+	out := make([]*Individual, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] || id < 0 || id >= len(env.population) {
+			continue
+		}
+		seen[id] = true
+		out = append(out, env.population[id])
+	}
+	return out
+}
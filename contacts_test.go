@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func benchmarkContactMemory(b *testing.B, days int) {
+	env, rng := benchmarkEnv(b, 10000, func(c *Config) { c.contactMemoryDays = days })
+	benchmarkStep(b, env, rng)
+}
+
+// BenchmarkStepNoContactMemory is a day of 10,000 individuals without the
+// recent-contact log, the baseline for its overhead.
+func BenchmarkStepNoContactMemory(b *testing.B) { benchmarkContactMemory(b, 0) }
+
+// BenchmarkStepContactMemory14 is the same day remembering 14 days of contacts.
+func BenchmarkStepContactMemory14(b *testing.B) { benchmarkContactMemory(b, 14) }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Vaccination coverage map. With coverageMapEvery = N, every N days the area
+// is divided into a coverageGrid x coverageGrid grid and two heat maps are
+// drawn side by side: the share of living individuals in each cell who were
+// ever vaccinated (cumulative coverage), and their mean current protection
+// after waning (see vaccineProtection). Red cells are gaps, green cells are
+// well covered, grey cells have no living individuals. The frames are saved
+// as output_gif/coverage_<gifFilename>.
+
+// coverageHeader is the height in pixels of the label strip above the maps.
+const coverageHeader = 24
+
+// coverageCell accumulates one grid cell.
+type coverageCell struct {
+	alive      int
+	vaccinated int
+	protection float64
+}
+
+// coverageGrid bins the living population into a grid x grid array of cells.
+func coverageGrid(env *Environment, grid int) []coverageCell {
+	cells := make([]coverageCell, grid*grid)
+	cellIndex := func(v float64) int {
+		i := int(v / env.areaSize * float64(grid))
+		return max(0, min(i, grid-1))
+	}
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus == Dead {
+			continue
+		}
+		c := &cells[cellIndex(ind.position.y)*grid+cellIndex(ind.position.x)]
+		c.alive++
+		if ind.vaccinated {
+			c.vaccinated++
+		}
+		c.protection += vaccineProtection(ind)
+	}
+	return cells
+}
+
+// coverageColor maps a level in [0, 1] from red (0) through yellow to green (1).
+func coverageColor(level float64) color.RGBA {
+	level = clamp01(level)
+	if level < 0.5 {
+		return color.RGBA{200, uint8(40 + 300*level), 40, 255}
+	}
+	return color.RGBA{uint8(200 - 320*(level-0.5)), 190, 40, 255}
+}
+
+// DrawCoverageMap renders the cumulative coverage and current protection maps
+// for the current day, each size x size pixels.
+func DrawCoverageMap(env *Environment, size, grid int) image.Image {
+	if grid <= 0 {
+		grid = 10
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 2*size, size+coverageHeader))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	cells := coverageGrid(env, grid)
+	alive, vaccinated, protection := 0, 0, 0.0
+	for _, c := range cells {
+		alive += c.alive
+		vaccinated += c.vaccinated
+		protection += c.protection
+	}
+
+	// Left panel: ever vaccinated; right panel: current protection
+	for panel := 0; panel < 2; panel++ {
+		for i, c := range cells {
+			col := color.RGBA{40, 40, 40, 255}
+			if c.alive > 0 {
+				level := float64(c.vaccinated) / float64(c.alive)
+				if panel == 1 {
+					level = c.protection / float64(c.alive)
+				}
+				col = coverageColor(level)
+			}
+			x, y := i%grid, i/grid
+			// one pixel of black between cells
+			r := image.Rect(panel*size+x*size/grid, coverageHeader+y*size/grid,
+				panel*size+(x+1)*size/grid-1, coverageHeader+(y+1)*size/grid-1)
+			draw.Draw(img, r, &image.Uniform{col}, image.Point{}, draw.Src)
+		}
+	}
+
+	if alive > 0 {
+		drawLabel(img, 6, 16, color.White, fmt.Sprintf("%s | Vaccinated (ever): %.1f%%",
+			dayLabel(env), 100*float64(vaccinated)/float64(alive)))
+		drawLabel(img, size+6, 16, color.White, fmt.Sprintf("Current protection: %.1f%%",
+			100*protection/float64(alive)))
+	}
+	return img
+}
+
+// coverageMap collects coverage map frames during a run.
+type coverageMap struct {
+	grid, size int
+	frames     []image.Image
+}
+
+// capture adds a frame for the current day.
+func (m *coverageMap) capture(env *Environment) {
+	m.frames = append(m.frames, DrawCoverageMap(env, m.size, m.grid))
+}
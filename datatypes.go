@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 type Disease struct {
 	name                 string
 	transmissionRate     float64
@@ -9,6 +11,10 @@ type Disease struct {
 	latentPeriod         int
 	infectiousPeriod     int
 	immunityDuration     int
+	severeFraction       float64 // share of infections needing a general ward bed
+	criticalFraction     float64 // share of infections needing an ICU bed
+	maxInfectionDays     int     // infections resolve by this many days (0 = no cap)
+	dispersion           float64 // gamma shape k of individual infectiousness, 0 = all alike (see superspreading.go)
 }
 
 type HealthStatus string
@@ -16,25 +22,72 @@ type HealthStatus string
 const (
 	Healthy     HealthStatus = "Healthy"
 	Susceptible HealthStatus = "Susceptible"
+	Exposed     HealthStatus = "Exposed" // infected but not yet infectious (seir only)
 	Infected    HealthStatus = "Infected"
 	Recovered   HealthStatus = "Recovered"
 	Dead        HealthStatus = "Dead"
 )
 
+// Severity describes how much hospital care an infected individual needs.
+type Severity string
+
+const (
+	Mild     Severity = "Mild"     // recovers at home
+	Severe   Severity = "Severe"   // needs a general ward bed
+	Critical Severity = "Critical" // needs an ICU bed
+)
+
 type Individual struct {
+	id                       int // index in env.population
 	gender                   string
 	age                      int
 	healthStatus             HealthStatus
-	disease                  *Disease
-	daysInfected             int
+	infection                *Infection // current infection, nil unless Infected
 	daysSinceRecovery        int
-	daysSinceVacination	     int
+	daysSinceVacination      int
 	vaccinated               bool
 	hygieneLevel             float64
 	socialDistanceCompliance float64
 	movementPattern          *MovementPattern
 	position                 OrderedPair
 	inHospital               bool
+	tags                     []string
+	vehicle                  *Vehicle        // train/flight boarded today, nil if not traveling
+	contacts                 *contactLog     // recent contacts, nil unless contact memory is enabled
+	road                     roadPosition    // position on the road network, if one is loaded
+	deathDay                 int             // day the individual died; meaningful only if Dead
+	timesInfected            int             // infections so far, including reinfections
+	immunityBoost            float64         // protection from non-infecting exposures, see boosting.go
+	exposed                  bool            // exposed today without infection (boosting only)
+	exposureRisk             float64         // sum of daily infection probabilities, see exposurerisk.go
+	riskDays                 int             // days with a nonzero infection probability
+	waitingForBed            bool            // in the hospital queue (hospitalQueue only)
+	waitingSince             int             // day the individual joined the hospital queue
+	deniedCare               bool            // denied a bed by the admission criteria (hospitalQueue only)
+	sideEffectsUntil         int             // vaccine side effects end on this day, 0 if none
+	quarantinedUntil         int             // isolation ends on this day, 0 if not in quarantine
+	backgroundDeath          bool            // died of other causes (backgroundMortality only)
+	shockedUntil             int             // caught up in a compliance shock until this day, 0 if not
+	pathogens                []pathogenState // state per env.pathogens, see pathogens.go
+	home                     *Household      // household, nil unless households are enabled
+	region                   int             // index in env.regions of the region it is in, 0 without regions
+	exposedBy                *Infection      // likely source of today's infection (with a transmission log), see chains.go
+	profile                  *ProfileSpec    // behavior profile, nil for the default behavior, see profiles.go
+}
+
+// Infection is an individual's ongoing infection. It exists only while the
+// individual is Infected and is dropped on recovery or death.
+type Infection struct {
+	disease      *Disease
+	daysInfected int
+	severity     Severity
+	latencyOver  bool // set by EventLatencyEnd: an Exposed individual becomes infectious today
+	mustResolve  bool // set by EventInfectionCap: the infection ends today
+	detected     bool // reported by detection or a positive test
+	chain        int  // index in the transmission log, -1 if not logged
+
+	infectiousness float64 // multiplier on its contacts, only with a dispersion (see superspreading.go)
+	treated        bool    // given an antiviral course from the stockpile (see stockpile.go)
 }
 
 // David u can decide how to structure this
@@ -51,15 +104,135 @@ const (
 	Flight moveType = "Flight"
 )
 
+// stepDistribution selects how the length of a single move is drawn, up to the move radius.
+type stepDistribution string
+
+const (
+	StepDisk        stepDistribution = "disk"        // uniform over the disk: sqrt(U)*R
+	StepUniform     stepDistribution = "uniform"     // uniform length in [0, R]
+	StepExponential stepDistribution = "exponential" // exponential, truncated at R
+	StepLevy        stepDistribution = "levy"        // truncated power law on [R/1000, R]
+)
+
 type Environment struct {
-	population               []*Individual
-	areaSize                 float64
-	socialDistanceThreshold  float64
-	hygieneLevel             float64
-	mobilityRate             float64
-	vaccinationRate          float64
-	medicalCareLevel         float64
-	medicalCapacity          int
+	population              []*Individual
+	disease                 *Disease // the disease circulating in this environment
+	areaSize                float64
+	socialDistanceThreshold float64
+	hygieneLevel            float64
+	mobilityRate            float64
+	vaccinationRate         float64
+	medicalCareLevel        float64
+	medicalCapacity         int                  // total hospital beds (general ward + ICU)
+	icuCapacity             int                  // ICU beds, a subset of medicalCapacity
+	hygieneInit             behaviorDistribution // initial individual hygieneLevel
+	weather                 *weatherSeries       // per-day climate modifiers, nil if none
+	assimilation            *Assimilation        // observed incidence curve to follow, nil if none
+	complianceInit          behaviorDistribution // initial individual socialDistanceCompliance
+	tagSpecs                []*TagSpec
+	profiles                []*ProfileSpec                // behavior profiles, see profiles.go
+	stratifyByTag           bool                          // append per-tag counts to the daily stats
+	statsPer100k            bool                          // report counts per 100,000 population
+	reportIncidence         bool                          // report new infections per day
+	transitions             dailyTransitions              // health transitions counted during the current day
+	day                     int                           // current simulated day (0 = initial state)
+	startDate               time.Time                     // calendar date of day 0; zero if no calendar
+	probsBuf                []transitionProbs             // reused by UpdatePopulationHealthStatus
+	stepDistributions       map[moveType]stepDistribution // step length law per move type (default disk)
+	levyExponent            float64                       // tail exponent of the truncated Lévy step law
+	kernel                  Kernel                        // distance decay of transmission, see kernel.go
+	chains                  *chainLog                     // transmission log, nil unless chainStats or reportReff, see chains.go
+	information             InformationConfig             // what policy learns about prevalence, see information.go
+	transit                 TransitConfig
+	distancingTrigger       triggerMetric    // signal driving the social distance policy
+	vehicles                []*Vehicle       // today's train/flight manifests
+	contactMemoryDays       int              // days of contacts remembered per individual (0 = off)
+	contactsPerDay          int              // contacts remembered per individual per day
+	lifeTable               []lifeTablePoint // remaining life expectancy by age, for YLL
+	ifrTable                []ifrBand        // infection fatality ratio by age band; nil = mortalityRate by age bucket
+	ageCurves               *ageCurves       // age curves from ageCurvesFile, nil = age buckets
+	batch                   *infectedBatch   // today's infected while probabilities are computed, nil if off
+	qaly                    QALYConfig
+	burden                  diseaseBurden // deaths, YLL and illness days accumulated over the run
+	spatialIndex            spatialIndexKind
+	index                   neighborIndex // today's spatial index; nil when stale or not in use
+	numWorkers              int           // goroutines for the per-day population updates, 0 = GOMAXPROCS
+	exposureRiskReport      bool          // summarize exposure risk at the end of the run
+	npiReport               bool          // tally each mechanism's effect on transmission, see npi.go
+	chainStats              bool          // report transmission chain statistics, see chains.go
+	reportReff              bool          // report R_effective by date of infection, see reff.go
+	npi                     npiTally      // the tally so far
+	fastForward             FastForward   // skip transmission on days with no one infectious
+	batchTransmission       bool          // score contacts from flat slices of the infected, see batch.go
+	compressOutputs         bool          // gzip the outputs with fixed names, see compress.go
+	households              HouseholdConfig
+	vaccineSupply           VaccineSupply
+	vaccineStock            vaccineStock
+	rollout                 VaccineRollout
+	stockpile               *stockpileState
+	annotations             map[int]string // day -> scenario note shown in stats and frames
+	roads                   *roadNetwork   // movement follows this network if set
+	events                  *eventQueue    // scheduled individual events; nil until first use
+	deadRender              deadRenderOptions
+	palette                 string     // key of palettes, "" = default
+	points                  pointStyle // dot opacity, size and jitter on the spatial map
+	boosting                ImmunityBoosting
+	hospitalQueue           HospitalQueueConfig
+	sideEffects             VaccineSideEffects
+	hygieneSupply           HygieneSupply
+	backgroundMortality     BackgroundMortality
+	shocks                  ShockConfig
+	lockdown                LockdownConfig   // mobility cuts while the infected fraction is high
+	seir                    bool             // new infections are Exposed for the disease's latentPeriod first
+	immunityWaning          immunityWaning   // state Recovered individuals return to
+	quarantine              QuarantineConfig // detection and isolation of infections
+	tracing                 TracingConfig    // contact tracing of detected infections
+	pathogens               []*Pathogen      // co-circulating diseases from [disease.NAME] blocks
+	pathogenTotals          []pathogenTotals // infections and deaths per pathogen so far
+	pathogenProbs           []float64        // reused by updatePathogens
+	hospital                hospitalState    // bed assignment when hospitalQueue is enabled
+	largePopulation         bool             // multi-million mode: sampled statistics and rendering
+	renderSample            int              // max individuals drawn per frame in largePopulation mode
+	renderSet               []*Individual    // the individuals drawn, chosen on the first frame
+	streams                 *streamSplitter  // daily chunk streams of a jumpable generator, nil if not jumpable
+	flows                   *flowTally       // moves between health states, nil unless flowDiagram
+	regions                 []*Region        // regions from [region.NAME] blocks, see regions.go
+	regionInfections        []int            // infections per region so far
+	cellsPerSide            int              // policy cells per side of the area, 0 = central policy
+	cells                   []PolicyCell     // local policy controllers, see policycells.go
+}
+
+// Vehicle is a train or flight with its passenger manifest for one day.
+// Everyone on board is in contact with everyone else, regardless of position.
+type Vehicle struct {
+	moveType   moveType
+	passengers []*Individual
+}
+
+// TransitConfig controls explicit transit vehicles.
+type TransitConfig struct {
+	enabled        bool
+	trainCapacity  int
+	flightCapacity int
+	contactFactor  float64 // per-contact transmission on board, relative to a contact at distance 0
+}
+
+// dailyTransitions counts health-state transitions during one day.
+// It is reset at the start of every UpdatePopulationHealthStatus call.
+type dailyTransitions struct {
+	newInfections    int
+	newDeaths        int
+	newRecoveries    int
+	newAdmissions    int // patients who started needing (or, with hospitalQueue, got) a bed
+	backgroundDeaths int // deaths from other causes, see mortality.go
+}
+
+// TagSpec describes a population subgroup (e.g. "healthcare_worker") assigned at initialization.
+type TagSpec struct {
+	name                string
+	fraction            float64 // share of the population carrying this tag
+	exposureMult        float64 // multiplier on infection/exposure probabilities
+	vaccinationPriority float64 // relative weight in vaccination rollout order
 }
 
 type OrderedPair struct {
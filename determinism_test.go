@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// TestStateChecksumsDeterministic runs the default config twice per seed, as
+// verify-determinism does, and fails on the first day the state checksums
+// differ. Any draw that bypasses the run's generator (such as the global
+// math/rand source, which is seeded randomly) shows up as a divergence.
+func TestStateChecksumsDeterministic(t *testing.T) {
+	config := getDefaultConfig()
+	config.popSize = 300
+	config.numDays = 30
+	config, err := validateConfig(config, NewConfigValidator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{1, 4} {
+		for seed := int64(1); seed <= 3; seed++ {
+			other := *config
+			other.numWorkers = workers
+			first := stateChecksums(config, seed, config.numDays)
+			second := stateChecksums(&other, seed, config.numDays)
+			if len(first) != len(second) {
+				t.Fatalf("seed %d, %d workers: runs stopped after %d and %d days", seed, workers, len(first)-1, len(second)-1)
+			}
+			for day := range first {
+				if first[day] != second[day] {
+					t.Fatalf("seed %d, %d workers: runs diverge on day %d", seed, workers, day)
+				}
+			}
+		}
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"image/color/palette"
 	"image/draw"
 	"image/gif"
+	"image/png"
 	"math"
 	"os"
+	"slices"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
@@ -27,7 +29,7 @@ func AnimateEnvironment(timePoints []*Environment, canvasWidth, frequency int, s
 
 	for i := range timePoints {
 		if i%frequency == 0 {
-			fmt.Println("frame", i)
+			fmt.Fprintln(msgOut, "frame", i)
 			images = append(images, timePoints[i].DrawToCanvas(canvasWidth, scalingFactor))
 		}
 	}
@@ -56,27 +58,71 @@ func (env *Environment) DrawToCanvas(canvasWidth int, scalingFactor float64) ima
 		env.areaSize = 1.0
 	}
 
-	// Draw each individual as a circle
-	for _, ind := range env.population {
-		if ind == nil {
-			continue
-		}
+	// Radius in pixels; if scalingFactor is non-positive, use a default
+	radius := scalingFactor
+	if radius <= 0 {
+		radius = 2.0
+	}
+
+	// Draw each individual as a circle. Unless dead agents are drawn as plain
+	// grey dots, they go in a first pass so they never cover the living.
+	mode := env.deadRender.mode
+	layered := mode != "" && mode != DeadGrey
+	passes := 1
+	if layered {
+		passes = 2
+	}
+	drawPolicyCells(&c, env, canvasWidth)
+
+	drawn := renderPopulation(env)
+	p := paletteFor(env)
+	radii := pointRadii(env.points, env, drawn, canvasWidth, radius)
+	for pass := 0; pass < passes; pass++ {
+		for i, ind := range drawn {
+			if ind == nil {
+				continue
+			}
+			dead := ind.healthStatus == Dead
+			if layered && dead != (pass == 0) {
+				continue
+			}
 
-		r, g, b := colorForHealthStatus(ind)
-		c.SetFillColor(canvas.MakeColor(r, g, b))
+			// Map position from [0, areaSize] to [0, canvasWidth], or to its region's panel
+			cx, cy := regionPixel(env, canvasWidth, ind.region, ind.position)
+			dx, dy := pointJitter(env.points, ind)
+			cx, cy = cx+dx, cy+dy
+			rad := radius
+			if radii != nil {
+				rad = radii[i]
+			}
 
-		// Map position from [0, areaSize] to [0, canvasWidth]
-		cx := (ind.position.x / env.areaSize) * float64(canvasWidth)
-		cy := (ind.position.y / env.areaSize) * float64(canvasWidth)
+			if dead && layered {
+				drawDead(&c, env, ind, cx, cy, rad)
+				continue
+			}
 
-		// Radius in pixels; if scalingFactor is non-positive, use a default
-		radius := scalingFactor
-		if radius <= 0 {
-			radius = 2.0
+			r, g, b := colorForHealthStatus(p, ind)
+			if pr, pg, pb, ok := pathogenColor(ind); ok {
+				r, g, b = pr, pg, pb
+			}
+			c.SetFillColor(pointFill(env.points, r, g, b))
+			c.Circle(cx, cy, rad)
+			c.Fill()
 		}
+	}
 
-		c.Circle(cx, cy, radius)
-		c.Fill()
+	// With regions, grey lines separate the panels
+	cols, panel := regionPanels(env, canvasWidth)
+	if len(env.regions) > 1 {
+		c.SetStrokeColor(canvas.MakeColor(90, 90, 90))
+		c.SetLineWidth(1)
+		for i := 1; i < cols; i++ {
+			c.MoveTo(float64(i)*panel, 0)
+			c.LineTo(float64(i)*panel, float64(canvasWidth))
+			c.MoveTo(0, float64(i)*panel)
+			c.LineTo(float64(canvasWidth), float64(i)*panel)
+		}
+		c.Stroke()
 	}
 
 	// Get underlying image from canvas
@@ -94,43 +140,184 @@ func (env *Environment) DrawToCanvas(canvasWidth int, scalingFactor float64) ima
 	}
 
 	// Compute status counts
-	h, v, s, inf, r, d := statusCountsFromEnv(env)
+	h, v, s, e, inf, r, d := statusCountsFromEnv(env)
 	// Total population (vaccinated is overlapping property, so not included in total)
-	total := h + s + inf + r + d
-
-	// Build overlay string (you can prepend time/day here if you store it in Environment)
-	label := fmt.Sprintf("N=%d | H:%d  S:%d  I:%d  R:%d  D:%d  V:%d",
-		total, h, s, inf, r, d, v)
+	total := h + s + e + inf + r + d
+
+	// Build overlay string, prefixed with the simulated day (and date, if configured)
+	label := fmt.Sprintf("%s | N=%d | H:%d  S:%d%s  I:%d  R:%d  D:%d  V:%d",
+		dayLabel(env), total, h, s, exposedLabel(env, e), inf, r, d, v)
+	label += pathogenLabel(env, collectPathogenCounts(env))
+	if len(drawn) < len(env.population) {
+		label += fmt.Sprintf(" | %d shown", len(drawn))
+	}
 
-	// Draw label at the top-left
+	// Draw label at the top-left, with the latest scenario annotation below it
 	drawLabel(rgba, 10, 20, color.White, label)
+	if note := annotationLabel(env); note != "" {
+		drawLabel(rgba, 10, 36, color.White, note)
+	}
+
+	// Each region's name and infected count at the bottom of its panel
+	if len(env.regions) > 1 {
+		counts := collectRegionCounts(env)
+		for k, r := range env.regions {
+			x, y := float64(k%cols)*panel, float64(k/cols+1)*panel
+			drawLabel(rgba, int(x)+6, int(y)-6, color.White, fmt.Sprintf("%s I:%d", r.name, counts[k].Infected))
+		}
+	}
+
+	// Cumulative deaths in the bottom-left corner, useful when dead agents are hidden
+	if env.deadRender.counter {
+		drawLabel(rgba, 10, canvasWidth-10, color.White, fmt.Sprintf("Deaths: %d", d))
+	}
 
 	return rgba
 }
 
-// colorForHealthStatus returns an RGB color for an individual based on their health status and vaccination status
-func colorForHealthStatus(ind *Individual) (uint8, uint8, uint8) {
-	if ind == nil {
-		return 255, 255, 255 // fallback: white
+// drawPolicyCells outlines the policy cells in dark grey, and those in
+// lockdown in dark red (no-op without policy cells).
+func drawPolicyCells(c *canvas.Canvas, env *Environment, canvasWidth int) {
+	if len(env.cells) == 0 {
+		return
 	}
-	if ind.vaccinated && ind.healthStatus != Dead {
-		return 64, 128, 64 // Dark Green
+	n := env.cellsPerSide
+	cols, panel := regionPanels(env, canvasWidth)
+	side := panel / float64(n)
+	for _, locked := range []bool{false, true} {
+		if locked {
+			c.SetStrokeColor(canvas.MakeColor(150, 30, 30))
+		} else {
+			c.SetStrokeColor(canvas.MakeColor(45, 45, 45))
+		}
+		c.SetLineWidth(1)
+		for k, cell := range env.cells {
+			if cell.lockdown.active != locked {
+				continue
+			}
+			r := k / (n * n)
+			x := float64(r%cols)*panel + float64(k%n)*side
+			y := float64(r/cols)*panel + float64(k/n%n)*side
+			c.MoveTo(x, y)
+			c.LineTo(x+side, y)
+			c.LineTo(x+side, y+side)
+			c.LineTo(x, y+side)
+			c.LineTo(x, y)
+		}
+		c.Stroke()
 	}
+}
+
+// deadRenderMode selects how dead individuals appear on the spatial map.
+type deadRenderMode string
+
+const (
+	DeadGrey   deadRenderMode = "grey"   // grey dot, like any other agent
+	DeadFade   deadRenderMode = "fade"   // grey dot that darkens to nothing over fadeFrames frames
+	DeadCross  deadRenderMode = "cross"  // small grey cross marker
+	DeadRemove deadRenderMode = "remove" // not drawn at all
+)
+
+// deadRenderOptions controls how dead individuals are drawn on the spatial map.
+type deadRenderOptions struct {
+	mode           deadRenderMode
+	fadeFrames     int  // frames until a dead agent has fully faded (fade mode)
+	frameFrequency int  // days between frames, to convert days since death to frames
+	counter        bool // overlay the cumulative death count
+}
 
-	switch ind.healthStatus {
-	case Healthy:
-		return 128, 255, 0 // Green
-	case Susceptible:
-		return 255, 255, 0 // Yellow
-	case Infected:
-		return 255, 0, 0 // Red
-	case Recovered:
-		return 0, 128, 255 // Blue
-	case Dead:
-		return 160, 160, 160 // Grey
-	default:
-		return 255, 255, 255 // unknown: white
+// drawDead draws a dead individual at pixel (cx, cy) according to env.deadRender.
+// Dead agents are drawn before the living, so the living stay visible on top.
+func drawDead(c *canvas.Canvas, env *Environment, ind *Individual, cx, cy, radius float64) {
+	const grey = 160
+	opts := env.deadRender
+	switch opts.mode {
+	case DeadFade:
+		// Fading against the black background is darkening the grey
+		frames := float64(env.day-ind.deathDay) / float64(max(opts.frameFrequency, 1))
+		f := 1 - frames/float64(max(opts.fadeFrames, 1))
+		if f <= 0 {
+			return
+		}
+		v := uint8(grey * f)
+		c.SetFillColor(canvas.MakeColor(v, v, v))
+		c.Circle(cx, cy, radius)
+		c.Fill()
+	case DeadCross:
+		c.SetStrokeColor(canvas.MakeColor(grey, grey, grey))
+		c.SetLineWidth(1)
+		c.MoveTo(cx-radius, cy-radius)
+		c.LineTo(cx+radius, cy+radius)
+		c.MoveTo(cx-radius, cy+radius)
+		c.LineTo(cx+radius, cy-radius)
+		c.Stroke()
+	case DeadRemove:
+		// not drawn
+	}
+}
+
+// statusPalette holds the colors of the health states on the map and the pie chart.
+type statusPalette struct {
+	healthy, vaccinated, susceptible, exposed, infected, recovered, dead color.RGBA
+}
+
+// palettes are the color schemes selectable with the palette config key.
+var palettes = map[string]statusPalette{
+	"default": {
+		healthy:     color.RGBA{128, 255, 0, 255},   // Green
+		vaccinated:  color.RGBA{64, 128, 64, 255},   // Dark Green
+		susceptible: color.RGBA{255, 255, 0, 255},   // Yellow
+		exposed:     color.RGBA{160, 32, 240, 255},  // Purple
+		infected:    color.RGBA{255, 0, 0, 255},     // Red
+		recovered:   color.RGBA{0, 128, 255, 255},   // Blue
+		dead:        color.RGBA{160, 160, 160, 255}, // Grey
+	},
+	// Okabe-Ito colors, distinguishable with the common forms of color blindness
+	"colorblind": {
+		healthy:     color.RGBA{0, 158, 115, 255},   // Bluish green
+		vaccinated:  color.RGBA{0, 114, 178, 255},   // Blue
+		susceptible: color.RGBA{240, 228, 66, 255},  // Yellow
+		exposed:     color.RGBA{204, 121, 167, 255}, // Reddish purple
+		infected:    color.RGBA{213, 94, 0, 255},    // Vermillion
+		recovered:   color.RGBA{86, 180, 233, 255},  // Sky blue
+		dead:        color.RGBA{160, 160, 160, 255}, // Grey
+	},
+}
+
+// paletteNames lists the palettes in the order shown in the config help.
+var paletteNames = []string{"default", "colorblind"}
+
+// paletteFor returns env's palette, or the default one.
+func paletteFor(env *Environment) statusPalette {
+	if p, ok := palettes[env.palette]; ok {
+		return p
+	}
+	return palettes["default"]
+}
+
+// colorForHealthStatus returns an RGB color from palette p for an individual based on their health status and vaccination status
+func colorForHealthStatus(p statusPalette, ind *Individual) (uint8, uint8, uint8) {
+	if ind == nil {
+		return 255, 255, 255 // fallback: white
+	}
+	c := color.RGBA{255, 255, 255, 255} // unknown: white
+	switch {
+	case ind.vaccinated && ind.healthStatus != Dead:
+		c = p.vaccinated
+	case ind.healthStatus == Healthy:
+		c = p.healthy
+	case ind.healthStatus == Susceptible:
+		c = p.susceptible
+	case ind.healthStatus == Exposed:
+		c = p.exposed
+	case ind.healthStatus == Infected:
+		c = p.infected
+	case ind.healthStatus == Recovered:
+		c = p.recovered
+	case ind.healthStatus == Dead:
+		c = p.dead
 	}
+	return c.R, c.G, c.B
 }
 
 // SaveEnvironmentGIF encodes a sequence of frames into a single GIF file.
@@ -163,8 +350,53 @@ func SaveEnvironmentGIF(filename string, frames []image.Image, delay int) error
 	return nil
 }
 
+// frameHistory holds the spatial and pie frames captured so far, with the day of each frame.
+type frameHistory struct {
+	days    []int
+	spatial []image.Image
+	pie     []image.Image
+}
+
+// add appends the frames captured on day. Frames for a day that is already
+// the latest in the history are ignored.
+func (h *frameHistory) add(day int, spatial, pie image.Image) {
+	if n := len(h.days); n > 0 && h.days[n-1] == day {
+		return
+	}
+	h.days = append(h.days, day)
+	h.spatial = append(h.spatial, spatial)
+	h.pie = append(h.pie, pie)
+}
+
+// thin drops frames older than cutoffDay so that at most one frame per week
+// (the last one captured in that week) remains. Frames from cutoffDay on are kept.
+func (h *frameHistory) thin(cutoffDay int) {
+	kept := 0
+	for i, day := range h.days {
+		keep := day >= cutoffDay ||
+			i == len(h.days)-1 ||
+			h.days[i+1] >= cutoffDay ||
+			h.days[i+1]/7 != day/7
+		if !keep {
+			continue
+		}
+		h.days[kept] = h.days[i]
+		h.spatial[kept] = h.spatial[i]
+		h.pie[kept] = h.pie[i]
+		kept++
+	}
+	for i := kept; i < len(h.days); i++ {
+		// release dropped images for the garbage collector
+		h.spatial[i] = nil
+		h.pie[i] = nil
+	}
+	h.days = h.days[:kept]
+	h.spatial = h.spatial[:kept]
+	h.pie = h.pie[:kept]
+}
+
 // DrawEnvironmentPie renders a pie chart of population status for a single Environment at one time step.
-// The pie shows counts of Healthy, Vaccinated(alive), Susceptible, Infected, Recovered, and Dead.
+// The pie shows counts of Healthy, Vaccinated(alive), Susceptible, Exposed (with seir), Infected, Recovered, and Dead.
 // A text overlay at the top also shows the exact counts and total population.
 func DrawEnvironmentPie(env *Environment, size int) image.Image {
 	if env == nil {
@@ -179,18 +411,20 @@ func DrawEnvironmentPie(env *Environment, size int) image.Image {
 	// Background: Black
 	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
 
-	h, v, s, inf, r, d := statusCountsFromEnv(env)
-	total := h + s + inf + r + d
+	h, v, s, e, inf, r, d := statusCountsFromEnv(env)
+	total := h + s + e + inf + r + d
 	if total == 0 {
 		return img
 	}
 
-	colHealthy := color.RGBA{128, 255, 0, 255}    // Green
-	colVaccniated := color.RGBA{64, 128, 64, 255} // Dark Green
-	colSuscept := color.RGBA{255, 255, 0, 255}    // Yellow
-	colInfect := color.RGBA{255, 0, 0, 255}       // Red
-	colRecov := color.RGBA{0, 128, 255, 255}      // Blue
-	colDead := color.RGBA{160, 160, 160, 255}     // Grey
+	p := paletteFor(env)
+	colHealthy := p.healthy
+	colVaccniated := p.vaccinated
+	colSuscept := p.susceptible
+	colExposed := p.exposed
+	colInfect := p.infected
+	colRecov := p.recovered
+	colDead := p.dead
 
 	type slice struct {
 		start float64
@@ -216,10 +450,11 @@ func DrawEnvironmentPie(env *Environment, size int) image.Image {
 		acc += frac * tau
 	}
 
-	// Order: D, R, I, S, V, H
+	// Order: D, R, I, E, S, V, H
 	addSlice(d, colDead)
 	addSlice(r, colRecov)
 	addSlice(inf, colInfect)
+	addSlice(e, colExposed)
 	addSlice(s, colSuscept)
 	addSlice(v, colVaccniated)
 	addSlice(h, colHealthy)
@@ -254,17 +489,20 @@ func DrawEnvironmentPie(env *Environment, size int) image.Image {
 		}
 	}
 
-	// Overlay text with counts at the top of the pie chart
-	label := fmt.Sprintf("N=%d | H:%d  S:%d  I:%d  R:%d  D:%d  V:%d",
-		total, h, s, inf, r, d, v)
+	// Overlay text with day and counts at the top of the pie chart
+	label := fmt.Sprintf("%s | N=%d | H:%d  S:%d%s  I:%d  R:%d  D:%d  V:%d",
+		dayLabel(env), total, h, s, exposedLabel(env, e), inf, r, d, v)
 	drawLabel(img, 10, 20, color.White, label)
+	if note := annotationLabel(env); note != "" {
+		drawLabel(img, 10, 36, color.White, note)
+	}
 
 	return img
 }
 
 // statusCountsFromEnv returns the counts of individuals by status:
-// healthy (non-infected), vaccinated (alive), susceptible, infected, recovered, and dead.
-func statusCountsFromEnv(env *Environment) (healthy, vaccinated, susceptible, infected, recovered, dead int) {
+// healthy (non-infected), vaccinated (alive), susceptible, exposed, infected, recovered, and dead.
+func statusCountsFromEnv(env *Environment) (healthy, vaccinated, susceptible, exposed, infected, recovered, dead int) {
 	if env == nil {
 		return
 	}
@@ -284,6 +522,8 @@ func statusCountsFromEnv(env *Environment) (healthy, vaccinated, susceptible, in
 			healthy++
 		case Susceptible:
 			susceptible++
+		case Exposed:
+			exposed++
 		case Infected: // Already counted in totalInfected
 		case Recovered:
 			recovered++
@@ -294,6 +534,14 @@ func statusCountsFromEnv(env *Environment) (healthy, vaccinated, susceptible, in
 	return
 }
 
+// exposedLabel returns the Exposed count for the frame labels, only with seir.
+func exposedLabel(env *Environment, exposed int) string {
+	if !env.seir {
+		return ""
+	}
+	return fmt.Sprintf("  E:%d", exposed)
+}
+
 // AnimateEnvironmentPie generates a sequence of pie-chart images for a sequence of Environments.
 // Each Environment in timePoints becomes one frame (subsampled by frequency).
 func AnimateEnvironmentPie(timePoints []*Environment, size, frequency int) []image.Image {
@@ -305,7 +553,7 @@ func AnimateEnvironmentPie(timePoints []*Environment, size, frequency int) []ima
 
 	for i := range timePoints {
 		if i%frequency == 0 {
-			fmt.Println("pie frame", i)
+			fmt.Fprintln(msgOut, "pie frame", i)
 			images = append(images, DrawEnvironmentPie(timePoints[i], size))
 		}
 	}
@@ -313,6 +561,284 @@ func AnimateEnvironmentPie(timePoints []*Environment, size, frequency int) []ima
 	return images
 }
 
+// Margins of the epidemic curve plot area, in pixels.
+const (
+	curveLeft   = 50 // room for the count labels
+	curveTop    = 40 // room for the day label and legend
+	curveRight  = 10
+	curveBottom = 20 // room for the day axis labels
+)
+
+// DrawEpidemicCurve renders the Infected, Recovered and Dead counts of stats
+// as a line chart of width x height pixels, with axes fitted to the rows.
+func DrawEpidemicCurve(stats []DayStats, width, height int) image.Image {
+	lastDay, peak := curveAxes(stats)
+	return drawEpidemicCurve(stats, width, height, lastDay, peak)
+}
+
+// curveAxes returns the last day and the largest count in stats.
+func curveAxes(stats []DayStats) (lastDay, peak int) {
+	lastDay, peak = 1, 1
+	for _, s := range stats {
+		lastDay = max(lastDay, s.Day)
+		peak = max(peak, s.Infected, s.Recovered, s.Dead)
+	}
+	return lastDay, peak
+}
+
+// drawEpidemicCurve draws stats on axes running to lastDay and peak, so the
+// frames of a growing curve all share the final axes.
+func drawEpidemicCurve(stats []DayStats, width, height, lastDay, peak int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	plotW := max(width-curveLeft-curveRight, 1)
+	plotH := max(height-curveTop-curveBottom, 1)
+	px := func(day int) int { return curveLeft + day*plotW/max(lastDay, 1) }
+	py := func(count int) int { return curveTop + plotH - count*plotH/max(peak, 1) }
+
+	// Axes with the largest count and the last day
+	axis := color.RGBA{100, 100, 100, 255}
+	drawLine(img, curveLeft, curveTop, curveLeft, curveTop+plotH, axis)
+	drawLine(img, curveLeft, curveTop+plotH, curveLeft+plotW, curveTop+plotH, axis)
+	drawLabel(img, 4, curveTop+10, color.White, fmt.Sprintf("%d", peak))
+	drawLabel(img, 4, curveTop+plotH, color.White, "0")
+	drawLabel(img, curveLeft, height-4, color.White, "0")
+	dayText := fmt.Sprintf("Day %d", lastDay)
+	drawLabel(img, curveLeft+plotW-7*len(dayText), height-4, color.White, dayText)
+
+	p := palettes["default"]
+	series := []struct {
+		name  string
+		col   color.RGBA
+		count func(DayStats) int
+	}{
+		{"Infected", p.infected, func(s DayStats) int { return s.Infected }},
+		{"Recovered", p.recovered, func(s DayStats) int { return s.Recovered }},
+		{"Dead", p.dead, func(s DayStats) int { return s.Dead }},
+	}
+	x := curveLeft
+	for _, sr := range series {
+		for i := 1; i < len(stats); i++ {
+			x0, y0 := px(stats[i-1].Day), py(sr.count(stats[i-1]))
+			x1, y1 := px(stats[i].Day), py(sr.count(stats[i]))
+			// two pixels thick
+			drawLine(img, x0, y0, x1, y1, sr.col)
+			drawLine(img, x0, y0-1, x1, y1-1, sr.col)
+		}
+		drawLabel(img, x, 34, sr.col, sr.name)
+		x += 7*len(sr.name) + 14
+	}
+
+	if n := len(stats); n > 0 {
+		s := stats[n-1]
+		drawLabel(img, 10, 16, color.White, fmt.Sprintf("Day %d | I:%d  R:%d  D:%d", s.Day, s.Infected, s.Recovered, s.Dead))
+	}
+	return img
+}
+
+// EpidemicCurveFrames returns the frames of an animated epidemic curve that
+// grows by every days per frame, ending with the full curve.
+func EpidemicCurveFrames(stats []DayStats, width, height, every int) []image.Image {
+	if len(stats) == 0 {
+		return nil
+	}
+	if every <= 0 {
+		every = 1
+	}
+	lastDay, peak := curveAxes(stats)
+	var frames []image.Image
+	for i, s := range stats {
+		if s.Day%every == 0 || i == len(stats)-1 {
+			frames = append(frames, drawEpidemicCurve(stats[:i+1], width, height, lastDay, peak))
+		}
+	}
+	return frames
+}
+
+// SaveEpidemicCurve writes the full epidemic curve of stats to filename as a PNG.
+func SaveEpidemicCurve(filename string, stats []DayStats, width, height int) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, DrawEpidemicCurve(stats, width, height)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DrawScenarioChart renders the infected count of every scenario over time as
+// a line chart of width x height pixels, one color per scenario.
+func DrawScenarioChart(results []scenarioResult, width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	lastDay, peak := 1, 1
+	for _, res := range results {
+		for _, s := range res.rows {
+			lastDay = max(lastDay, s.Day)
+			peak = max(peak, s.Infected)
+		}
+	}
+	plotW := max(width-curveLeft-curveRight, 1)
+	plotH := max(height-curveTop-curveBottom, 1)
+	px := func(day int) int { return curveLeft + day*plotW/lastDay }
+	py := func(count int) int { return curveTop + plotH - count*plotH/peak }
+
+	axis := color.RGBA{100, 100, 100, 255}
+	drawLine(img, curveLeft, curveTop, curveLeft, curveTop+plotH, axis)
+	drawLine(img, curveLeft, curveTop+plotH, curveLeft+plotW, curveTop+plotH, axis)
+	drawLabel(img, 4, curveTop+10, color.White, fmt.Sprintf("%d", peak))
+	drawLabel(img, 4, curveTop+plotH, color.White, "0")
+	drawLabel(img, curveLeft, height-4, color.White, "0")
+	dayText := fmt.Sprintf("Day %d", lastDay)
+	drawLabel(img, curveLeft+plotW-7*len(dayText), height-4, color.White, dayText)
+	drawLabel(img, 10, 16, color.White, "Infected by scenario")
+
+	x := curveLeft
+	for k, res := range results {
+		c := pathogenColors[k%len(pathogenColors)]
+		col := color.RGBA{c[0], c[1], c[2], 255}
+		for i := 1; i < len(res.rows); i++ {
+			x0, y0 := px(res.rows[i-1].Day), py(res.rows[i-1].Infected)
+			x1, y1 := px(res.rows[i].Day), py(res.rows[i].Infected)
+			drawLine(img, x0, y0, x1, y1, col)
+			drawLine(img, x0, y0-1, x1, y1-1, col)
+		}
+		drawLabel(img, x, 34, col, res.name)
+		x += 7*len(res.name) + 14
+	}
+	return img
+}
+
+// SaveScenarioChart writes the scenario comparison chart to filename as a PNG.
+func SaveScenarioChart(filename string, results []scenarioResult, width, height int) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, DrawScenarioChart(results, width, height)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DrawReplicateChart plots the infected curve of every replicate in grey,
+// with the central 95% band over the replicates shaded and their mean on top.
+func DrawReplicateChart(results []scenarioResult, width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	days, _, bands := dayBands(results)
+	infected := slices.IndexFunc(replicateColumns, func(c replicateColumn) bool { return c.name == "Infected" })
+	lastDay, peak := 1, 1
+	for _, res := range results {
+		for _, s := range res.rows {
+			lastDay = max(lastDay, s.Day)
+			peak = max(peak, s.Infected)
+		}
+	}
+	plotW := max(width-curveLeft-curveRight, 1)
+	plotH := max(height-curveTop-curveBottom, 1)
+	px := func(day int) int { return curveLeft + day*plotW/lastDay }
+	py := func(count float64) int { return curveTop + plotH - int(count*float64(plotH)/float64(peak)) }
+
+	axis := color.RGBA{100, 100, 100, 255}
+	drawLine(img, curveLeft, curveTop, curveLeft, curveTop+plotH, axis)
+	drawLine(img, curveLeft, curveTop+plotH, curveLeft+plotW, curveTop+plotH, axis)
+	drawLabel(img, 4, curveTop+10, color.White, fmt.Sprintf("%d", peak))
+	drawLabel(img, 4, curveTop+plotH, color.White, "0")
+	drawLabel(img, curveLeft, height-4, color.White, "0")
+	dayText := fmt.Sprintf("Day %d", lastDay)
+	drawLabel(img, curveLeft+plotW-7*len(dayText), height-4, color.White, dayText)
+	drawLabel(img, 10, 16, color.White, fmt.Sprintf("Infected over %d replicates", len(results)))
+
+	// The 95% band, one vertical line per pixel column between two days
+	shade := color.RGBA{60, 60, 110, 255}
+	for i := 1; i < len(days); i++ {
+		x0, x1 := px(days[i-1]), px(days[i])
+		for x := x0; x <= x1; x++ {
+			f := 0.0
+			if x1 > x0 {
+				f = float64(x-x0) / float64(x1-x0)
+			}
+			lo := bands[i-1][infected].lo*(1-f) + bands[i][infected].lo*f
+			hi := bands[i-1][infected].hi*(1-f) + bands[i][infected].hi*f
+			drawLine(img, x, py(lo), x, py(hi), shade)
+		}
+	}
+
+	grey := color.RGBA{130, 130, 130, 255}
+	for _, res := range results {
+		for i := 1; i < len(res.rows); i++ {
+			drawLine(img, px(res.rows[i-1].Day), py(float64(res.rows[i-1].Infected)),
+				px(res.rows[i].Day), py(float64(res.rows[i].Infected)), grey)
+		}
+	}
+
+	mean := color.RGBA{255, 80, 80, 255}
+	for i := 1; i < len(days); i++ {
+		x0, y0 := px(days[i-1]), py(bands[i-1][infected].mean)
+		x1, y1 := px(days[i]), py(bands[i][infected].mean)
+		drawLine(img, x0, y0, x1, y1, mean)
+		drawLine(img, x0, y0-1, x1, y1-1, mean)
+	}
+	drawLabel(img, curveLeft, 34, mean, "mean")
+	drawLabel(img, curveLeft+56, 34, shade, "95% band")
+	drawLabel(img, curveLeft+140, 34, grey, "replicates")
+	return img
+}
+
+// SaveReplicateChart writes the replicate chart to filename as a PNG.
+func SaveReplicateChart(filename string, results []scenarioResult, width, height int) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, DrawReplicateChart(results, width, height)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// drawLine draws a one-pixel line from (x0, y0) to (x1, y1) (Bresenham).
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, col color.Color) {
+	abs := func(v int) int {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.Set(x0, y0, col)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
 // drawLabel renders a simple text string onto an RGBA image at (x, y) in white (or any given color).
 func drawLabel(img *image.RGBA, x, y int, col color.Color, s string) {
 	d := &font.Drawer{
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Health economics: years of life lost (YLL) and quality-adjusted life years (QALYs) lost.
+//
+// YLL counts, for every disease death, the remaining life expectancy at the
+// age of death, read from a life table. QALY losses add the quality of life
+// lost while ill (per infected day, by severity) and an expected long-term
+// loss for recoveries with sequelae. Totals accumulate over the run and are
+// printed in the final summary.
+
+// lifeTablePoint is the remaining life expectancy (in years) at a given age.
+type lifeTablePoint struct {
+	age       int
+	remaining float64
+}
+
+// defaultLifeTable approximates a standard reference life table
+// (remaining years at each age), used when no lifeExpectancy.AGE keys are given.
+var defaultLifeTable = []lifeTablePoint{
+	{0, 88.9}, {10, 79.0}, {20, 69.0}, {30, 59.2}, {40, 49.3},
+	{50, 39.6}, {60, 30.2}, {70, 21.2}, {80, 13.1}, {90, 6.9},
+}
+
+// QALYConfig controls the optional QALY loss estimate.
+type QALYConfig struct {
+	enabled            bool
+	illnessDisutility  float64 // share of a day's quality lost per day of mild illness
+	sequelaeFraction   float64 // share of recoveries left with lasting sequelae
+	sequelaeDisutility float64 // share of quality lost per year while living with sequelae
+	sequelaeYears      float64 // how long sequelae last
+}
+
+// severityDisutilityMult scales illnessDisutility for severe and critical cases (example values).
+var severityDisutilityMult = map[Severity]float64{
+	Mild:     1.0,
+	Severe:   2.5,
+	Critical: 4.0,
+}
+
+// diseaseBurden accumulates the inputs to YLL and QALY estimates over a run.
+type diseaseBurden struct {
+	deaths      int
+	yll         float64
+	illnessDays map[Severity]int // person-days spent infected, by severity
+	recoveries  int
+}
+
+// lifeTableFromConfig turns age -> remaining years entries into a sorted table.
+// Returns the default table if there are no entries.
+func lifeTableFromConfig(entries map[int]float64) []lifeTablePoint {
+	if len(entries) == 0 {
+		return defaultLifeTable
+	}
+	table := make([]lifeTablePoint, 0, len(entries))
+	for age, rem := range entries {
+		table = append(table, lifeTablePoint{age: age, remaining: rem})
+	}
+	sort.Slice(table, func(i, j int) bool { return table[i].age < table[j].age })
+	return table
+}
+
+// remainingLifeYears interpolates the life table linearly at the given age,
+// holding the end values outside the table's range.
+func remainingLifeYears(table []lifeTablePoint, age int) float64 {
+	if len(table) == 0 {
+		return 0
+	}
+	if age <= table[0].age {
+		return table[0].remaining
+	}
+	for i := 1; i < len(table); i++ {
+		if age <= table[i].age {
+			lo, hi := table[i-1], table[i]
+			t := float64(age-lo.age) / float64(hi.age-lo.age)
+			return lo.remaining + t*(hi.remaining-lo.remaining)
+		}
+	}
+	return table[len(table)-1].remaining
+}
+
+// recordDeath adds a disease death and its years of life lost to the run totals.
+func recordDeath(env *Environment, ind *Individual) {
+	if env == nil || ind == nil {
+		return
+	}
+	env.burden.deaths++
+	env.burden.yll += remainingLifeYears(env.lifeTable, ind.age)
+}
+
+// recordIllnessDay adds one day of illness at ind's severity to the run totals.
+func recordIllnessDay(env *Environment, ind *Individual) {
+	if env == nil || ind == nil {
+		return
+	}
+	if env.burden.illnessDays == nil {
+		env.burden.illnessDays = map[Severity]int{}
+	}
+	sev := Mild
+	if ind.infection != nil {
+		sev = ind.infection.severity
+	}
+	if sev == "" {
+		sev = Mild
+	}
+	env.burden.illnessDays[sev]++
+}
+
+// qalyLosses splits the QALY loss into acute illness and sequelae components.
+func qalyLosses(env *Environment) (illness, sequelae float64) {
+	q := env.qaly
+	for sev, days := range env.burden.illnessDays {
+		illness += float64(days) / 365.0 * clamp01(q.illnessDisutility*severityDisutilityMult[sev])
+	}
+	sequelae = float64(env.burden.recoveries) * q.sequelaeFraction * q.sequelaeDisutility * q.sequelaeYears
+	return illness, sequelae
+}
+
+// printBurdenSummary prints the run's YLL total and, if enabled, QALY losses.
+func printBurdenSummary(env *Environment) {
+	b := env.burden
+	meanYLL := 0.0
+	if b.deaths > 0 {
+		meanYLL = b.yll / float64(b.deaths)
+	}
+	fmt.Fprintf(msgOut, "Years of life lost: %.1f over %d deaths (%.1f per death)\n", b.yll, b.deaths, meanYLL)
+	if !env.qaly.enabled {
+		return
+	}
+	illness, sequelae := qalyLosses(env)
+	fmt.Fprintf(msgOut, "QALYs lost: %.1f (deaths %.1f, acute illness %.1f, sequelae %.1f)\n",
+		b.yll+illness+sequelae, b.yll, illness, sequelae)
+}
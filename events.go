@@ -0,0 +1,136 @@
+package main
+
+import (
+	"container/heap"
+	"math/rand"
+)
+
+// Individual-level events that happen at a known time (rather than with a daily
+// probability) are kept in a single priority queue on the environment, keyed by
+// simulated time in days. Times may be fractional: an event at 12.5 fires while
+// day 12 is processed, after any event at 12.25. Events at equal times fire in
+// the order they were scheduled, so runs stay reproducible.
+
+// eventKind identifies what a scheduled event does when it fires.
+type eventKind int
+
+const (
+	// EventInfectionCap marks an infection as having run for the disease's
+	// maxInfectionDays; it must resolve (recovery or death) on that day.
+	EventInfectionCap eventKind = iota
+	// EventSideEffectsEnd ends an individual's vaccine side effects.
+	EventSideEffectsEnd
+	// EventShockStart starts a compliance shock (a population-wide event, no individual).
+	EventShockStart
+	// EventShockEnd ends an individual's part in a compliance shock.
+	EventShockEnd
+	// EventDetection detects an individual's infection, which may start a quarantine.
+	EventDetection
+	// EventQuarantineEnd ends an individual's quarantine.
+	EventQuarantineEnd
+	// EventTraceNotice notifies a traced contact, which may start a quarantine.
+	EventTraceNotice
+	// EventPathogenStart introduces the pathogens starting today (a population-wide event, no individual).
+	EventPathogenStart
+	// EventLatencyEnd ends an Exposed individual's latent period (seir only);
+	// they become Infected that day.
+	EventLatencyEnd
+)
+
+// scheduledEvent is one pending event for an individual.
+type scheduledEvent struct {
+	time float64
+	seq  uint64 // scheduling order, breaks ties between equal times
+	kind eventKind
+	ind  *Individual
+	// infection the event belongs to, if any. The event is dropped if that
+	// infection has ended (or been replaced) by the time it fires.
+	infection *Infection
+}
+
+// eventQueue is a min-heap of scheduled events ordered by (time, seq).
+type eventQueue struct {
+	items   []*scheduledEvent
+	nextSeq uint64
+}
+
+func (q *eventQueue) Len() int { return len(q.items) }
+func (q *eventQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if a.time != b.time {
+		return a.time < b.time
+	}
+	return a.seq < b.seq
+}
+func (q *eventQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *eventQueue) Push(x any)    { q.items = append(q.items, x.(*scheduledEvent)) }
+func (q *eventQueue) Pop() any {
+	n := len(q.items)
+	ev := q.items[n-1]
+	q.items[n-1] = nil
+	q.items = q.items[:n-1]
+	return ev
+}
+
+// scheduleEvent queues ev to fire at simulated time t (in days).
+func scheduleEvent(env *Environment, t float64, ev scheduledEvent) {
+	if env == nil {
+		return
+	}
+	if env.events == nil {
+		env.events = &eventQueue{}
+	}
+	ev.time = t
+	ev.seq = env.events.nextSeq
+	env.events.nextSeq++
+	heap.Push(env.events, &ev)
+}
+
+// runDueEvents fires, in time order, every queued event due before the end of
+// the current day (time < env.day+1).
+func runDueEvents(env *Environment, rng *rand.Rand) {
+	q := env.events
+	if q == nil {
+		return
+	}
+	end := float64(env.day + 1)
+	for q.Len() > 0 && q.items[0].time < end {
+		fireEvent(env, heap.Pop(q).(*scheduledEvent), rng)
+	}
+}
+
+// fireEvent applies a single event, ignoring it if it has gone stale.
+// rng is used by events that make random draws.
+func fireEvent(env *Environment, ev *scheduledEvent, rng *rand.Rand) {
+	switch ev.kind {
+	case EventShockStart:
+		startShock(env, rng)
+		return
+	case EventPathogenStart:
+		introducePathogens(env, rng)
+		return
+	}
+	ind := ev.ind
+	if ind == nil || ind.healthStatus == Dead {
+		return
+	}
+	if ev.infection != nil && ind.infection != ev.infection {
+		return
+	}
+	switch ev.kind {
+	case EventInfectionCap:
+		ev.infection.mustResolve = true
+	case EventLatencyEnd:
+		ev.infection.latencyOver = true
+	case EventSideEffectsEnd:
+		endSideEffects(ind, int(ev.time))
+	case EventShockEnd:
+		endShock(ind, int(ev.time))
+	case EventDetection:
+		detect(env, ind, int(ev.time), rng)
+	case EventQuarantineEnd:
+		endQuarantine(ind, int(ev.time))
+	case EventTraceNotice:
+		notifyContact(env, ind, int(ev.time), rng)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Exposure risk. Every day each Susceptible individual has some probability
+// of being infected (b in the health update). Its sum over the run is the
+// individual's exposure risk: the expected number of infections its contacts
+// would have caused had it stayed susceptible. Unlike the infection count it
+// is not a matter of luck, so it shows who was at risk, including those who
+// happened not to be infected. With exposureRisk = true, the score of every
+// individual is written to output_gif/exposure_risk.csv together with the
+// traits that may explain it, and its distribution is summarized on the
+// console.
+
+// exposureRiskFileName is the name of the per-individual file in the output directory.
+const exposureRiskFileName = "exposure_risk.csv"
+
+// addExposureRisk adds today's infection probability of ind. It only changes
+// ind, so it is safe during the parallel probability phase.
+func addExposureRisk(ind *Individual, p transitionProbs) {
+	if p.b > 0 {
+		ind.exposureRisk += p.b
+		ind.riskDays++
+	}
+}
+
+// SaveExposureRisk writes one row per individual with its exposure risk and
+// its state at the end of the run.
+func SaveExposureRisk(path string, env *Environment) error {
+	w, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "ID, Age, Gender, Tags, Risk, DaysAtRisk, Infections, HealthStatus, Vaccinated, Hygiene, Compliance, MoveType, X, Y")
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		mt := moveType("")
+		if ind.movementPattern != nil {
+			mt = ind.movementPattern.moveType
+		}
+		fmt.Fprintf(w, "%d, %d, %s, %s, %.4f, %d, %d, %s, %t, %.3f, %.3f, %s, %.2f, %.2f\n",
+			ind.id, ind.age, ind.gender, strings.Join(ind.tags, "|"), ind.exposureRisk, ind.riskDays,
+			ind.timesInfected, ind.healthStatus, ind.vaccinated, ind.hygieneLevel,
+			ind.socialDistanceCompliance, mt, ind.position.x, ind.position.y)
+	}
+	return w.Close()
+}
+
+// printExposureRiskSummary reports the distribution of exposure risk and how
+// many of the most exposed individuals were never infected.
+func printExposureRiskSummary(env *Environment) {
+	if !env.exposureRiskReport {
+		return
+	}
+	var risks []float64
+	for _, ind := range env.population {
+		if ind != nil {
+			risks = append(risks, ind.exposureRisk)
+		}
+	}
+	if len(risks) == 0 {
+		return
+	}
+	slices.Sort(risks)
+	at := func(q float64) float64 { return risks[int(q*float64(len(risks)-1))] }
+	fmt.Fprintf(msgOut, "Exposure risk: median %.3f, 90th percentile %.3f, 99th percentile %.3f, max %.3f\n",
+		at(0.5), at(0.9), at(0.99), risks[len(risks)-1])
+
+	// The top 10% by risk, and how many of them escaped infection
+	cutoff := at(0.9)
+	if cutoff <= 0 {
+		return
+	}
+	top, spared := 0, 0
+	for _, ind := range env.population {
+		if ind == nil || ind.exposureRisk < cutoff {
+			continue
+		}
+		top++
+		if ind.timesInfected == 0 {
+			spared++
+		}
+	}
+	fmt.Fprintf(msgOut, "Most exposed 10%%: %d individuals, %d (%.1f%%) never infected\n",
+		top, spared, 100*float64(spared)/float64(top))
+}
@@ -0,0 +1,60 @@
+package main
+
+import "fmt"
+
+// Fast-forward through quiet days. Most of a day's cost is the neighbor
+// searches for transmission: every Healthy, Susceptible and (with boosting)
+// Recovered individual looks for infectious neighbors. On a day when no one
+// is infectious those searches cannot find anyone, so with fastForward on
+// (the default) they are skipped and the probabilities are set to the zero
+// they would have returned. The same holds per co-circulating pathogen.
+// Vaccination, behavior, waning, movement and random draws are all kept, so
+// a seeded run gives exactly the same result with or without fastForward;
+// only long tails after an outbreak (or before a seeding event) run faster.
+
+// FastForward counts the days on which transmission was skipped.
+type FastForward struct {
+	enabled bool
+	quiet   bool // no one is infectious today
+	days    int  // quiet days so far
+}
+
+// startDay decides whether today is quiet. It must be called after the day's
+// events, so imported and seeded infections count.
+func (ff *FastForward) startDay(env *Environment) {
+	ff.quiet = ff.enabled && !anyInfectious(env)
+	if ff.quiet {
+		ff.days++
+	}
+}
+
+// anyInfectious reports whether any living individual is Infected.
+func anyInfectious(env *Environment) bool {
+	for _, ind := range env.population {
+		if ind != nil && ind.healthStatus == Infected {
+			return true
+		}
+	}
+	return false
+}
+
+// pathogenQuiet reports whether no living individual is infected with
+// pathogen k, so no one can catch it today.
+func pathogenQuiet(env *Environment, k int) bool {
+	if !env.fastForward.enabled {
+		return false
+	}
+	for _, ind := range env.population {
+		if ind != nil && ind.healthStatus != Dead && k < len(ind.pathogens) && ind.pathogens[k].status == Infected {
+			return false
+		}
+	}
+	return true
+}
+
+// printFastForwardSummary reports how many days ran without transmission.
+func printFastForwardSummary(env *Environment) {
+	if ff := env.fastForward; ff.enabled && ff.days > 0 {
+		fmt.Fprintf(msgOut, "Fast-forwarded %d quiet days with no one infectious\n", ff.days)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Theoretical benchmarks for the end-of-run summary. For the simple SIR model
+// in a fully susceptible, well-mixed population, the final size z (share
+// ever infected) solves z = 1 - exp(-R0*z), and an epidemic cannot grow once
+// more than 1 - 1/R0 of the population is immune. The agent model is spatial,
+// stochastic and has interventions and reinfection, so the simulated attack
+// rate is not expected to match; the comparison shows how far it departs.
+
+// theoryEarlyDays is how much of the start of the run R0 is estimated from.
+const theoryEarlyDays = 10
+
+// sirFinalSize returns the SIR final size for r0 (0 if r0 <= 1).
+func sirFinalSize(r0 float64) float64 {
+	if r0 <= 1 {
+		return 0
+	}
+	// Newton's method on f(z) = z - 1 + exp(-r0*z); f is convex, so starting
+	// from z = 1 the iterates decrease monotonically to the positive root.
+	z := 1.0
+	for i := 0; i < 100; i++ {
+		e := math.Exp(-r0 * z)
+		step := (z - 1 + e) / (1 - r0*e)
+		z -= step
+		if math.Abs(step) < 1e-12 {
+			break
+		}
+	}
+	return z
+}
+
+// herdImmunityThreshold returns 1 - 1/r0 (0 if r0 <= 1).
+func herdImmunityThreshold(r0 float64) float64 {
+	if r0 <= 1 {
+		return 0
+	}
+	return 1 - 1/r0
+}
+
+// printFinalSizeSummary compares the simulated attack rate with the final size
+// and herd immunity threshold implied by R0 estimated from the run's first days.
+func printFinalSizeSummary(env *Environment, s *epidemicSeries) {
+	n := len(env.population)
+	if n == 0 || len(s.days) < 2 {
+		return
+	}
+	early := s.head(theoryEarlyDays)
+	est := earlyGrowth(early, n)
+
+	everInfected := 0
+	for _, ind := range env.population {
+		if ind != nil && ind.timesInfected > 0 {
+			everInfected++
+		}
+	}
+	infections := 0
+	for _, k := range s.newInfections {
+		infections += k
+	}
+
+	fmt.Fprintf(msgOut, "Estimated R0 ~%.2f (days %d-%d of this run)\n", est.r0, early.days[0], early.days[len(early.days)-1])
+	if est.r0 > 1 {
+		fmt.Fprintf(msgOut, "  SIR theory: final size %.1f%%, herd immunity threshold %.1f%%\n",
+			100*sirFinalSize(est.r0), 100*herdImmunityThreshold(est.r0))
+	} else {
+		fmt.Fprintln(msgOut, "  SIR theory: R0 <= 1, no major outbreak expected")
+	}
+	fmt.Fprintf(msgOut, "  Simulated attack rate: %.1f%% infected at least once (%.1f%% counting reinfections)\n",
+		100*float64(everInfected)/float64(n), 100*float64(infections)/float64(n))
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+)
+
+// Compartment flows. With flowDiagram = true, every individual's health
+// state is compared with the day before at the end of each day, and each
+// change counts as one move along a link between two states (Healthy ->
+// Susceptible, Susceptible -> Infected, Infected -> Recovered, Recovered ->
+// Healthy, and so on). Moves into Exposed or Infected by someone infected
+// before are counted as reinfections. Deaths from other causes (see
+// mortality.go) end in their own state. At the end of the run the states,
+// with how many individuals started and ended in each, and the links are
+// written to output_gif/flows.json, and drawn as a Sankey diagram in
+// output_gif/flows.png. Links against the order of the states (waning and
+// escaped exposures) are drawn as loops under the diagram.
+
+// flowNode is a state of the flow diagram.
+type flowNode int
+
+const (
+	flowHealthy flowNode = iota
+	flowSusceptible
+	flowExposed
+	flowInfected
+	flowRecovered
+	flowDead
+	flowOtherDeath // died of other causes
+	numFlowNodes
+)
+
+var flowNodeNames = [numFlowNodes]string{"Healthy", "Susceptible", "Exposed", "Infected", "Recovered", "Dead", "Dead (other)"}
+
+// Names of the flow files in the output directory.
+const (
+	flowJSONFileName  = "flows.json"
+	flowImageFileName = "flows.png"
+)
+
+// infected reports whether n is a state of an ongoing infection.
+func (n flowNode) infected() bool { return n == flowExposed || n == flowInfected }
+
+// flowNodeOf returns the state of ind in the flow diagram.
+func flowNodeOf(ind *Individual) flowNode {
+	switch ind.healthStatus {
+	case Susceptible:
+		return flowSusceptible
+	case Exposed:
+		return flowExposed
+	case Infected:
+		return flowInfected
+	case Recovered:
+		return flowRecovered
+	case Dead:
+		if ind.backgroundDeath {
+			return flowOtherDeath
+		}
+		return flowDead
+	}
+	return flowHealthy
+}
+
+// flowTally counts the moves between states over the run.
+type flowTally struct {
+	start        [numFlowNodes]int
+	moves        [numFlowNodes][numFlowNodes]int
+	reinfections [numFlowNodes][numFlowNodes]int // moves that were reinfections
+	last         []flowNode                      // everyone's state at the end of the day before
+}
+
+// begin takes the current states as the start of the run.
+func (t *flowTally) begin(env *Environment) {
+	if t == nil {
+		return
+	}
+	t.start = [numFlowNodes]int{}
+	t.remember(env)
+	for _, n := range t.last {
+		if n >= 0 {
+			t.start[n]++
+		}
+	}
+}
+
+// remember takes the current states as the day before's, e.g. after a
+// resume.
+func (t *flowTally) remember(env *Environment) {
+	t.last = make([]flowNode, len(env.population))
+	for i, ind := range env.population {
+		t.last[i] = -1
+		if ind != nil {
+			t.last[i] = flowNodeOf(ind)
+		}
+	}
+}
+
+// record counts the moves made today.
+func (t *flowTally) record(env *Environment) {
+	if t == nil {
+		return
+	}
+	for i, ind := range env.population {
+		if ind == nil || i >= len(t.last) {
+			continue
+		}
+		from, to := t.last[i], flowNodeOf(ind)
+		if from == to || from < 0 {
+			continue
+		}
+		t.moves[from][to]++
+		if to.infected() && !from.infected() && ind.timesInfected > 1 {
+			t.reinfections[from][to]++
+		}
+		t.last[i] = to
+	}
+}
+
+// flowLink is one link of the diagram in flows.json.
+type flowLink struct {
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	Value        int    `json:"value"`
+	Reinfections int    `json:"reinfections,omitempty"` // part of value that were reinfections
+}
+
+// flowState is one state of the diagram in flows.json.
+type flowState struct {
+	Name  string `json:"name"`
+	Start int    `json:"start"` // individuals in the state at the start of the run
+	End   int    `json:"end"`   // and at the end
+}
+
+// flowFile is the content of flows.json.
+type flowFile struct {
+	Model        string      `json:"model"`
+	Days         int         `json:"days"`
+	Population   int         `json:"population"`
+	Reinfections int         `json:"reinfections"`
+	Nodes        []flowState `json:"nodes"`
+	Links        []flowLink  `json:"links"`
+}
+
+// flowChart is the diagram at the end of the run: the states with any
+// individual or move, and the links between them.
+type flowChart struct {
+	nodes []flowNode
+	end   [numFlowNodes]int
+	t     *flowTally
+}
+
+// newFlowChart builds the diagram of env's tally.
+func newFlowChart(env *Environment) flowChart {
+	d := flowChart{t: env.flows}
+	for _, ind := range env.population {
+		if ind != nil {
+			d.end[flowNodeOf(ind)]++
+		}
+	}
+	for n := range numFlowNodes {
+		used := d.t.start[n] > 0 || d.end[n] > 0
+		for m := range numFlowNodes {
+			used = used || d.t.moves[n][m] > 0 || d.t.moves[m][n] > 0
+		}
+		if used {
+			d.nodes = append(d.nodes, n)
+		}
+	}
+	return d
+}
+
+// file returns the diagram as saved to flows.json.
+func (d flowChart) file(env *Environment) flowFile {
+	f := flowFile{Model: modelTopology(env.seir, env.immunityWaning), Days: env.day, Nodes: []flowState{}, Links: []flowLink{}}
+	for _, n := range d.nodes {
+		f.Nodes = append(f.Nodes, flowState{Name: flowNodeNames[n], Start: d.t.start[n], End: d.end[n]})
+		f.Population += d.end[n]
+	}
+	for _, from := range d.nodes {
+		for _, to := range d.nodes {
+			if v := d.t.moves[from][to]; v > 0 {
+				r := d.t.reinfections[from][to]
+				f.Links = append(f.Links, flowLink{Source: flowNodeNames[from], Target: flowNodeNames[to], Value: v, Reinfections: r})
+				f.Reinfections += r
+			}
+		}
+	}
+	return f
+}
+
+// SaveFlowsJSON writes the states and links of the run to path as JSON.
+func SaveFlowsJSON(path string, env *Environment) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(newFlowChart(env).file(env))
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// SaveFlowDiagram writes the Sankey diagram of the run to path as a PNG.
+func SaveFlowDiagram(path string, env *Environment) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, newFlowChart(env).draw(paletteFor(env), env.day, flowWidth, flowHeight)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// flowNodeColor returns the color of a state in palette p.
+func flowNodeColor(p statusPalette, n flowNode) color.RGBA {
+	switch n {
+	case flowSusceptible:
+		return p.susceptible
+	case flowExposed:
+		return p.exposed
+	case flowInfected:
+		return p.infected
+	case flowRecovered:
+		return p.recovered
+	case flowDead:
+		return p.dead
+	case flowOtherDeath:
+		return color.RGBA{90, 90, 90, 255}
+	}
+	return p.healthy
+}
+
+// shade returns c darkened to the share f of its brightness.
+func shade(c color.RGBA, f float64) color.RGBA {
+	return color.RGBA{uint8(float64(c.R) * f), uint8(float64(c.G) * f), uint8(float64(c.B) * f), 255}
+}
+
+// Layout of the flow diagram, in pixels.
+const (
+	flowWidth     = 900
+	flowHeight    = 540
+	flowTop       = 60 // room for the title and the state labels
+	flowNodeWidth = 14
+	flowLaneGap   = 14 // above each loop under the diagram, with its label
+	flowLoopGap   = 4  // between nested loops beside a state
+)
+
+// draw renders the diagram as a Sankey diagram of width x height pixels. The
+// states are columns in their usual order, each as tall as the individuals
+// going through it. Links run from the right side of a state to the left side
+// of the next, as wide as their moves, with reinfections in a darker shade.
+// Links back to an earlier state loop under the diagram.
+func (d flowChart) draw(p statusPalette, days, width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	drawLabel(img, 10, 16, color.White, fmt.Sprintf("Flows between states over %d days", days))
+	if len(d.nodes) == 0 {
+		return img
+	}
+
+	col := make(map[flowNode]int, len(d.nodes))
+	for i, n := range d.nodes {
+		col[n] = i
+	}
+	// Each state is as tall as its larger side: who started in it or came in,
+	// against who left or stayed
+	var in, out, backIn [numFlowNodes]int
+	tallest, back, loops := 1, 0, 0
+	for _, from := range d.nodes {
+		for _, to := range d.nodes {
+			v := d.t.moves[from][to]
+			out[from] += v
+			in[to] += v
+			if v > 0 && col[to] < col[from] {
+				back += v
+				backIn[to] += v
+				loops++
+			}
+		}
+	}
+	size := func(n flowNode) int { return max(d.t.start[n]+in[n], out[n]+d.end[n]) }
+	for _, n := range d.nodes {
+		tallest = max(tallest, size(n))
+	}
+	plotH := max(height-flowTop-curveBottom-loops*flowLaneGap, 1)
+	scale := float64(plotH) / float64(tallest+back)
+	px := func(v int) int { return int(math.Round(float64(v) * scale)) }
+
+	// Room on the left for the loops into the first state
+	left := 20 + px(backIn[d.nodes[0]]) + 2*loops*flowLoopGap
+	right := width - 20 - flowNodeWidth - px(back) - 2*loops*flowLoopGap
+	x := func(n flowNode) int {
+		if len(d.nodes) == 1 {
+			return left
+		}
+		return left + col[n]*(right-left)/(len(d.nodes)-1)
+	}
+	bottom := flowTop + px(tallest)
+
+	// Where the next link enters (left side) and leaves (right side) each
+	// state; arrivals stack under the starting individuals
+	var inY, outY [numFlowNodes]int
+	for _, n := range d.nodes {
+		inY[n] = flowTop + px(d.t.start[n])
+		outY[n] = flowTop
+	}
+
+	// Forward links first, so loops leave and enter at the bottom of the
+	// states. Loops nest: each is further out and lower than the ones before
+	// it, and leaves and enters above them
+	lane := bottom + flowLaneGap
+	var loopIn, loopOut, backInY, backOutY [numFlowNodes]int
+	for _, from := range d.nodes {
+		for _, to := range d.nodes {
+			if v := d.t.moves[from][to]; v > 0 && col[to] < col[from] {
+				backOutY[from] += px(v)
+				backInY[to] += px(v)
+			}
+		}
+	}
+	for _, forward := range []bool{true, false} {
+		for _, from := range d.nodes {
+			for _, to := range d.nodes {
+				v := d.t.moves[from][to]
+				if v == 0 || (col[to] > col[from]) != forward {
+					continue
+				}
+				r := d.t.reinfections[from][to]
+				c := shade(flowNodeColor(p, from), 0.6)
+				thick := max(px(v), 1)
+				if forward {
+					y0, y1 := outY[from], inY[to]
+					outY[from] += px(v)
+					inY[to] += px(v)
+					xs, xt := x(from)+flowNodeWidth, x(to)
+					drawRibbon(img, xs, y0, xt, y1, thick-px(r), c)
+					drawRibbon(img, xs, y0+thick-px(r), xt, y1+thick-px(r), px(r), shade(c, 0.5))
+					drawLabel(img, (xs+xt)/2-3*len(fmt.Sprint(v)), (y0+y1+thick)/2+5, color.White, fmt.Sprint(v))
+					continue
+				}
+				// Back to an earlier state: out to the right, down to a lane
+				// under the diagram, left, and up into the state
+				backOutY[from] -= px(v)
+				backInY[to] -= px(v)
+				y0, y1 := outY[from]+backOutY[from], inY[to]+backInY[to]
+				xs := x(from) + flowNodeWidth + loopOut[from] + flowLoopGap
+				xt := x(to) - loopIn[to] - flowLoopGap - thick
+				loopOut[from] += thick + flowLoopGap
+				loopIn[to] += thick + flowLoopGap
+				fillRect(img, x(from)+flowNodeWidth, y0, xs+thick, y0+thick, c)
+				fillRect(img, xs, y0, xs+thick, lane+thick, c)
+				fillRect(img, xt, lane, xs+thick, lane+thick, c)
+				fillRect(img, xt, y1, xt+thick, lane+thick, c)
+				fillRect(img, xt, y1, x(to), y1+thick, c)
+				drawLabel(img, xt, lane-2, color.White, fmt.Sprintf("%s->%s %d", flowNodeNames[from], flowNodeNames[to], v))
+				lane += thick + flowLaneGap
+			}
+		}
+	}
+
+	for _, n := range d.nodes {
+		c := flowNodeColor(p, n)
+		fillRect(img, x(n), flowTop, x(n)+flowNodeWidth, flowTop+max(px(size(n)), 1), c)
+		drawLabel(img, x(n), flowTop-18, c, flowNodeNames[n])
+		drawLabel(img, x(n), flowTop-4, color.White, fmt.Sprintf("%d->%d", d.t.start[n], d.end[n]))
+	}
+	return img
+}
+
+// drawRibbon fills a band thick pixels wide from (x0, y0) to (x1, y1),
+// following a smooth S-curve.
+func drawRibbon(img *image.RGBA, x0, y0, x1, y1, thick int, c color.RGBA) {
+	if thick <= 0 || x1 <= x0 {
+		return
+	}
+	for x := x0; x <= x1; x++ {
+		f := float64(x-x0) / float64(x1-x0)
+		f = f * f * (3 - 2*f)
+		y := int(math.Round(float64(y0) + f*float64(y1-y0)))
+		drawLine(img, x, y, x, y+thick-1, c)
+	}
+}
+
+// fillRect fills the rectangle from (x0, y0) to (x1, y1), exclusive.
+func fillRect(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	draw.Draw(img, image.Rect(x0, y0, x1, y1), &image.Uniform{c}, image.Point{}, draw.Src)
+}
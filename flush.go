@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"os"
+	"path/filepath"
+)
+
+// Incremental output. Normally the stats go to stdout and the GIFs are only
+// written once the run ends, so a crash or an out-of-memory kill late in a
+// long run loses every frame. With flushEveryDays = K, outputs are written
+// as the run goes:
+//   - stats rows also go to outputDir/stats.csv (.gz with compressOutputs),
+//     flushed every K days,
+//   - the -trackAgent log is flushed every K days,
+//   - the frames captured in each K-day period are written to
+//     outputDir/chunks/ as small GIFs and dropped from memory.
+// At the end the full GIFs are assembled from the chunks, which are then
+// removed. After a crash, the chunks hold all frames up to the last flush.
+
+// outputStream writes outputs incrementally during a run.
+type outputStream struct {
+	every     int
+	dir       string
+	statsPath string
+	stats     *outputFile
+	chunkDir  string
+	chunks    int // chunks written so far
+	delay     int // GIF frame delay, for the chunks
+	lastFlush int // day of the last flush
+}
+
+// newOutputStream creates the output directories and opens the stats file
+// statsName with the given header line.
+func newOutputStream(dir, statsName string, every, delay int, header string) (*outputStream, error) {
+	chunkDir := filepath.Join(dir, "chunks")
+	if err := os.MkdirAll(chunkDir, 0755); err != nil {
+		return nil, err
+	}
+	statsPath := filepath.Join(dir, statsName)
+	w, err := createOutput(statsPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &outputStream{every: every, dir: dir, statsPath: statsPath, stats: w, chunkDir: chunkDir, delay: delay}
+	fmt.Fprintln(s.stats, header)
+	return s, nil
+}
+
+// writeStats appends one stats line to the stats file.
+func (s *outputStream) writeStats(line string) {
+	if s != nil {
+		fmt.Fprintln(s.stats, line)
+	}
+}
+
+// due reports whether K days have passed since the last flush.
+func (s *outputStream) due(day int) bool {
+	return s != nil && day-s.lastFlush >= s.every
+}
+
+// maybeFlush flushes everything written so far if K days have passed since
+// the last flush, moving the frames held in memory to a chunk on disk.
+func (s *outputStream) maybeFlush(day int, frames *frameHistory, tracker *agentTracker) error {
+	if !s.due(day) {
+		return nil
+	}
+	s.lastFlush = day
+	return s.flush(frames, tracker)
+}
+
+// flush writes out buffered stats and tracker lines and the frames in memory.
+func (s *outputStream) flush(frames *frameHistory, tracker *agentTracker) error {
+	if err := s.stats.Flush(); err != nil {
+		return err
+	}
+	if tracker != nil {
+		if err := tracker.w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(frames.days) == 0 {
+		return nil
+	}
+	s.chunks++
+	if err := SaveEnvironmentGIF(s.chunkPath("spatial", s.chunks), frames.spatial, s.delay); err != nil {
+		return err
+	}
+	if err := SaveEnvironmentGIF(s.chunkPath("pie", s.chunks), frames.pie, s.delay); err != nil {
+		return err
+	}
+	*frames = frameHistory{}
+	return nil
+}
+
+func (s *outputStream) chunkPath(kind string, n int) string {
+	return filepath.Join(s.chunkDir, fmt.Sprintf("%s_%04d.gif", kind, n))
+}
+
+// allFrames returns every frame of the given kind ("spatial" or "pie"): those
+// in the chunks on disk, in order, followed by inMemory.
+func (s *outputStream) allFrames(kind string, inMemory []image.Image) ([]image.Image, error) {
+	if s == nil {
+		return inMemory, nil
+	}
+	var all []image.Image
+	for n := 1; n <= s.chunks; n++ {
+		f, err := os.Open(s.chunkPath(kind, n))
+		if err != nil {
+			return nil, err
+		}
+		g, err := gif.DecodeAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, img := range g.Image {
+			all = append(all, img)
+		}
+	}
+	return append(all, inMemory...), nil
+}
+
+// close flushes and closes the stats file.
+func (s *outputStream) close() error {
+	if s == nil {
+		return nil
+	}
+	return s.stats.Close()
+}
+
+// removeChunks deletes the chunk files once the full GIFs have been written.
+func (s *outputStream) removeChunks() {
+	if s != nil {
+		os.RemoveAll(s.chunkDir)
+	}
+}
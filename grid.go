@@ -0,0 +1,87 @@
+package main
+
+import "math"
+
+// hashGrid is a uniform grid over the area, rebuilt each day. Individuals are
+// bucketed by the cell their position falls in (a counting sort, so building
+// is O(N)), and a radius query only visits the cells overlapping the query's
+// bounding square. With about gridOccupancy individuals per cell on average,
+// a query of radius r costs O(1 + r²·density) instead of O(N).
+//
+// Like the k-d tree, the grid captures positions at build time and must be
+// rebuilt after anyone moves.
+type hashGrid struct {
+	side     int     // cells per side
+	cellSize float64 // side length of a cell
+	start    []int   // items of cell c are items[start[c]:start[c+1]]
+	items    []*Individual
+}
+
+// gridOccupancy is the target mean number of individuals per cell.
+const gridOccupancy = 2
+
+// maxGridSide bounds the number of cells (maxGridSide² in all).
+const maxGridSide = 4096
+
+// buildHashGrid builds a grid over the non-nil individuals in pop, spread
+// over [0, areaSize]². Positions outside the area go in the border cells.
+func buildHashGrid(pop []*Individual, areaSize float64) *hashGrid {
+	n := 0
+	for _, ind := range pop {
+		if ind != nil {
+			n++
+		}
+	}
+	side := int(math.Ceil(math.Sqrt(float64(n) / gridOccupancy)))
+	side = max(1, min(side, maxGridSide))
+	g := &hashGrid{side: side, cellSize: areaSize / float64(side)}
+	if g.cellSize <= 0 {
+		g.cellSize = 1
+	}
+
+	// Counting sort by cell
+	cellOf := make([]int32, len(pop))
+	g.start = make([]int, side*side+1)
+	for i, ind := range pop {
+		if ind == nil {
+			continue
+		}
+		c := g.cell(ind.position.y)*side + g.cell(ind.position.x)
+		cellOf[i] = int32(c)
+		g.start[c+1]++
+	}
+	for c := 1; c <= side*side; c++ {
+		g.start[c] += g.start[c-1]
+	}
+	next := make([]int, side*side)
+	copy(next, g.start[:side*side])
+	g.items = make([]*Individual, n)
+	for i, ind := range pop {
+		if ind == nil {
+			continue
+		}
+		c := cellOf[i]
+		g.items[next[c]] = ind
+		next[c]++
+	}
+	return g
+}
+
+// cell returns the row or column index of coordinate v, clamped to the grid.
+func (g *hashGrid) cell(v float64) int {
+	return max(0, min(int(v/g.cellSize), g.side-1))
+}
+
+// within calls visit for every individual within distance r of center.
+func (g *hashGrid) within(center OrderedPair, r float64, visit func(ind *Individual, d float64)) {
+	x0, x1 := g.cell(center.x-r), g.cell(center.x+r)
+	y0, y1 := g.cell(center.y-r), g.cell(center.y+r)
+	for cy := y0; cy <= y1; cy++ {
+		row := cy * g.side
+		for _, ind := range g.items[g.start[row+x0]:g.start[row+x1+1]] {
+			if d := dist(center, ind.position); d <= r {
+				visit(ind, d)
+			}
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"math"
 	"math/rand"
 	"time"
@@ -33,79 +32,208 @@ func dist(a, b OrderedPair) float64 {
 	return math.Sqrt(dx*dx + dy*dy)
 }
 
-func printStats(day int, env *Environment, tightened bool) {
-	infFrac, totalInfected, totalVaccinated, _, _, n := ComputePopulationStats(env)
+// collectDayStats gathers the daily statistics row for the current state of env.
+// Status counts and the infected list for the clustering statistics come from a
+// single pass over the population, which matters for very large populations.
+func collectDayStats(day int, env *Environment, tightened bool) DayStats {
+	load := computeCareLoad(env)
 
-	healthyCount := 0
-	susceptibleCount := 0
-	recoveredCount := 0
-	deadCount := 0
+	row := DayStats{
+		Day:             day,
+		EnvHygiene:      env.hygieneLevel,
+		EnvVaxRate:      env.vaccinationRate,
+		SDThreshold:     env.socialDistanceThreshold,
+		PolicyTightened: tightened,
+		WardOccupied:    load.wardOccupied(),
+		ICUOccupied:     load.icuOccupied(),
+		StaffedBeds:     load.wardBeds + load.icuBeds,
+		NewInfections:   env.transitions.newInfections,
+		NewDeaths:       env.transitions.newDeaths,
+		NewRecoveries:   env.transitions.newRecoveries,
+		NewAdmissions:   env.transitions.newAdmissions,
+		DosesInStock:    dosesInStock(env),
+		DosesWasted:     env.vaccineStock.wasted,
+		HygieneStock:    int(env.hygieneSupply.stock),
+		HygieneSupply:   env.hygieneSupply.ratio,
+		WardQueue:       len(env.hospital.wardQueue),
+		Quarantined:     countQuarantined(env),
+		ICUQueue:        len(env.hospital.icuQueue),
+		Tags:            collectTagCounts(env),
+		Profiles:        collectProfileCounts(env),
+		Pathogens:       collectPathogenCounts(env),
+		Regions:         collectRegionCounts(env),
+		Annotation:      env.annotations[day],
+		Lockdown:        env.lockdown.active,
+		LockedCells:     lockedCells(env),
+	}
+	if len(env.cells) > 0 {
+		row.Lockdown = row.LockedCells > 0
+		row.CellSDMin, row.CellSDMax = cellThresholdRange(env)
+	}
+	row.Traced, row.TracedIsolated = tracedToday(env, day)
+	row.Tested, row.ReportedCases = reportedToday(env, day)
 
+	infected := make([]*Individual, 0)
+	total := 0
 	for _, ind := range env.population {
 		if ind == nil {
 			continue
 		}
+		total++
+		if ind.vaccinated {
+			row.Vaccinated++
+		}
 		switch ind.healthStatus {
 		case Healthy:
-			healthyCount++
+			row.Healthy++
 		case Susceptible:
-			susceptibleCount++
+			row.Susceptible++
+		case Exposed:
+			row.Exposed++
 		case Infected:
-			// already counted in totalInfected, but we keep per-status counts for clarity
+			row.Infected++
+			infected = append(infected, ind)
 		case Recovered:
-			recoveredCount++
+			row.Recovered++
 		case Dead:
-			deadCount++
+			row.Dead++
+			if ind.backgroundDeath {
+				row.BackgroundDeaths++
+			} else {
+				row.DiseaseDeaths++
+			}
 		}
 	}
+	if total > 0 {
+		row.InfectedFrac = float64(row.Infected) / float64(total)
+	}
+	row.InfectedNNDist, row.ClusterIndex = infectionClustering(env, infected)
 
-	fmt.Printf(
-		"%d, %d, %d, %d, %d, %d, %.4f, %d, %.3f, %.3f, %.3f, %v\n",
-		day,
-		healthyCount,
-		susceptibleCount,
-		totalInfected,
-		recoveredCount,
-		deadCount,
-		infFrac,
-		totalVaccinated,
-		env.hygieneLevel,
-		env.vaccinationRate,
-		env.socialDistanceThreshold,
-		tightened,
-	)
-
-	_ = n
+	return row
+}
+
+// seedInfections starts the epidemic in env: config's initialInfected random
+// individuals with the main disease, the regional seeds and the pathogens.
+// Every run seeds this way, right after its setup.
+func seedInfections(env *Environment, config *Config, rng *rand.Rand) {
+	for i := 0; i < config.initialInfected; i++ {
+		infectOneRandom(env, env.disease, rng)
+	}
+	seedRegions(env, rng)
+	seedPathogens(env, rng)
 }
 
-func infectOneRandom(env *Environment, dis *Disease) {
+func infectOneRandom(env *Environment, dis *Disease, rng *rand.Rand) {
 	n := len(env.population)
 	if n == 0 {
 		return
 	}
 
 	for {
-		idx := rand.Intn(n)
+		idx := rng.Intn(n)
 		ind := env.population[idx]
 		if ind == nil {
 			continue
 		}
-		if ind.healthStatus == Dead || ind.healthStatus == Infected {
+		if ind.healthStatus == Dead || ind.healthStatus == Infected || ind.healthStatus == Exposed {
 			continue
 		}
 
-		ind.healthStatus = Infected
-		ind.daysInfected = 0
-		ind.disease = dis
+		infect(env, ind, dis, rng)
+		countAdmission(env, ind)
 		break
 	}
 }
 
-func attachDiseaseToAll(env *Environment, dis *Disease) {
-	for _, ind := range env.population {
-		if ind == nil {
-			continue
+// infect starts a new infection with dis in ind, drawing its severity,
+// and counts it in today's transitions. If the disease caps the infection
+// length, the day it must resolve is scheduled on the event queue.
+func infect(env *Environment, ind *Individual, dis *Disease, rng *rand.Rand) {
+	ind.healthStatus = Infected
+	ind.infection = &Infection{disease: dis, chain: -1}
+	ind.timesInfected++
+	assignSeverity(env, ind, rng)
+	drawInfectiousness(ind.infection, dis, rng)
+	if env != nil {
+		recordTransmission(env, ind)
+		env.transitions.newInfections++
+		countRegionInfection(env, ind)
+		if dis != nil && dis.maxInfectionDays > 0 {
+			// daysInfected reaches the cap on the day after maxInfectionDays full days
+			scheduleEvent(env, float64(env.day+dis.maxInfectionDays+1), scheduledEvent{
+				kind: EventInfectionCap, ind: ind, infection: ind.infection,
+			})
+		}
+		scheduleDetection(env, ind, rng)
+	}
+}
+
+// startInfection infects ind by transmission with the main disease. With
+// seir the infection starts with a non-infectious latent period.
+func startInfection(env *Environment, ind *Individual, rng *rand.Rand) {
+	var dis *Disease
+	if env != nil {
+		dis = env.disease
+	}
+	infect(env, ind, dis, rng) // starts a fresh infection record
+	if env != nil && env.seir && dis != nil && dis.latentPeriod > 0 {
+		ind.healthStatus = Exposed
+		scheduleEvent(env, float64(env.day+dis.latentPeriod), scheduledEvent{
+			kind: EventLatencyEnd, ind: ind, infection: ind.infection,
+		})
+	}
+	countAdmission(env, ind)
+	// when infected, daysSinceRecovery should reset
+	ind.daysSinceRecovery = 0
+}
+
+// stepDay runs one simulated day (env.day must already be set) without
+// recording stats or frames, for the auxiliary runs: the pre-run check, the
+// determinism check, the no-disease baseline and scenario comparisons. It
+// reports whether the distancing policy was tightened.
+func stepDay(env *Environment, rng *rand.Rand) (bool, error) {
+	runDueEvents(env, rng)
+	boardVehicles(env, rng)
+	rebuildSpatialIndex(env)
+	recordContacts(env)
+	if err := UpdatePopulationHealthStatus(env, rng); err != nil {
+		return false, err
+	}
+	_, tightened, err := UpdateEnvironment(env, rng)
+	if err != nil {
+		return false, err
+	}
+	moveAll(env, rng)
+	invalidateSpatialIndex(env)
+	return tightened, nil
+}
+
+// warmUp runs behavior, environment and movement dynamics for the given number
+// of days before any infection is introduced, so hygiene and compliance levels
+// can settle from their random initial values. Health transitions and
+// vaccination rollout are not run, and no stats or frames are recorded.
+func warmUp(env *Environment, days int, rng *rand.Rand) error {
+	rng = rngOrDefault(rng)
+	for i := 0; i < days; i++ {
+		for _, ind := range env.population {
+			if ind == nil || ind.healthStatus == Dead {
+				continue
+			}
+			updateHygieneLevel(env, ind, rng)
+			_, _ = updateSocialDistanceCompliance(env, ind, rng)
+		}
+
+		// Policy and environmental hygiene respond as usual; the vaccination
+		// rate is left alone since no one is vaccinated during warm-up.
+		infFrac, _, _, popHygieneMean, avgTransDist, _ := ComputePopulationStats(env)
+		if _, err := updateSocialDistanceThreshold(env, infFrac, avgTransDist); err != nil {
+			return err
+		}
+		if _, err := updateEnvHygieneLevel(env, popHygieneMean, infFrac, rng); err != nil {
+			return err
 		}
-		ind.disease = dis
+
+		moveAll(env, rng)
 	}
+	return nil
 }
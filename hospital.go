@@ -0,0 +1,158 @@
+package main
+
+import "math/rand"
+
+// careLoad summarizes hospital bed demand against capacity for one time step.
+// Demand is split by severity: Severe cases need a general ward bed and
+// Critical cases need an ICU bed. Mild cases recover at home.
+type careLoad struct {
+	wardDemand int
+	icuDemand  int
+	wardBeds   int
+	icuBeds    int
+}
+
+// healthcareWorkerTag is the tag that marks staff whose absence reduces hospital capacity.
+const healthcareWorkerTag = "healthcare_worker"
+
+// staffAbsenceFraction returns the fraction of living healthcare workers who are
+// currently unable to work (infected). Returns 0 if no one carries the tag.
+func staffAbsenceFraction(env *Environment) float64 {
+	if env == nil || env.tagSpec(healthcareWorkerTag) == nil {
+		return 0
+	}
+	staff, absent := 0, 0
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus == Dead || !ind.hasTag(healthcareWorkerTag) {
+			continue
+		}
+		staff++
+		if ind.healthStatus == Infected {
+			absent++
+		}
+	}
+	if staff == 0 {
+		return 0
+	}
+	return float64(absent) / float64(staff)
+}
+
+// effectiveCapacity returns the total and ICU beds that can actually be staffed,
+// scaling env.medicalCapacity and env.icuCapacity down by healthcare worker absence.
+func effectiveCapacity(env *Environment) (total, icu int) {
+	if env == nil {
+		return 0, 0
+	}
+	staffed := 1.0 - staffAbsenceFraction(env)
+	total = int(float64(env.medicalCapacity) * staffed)
+	icu = int(float64(env.icuCapacity) * staffed)
+	return total, icu
+}
+
+// computeCareLoad counts current ward/ICU demand among infected individuals.
+// General ward beds are whatever part of the staffed capacity is not ICU.
+func computeCareLoad(env *Environment) careLoad {
+	load := careLoad{}
+	if env == nil {
+		return load
+	}
+	total, icu := effectiveCapacity(env)
+	load.icuBeds = icu
+	load.wardBeds = total - icu
+	if load.wardBeds < 0 {
+		load.wardBeds = 0
+	}
+
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus != Infected || ind.infection == nil {
+			continue
+		}
+		switch ind.infection.severity {
+		case Severe:
+			load.wardDemand++
+		case Critical:
+			load.icuDemand++
+		}
+	}
+	return load
+}
+
+// wardOccupied returns the number of general ward beds in use.
+func (l careLoad) wardOccupied() int {
+	if l.wardDemand < l.wardBeds {
+		return l.wardDemand
+	}
+	return l.wardBeds
+}
+
+// icuOccupied returns the number of ICU beds in use.
+func (l careLoad) icuOccupied() int {
+	if l.icuDemand < l.icuBeds {
+		return l.icuDemand
+	}
+	return l.icuBeds
+}
+
+// wardOverload returns the unmet ward demand relative to ward capacity (0 if not overloaded).
+func (l careLoad) wardOverload() float64 {
+	return overloadRatio(l.wardDemand, l.wardBeds)
+}
+
+// icuOverload returns the unmet ICU demand relative to ICU capacity (0 if not overloaded).
+func (l careLoad) icuOverload() float64 {
+	return overloadRatio(l.icuDemand, l.icuBeds)
+}
+
+// overloadRatio maps demand vs capacity to (demand-capacity)/capacity, floored at 0.
+// With no beds at all, any demand counts as fully overloaded (ratio 1).
+func overloadRatio(demand, capacity int) float64 {
+	if demand <= capacity {
+		return 0
+	}
+	if capacity <= 0 {
+		return 1.0
+	}
+	return float64(demand-capacity) / float64(capacity)
+}
+
+// assignSeverity draws the severity of a new infection.
+// Older individuals are more likely to need hospital care, using the same
+// three age buckets as computeC, or the severity age curve.
+func assignSeverity(env *Environment, ind *Individual, rng *rand.Rand) {
+	if ind == nil || ind.infection == nil {
+		return
+	}
+	rng = rngOrDefault(rng)
+
+	severe, critical := 0.0, 0.0
+	if dis := ind.infection.disease; dis != nil {
+		severe = dis.severeFraction
+		critical = dis.criticalFraction
+	}
+
+	ageMult := 1.0
+	switch {
+	case ind.age < 40:
+		ageMult = 0.5
+	case ind.age <= 60:
+		ageMult = 1.0
+	default:
+		ageMult = 2.0
+	}
+	ageMult = ageFactor(env, AgeSeverity, ind, ageMult)
+	critical = clamp01(critical * ageMult)
+	severe = clamp01(severe * ageMult)
+	if critical+severe > 1.0 {
+		severe = 1.0 - critical
+	}
+
+	r := rng.Float64()
+	switch {
+	case r < critical:
+		ind.infection.severity = Critical
+	case r < critical+severe:
+		ind.infection.severity = Severe
+	default:
+		ind.infection.severity = Mild
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Households. With householdSizeMean > 0 the population is split into
+// households of 1 + Poisson(householdSizeMean - 1) members. Each household
+// has a home: the position of its first member, with the others placed
+// within householdSpread of it. Every day an individual sets out from home
+// rather than from where it was the day before, so households gather again
+// each night and the population stays clustered around the homes instead of
+// spreading out in a random walk. Isolated individuals stay at home.
+//
+// Housemates meet every night whatever the distance between them during the
+// day, and without distancing: each infected housemate (not in hospital)
+// exposes the others with householdTransmissionMultiplier times the
+// per-contact probability of a contact at distance 0. This comes on top of
+// any daytime contact. With a road network individuals walk the roads as
+// before and do not return home; household transmission still applies.
+//
+// Behavior clusters in families too. With householdCorrelation = rho > 0,
+// vaccine acceptance and quarantine compliance are correlated within a
+// household through a household-level random effect: each household has an
+// attitude z ~ N(0, 1) per decision, and a member takes the decision if
+// Phi(sqrt(rho)*z + sqrt(1-rho)*e) falls below its usual probability, with e
+// a fresh N(0, 1) draw. Every individual keeps its own acceptance
+// probability, but housemates tend to decide alike: rho is the correlation
+// of their underlying propensities (0 = independent, 1 = identical).
+
+// householdSpread is the radius, as a share of areaSize, around a home
+// within which its members start.
+const householdSpread = 0.001
+
+// HouseholdConfig configures households.
+type HouseholdConfig struct {
+	sizeMean     float64 // mean household size, 0 = no households
+	transmission float64 // per-contact transmission multiplier between housemates
+	correlation  float64 // correlation of housemates' decisions, 0 = independent
+
+	count int // households created
+}
+
+// enabled reports whether individuals live in households.
+func (h HouseholdConfig) enabled() bool { return h.sizeMean > 0 }
+
+// householdDecision is a decision correlated within households.
+type householdDecision int
+
+const (
+	DecisionVaccination householdDecision = iota // accept a vaccine offer
+	DecisionQuarantine                           // isolate when detected
+	numDecisions
+)
+
+// Household is a group of individuals sharing a home.
+type Household struct {
+	position  OrderedPair // home
+	region    int         // region of the home, see regions.go
+	members   []*Individual
+	attitudes [numDecisions]float64 // household random effect per decision
+}
+
+// assignHouseholds groups the population into households, in population
+// order, and moves every member next to its home.
+func assignHouseholds(env *Environment, rng *rand.Rand) {
+	h := &env.households
+	if !h.enabled() {
+		return
+	}
+	for i := 0; i < len(env.population); {
+		size := min(1+poisson(max(h.sizeMean-1, 0), rng), len(env.population)-i)
+		home := &Household{position: env.population[i].position, region: env.population[i].region}
+		if h.correlation > 0 {
+			for d := range home.attitudes {
+				home.attitudes[d] = rng.NormFloat64()
+			}
+		}
+		for _, ind := range env.population[i : i+size] {
+			if ind == nil {
+				continue
+			}
+			if len(home.members) > 0 {
+				r := env.areaSize * householdSpread * math.Sqrt(rng.Float64())
+				angle := rng.Float64() * 2 * math.Pi
+				ind.position = wrapPosition(env, OrderedPair{
+					x: home.position.x + r*math.Cos(angle),
+					y: home.position.y + r*math.Sin(angle),
+				})
+			}
+			ind.home = home
+			ind.region = home.region
+			home.members = append(home.members, ind)
+		}
+		h.count++
+		i += size
+	}
+}
+
+// poisson draws from a Poisson distribution with the given mean (Knuth's
+// method; household sizes are small).
+func poisson(mean float64, rng *rand.Rand) int {
+	if mean <= 0 {
+		return 0
+	}
+	limit := math.Exp(-mean)
+	k, p := 0, rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
+
+// wrapPosition wraps p around the edges of the area, as movement does.
+func wrapPosition(env *Environment, p OrderedPair) OrderedPair {
+	p.x = math.Mod(p.x+env.areaSize, env.areaSize)
+	p.y = math.Mod(p.y+env.areaSize, env.areaSize)
+	return p
+}
+
+// movementOrigin returns where ind sets out from today: its home, or its
+// current position if it has none.
+func movementOrigin(env *Environment, ind *Individual) OrderedPair {
+	if ind.home != nil && env.roads == nil {
+		return ind.home.position
+	}
+	return ind.position
+}
+
+// infectiousHousemates returns the number of ind's housemates who can infect
+// it tonight with the main disease.
+func infectiousHousemates(ind *Individual) int {
+	if ind.home == nil {
+		return 0
+	}
+	n := 0
+	for _, m := range ind.home.members {
+		if m != ind && m.healthStatus == Infected && !m.inHospital {
+			n++
+		}
+	}
+	return n
+}
+
+// decisionDraw returns the uniform draw that decides d for ind: ind takes
+// the decision if it falls below the decision's probability. Without
+// correlation it is a plain draw; otherwise it is shared in part with ind's
+// housemates.
+func decisionDraw(env *Environment, ind *Individual, d householdDecision, rng *rand.Rand) float64 {
+	rho := env.households.correlation
+	if rho <= 0 || ind.home == nil {
+		return drawFloat(rng)
+	}
+	x := math.Sqrt(rho)*ind.home.attitudes[d] + math.Sqrt(1-rho)*rng.NormFloat64()
+	return 0.5 * math.Erfc(-x/math.Sqrt2) // standard normal CDF
+}
+
+// printHouseholdVaccinationSummary reports how often housemates share a
+// vaccination status, against what independent decisions at the same
+// coverage would give.
+func printHouseholdVaccinationSummary(env *Environment) {
+	h := env.households
+	if !h.enabled() || len(env.population) == 0 {
+		return
+	}
+	vaccinated, living := 0, 0
+	homes := map[*Household]bool{}
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus == Dead {
+			continue
+		}
+		living++
+		if ind.vaccinated {
+			vaccinated++
+		}
+		if ind.home != nil && len(ind.home.members) > 1 {
+			homes[ind.home] = true
+		}
+	}
+	if vaccinated == 0 || living == 0 || len(homes) == 0 {
+		return
+	}
+	p := float64(vaccinated) / float64(living)
+	alike, expected := 0, 0.0
+	for home := range homes {
+		n, v := 0, 0
+		for _, m := range home.members {
+			if m.healthStatus == Dead {
+				continue
+			}
+			n++
+			if m.vaccinated {
+				v++
+			}
+		}
+		if v == 0 || v == n {
+			alike++
+		}
+		expected += math.Pow(p, float64(n)) + math.Pow(1-p, float64(n))
+	}
+	fmt.Fprintf(msgOut, "Households all vaccinated or all unvaccinated: %.1f%% (%.1f%% expected if independent)\n",
+		100*float64(alike)/float64(len(homes)), 100*expected/float64(len(homes)))
+}
+
+// printHouseholdSummary reports the households created, at the start of the run.
+func printHouseholdSummary(env *Environment) {
+	h := env.households
+	if !h.enabled() || h.count == 0 {
+		return
+	}
+	fmt.Fprintf(msgOut, "Households: %d (mean size %.2f)", h.count, float64(len(env.population))/float64(h.count))
+	if h.correlation > 0 {
+		fmt.Fprintf(msgOut, ", decisions correlated within households (rho %.2f)", h.correlation)
+	}
+	fmt.Fprintln(msgOut)
+}
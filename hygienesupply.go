@@ -0,0 +1,84 @@
+package main
+
+import "fmt"
+
+// Hygiene supplies. With hygieneSupply enabled, practicing hygiene (sanitizer,
+// masks, soap) uses up a finite stock. Stock is counted in person-days: one
+// person at hygiene level 1 uses one unit a day, so each day the population
+// uses the sum of its hygiene levels. The stock is replenished every day.
+// When the stock cannot cover today's use, individual hygiene gains are scaled
+// down by the share that can be covered; levels still decay as usual, so a
+// long shortage wears hygiene down.
+
+// HygieneSupply configures and tracks the hygiene supply stock.
+type HygieneSupply struct {
+	enabled      bool
+	stockDays    float64 // initial stock, in days of full-hygiene use by the whole population
+	restockShare float64 // daily delivery, as a share of the whole population's full-hygiene use
+
+	stock        float64 // person-days on hand
+	ratio        float64 // share of today's use the stock covers, in [0, 1]
+	shortageDays int     // days with ratio < 1 so far
+}
+
+// initHygieneSupply fills the initial stock for a population of n.
+func initHygieneSupply(hs *HygieneSupply, n int) {
+	hs.stock = hs.stockDays * float64(n)
+	hs.ratio = 1
+}
+
+// restockHygiene receives today's delivery and works out how much of today's
+// use it covers, before individual hygiene levels are updated.
+func restockHygiene(env *Environment) {
+	hs := &env.hygieneSupply
+	if !hs.enabled {
+		return
+	}
+	hs.stock += hs.restockShare * float64(len(env.population))
+	use := hygieneUse(env)
+	hs.ratio = 1
+	if use > hs.stock {
+		hs.ratio = hs.stock / use
+		hs.shortageDays++
+	}
+}
+
+// consumeHygiene takes today's use out of the stock, after hygiene levels
+// have been updated.
+func consumeHygiene(env *Environment) {
+	hs := &env.hygieneSupply
+	if !hs.enabled {
+		return
+	}
+	hs.stock = max(hs.stock-hygieneUse(env), 0)
+}
+
+// hygieneUse returns the supplies the living population uses in a day.
+func hygieneUse(env *Environment) float64 {
+	use := 0.0
+	for _, ind := range env.population {
+		if ind != nil && ind.healthStatus != Dead {
+			use += clamp01(ind.hygieneLevel)
+		}
+	}
+	return use
+}
+
+// limitHygieneGain scales an individual's hygiene gain from current to next
+// by the share of today's use the stock covers.
+func limitHygieneGain(env *Environment, current, next float64) float64 {
+	if env == nil || !env.hygieneSupply.enabled || next <= current {
+		return next
+	}
+	return current + (next-current)*env.hygieneSupply.ratio
+}
+
+// printHygieneSupplySummary reports shortage days at the end of the run.
+func printHygieneSupplySummary(env *Environment) {
+	hs := env.hygieneSupply
+	if !hs.enabled {
+		return
+	}
+	fmt.Fprintf(msgOut, "Hygiene supplies: short on %d of %d days, %.0f person-days left in stock\n",
+		hs.shortageDays, env.day, hs.stock)
+}
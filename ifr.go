@@ -0,0 +1,84 @@
+package main
+
+import "sort"
+
+// Infection fatality ratio by age.
+//
+// By default computeC scales mortalityRate by a three-bucket age multiplier.
+// With ifrByAge, the daily death probability is derived from an age-banded
+// infection fatality ratio (IFR) instead: each day c = d * IFR / (1 - IFR),
+// where d is the day's recovery probability. Because the ratio c/d is the
+// same every day, an infection ends in death with probability IFR (before the
+// overload, care and vaccination modifiers), so the model can be calibrated
+// against published IFR estimates. An ifr column in the age curves file
+// (see agecurves.go) does the same with an interpolated curve.
+
+// ifrBand is the IFR from age up to the next band's age.
+type ifrBand struct {
+	age int
+	ifr float64
+}
+
+// defaultIFRTable is the COVID-19 IFR by 10-year age band from Verity et al.
+// (2020), Lancet Infect Dis 20(6).
+var defaultIFRTable = []ifrBand{
+	{0, 0.0000161},
+	{10, 0.0000695},
+	{20, 0.000309},
+	{30, 0.000844},
+	{40, 0.00161},
+	{50, 0.00595},
+	{60, 0.0193},
+	{70, 0.0428},
+	{80, 0.0780},
+}
+
+// ifrTableFromConfig turns age -> IFR entries into a sorted table. Returns
+// nil if ifrByAge is off, or the default table if there are no entries.
+func ifrTableFromConfig(enabled bool, entries map[int]float64) []ifrBand {
+	if !enabled {
+		return nil
+	}
+	if len(entries) == 0 {
+		return defaultIFRTable
+	}
+	table := make([]ifrBand, 0, len(entries))
+	for age, ifr := range entries {
+		table = append(table, ifrBand{age: age, ifr: ifr})
+	}
+	sort.Slice(table, func(i, j int) bool { return table[i].age < table[j].age })
+	return table
+}
+
+// ifrAt returns the IFR of the band containing age. Ages below the first
+// band use the first band.
+func ifrAt(table []ifrBand, age int) float64 {
+	ifr := table[0].ifr
+	for _, b := range table {
+		if age < b.age {
+			break
+		}
+		ifr = b.ifr
+	}
+	return ifr
+}
+
+// usesIFR reports whether deaths follow an IFR table or curve.
+func usesIFR(env *Environment) bool {
+	return env.ifrTable != nil || env.ageCurves.has(AgeIFR)
+}
+
+// ifrDeathProb returns the base daily death probability of an infected
+// individual whose recovery probability today is d.
+func ifrDeathProb(env *Environment, ind *Individual, d float64) float64 {
+	var ifr float64
+	if env.ageCurves.has(AgeIFR) {
+		ifr = env.ageCurves.curves[AgeIFR].at(ind.age)
+	} else {
+		ifr = ifrAt(env.ifrTable, ind.age)
+	}
+	if ifr >= 1 {
+		return 1
+	}
+	return d * ifr / (1 - ifr)
+}
@@ -0,0 +1,113 @@
+package main
+
+// Information source. The policy controller (the social distance threshold,
+// the hygiene campaign and lockdowns), and so everyone's behavior, reacts to
+// the infected fraction. By default that is the true fraction on the day,
+// which no real government knows. informationSource makes it react to what is
+// reported instead:
+//
+//	true      today's true infected fraction (default)
+//	reported  the true fraction times ascertainmentRate, the share of
+//	          infections that show up in the case counts
+//	detected  the fraction with an infection found by detection or testing
+//	          (detectionRate or testingRate)
+//
+// Reported and detected counts reach the policy reportingDelay days late, so
+// the response lags the epidemic and overshoots it on the way down. The
+// overload tightening, which estimates the caseload from the infected
+// fraction, uses the reported one too, but the hospital triggers
+// (distancingTrigger = hospital or icu) still see the true bed demand, since
+// hospitals know how full they are. Policy cells react to their local
+// fraction from the same source, with the same delay.
+
+// informationSource selects what the policy controller learns about prevalence.
+type informationSource string
+
+const (
+	InformationTrue     informationSource = "true"
+	InformationReported informationSource = "reported"
+	InformationDetected informationSource = "detected"
+)
+
+// InformationConfig holds the information source and the reports in transit.
+type InformationConfig struct {
+	source        informationSource
+	ascertainment float64 // share of infections in the case counts (reported only)
+	delay         int     // days from a count to its report
+
+	pending []float64 // fractions counted over the last delay days, oldest first
+}
+
+// lagged reports whether the controller sees something other than the truth.
+func (c InformationConfig) lagged() bool {
+	return c.source == InformationReported || c.source == InformationDetected
+}
+
+// observed returns the infected fraction as counted today from a group of
+// present individuals, infected of them Infected and detected of those
+// detected.
+func (c InformationConfig) observed(infected, detected, present int) float64 {
+	if present == 0 {
+		return 0
+	}
+	switch c.source {
+	case InformationReported:
+		return c.ascertainment * float64(infected) / float64(present)
+	case InformationDetected:
+		return float64(detected) / float64(present)
+	default:
+		return float64(infected) / float64(present)
+	}
+}
+
+// report adds today's count to pending and returns the count reported today,
+// the one made delay days ago (0 early in the run, before any report).
+func (c InformationConfig) report(pending *[]float64, count float64) float64 {
+	if c.delay <= 0 {
+		return count
+	}
+	*pending = append(*pending, count)
+	if len(*pending) <= c.delay {
+		return 0
+	}
+	out := (*pending)[0]
+	*pending = (*pending)[1:]
+	return out
+}
+
+// reportedPrevalence returns the infected fraction the central controller
+// sees today, given the true one. It is called once a day.
+func reportedPrevalence(env *Environment, infectedFraction float64) float64 {
+	c := &env.information
+	if !c.lagged() {
+		return infectedFraction
+	}
+	// counted like ComputePopulationStats, so reported with full ascertainment
+	// and no delay is the truth
+	infected, detected, present := 0, 0, 0
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		present++
+		if ind.healthStatus == Infected {
+			infected++
+			if ind.infection != nil && ind.infection.detected {
+				detected++
+			}
+		}
+	}
+	return c.report(&c.pending, c.observed(infected, detected, present))
+}
+
+// informationLabel names what the controller's infected fraction is, for logs.
+func informationLabel(env *Environment) string {
+	switch env.information.source {
+	case InformationReported:
+		return "reported infected"
+	case InformationDetected:
+		return "detected infected"
+	default:
+		return "infected"
+	}
+}
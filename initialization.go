@@ -5,7 +5,7 @@ import "math/rand"
 // initialize disease function
 // takes input of Disease field and returns a pointer
 // Once disease is initialized, it cannot be changed
-func initializeDisease(name string, transmissionRate, transmissionDistance, recoveryRate, mortalityRate float64, latentPeriod, infectiousPeriod, immunityDuration int) *Disease {
+func initializeDisease(name string, transmissionRate, transmissionDistance, recoveryRate, mortalityRate float64, latentPeriod, infectiousPeriod, immunityDuration int, severeFraction, criticalFraction float64) *Disease {
 	return &Disease{
 		name:                 name,
 		transmissionRate:     transmissionRate,
@@ -15,6 +15,8 @@ func initializeDisease(name string, transmissionRate, transmissionDistance, reco
 		latentPeriod:         latentPeriod,
 		infectiousPeriod:     infectiousPeriod,
 		immunityDuration:     immunityDuration,
+		severeFraction:       severeFraction,
+		criticalFraction:     criticalFraction,
 	}
 }
 
@@ -31,6 +33,11 @@ func initializeEnvironment(
 	vaccinationRate float64,
 	medicalCareLevel float64,
 	medicalCapacity int,
+	icuCapacity int,
+	chunkSize int,
+	hygieneInit behaviorDistribution,
+	complianceInit behaviorDistribution,
+	rng *rand.Rand,
 ) *Environment {
 
 	env := &Environment{
@@ -42,11 +49,28 @@ func initializeEnvironment(
 		vaccinationRate:         vaccinationRate,
 		medicalCareLevel:        medicalCareLevel,
 		medicalCapacity:         medicalCapacity,
+		icuCapacity:             icuCapacity,
+		hygieneInit:             hygieneInit,
+		complianceInit:          complianceInit,
 	}
 
-	// Fill population with initialized individuals
+	// Fill population with initialized individuals. With chunkSize > 0 they are
+	// allocated in slabs of chunkSize instead of one by one, which cuts
+	// allocation and GC overhead for multi-million populations.
+	var slab []Individual
 	for i := 0; i < popSize; i++ {
-		person := initializeIndividual(env)
+		var person *Individual
+		if chunkSize > 0 {
+			if len(slab) == 0 {
+				slab = make([]Individual, min(chunkSize, popSize-i))
+			}
+			person = &slab[0]
+			slab = slab[1:]
+			fillIndividual(env, person, rng)
+		} else {
+			person = initializeIndividual(env, rng)
+		}
+		person.id = i
 		env.population[i] = person
 	}
 
@@ -55,25 +79,32 @@ func initializeEnvironment(
 
 // initialize individual function
 // randomly initialize individual, all its fields are randomized
-func initializeIndividual(env *Environment) *Individual {
+func initializeIndividual(env *Environment, rng *rand.Rand) *Individual {
+	person := &Individual{}
+	fillIndividual(env, person, rng)
+	return person
+}
+
+// fillIndividual randomly initializes the individual at p in place.
+func fillIndividual(env *Environment, p *Individual, rng *rand.Rand) {
 	// Random position in the map
 	pos := OrderedPair{
-		x: rand.Float64() * env.areaSize,
-		y: rand.Float64() * env.areaSize,
+		x: rng.Float64() * env.areaSize,
+		y: rng.Float64() * env.areaSize,
 	}
 
 	// Random age 0–90 for now
-	age := rand.Intn(91)
+	age := rng.Intn(91)
 
-	// Hygiene + distancing compliance (0–1)
-	hygiene := rand.Float64()
-	socialDistance := rand.Float64()
+	// Hygiene + distancing compliance (0–1), uniform unless configured otherwise
+	hygiene := env.hygieneInit.draw(rng)
+	socialDistance := env.complianceInit.draw(rng)
 
 	health := Healthy
 
 	// Random movement type at initialization
 	var mt moveType
-	switch r := rand.Float64(); {
+	switch r := rng.Float64(); {
 	case r < 0.01:
 		mt = Flight
 	case r < 0.05:
@@ -84,12 +115,11 @@ func initializeIndividual(env *Environment) *Individual {
 
 	movement := NewMovementPattern(mt, env)
 
-	return &Individual{
-		gender:                   randomGender(),
+	*p = Individual{
+		gender:                   randomGender(rng),
 		age:                      age,
 		healthStatus:             health,
-		disease:                  nil,
-		daysInfected:             0,
+		infection:                nil,
 		daysSinceRecovery:        0,
 		daysSinceVacination:      0,
 		vaccinated:               false,
@@ -102,8 +132,8 @@ func initializeIndividual(env *Environment) *Individual {
 }
 
 // A simple helper for gender assignment (expand later if needed)
-func randomGender() string {
-	if rand.Intn(2) == 0 {
+func randomGender(rng *rand.Rand) string {
+	if rng.Intn(2) == 0 {
 		return "Male"
 	}
 	return "Female"
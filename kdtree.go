@@ -0,0 +1,155 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// spatialIndexKind selects how neighbor queries find individuals within a radius.
+type spatialIndexKind string
+
+const (
+	IndexScan   spatialIndexKind = "scan"   // check every individual (default)
+	IndexKDTree spatialIndexKind = "kdtree" // 2-d tree rebuilt each day
+	IndexGrid   spatialIndexKind = "grid"   // uniform hash grid rebuilt each day, see grid.go
+)
+
+// neighborIndex answers radius queries over the positions at build time.
+type neighborIndex interface {
+	// within calls visit for every individual within distance r of center.
+	within(center OrderedPair, r float64, visit func(ind *Individual, d float64))
+}
+
+// kdTree is a static 2-d tree over individuals' positions.
+// It is stored implicitly: for any range [lo, hi) the median element sits at
+// (lo+hi)/2, everything before it is on the low side of its splitting axis and
+// everything after it on the high side. Axes alternate x, y with depth.
+//
+// The tree holds pointers, so health status is read at query time, but
+// positions are captured at build time: it must be rebuilt after anyone moves.
+type kdTree struct {
+	items []*Individual
+}
+
+// buildKDTree builds a tree over the non-nil individuals in pop.
+func buildKDTree(pop []*Individual) *kdTree {
+	items := make([]*Individual, 0, len(pop))
+	for _, ind := range pop {
+		if ind != nil {
+			items = append(items, ind)
+		}
+	}
+	t := &kdTree{items: items}
+	t.build(0, len(items), 0)
+	return t
+}
+
+func (t *kdTree) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+	part := t.items[lo:hi]
+	if depth%2 == 0 {
+		sort.Slice(part, func(i, j int) bool { return part[i].position.x < part[j].position.x })
+	} else {
+		sort.Slice(part, func(i, j int) bool { return part[i].position.y < part[j].position.y })
+	}
+	mid := (lo + hi) / 2
+	t.build(lo, mid, depth+1)
+	t.build(mid+1, hi, depth+1)
+}
+
+// within calls visit for every individual within distance r of center.
+func (t *kdTree) within(center OrderedPair, r float64, visit func(ind *Individual, d float64)) {
+	t.search(0, len(t.items), 0, center, r, visit)
+}
+
+func (t *kdTree) search(lo, hi, depth int, center OrderedPair, r float64, visit func(*Individual, float64)) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	ind := t.items[mid]
+	if d := dist(center, ind.position); d <= r {
+		visit(ind, d)
+	}
+	if hi-lo == 1 {
+		return
+	}
+
+	// Signed distance from center to the splitting line
+	delta := center.x - ind.position.x
+	if depth%2 == 1 {
+		delta = center.y - ind.position.y
+	}
+	if delta <= r {
+		t.search(lo, mid, depth+1, center, r, visit)
+	}
+	if delta >= -r {
+		t.search(mid+1, hi, depth+1, center, r, visit)
+	}
+}
+
+// rebuildSpatialIndex refreshes env's spatial index from current positions.
+// Call it once per day before neighbor queries; it is a no-op for the scan index.
+func rebuildSpatialIndex(env *Environment) {
+	if env == nil {
+		return
+	}
+	switch env.spatialIndex {
+	case IndexKDTree:
+		env.index = buildKDTree(env.population)
+	case IndexGrid:
+		env.index = buildHashGrid(env.population, env.areaSize)
+	}
+}
+
+// invalidateSpatialIndex drops env's spatial index after positions change;
+// neighbor queries fall back to a full scan until it is rebuilt.
+func invalidateSpatialIndex(env *Environment) {
+	if env != nil {
+		env.index = nil
+	}
+}
+
+// nearest returns the distance from center to the closest individual in the
+// tree other than skip, or +Inf if there is none.
+func (t *kdTree) nearest(center OrderedPair, skip *Individual) float64 {
+	best := math.Inf(1)
+	t.nearestIn(0, len(t.items), 0, center, skip, &best)
+	return best
+}
+
+func (t *kdTree) nearestIn(lo, hi, depth int, center OrderedPair, skip *Individual, best *float64) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	ind := t.items[mid]
+	if ind != skip {
+		if d := dist(center, ind.position); d < *best {
+			*best = d
+		}
+	}
+	if hi-lo == 1 {
+		return
+	}
+
+	delta := center.x - ind.position.x
+	if depth%2 == 1 {
+		delta = center.y - ind.position.y
+	}
+	// Search the side of the splitting line that holds center first; the other
+	// side can only help if the line is closer than the best distance so far.
+	if delta <= 0 {
+		t.nearestIn(lo, mid, depth+1, center, skip, best)
+		if -delta < *best {
+			t.nearestIn(mid+1, hi, depth+1, center, skip, best)
+		}
+	} else {
+		t.nearestIn(mid+1, hi, depth+1, center, skip, best)
+		if delta < *best {
+			t.nearestIn(lo, mid, depth+1, center, skip, best)
+		}
+	}
+}
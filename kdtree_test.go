@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// randomPopulation places n individuals uniformly in a size x size area.
+func randomPopulation(rng *rand.Rand, n int, size float64) []*Individual {
+	pop := make([]*Individual, n)
+	for i := range pop {
+		pop[i] = &Individual{id: i, position: OrderedPair{rng.Float64() * size, rng.Float64() * size}}
+	}
+	return pop
+}
+
+// scanWithin returns the ids of the individuals within r of center, checking
+// every pair.
+func scanWithin(pop []*Individual, center OrderedPair, r float64) []int {
+	var ids []int
+	for _, ind := range pop {
+		if dist(center, ind.position) <= r {
+			ids = append(ids, ind.id)
+		}
+	}
+	return ids
+}
+
+// TestIndexWithinMatchesScan checks that the k-d tree and the hash grid find
+// exactly the individuals the all-pairs scan finds, with their distances, on
+// random populations, radii and centers (inside and outside the area).
+func TestIndexWithinMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	indexes := map[spatialIndexKind]func(pop []*Individual, size float64) neighborIndex{
+		IndexKDTree: func(pop []*Individual, _ float64) neighborIndex { return buildKDTree(pop) },
+		IndexGrid:   func(pop []*Individual, size float64) neighborIndex { return buildHashGrid(pop, size) },
+	}
+	for _, n := range []int{0, 1, 2, 7, 100, 2000} {
+		size := 100.0
+		pop := randomPopulation(rng, n, size)
+		for kind, build := range indexes {
+			index := build(pop, size)
+			for q := 0; q < 200; q++ {
+				center := OrderedPair{rng.Float64()*120 - 10, rng.Float64()*120 - 10}
+				r := []float64{0, 0.5, 3, 10, 40, 200}[q%6]
+				var got []int
+				index.within(center, r, func(ind *Individual, d float64) {
+					if want := dist(center, ind.position); d != want {
+						t.Fatalf("%s, n=%d: distance %g reported for individual %d, want %g", kind, n, d, ind.id, want)
+					}
+					got = append(got, ind.id)
+				})
+				slices.Sort(got)
+				if want := scanWithin(pop, center, r); !slices.Equal(got, want) {
+					t.Fatalf("%s, n=%d, center %v, r=%g: found %d individuals %v, the scan finds %d %v",
+						kind, n, center, r, len(got), got, len(want), want)
+				}
+			}
+		}
+	}
+}
+
+// TestKDTreeNearest checks nearest against the scan.
+func TestKDTreeNearest(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	pop := randomPopulation(rng, 500, 50)
+	tree := buildKDTree(pop)
+	for _, ind := range pop {
+		want := math.Inf(1)
+		for _, other := range pop {
+			if other != ind {
+				want = min(want, dist(ind.position, other.position))
+			}
+		}
+		if got := tree.nearest(ind.position, ind); got != want {
+			t.Fatalf("nearest to individual %d: got %g, want %g", ind.id, got, want)
+		}
+	}
+}
+
+// withinFound keeps the benchmarks' neighbor counts alive.
+var withinFound int
+
+// benchmarkPopulation is 5000 individuals at the default density.
+func benchmarkPopulation() []*Individual {
+	return randomPopulation(rand.New(rand.NewSource(3)), 5000, 100*math.Sqrt(5))
+}
+
+// BenchmarkWithinScan finds everyone's neighbors within 5 by checking every
+// pair, as the scan index does.
+func BenchmarkWithinScan(b *testing.B) {
+	pop := benchmarkPopulation()
+	b.ReportAllocs()
+	for b.Loop() {
+		found := 0
+		for _, ind := range pop {
+			for _, other := range pop {
+				if dist(ind.position, other.position) <= 5 {
+					found++
+				}
+			}
+		}
+		withinFound = found
+	}
+}
+
+// BenchmarkWithinKDTree builds the tree and finds everyone's neighbors
+// within 5 through it, as a day with spatialIndex = kdtree does.
+func BenchmarkWithinKDTree(b *testing.B) {
+	pop := benchmarkPopulation()
+	b.ReportAllocs()
+	for b.Loop() {
+		tree := buildKDTree(pop)
+		found := 0
+		for _, ind := range pop {
+			tree.within(ind.position, 5, func(*Individual, float64) { found++ })
+		}
+		withinFound = found
+	}
+}
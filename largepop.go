@@ -0,0 +1,59 @@
+package main
+
+import "math"
+
+// largePopulation mode lets runs go past a million individuals. Nothing about
+// the model changes; the mode trades exactness of some outputs for memory and
+// time:
+//   - individuals are allocated in slabs of populationChunkSize,
+//   - neighbor queries always use the k-d tree index,
+//   - the clustering statistics are estimated from a sample of the infected,
+//   - frames draw an evenly spaced subset of at most renderSample individuals,
+//   - the pre-run burn-in uses a smaller population at the same density.
+
+const (
+	// maxPopulation is the largest popSize accepted without largePopulation.
+	maxPopulation = 1000000
+	// maxLargePopulation is the largest popSize accepted in largePopulation mode.
+	maxLargePopulation = 50000000
+	// populationChunkSize is the number of individuals allocated together.
+	populationChunkSize = 1 << 16
+	// burnInPopulation caps the population of the pre-run burn-in.
+	burnInPopulation = 100000
+)
+
+// renderPopulation returns the individuals drawn on the spatial map: everyone,
+// or in largePopulation mode an evenly spaced subset of at most
+// env.renderSample. Individuals are placed at random, so taking every k-th one
+// is a random sample. The subset is chosen once and kept, so the same dots
+// are followed from frame to frame.
+func renderPopulation(env *Environment) []*Individual {
+	n := len(env.population)
+	if !env.largePopulation || env.renderSample <= 0 || n <= env.renderSample {
+		return env.population
+	}
+	if env.renderSet == nil {
+		env.renderSet = make([]*Individual, 0, env.renderSample)
+		for i := 0; i < env.renderSample; i++ {
+			env.renderSet = append(env.renderSet, env.population[i*n/env.renderSample])
+		}
+	}
+	return env.renderSet
+}
+
+// burnInConfig returns the config to use for the pre-run burn-in. Populations
+// above burnInPopulation are scaled down, shrinking the area so that density,
+// and with it the contact structure, stays the same.
+func burnInConfig(config *Config) *Config {
+	if config.popSize <= burnInPopulation {
+		return config
+	}
+	scaled := *config
+	f := float64(burnInPopulation) / float64(config.popSize)
+	scaled.popSize = burnInPopulation
+	scaled.areaSize = config.areaSize * math.Sqrt(f)
+	scaled.initialInfected = max(1, int(float64(config.initialInfected)*f))
+	scaled.medicalCapacity = int(float64(config.medicalCapacity) * f)
+	scaled.icuCapacity = int(float64(config.icuCapacity) * f)
+	return &scaled
+}
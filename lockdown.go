@@ -0,0 +1,127 @@
+package main
+
+import "fmt"
+
+// Lockdowns. With lockdownThreshold > 0, a lockdown starts on the first day
+// the infected fraction exceeds the threshold. While it lasts, step lengths
+// are multiplied by lockdownMobility and so are the chances of taking a train
+// or a flight. It lasts at least lockdownMinDays days, and is lifted on the
+// first day after that when the infected fraction is below lockdownRelease
+// (half the threshold if unset). A later rise above the threshold starts a
+// new lockdown. The main run logs each change, and the stats gain a Lockdown
+// column. With policy cells (see policycells.go), each cell runs these rules
+// on its own infected fraction instead.
+
+// LockdownConfig holds the lockdown rules and the current lockdown state.
+type LockdownConfig struct {
+	threshold float64 // infected fraction that starts a lockdown, 0 = never
+	release   float64 // infected fraction below which it is lifted, 0 = threshold/2
+	mobility  float64 // step length and train/flight multiplier during a lockdown
+	minDays   int     // shortest lockdown
+
+	active  bool
+	changed bool // started or lifted today
+	since   int  // day the current lockdown started
+	count   int  // lockdowns started so far
+	days    int  // days spent in lockdown so far
+}
+
+// enabled reports whether lockdowns can happen in this run.
+func (l LockdownConfig) enabled() bool { return l.threshold > 0 }
+
+// releaseAt returns the infected fraction below which a lockdown is lifted.
+func (l LockdownConfig) releaseAt() float64 {
+	if l.release > 0 {
+		return l.release
+	}
+	return l.threshold / 2
+}
+
+// updateLockdown starts or lifts the lockdown for today given the infected
+// fraction.
+func updateLockdown(env *Environment, infectedFraction float64) {
+	env.lockdown.update(env.day, infectedFraction)
+}
+
+// update starts or lifts the lockdown on day given the infected fraction.
+func (l *LockdownConfig) update(day int, infectedFraction float64) {
+	if !l.enabled() {
+		return
+	}
+	l.changed = false
+	switch {
+	case !l.active && infectedFraction > l.threshold:
+		l.active, l.changed = true, true
+		l.since = day
+		l.count++
+	case l.active && day-l.since >= l.minDays && infectedFraction < l.releaseAt():
+		l.active, l.changed = false, true
+	}
+	if l.active {
+		l.days++
+	}
+}
+
+// logLockdown prints today's lockdown changes, if there were any, with the
+// infected fraction the decision was based on.
+func logLockdown(env *Environment, infectedFraction float64) {
+	what := informationLabel(env)
+	if len(env.cells) == 0 {
+		env.lockdown.log(env.day, "", what, infectedFraction)
+		return
+	}
+	for k, c := range env.cells {
+		c.lockdown.log(env.day, " in cell "+cellName(env, k), what, c.seen)
+	}
+}
+
+// log prints the change of the lockdown of place (empty for the central
+// one) on day, if there was one.
+func (l LockdownConfig) log(day int, place, what string, infectedFraction float64) {
+	switch {
+	case !l.changed:
+	case l.active:
+		fmt.Fprintf(msgOut, "Day %d: lockdown started%s (%.1f%% %s)\n", day, place, 100*infectedFraction, what)
+	default:
+		fmt.Fprintf(msgOut, "Day %d: lockdown lifted%s after %d days (%.1f%% %s)\n", day, place, day-l.since, 100*infectedFraction, what)
+	}
+}
+
+// lockdownMobility returns today's multiplier on ind's step lengths and on
+// its chances of taking a train or a flight.
+func lockdownMobility(env *Environment, ind *Individual) float64 {
+	l := &env.lockdown
+	if c := policyCell(env, ind); c != nil {
+		l = &c.lockdown
+	}
+	if !l.active {
+		return 1
+	}
+	return l.mobility
+}
+
+// printLockdownSummary reports the lockdowns of the run.
+func printLockdownSummary(env *Environment) {
+	l := env.lockdown
+	if !l.enabled() {
+		return
+	}
+	if len(env.cells) > 0 {
+		count, days, hit := 0, 0, 0
+		for _, c := range env.cells {
+			count += c.lockdown.count
+			days += c.lockdown.days
+			if c.lockdown.count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(msgOut, "Local lockdowns: %d in %d of %d cells, %d cell-days in total (in force in %d at the end)\n",
+			count, hit, len(env.cells), days, lockedCells(env))
+		return
+	}
+	fmt.Fprintf(msgOut, "Lockdowns: %d, %d days in total", l.count, l.days)
+	if l.active {
+		fmt.Fprintf(msgOut, " (in force since day %d)", l.since)
+	}
+	fmt.Fprintln(msgOut)
+}
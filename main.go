@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
-	"image"
+	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,15 +20,39 @@ type Config struct {
 	diseaseName          string
 	transmissionRate     float64
 	transmissionDistance float64
+	transmissionKernel   kernelKind
 	recoveryRate         float64
 	mortalityRate        float64
 	latentPeriod         int
 	infectiousPeriod     int
 	immunityDuration     int
+	severeFraction       float64
+	criticalFraction     float64
+	maxInfectionDays     int
+	dispersion           float64 // infectiousnessDispersion: gamma shape of individual infectiousness, 0 = none
+	boosting             ImmunityBoosting
+	hospitalQueue        HospitalQueueConfig
+	sideEffects          VaccineSideEffects
+	seir                 bool
+	immunityWaning       immunityWaning
+	quarantine           QuarantineConfig
+	tracing              TracingConfig
+	households           HouseholdConfig
+	ifrByAge             bool
+	ifr                  map[int]float64 // age -> IFR from that age; empty = default table
+	hygieneSupply        HygieneSupply
+	backgroundMortality  BackgroundMortality
+	shocks               ShockConfig
+	lockdown             LockdownConfig
+	policyCells          int
+	information          InformationConfig
+	regions              []*Region // from [region.NAME] blocks, in config order
 
 	// Population parameters
 	popSize         int
 	initialInfected int
+	hygieneInit     behaviorDistribution // initial individual hygiene levels
+	complianceInit  behaviorDistribution // initial individual distancing compliance
 
 	// Environment parameters
 	areaSize                float64
@@ -36,9 +62,90 @@ type Config struct {
 	vaccinationRate         float64
 	medicalCareLevel        float64
 	medicalCapacity         int // if 0, will be calculated as 10% of popSize
+	icuCapacity             int // if -1, will be calculated as 20% of medicalCapacity; 0 means no ICU beds
+
+	// Movement parameters
+	stepDistributions map[moveType]stepDistribution
+	levyExponent      float64
+
+	// Policy parameters
+	distancingTrigger triggerMetric
+
+	// Transit vehicle parameters
+	transit TransitConfig
+
+	// Subgroup tags, in order of first appearance in the config
+	tags          []*TagSpec
+	stratifyByTag bool
+
+	// Behavior profiles, in order of first appearance in the config
+	profiles []*ProfileSpec
+
+	// Co-circulating pathogens from [disease.NAME] blocks, in config order
+	pathogens []*Pathogen
+
+	// Output parameters
+	statsPer100k    bool
+	reportIncidence bool
 
 	// Simulation parameters
-	numDays int
+	numDays           int
+	warmupDays        int       // days of infection-free dynamics before day 0
+	startDate         time.Time // zero = no calendar labels
+	statsWindowDays   int       // 0 = full detail for every day
+	flushEveryDays    int       // write outputs to disk every K days, 0 = only at the end
+	statsFilename     string    // also write all stats rows to output_gif/<name>, "" = off
+	compressOutputs   bool      // gzip the outputs with fixed names, see compress.go
+	exposureRisk      bool      // write every individual's exposure risk at the end
+	npiReport         bool      // report each mechanism's contribution to reducing transmission
+	chainStats        bool      // log who infected whom and report transmission chain statistics
+	reportReff        bool      // report R_effective by date of infection and add it to the stats file
+	numReplicates     int       // runs of the config with consecutive seeds, 1 = the main run only
+	checkpointDays    int       // days between saved states for -resume, 0 = off
+	fastForward       bool      // skip transmission on days with no one infectious
+	batchTransmission bool      // score contacts from flat slices of the infected, see batch.go
+	statsFormat       statsFormat
+	sweep             []sweepParam
+	sweepHeatmap      string
+	waveProminence    float64 // share of the highest infected count a wave must rise/fall by
+	contactMemoryDays int
+	contactsPerDay    int
+	spatialIndex      spatialIndexKind
+	numWorkers        int // goroutines for the per-day population updates, 0 = GOMAXPROCS
+	errorPolicy       errorPolicy
+	sanityCheckDays   int   // burn-in length for the pre-run R0 check, 0 = off
+	randomSeed        int64 // seed of the run's random number generator, 0 = time-based
+	rngAlgorithm      rngAlgorithm
+	largePopulation   bool
+	renderSample      int // max individuals drawn per frame in largePopulation mode
+
+	// Vaccination campaign parameters
+	vaccineSupply VaccineSupply
+	rollout       VaccineRollout
+	stockpile     StockpileConfig
+
+	// Road network file (edge list); empty = free movement
+	roadNetworkFile string
+
+	// Weather file (per-day transmission/activity multipliers); empty = none
+	weatherFile string
+
+	// Age curves file (age-dependent parameters); empty = built-in age buckets.
+	// It is read when the config is loaded.
+	ageCurvesFile string
+	ageCurves     *ageCurves
+
+	// Observed incidence curve imposed for the first assimilationDays days; empty = none
+	incidenceFile    string
+	incidenceScale   float64 // multiplies every observed count
+	assimilationDays int     // 0 = up to the last listed day
+
+	// Scenario annotations: day -> note
+	annotations map[int]string
+
+	// Health economics parameters
+	lifeExpectancy map[int]float64 // age -> remaining years; empty = default table
+	qaly           QALYConfig
 
 	// Visualization parameters
 	canvasWidth    int
@@ -46,6 +153,34 @@ type Config struct {
 	frameFrequency int
 	gifDelay       int
 	gifFilename    string
+	deadRendering  deadRenderMode
+	deadFadeFrames int
+	deathCounter   bool
+
+	// Vaccination coverage map, see coveragemap.go
+	coverageMapEvery int // days between maps, 0 = off
+	coverageGrid     int // cells per side
+	snapshotEvery    int // days between snapshots saved for the render subcommand, 0 = off
+	palette          string
+	points           pointStyle // dot opacity, density-aware size and jitter, see points.go
+
+	// Rendering schedule, see render.go
+	burstFrames int  // days of daily frames after a policy tightening, 0 = off
+	finalFrame  bool // always capture the last day
+
+	ageSummary bool // write the age pyramid/outcome figure at the end
+
+	// Epidemic curve line chart, as a PNG and a growing GIF
+	epidemicCurve bool
+
+	// Moves between health states, as JSON and a Sankey diagram
+	flowDiagram bool
+
+	// Self-contained run report: none, markdown or html
+	report reportFormat
+
+	// Keys and values as read from the config file, for the run report
+	settings [][2]string
 }
 
 // ValidationError represents a configuration validation error
@@ -81,11 +216,11 @@ func (v *ConfigValidator) HasErrors() bool {
 }
 
 func (v *ConfigValidator) PrintErrors() {
-	fmt.Println("\n=== Configuration Validation Errors ===")
+	fmt.Fprintln(msgOut, "\n=== Configuration Validation Errors ===")
 	for i, err := range v.errors {
-		fmt.Printf("%d. %s\n", i+1, err.Error())
+		fmt.Fprintf(msgOut, "%d. %s\n", i+1, err.Error())
 	}
-	fmt.Println("========================================\n")
+	fmt.Fprint(msgOut, "========================================\n\n")
 }
 
 // parseAndValidateFloat parses a float and validates it's within the given range
@@ -169,21 +304,99 @@ func (v *ConfigValidator) parseAndValidateFilename(key, value string) (string, b
 	return value, true
 }
 
+// parseAndValidateDate parses a calendar date in YYYY-MM-DD format
+func (v *ConfigValidator) parseAndValidateDate(key, value string) (time.Time, bool) {
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		v.AddError(key, value, fmt.Sprintf("must be a date in YYYY-MM-DD format, got '%s'", value))
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// parseAndValidateBool parses a boolean (true/false, yes/no, 1/0)
+func (v *ConfigValidator) parseAndValidateBool(key, value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "1":
+		return true, true
+	case "false", "no", "0":
+		return false, true
+	}
+	v.AddError(key, value, fmt.Sprintf("must be true or false, got '%s'", value))
+	return false, false
+}
+
+// validTagName checks that a tag name only uses letters, digits and underscores
+func validTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// tagSpec returns the config's spec for a tag, creating it with neutral multipliers if needed
+func (c *Config) tagSpec(name string) *TagSpec {
+	for _, spec := range c.tags {
+		if spec.name == name {
+			return spec
+		}
+	}
+	spec := &TagSpec{name: name, exposureMult: 1.0, vaccinationPriority: 1.0}
+	c.tags = append(c.tags, spec)
+	return spec
+}
+
+// profileSpec returns the config's behavior profile, creating it with the
+// default behavior if needed
+func (c *Config) profileSpec(name string) *ProfileSpec {
+	for _, spec := range c.profiles {
+		if spec.name == name {
+			return spec
+		}
+	}
+	spec := &ProfileSpec{name: name, hygiene: -1, compliance: -1, mobility: 1.0, vaccineAcceptance: 0.55}
+	c.profiles = append(c.profiles, spec)
+	return spec
+}
+
 func getDefaultConfig() *Config {
 	return &Config{
 		// Disease defaults
 		diseaseName:          "DemoDisease",
 		transmissionRate:     0.8,
 		transmissionDistance: 2.0,
+		transmissionKernel:   KernelExponential,
 		recoveryRate:         0.05,
 		mortalityRate:        0.01,
 		latentPeriod:         3,
 		infectiousPeriod:     10,
 		immunityDuration:     90,
+		severeFraction:       0.15,
+		criticalFraction:     0.05,
+		maxInfectionDays:     365,
+		boosting:             ImmunityBoosting{perExposure: 0.02, max: 0.5, halfLife: 60},
+		hospitalQueue:        HospitalQueueConfig{wardWaitingMult: 2.0, icuWaitingMult: 3.0, criteria: AdmissionCriteria{minSeverity: Severe, icuAllocation: ICUFirstCome}},
+		sideEffects:          VaccineSideEffects{rate: 0.3, maxDays: 2, mobility: 0.2},
+		hygieneSupply:        HygieneSupply{stockDays: 30, restockShare: 0.3},
+		immunityWaning:       WaningHealthy,
+		quarantine:           QuarantineConfig{detectionDelay: 2, complianceRate: 0.8, days: 14, transmission: 0.1, sensitivity: 0.8},
+		tracing:              TracingConfig{days: 7, delay: 2},
+		households:           HouseholdConfig{transmission: 2},
+		backgroundMortality:  BackgroundMortality{a: 0.00005, b: 0.085},
+		shocks:               ShockConfig{shares: map[int]float64{}, days: 3, mobility: 5, compliance: 0.2},
+		lockdown:             LockdownConfig{mobility: 0.2, minDays: 14},
+		information:          InformationConfig{source: InformationTrue, ascertainment: 1},
 
 		// Population defaults
 		popSize:         1000,
 		initialInfected: 10,
+		hygieneInit:     behaviorDistribution{kind: BehaviorUniform, a: 2, b: 2, value: 0.5},
+		complianceInit:  behaviorDistribution{kind: BehaviorUniform, a: 2, b: 2, value: 0.5},
 
 		// Environment defaults
 		areaSize:                100.0,
@@ -192,10 +405,58 @@ func getDefaultConfig() *Config {
 		mobilityRate:            1.0,
 		vaccinationRate:         0.20,
 		medicalCareLevel:        0.7,
-		medicalCapacity:         0, // will be calculated
+		medicalCapacity:         0,  // will be calculated
+		icuCapacity:             -1, // will be calculated
 
 		// Simulation defaults
-		numDays: 200,
+		numDays:         200,
+		errorPolicy:     PolicyAbort,
+		spatialIndex:    IndexScan,
+		fastForward:     true,
+		incidenceScale:  1,
+		numReplicates:   1,
+		rngAlgorithm:    RNGMathRand,
+		waveProminence:  0.2,
+		sanityCheckDays: 10,
+		renderSample:    200000,
+		sweepHeatmap:    SweepHeatmapNone,
+
+		// Contact memory defaults (off)
+		contactMemoryDays: 0,
+		contactsPerDay:    20,
+
+		// Vaccination campaign defaults (unlimited supply, 2%/day, whole run)
+		vaccineSupply: VaccineSupply{deliveries: map[int]int{}},
+		rollout:       VaccineRollout{order: RolloutRandom, grid: 4},
+		stockpile:     StockpileConfig{target: StockpileRandom, elderlyAge: 65, mortality: 0.5, recovery: 1.5},
+
+		annotations: map[int]string{},
+
+		// Health economics defaults
+		lifeExpectancy: map[int]float64{},
+		ifr:            map[int]float64{},
+		qaly: QALYConfig{
+			enabled:            false,
+			illnessDisutility:  0.2,
+			sequelaeFraction:   0.1,
+			sequelaeDisutility: 0.05,
+			sequelaeYears:      1.0,
+		},
+
+		// Movement defaults
+		stepDistributions: map[moveType]stepDistribution{},
+		levyExponent:      1.5,
+
+		// Policy defaults
+		distancingTrigger: TriggerPrevalence,
+
+		// Transit defaults (disabled)
+		transit: TransitConfig{
+			enabled:        false,
+			trainCapacity:  100,
+			flightCapacity: 150,
+			contactFactor:  0.05,
+		},
 
 		// Visualization defaults
 		canvasWidth:    800,
@@ -203,21 +464,39 @@ func getDefaultConfig() *Config {
 		frameFrequency: 2,
 		gifDelay:       5,
 		gifFilename:    "env_sim.gif",
+		deadRendering:  DeadGrey,
+		deadFadeFrames: 5,
+		coverageGrid:   10,
+		palette:        "default",
+		points:         pointStyle{alpha: 1},
 	}
 }
 
+// loadConfigFromFile reads and validates a config file: YAML or JSON by its
+// extension (see configfile.go), key = value lines otherwise.
 func loadConfigFromFile(filename string) (*Config, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml", ".json":
+		return loadStructuredConfig(file)
+	}
+	return loadConfig(file)
+}
 
+// loadConfig reads and validates a key = value config from r (a file or an
+// embedded preset).
+func loadConfig(r io.Reader) (*Config, error) {
 	config := getDefaultConfig()
 	validator := NewConfigValidator()
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	lineNum := 0
+	block, blockName := "", "" // current [block.NAME], if any
+	skipBlock := false         // inside an invalid block header
 	for scanner.Scan() {
 		lineNum++
 		line := strings.TrimSpace(scanner.Text())
@@ -227,186 +506,259 @@ func loadConfigFromFile(filename string) (*Config, error) {
 			continue
 		}
 
+		// A [disease.NAME] or [region.NAME] header starts a block; its keys run to the next header
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			header := strings.TrimSpace(line[1 : len(line)-1])
+			block, blockName, _ = strings.Cut(header, ".")
+			skipBlock = !config.startBlock(validator, line, block, blockName)
+			if skipBlock {
+				block, blockName = "", ""
+			}
+			continue
+		}
+		if skipBlock {
+			continue
+		}
+
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			fmt.Printf("Warning: skipping invalid line %d: %s\n", lineNum, line)
+			fmt.Fprintf(msgOut, "Warning: skipping invalid line %d: %s\n", lineNum, line)
 			continue
 		}
 
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
 
-		// Parse and validate based on key
-		switch key {
-		// Disease parameters
-		case "diseaseName":
-			if val, ok := validator.parseAndValidateString(key, value, 100); ok {
-				config.diseaseName = val
-			}
-
-		case "transmissionRate":
-			// Probability: 0.0 to 1.0
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 1.0, true); ok {
-				config.transmissionRate = val
-			}
-
-		case "transmissionDistance":
-			// Distance: must be positive, reasonable max of 100 units
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 100.0, false); ok {
-				config.transmissionDistance = val
-			}
-
-		case "recoveryRate":
-			// Probability per day: 0.0 to 1.0
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 1.0, true); ok {
-				config.recoveryRate = val
+		// Parse and validate against the config schema
+		if !config.setParam(validator, block, blockName, key, value) {
+			if block != "" {
+				fmt.Fprintf(msgOut, "Warning: unknown parameter '%s' in [%s.%s] on line %d\n", key, block, blockName, lineNum)
+			} else {
+				fmt.Fprintf(msgOut, "Warning: unknown parameter '%s' on line %d\n", key, lineNum)
 			}
+		}
+	}
 
-		case "mortalityRate":
-			// Probability: 0.0 to 1.0
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 1.0, true); ok {
-				config.mortalityRate = val
-			}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return validateConfig(config, validator)
+}
 
-		case "latentPeriod":
-			// Days: 0 to 365
-			if val, ok := validator.parseAndValidateNonNegativeInt(key, value, 365); ok {
-				config.latentPeriod = val
-			}
+// startBlock begins a [block.name] section, which must be a disease or a
+// region with a valid name; otherwise it records an error for header and
+// returns false. A block without keys uses the defaults.
+func (c *Config) startBlock(v *ConfigValidator, header, block, name string) bool {
+	if block != "disease" && block != "region" || !validTagName(name) {
+		v.AddError("block header", header, "must be [disease.NAME] or [region.NAME], NAME made of letters, digits and '_'")
+		return false
+	}
+	if block == "disease" {
+		c.pathogen(name)
+	} else {
+		c.region(name)
+	}
+	return true
+}
 
-		case "infectiousPeriod":
-			// Days: 1 to 365
-			if val, ok := validator.parseAndValidatePositiveInt(key, value, 365); ok {
-				config.infectiousPeriod = val
+// setParam parses one parameter against the config schema, at the top level
+// or inside the [block.blockName] section, and stores it. It returns false if
+// the key is unknown.
+func (c *Config) setParam(v *ConfigValidator, block, blockName, key, value string) bool {
+	if block != "" {
+		spec, sub := lookupBlockParam(block, key)
+		if spec == nil {
+			return false
+		}
+		suffix := blockName
+		if sub != "" {
+			suffix += "." + sub
+		}
+		key = fmt.Sprintf("[%s.%s] %s", block, blockName, key)
+		spec.apply(c, v, key, suffix, value)
+		c.settings = append(c.settings, [2]string{key, value})
+		return true
+	}
+	spec, suffix := lookupParam(key)
+	if spec == nil {
+		return false
+	}
+	if values, ok := sweepRange(v, spec, key, value); ok {
+		// A range declares a sweep; each value is checked, the first one is kept
+		if values != nil {
+			c.addSweep(key, values)
+			for _, value := range values[1:] {
+				spec.apply(c, v, key, suffix, value)
 			}
+			spec.apply(c, v, key, suffix, values[0])
+		}
+		c.settings = append(c.settings, [2]string{key, value})
+		return true
+	}
+	spec.apply(c, v, key, suffix, value)
+	c.settings = append(c.settings, [2]string{key, value})
+	return true
+}
 
-		case "immunityDuration":
-			// Days: 0 (no immunity) to 3650 (10 years)
-			if val, ok := validator.parseAndValidateNonNegativeInt(key, value, 3650); ok {
-				config.immunityDuration = val
-			}
+// validateConfig applies the command-line overrides to a config that has
+// been read, whatever the file format, then runs the checks that involve
+// several parameters and fills in the derived defaults.
+func validateConfig(config *Config, validator *ConfigValidator) (*Config, error) {
+	config.applyOverrides(validator)
 
-		// Population parameters
-		case "popSize":
-			// Population: 1 to 1,000,000
-			if val, ok := validator.parseAndValidatePositiveInt(key, value, 1000000); ok {
-				config.popSize = val
-			}
+	// Cross-field validations
+	if config.popSize > maxPopulation && !config.largePopulation {
+		validator.AddError("popSize", fmt.Sprintf("%d", config.popSize),
+			fmt.Sprintf("populations above %d require largePopulation = true", maxPopulation))
+	}
 
-		case "initialInfected":
-			// Initial infected: 0 to popSize (will validate later)
-			if val, ok := validator.parseAndValidateNonNegativeInt(key, value, 1000000); ok {
-				config.initialInfected = val
-			}
+	if config.initialInfected > config.popSize {
+		validator.AddError("initialInfected", fmt.Sprintf("%d", config.initialInfected),
+			fmt.Sprintf("cannot exceed popSize (%d)", config.popSize))
+	}
 
-		// Environment parameters
-		case "areaSize":
-			// Area size: must be positive
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 10000.0, false); ok {
-				config.areaSize = val
-			}
+	if config.medicalCapacity > config.popSize {
+		validator.AddError("medicalCapacity", fmt.Sprintf("%d", config.medicalCapacity),
+			fmt.Sprintf("cannot exceed popSize (%d)", config.popSize))
+	}
 
-		case "socialDistanceThreshold":
-			// Distance: 0 to areaSize (reasonable max)
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 100.0, true); ok {
-				config.socialDistanceThreshold = val
-			}
+	if config.severeFraction+config.criticalFraction > 1.0 {
+		validator.AddError("criticalFraction", fmt.Sprintf("%.4f", config.criticalFraction),
+			fmt.Sprintf("severeFraction + criticalFraction cannot exceed 1.0 (severeFraction = %.4f)", config.severeFraction))
+	}
 
-		case "hygieneLevel":
-			// Level: 0.0 to 1.0
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 1.0, true); ok {
-				config.hygieneLevel = val
-			}
+	if config.medicalCapacity > 0 && config.icuCapacity > config.medicalCapacity {
+		validator.AddError("icuCapacity", fmt.Sprintf("%d", config.icuCapacity),
+			fmt.Sprintf("cannot exceed medicalCapacity (%d)", config.medicalCapacity))
+	}
 
-		case "mobilityRate":
-			// Rate: 0.0 to 10.0 (allows higher mobility)
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 10.0, true); ok {
-				config.mobilityRate = val
-			}
+	if config.ageCurvesFile != "" {
+		curves, err := loadAgeCurves(config.ageCurvesFile)
+		switch {
+		case err != nil:
+			validator.AddError("ageCurvesFile", config.ageCurvesFile, err.Error())
+		case curves.has(AgeIFR) && config.ifrByAge:
+			validator.AddError("ageCurvesFile", config.ageCurvesFile, "has an ifr column, which cannot be combined with ifrByAge")
+		default:
+			config.ageCurves = curves
+		}
+	}
 
-		case "vaccinationRate":
-			// Rate: 0.0 to 1.0
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 1.0, true); ok {
-				config.vaccinationRate = val
-			}
+	for _, spec := range config.tags {
+		if spec.fraction == 0 {
+			validator.AddError("tag."+spec.name, "", "tag multipliers set but no tag fraction given (add tag."+spec.name+" = <fraction>)")
+		}
+	}
 
-		case "medicalCareLevel":
-			// Level: 0.0 to 1.0
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.0, 1.0, true); ok {
-				config.medicalCareLevel = val
-			}
+	profileTotal := 0.0
+	for _, spec := range config.profiles {
+		if spec.fraction == 0 {
+			validator.AddError("profile."+spec.name, "", "profile settings given but no fraction (add profile."+spec.name+" = <fraction>)")
+		}
+		profileTotal += spec.fraction
+	}
+	if profileTotal > 1+1e-9 {
+		validator.AddError("profile.NAME", fmt.Sprintf("%.4f", profileTotal), "profile fractions cannot add up to more than 1.0")
+	}
 
-		case "medicalCapacity":
-			// Capacity: 0 (auto-calculate) to popSize
-			if val, ok := validator.parseAndValidateNonNegativeInt(key, value, 1000000); ok {
-				config.medicalCapacity = val
-			}
+	for _, p := range config.pathogens {
+		key := "[disease." + p.disease.name + "] "
+		if p.initialInfected > config.popSize {
+			validator.AddError(key+"initialInfected", fmt.Sprintf("%d", p.initialInfected),
+				fmt.Sprintf("cannot exceed popSize (%d)", config.popSize))
+		}
+		if p.startDay > config.numDays {
+			validator.AddError(key+"startDay", fmt.Sprintf("%d", p.startDay),
+				fmt.Sprintf("cannot exceed numDays (%d)", config.numDays))
+		}
+		if p.disease.transmissionDistance > config.areaSize {
+			validator.AddError(key+"transmissionDistance", fmt.Sprintf("%.2f", p.disease.transmissionDistance),
+				fmt.Sprintf("cannot exceed areaSize (%.2f)", config.areaSize))
+		}
+	}
 
-		// Simulation parameters
-		case "numDays":
-			// Days: 1 to 10000
-			if val, ok := validator.parseAndValidatePositiveInt(key, value, 10000); ok {
-				config.numDays = val
-			}
+	weights := 0.0
+	for _, r := range config.regions {
+		weights += r.weight
+		if r.initialInfected > config.popSize {
+			validator.AddError("[region."+r.name+"] initialInfected", fmt.Sprintf("%d", r.initialInfected),
+				fmt.Sprintf("cannot exceed popSize (%d)", config.popSize))
+		}
+	}
+	if len(config.regions) > 0 && weights == 0 {
+		validator.AddError("[region.NAME] population", "0", "at least one region must have a population above 0")
+	}
+	resolveTravel(config, validator)
 
-		// Visualization parameters
-		case "canvasWidth":
-			// Width: 100 to 4096 pixels
-			if val, ok := validator.parseAndValidateInt(key, value, 100, 4096); ok {
-				config.canvasWidth = val
-			}
+	if config.socialDistanceThreshold > config.areaSize {
+		validator.AddError("socialDistanceThreshold", fmt.Sprintf("%.2f", config.socialDistanceThreshold),
+			fmt.Sprintf("cannot exceed areaSize (%.2f)", config.areaSize))
+	}
 
-		case "pointRadius":
-			// Radius: 0.5 to 50 pixels
-			if val, ok := validator.parseAndValidateFloat(key, value, 0.5, 50.0, true); ok {
-				config.pointRadius = val
-			}
+	if config.transmissionDistance > config.areaSize {
+		validator.AddError("transmissionDistance", fmt.Sprintf("%.2f", config.transmissionDistance),
+			fmt.Sprintf("cannot exceed areaSize (%.2f)", config.areaSize))
+	}
 
-		case "frameFrequency":
-			// Frequency: 1 to numDays
-			if val, ok := validator.parseAndValidatePositiveInt(key, value, 10000); ok {
-				config.frameFrequency = val
-			}
+	for day := range config.shocks.shares {
+		if day < 1 || day > config.numDays {
+			validator.AddError(fmt.Sprintf("shock.%d", day), fmt.Sprintf("%g", config.shocks.shares[day]),
+				fmt.Sprintf("day must be between 1 and numDays (%d)", config.numDays))
+		}
+	}
 
-		case "gifDelay":
-			// Delay: 1 to 1000 (centiseconds)
-			if val, ok := validator.parseAndValidatePositiveInt(key, value, 1000); ok {
-				config.gifDelay = val
-			}
+	for day := range config.annotations {
+		if day > config.numDays {
+			validator.AddError(fmt.Sprintf("annotation.%d", day), config.annotations[day],
+				fmt.Sprintf("day cannot exceed numDays (%d)", config.numDays))
+		}
+	}
 
-		case "gifFilename":
-			if val, ok := validator.parseAndValidateFilename(key, value); ok {
-				config.gifFilename = val
-			}
+	if vs := config.vaccineSupply; vs.endDay > 0 && vs.endDay < vs.startDay {
+		validator.AddError("vaccinationEndDay", fmt.Sprintf("%d", vs.endDay),
+			fmt.Sprintf("cannot be before vaccinationStartDay (%d)", vs.startDay))
+	}
 
-		default:
-			fmt.Printf("Warning: unknown parameter '%s' on line %d\n", key, lineNum)
+	if r := &config.rollout; r.cellsText != "" {
+		cells, err := parseRolloutCells(r.cellsText, r.grid)
+		if err != nil {
+			validator.AddError("rolloutCells", r.cellsText, err.Error())
 		}
+		r.cells = cells
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if config.rollout.order == RolloutCustom && config.rollout.cellsText == "" {
+		validator.AddError("vaccineRollout", string(RolloutCustom), "requires rolloutCells")
 	}
 
-	// Cross-field validations
-	if config.initialInfected > config.popSize {
-		validator.AddError("initialInfected", fmt.Sprintf("%d", config.initialInfected),
-			fmt.Sprintf("cannot exceed popSize (%d)", config.popSize))
+	crit := config.hospitalQueue.criteria
+	if !config.hospitalQueue.enabled && (crit.restricts() || crit.icuAllocation != ICUFirstCome) {
+		validator.AddError("hospitalQueue", "false", "admission criteria and icuAllocation require hospitalQueue = true")
+	}
+	if crit.comorbidityTag != "" && !slices.ContainsFunc(config.tags, func(t *TagSpec) bool { return t.name == crit.comorbidityTag }) {
+		validator.AddError("comorbidityTag", crit.comorbidityTag, "is not a tag (add tag."+crit.comorbidityTag+" = <fraction>)")
+	}
+	if crit.icuExcludeComorbid && crit.comorbidityTag == "" {
+		validator.AddError("icuExcludeComorbid", "true", "requires comorbidityTag")
 	}
 
-	if config.medicalCapacity > config.popSize {
-		validator.AddError("medicalCapacity", fmt.Sprintf("%d", config.medicalCapacity),
-			fmt.Sprintf("cannot exceed popSize (%d)", config.popSize))
+	if l := config.lockdown; l.enabled() && l.release >= l.threshold {
+		validator.AddError("lockdownRelease", fmt.Sprintf("%g", l.release),
+			fmt.Sprintf("must be below lockdownThreshold (%g)", l.threshold))
 	}
 
-	if config.socialDistanceThreshold > config.areaSize {
-		validator.AddError("socialDistanceThreshold", fmt.Sprintf("%.2f", config.socialDistanceThreshold),
-			fmt.Sprintf("cannot exceed areaSize (%.2f)", config.areaSize))
+	if info := config.information; info.source != InformationReported && info.ascertainment != 1 {
+		validator.AddError("ascertainmentRate", fmt.Sprintf("%g", info.ascertainment), "requires informationSource = reported")
+	}
+	if info := config.information; !info.lagged() && info.delay > 0 {
+		validator.AddError("reportingDelay", fmt.Sprintf("%d", info.delay), "requires informationSource = reported or detected")
+	}
+	if config.information.source == InformationDetected && !config.quarantine.enabled() {
+		validator.AddError("informationSource", string(InformationDetected), "requires detectionRate > 0 or testingRate > 0")
 	}
 
-	if config.transmissionDistance > config.areaSize {
-		validator.AddError("transmissionDistance", fmt.Sprintf("%.2f", config.transmissionDistance),
-			fmt.Sprintf("cannot exceed areaSize (%.2f)", config.areaSize))
+	if config.tracing.enabled() && !config.quarantine.enabled() {
+		validator.AddError("tracingFraction", fmt.Sprintf("%g", config.tracing.fraction),
+			"requires detectionRate > 0 or testingRate > 0 (contacts are traced from detected infections)")
 	}
 
 	if config.frameFrequency > config.numDays {
@@ -414,6 +766,15 @@ func loadConfigFromFile(filename string) (*Config, error) {
 			fmt.Sprintf("cannot exceed numDays (%d)", config.numDays))
 	}
 
+	if n := config.sweepRuns(); n > maxSweepRuns {
+		validator.AddError("sweep", fmt.Sprintf("%d runs", n), fmt.Sprintf("a sweep cannot have more than %d combinations", maxSweepRuns))
+	}
+
+	// The stats file is written next to the GIFs, so it must be a bare name
+	if strings.ContainsAny(config.statsFilename, "/\\:") {
+		validator.AddError("statsFilename", config.statsFilename, "must be a file name, not a path")
+	}
+
 	// Check if there were validation errors
 	if validator.HasErrors() {
 		validator.PrintErrors()
@@ -424,79 +785,21 @@ func loadConfigFromFile(filename string) (*Config, error) {
 	if config.medicalCapacity == 0 {
 		config.medicalCapacity = int(0.1 * float64(config.popSize))
 	}
+	if config.icuCapacity < 0 {
+		config.icuCapacity = int(0.2 * float64(config.medicalCapacity))
+	}
 
 	return config, nil
 }
 
-// printConfigValidationHelp prints help information about valid parameter ranges
+// printConfigValidationHelp prints help information about valid parameter ranges,
+// generated from the config schema
 func printConfigValidationHelp() {
-	fmt.Println(`
-=== Configuration Parameter Validation Rules ===
-
-DISEASE PARAMETERS:
-  diseaseName          string    Non-empty, max 100 characters
-  transmissionRate     float64   0.0 - 1.0 (probability)
-  transmissionDistance float64   > 0.0, <= 100.0 (units)
-  recoveryRate         float64   0.0 - 1.0 (probability per day)
-  mortalityRate        float64   0.0 - 1.0 (probability)
-  latentPeriod         int       0 - 365 (days)
-  infectiousPeriod     int       1 - 365 (days)
-  immunityDuration     int       0 - 3650 (days, 0 = no immunity)
-
-POPULATION PARAMETERS:
-  popSize              int       1 - 1,000,000
-  initialInfected      int       0 - popSize
-
-ENVIRONMENT PARAMETERS:
-  areaSize             float64   > 0.0, <= 10,000.0
-  socialDistanceThreshold float64 0.0 - 100.0, <= areaSize
-  hygieneLevel         float64   0.0 - 1.0
-  mobilityRate         float64   0.0 - 10.0
-  vaccinationRate      float64   0.0 - 1.0
-  medicalCareLevel     float64   0.0 - 1.0
-  medicalCapacity      int       0 - popSize (0 = auto 10%)
-
-SIMULATION PARAMETERS:
-  numDays              int       1 - 10,000
-
-VISUALIZATION PARAMETERS:
-  canvasWidth          int       100 - 4096 (pixels)
-  pointRadius          float64   0.5 - 50.0 (pixels)
-  frameFrequency       int       1 - numDays
-  gifDelay             int       1 - 1000 (centiseconds)
-  gifFilename          string    Must end with .gif, no special chars
-
-================================================
-`)
+	writeConfigHelp(os.Stdout)
 }
 
-func main() {
-	configFile := flag.String("config", "", "Path to configuration file")
-	showHelp := flag.Bool("help-config", false, "Show configuration parameter validation rules")
-	flag.Parse()
-
-	if *showHelp {
-		printConfigValidationHelp()
-		return
-	}
-
-	var config *Config
-
-	if *configFile != "" {
-		var err error
-		config, err = loadConfigFromFile(*configFile)
-		if err != nil {
-			fmt.Printf("Error loading config file: %v\n", err)
-			fmt.Println("\nRun with -help-config to see valid parameter ranges.")
-			return
-		}
-		fmt.Printf("Loaded configuration from: %s\n", *configFile)
-	} else {
-		config = getDefaultConfig()
-		// If medicalCapacity is not specified, set it to 10% of population size.
-		config.medicalCapacity = int(0.1 * float64(config.popSize))
-	}
-
+// diseaseFromConfig builds the simulated disease from the config.
+func diseaseFromConfig(config *Config) *Disease {
 	disease := initializeDisease(
 		config.diseaseName,
 		config.transmissionRate,
@@ -506,10 +809,22 @@ func main() {
 		config.latentPeriod,
 		config.infectiousPeriod,
 		config.immunityDuration,
+		config.severeFraction,
+		config.criticalFraction,
 	)
+	disease.maxInfectionDays = config.maxInfectionDays
+	disease.dispersion = config.dispersion
+	return disease
+}
 
-	globalRng := rand.New(rand.NewSource(time.Now().UnixNano()))
-
+// environmentFromConfig initializes the population and copies the optional
+// model settings from config onto the environment. The road network, the
+// disease and the initial infections are set up separately.
+func environmentFromConfig(config *Config, rng *rand.Rand) *Environment {
+	chunkSize := 0
+	if config.largePopulation {
+		chunkSize = populationChunkSize
+	}
 	env := initializeEnvironment(
 		config.popSize,
 		config.areaSize,
@@ -519,73 +834,668 @@ func main() {
 		config.vaccinationRate,
 		config.medicalCareLevel,
 		config.medicalCapacity,
+		config.icuCapacity,
+		chunkSize,
+		config.hygieneInit,
+		config.complianceInit,
+		rng,
 	)
+	env.largePopulation = config.largePopulation
+	env.renderSample = config.renderSample
+
+	assignTags(env, config.tags, rng)
+	assignProfiles(env, config.profiles, rng)
+	env.regions = config.regions
+	assignRegions(env)
+	env.households = config.households
+	assignHouseholds(env, rng)
+	env.stratifyByTag = config.stratifyByTag
+	env.startDate = config.startDate
+	env.statsPer100k = config.statsPer100k
+	env.reportIncidence = config.reportIncidence
+	env.stepDistributions = config.stepDistributions
+	env.levyExponent = config.levyExponent
+	env.kernel = newKernel(config.transmissionKernel)
+	env.transit = config.transit
+	env.distancingTrigger = config.distancingTrigger
+	memoryDays := config.contactMemoryDays
+	if config.tracing.enabled() {
+		memoryDays = max(memoryDays, config.tracing.days) // tracing reads the contact logs
+	}
+	enableContactMemory(env, memoryDays, config.contactsPerDay)
+	env.lifeTable = lifeTableFromConfig(config.lifeExpectancy)
+	env.ifrTable = ifrTableFromConfig(config.ifrByAge, config.ifr)
+	env.ageCurves = config.ageCurves
+	env.qaly = config.qaly
+	env.spatialIndex = config.spatialIndex
+	env.numWorkers = config.numWorkers
+	env.exposureRiskReport = config.exposureRisk
+	env.npiReport = config.npiReport
+	env.chainStats = config.chainStats
+	env.reportReff = config.reportReff
+	if config.chainStats || config.reportReff || config.dispersion > 0 {
+		env.chains = &chainLog{}
+	}
+	if config.flowDiagram {
+		env.flows = &flowTally{}
+	}
+	env.fastForward.enabled = config.fastForward
+	env.batchTransmission = config.batchTransmission
+	env.compressOutputs = config.compressOutputs
+	if config.largePopulation && env.spatialIndex == IndexScan {
+		// a full scan per neighbor query is quadratic in the population
+		env.spatialIndex = IndexKDTree
+	}
+	env.vaccineSupply = config.vaccineSupply
+	env.vaccineStock.restockedThrough = -1
+	env.rollout = config.rollout
+	env.stockpile = newStockpile(config.stockpile)
+	if env.rollout.geographic() {
+		env.rollout.rank = rolloutRanks(env.rollout)
+	}
+	env.annotations = config.annotations
+	env.boosting = config.boosting
+	env.hospitalQueue = config.hospitalQueue
+	env.sideEffects = config.sideEffects
+	env.hygieneSupply = config.hygieneSupply
+	initHygieneSupply(&env.hygieneSupply, len(env.population))
+	env.backgroundMortality = config.backgroundMortality
+	env.shocks = config.shocks
+	env.lockdown = config.lockdown
+	env.information = config.information
+	setupPolicyCells(env, config.policyCells)
+	env.seir = config.seir
+	env.immunityWaning = config.immunityWaning
+	env.quarantine = config.quarantine
+	env.tracing = config.tracing
+	env.pathogens = config.pathogens
+	initPathogens(env)
+	scheduleShocks(env)
+	env.deadRender = deadRenderOptions{
+		mode:           config.deadRendering,
+		fadeFrames:     config.deadFadeFrames,
+		frameFrequency: config.frameFrequency,
+		counter:        config.deathCounter,
+	}
+	env.palette = config.palette
+	env.points = config.points
+	return env
+}
+
+// loadRunConfig returns the config of a run: the built-in preset if one is
+// named, else the config file if one is given, else the defaults, with the
+// command-line overrides (see overrides.go).
+func loadRunConfig(configFile, preset string) (*Config, error) {
+	if configFile != "" && preset != "" {
+		return nil, fmt.Errorf("-config and -preset are mutually exclusive")
+	}
+	var config *Config
+	var err error
+	switch {
+	case preset != "":
+		if config, err = loadPreset(preset); err != nil {
+			return nil, fmt.Errorf("loading preset: %v", err)
+		}
+		fmt.Fprintf(msgOut, "Loaded built-in preset: %s\n", preset)
+	case configFile != "":
+		if config, err = loadConfigFromFile(configFile); err != nil {
+			return nil, fmt.Errorf("loading config file: %v", err)
+		}
+		fmt.Fprintf(msgOut, "Loaded configuration from: %s\n", configFile)
+	default:
+		// medicalCapacity and icuCapacity are derived from popSize as usual
+		if config, err = validateConfig(getDefaultConfig(), NewConfigValidator()); err != nil {
+			return nil, fmt.Errorf("command-line overrides: %v", err)
+		}
+	}
+	if len(configOverrides) > 0 {
+		fmt.Fprintf(msgOut, "Command-line overrides: %s\n", overridesText())
+	}
+	return config, nil
+}
+
+func main() {
+	// Subcommands come before any flags
+	if len(os.Args) > 1 && os.Args[1] == "verify-determinism" {
+		runVerifyDeterminism(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		runRender(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench-transmission" {
+		runBenchTransmission(os.Args[2:])
+		return
+	}
+
+	configFile := flag.String("config", "", "Path to configuration file")
+	showHelp := flag.Bool("help-config", false, "Show configuration parameter validation rules")
+	showSchema := flag.Bool("config-schema", false, "Print the configuration schema (names, types, units, ranges) as JSON")
+	preset := flag.String("preset", "", "Run one of the example configs built into the binary (see -exportDefaults)")
+	exportDir := flag.String("exportDefaults", "", "Write the built-in example configs to this directory and exit")
+	var tracked agentIDs
+	flag.Var(&tracked, "trackAgent", "Log the full daily state of this individual (ID, repeatable or comma-separated) to output_gif/tracked_agents.csv")
+	seed := flag.Int64("seed", 0, "Random seed, overriding the config's randomSeed (0 = use the config)")
+	stream := flag.Int("stream", 0, "Random stream of the seed to use; replicate k of a run is stream k-1 (see rngAlgorithm)")
+	scenarios := flag.String("scenarios", "", "Comma-separated config files to run and compare as scenarios")
+	parallel := flag.Bool("parallel", false, "With -scenarios or a sweep, run the scenarios or combinations at the same time")
+	machine := flag.Bool("machine", false, "Machine-readable mode: stdout carries only the stats CSV, all other messages go to stderr")
+	resume := flag.String("resume", "", "Continue the run saved in this state file (see checkpointInterval); needs the same -config")
+	interactive := flag.Bool("repl", false, "Stop between days to inspect and adjust the running simulation from the terminal (type help)")
+	serve := flag.String("serve", "", "Serve a live dashboard on this address (e.g. :8080) with play/pause/step controls and parameter sliders")
+	registerOverrideFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *machine {
+		msgOut = os.Stderr
+	}
+
+	if *showHelp {
+		printConfigValidationHelp()
+		return
+	}
+
+	if *showSchema {
+		if err := writeConfigSchemaJSON(os.Stdout); err != nil {
+			fmt.Fprintln(msgOut, "failed to write config schema:", err)
+		}
+		return
+	}
+
+	if *exportDir != "" {
+		if err := exportDefaults(*exportDir); err != nil {
+			fmt.Fprintln(msgOut, "failed to export default configs:", err)
+		}
+		return
+	}
+
+	if *serve != "" && *interactive {
+		fmt.Fprintln(msgOut, "Error: -serve and -repl cannot be combined")
+		return
+	}
+
+	if *scenarios != "" {
+		runScenarios(*scenarios, *seed, *parallel)
+		return
+	}
+
+	config, err := loadRunConfig(*configFile, *preset)
+	if err != nil {
+		fmt.Fprintln(msgOut, "Error:", err)
+		if *configFile != "" {
+			fmt.Fprintln(msgOut, "\nRun with -help-config to see valid parameter ranges.")
+		}
+		return
+	}
+
+	// Parameters given as ranges run a sweep instead of a single run
+	if len(config.sweep) > 0 {
+		runSweep(config, *configFile, *preset, *seed, *parallel)
+		return
+	}
+
+	// Every random draw of the run comes from one generator, so a fixed seed
+	// reproduces the run exactly. The stream is also kept so the no-disease
+	// baseline can replay the same draws.
+	runSeed := config.randomSeed
+	if *seed != 0 {
+		runSeed = *seed
+	}
+	if runSeed == 0 {
+		runSeed = time.Now().UnixNano()
+	}
+	if *stream < 0 {
+		fmt.Fprintln(msgOut, "Error: -stream must be 0 or more")
+		return
+	}
+	runID := rngStream{alg: config.rngAlgorithm, seed: runSeed, stream: *stream}
+	if *resume == "" {
+		fmt.Fprintf(msgOut, "Random seed: %d", runSeed)
+		if runID.alg != RNGMathRand || runID.stream != 0 {
+			fmt.Fprintf(msgOut, " (%s, stream %d)", runID.alg, runID.stream)
+		}
+		fmt.Fprintln(msgOut)
+	}
+	// The source counts its draws so checkpoints can save its position
+	src := newCountingSource(runID)
+	globalRng := rand.New(src)
+
+	// A new run is set up like every other run, warning early about
+	// parameters that imply no epidemic or instant saturation. A resumed run
+	// replaces the new population with the saved one and carries on from the
+	// saved day.
+	var env *Environment
+	var resumedRows []DayStats
+	if *resume == "" {
+		env, err = prepareRun(config, runID, globalRng, true)
+	} else if env, err = setUpEnvironment(config, runID, globalRng, true); err == nil {
+		if src, resumedRows, err = LoadState(*resume, env, config.rngAlgorithm); err != nil {
+			err = fmt.Errorf("-resume: %v", err)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(msgOut, "Error:", err)
+		return
+	}
+	if *resume != "" {
+		globalRng = rand.New(src)
+		runID = src.id
+		fmt.Fprintf(msgOut, "Resumed from %s at the end of day %d (%v)\n", *resume, env.day, runID)
+	}
+	printHouseholdSummary(env)
 
 	// Two types of frames: spatial distribution and pie chart
-	var framesSpatial []image.Image
-	var framesPie []image.Image
+	frames := &frameHistory{}
+
+	// Stats rows go through a window so long runs can be kept bounded
+	stats := &statsWindow{size: config.statsWindowDays, env: env}
 
-	fmt.Printf("Day, Healthy, Susceptible, Infected, Recovered, Dead, InfectedFrac, Vaccinated, EnvHygiene, EnvVaxRate, SDThreshold, PolicyTightened\n")
+	fmt.Println(statsHeader(env))
 
-	attachDiseaseToAll(env, disease)
-	for i := 0; i < config.initialInfected; i++ {
-		infectOneRandom(env, disease)
+	// Outputs (GIFs, checkpoints) go in output_gif/
+	outputDir := "output_gif"
+
+	// With flushEveryDays, stats rows and frames are written out as the run goes
+	if config.flushEveryDays > 0 {
+		out, err := newOutputStream(outputDir, env.outputName("stats.csv"), config.flushEveryDays, config.gifDelay, statsHeader(env))
+		if err != nil {
+			fmt.Fprintln(msgOut, "Error: flushEveryDays:", err)
+			return
+		}
+		stats.out = out
 	}
 
-	// Day 0 statistics + Day 0 frames
-	printStats(0, env, false)
-	framesSpatial = append(framesSpatial, env.DrawToCanvas(config.canvasWidth, config.pointRadius))
-	framesPie = append(framesPie, DrawEnvironmentPie(env, config.canvasWidth))
+	// Daily infected counts, kept for wave detection at the end of the run
+	series := &epidemicSeries{}
 
-	for day := 1; day <= config.numDays; day++ {
-		if err := UpdatePopulationHealthStatus(env, globalRng); err != nil {
-			fmt.Printf("error in UpdatePopulationHealthStatus on day %d: %v\n", day, err)
+	// With -serve, a browser dashboard shows every day and controls the run
+	var dash *dashboard
+	if *serve != "" {
+		d, err := newDashboard(*serve, config.numDays, config.canvasWidth, config.pointRadius)
+		if err != nil {
+			fmt.Fprintln(msgOut, "Error: -serve:", err)
 			return
 		}
+		dash = d
+		host := *serve
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		fmt.Fprintf(msgOut, "Dashboard at http://%s/ (paused before day %d)\n", host, env.day+1)
+	}
 
-		infFrac, tightened, err := UpdateEnvironment(env, globalRng)
+	// All rows are also kept for the stats file, the epidemic curve, the report, replicates, checkpoints and the stockpile summary, if requested
+	var recorder *StatsRecorder
+	if config.statsFilename != "" || config.epidemicCurve || config.report.enabled() || config.numReplicates > 1 || config.checkpointDays > 0 || *resume != "" || config.stockpile.enabled() {
+		recorder = &StatsRecorder{env: env}
+	}
+
+	if *resume != "" {
+		// The saved rows are not printed again
+		for _, row := range resumedRows {
+			series.add(row.Day, row.Infected, row.NewInfections)
+			recorder.add(row)
+			dash.publishStats(row)
+		}
+	} else {
+		seedInfections(env, config, globalRng)
+
+		// Day 0 statistics + Day 0 frames
+		day0 := collectDayStats(0, env, false)
+		series.add(0, day0.Infected, day0.NewInfections)
+		stats.add(day0)
+		recorder.add(day0)
+		dash.publishStats(day0)
+		env.flows.begin(env)
+	}
+
+	// Renderers and the days they run on: spatial and pie frames every
+	// frameFrequency days, plus the coverage map if requested
+	render := newRenderScheduler(config.burstFrames, config.finalFrame)
+	render.add(config.frameFrequency, true, func(env *Environment) {
+		frames.add(env.day, env.DrawToCanvas(config.canvasWidth, config.pointRadius), DrawEnvironmentPie(env, config.canvasWidth))
+	})
+	var coverage *coverageMap
+	if config.coverageMapEvery > 0 {
+		coverage = &coverageMap{grid: config.coverageGrid, size: config.canvasWidth}
+		render.add(config.coverageMapEvery, false, coverage.capture)
+	}
+	// Snapshots saved for the render subcommand
+	var snapshots *snapshotWriter
+	if config.snapshotEvery > 0 {
+		var err error
+		snapshots, err = newSnapshotWriter(outputDir, config, env)
+		if err != nil {
+			fmt.Fprintln(msgOut, "Error: snapshotEvery:", err)
+			return
+		}
+		render.add(config.snapshotEvery, false, snapshots.write)
+	}
+	if dash != nil {
+		render.add(1, false, dash.capture)
+	}
+	render.run(env)
+
+	// Per-individual state log for -trackAgent
+	var tracker *agentTracker
+	if len(tracked) > 0 {
+		var err error
+		tracker, err = newAgentTracker(outputDir, env.outputName(trackedAgentsFileName), tracked, len(env.population))
 		if err != nil {
-			fmt.Printf("error in UpdateEnvironment on day %d: %v\n", day, err)
+			fmt.Fprintln(msgOut, "Error: -trackAgent:", err)
 			return
 		}
+		tracker.record(env)
+	}
+
+	// With -repl, commands are read before each day and after the last one
+	var shell *repl
+	if *interactive {
+		shell = newRepl(os.Stdin, msgOut)
+	}
+
+	aborted := false
+	for day := env.day + 1; day <= config.numDays; day++ {
+		if !shell.pause(env) || !dash.pause(env) {
+			break
+		}
+		env.day = day
+
+		// Fire scheduled individual events due today
+		runDueEvents(env, globalRng)
+
+		// Today's train/flight manifests (no-op unless transitVehicles is set)
+		boardVehicles(env, globalRng)
+
+		// Index today's positions (no-op unless spatialIndex = kdtree)
+		rebuildSpatialIndex(env)
+
+		// Remember today's contacts (no-op unless contactMemoryDays is set)
+		recordContacts(env)
 
-		for _, ind := range env.population {
-			if ind == nil || ind.healthStatus == Dead {
-				continue
+		if err := UpdatePopulationHealthStatus(env, globalRng); err != nil {
+			if handleDayError(config.errorPolicy, env, outputDir, day, "UpdatePopulationHealthStatus", err) {
+				aborted = true
+				break
 			}
-			ind.updateMove(env)
+			continue
 		}
 
-		_ = infFrac
-		printStats(day, env, tightened)
+		infFrac, tightened, err := UpdateEnvironment(env, globalRng)
+		if err != nil {
+			if handleDayError(config.errorPolicy, env, outputDir, day, "UpdateEnvironment", err) {
+				aborted = true
+				break
+			}
+			continue
+		}
 
-		// Add both spatial and pie frames every frameFrequency steps
-		if day%config.frameFrequency == 0 {
-			framesSpatial = append(framesSpatial, env.DrawToCanvas(config.canvasWidth, config.pointRadius))
-			framesPie = append(framesPie, DrawEnvironmentPie(env, config.canvasWidth))
+		moveAll(env, globalRng)
+		invalidateSpatialIndex(env)
+		env.flows.record(env)
+
+		logLockdown(env, infFrac)
+		row := collectDayStats(day, env, tightened)
+		series.add(day, row.Infected, row.NewInfections)
+		stats.add(row)
+		recorder.add(row)
+		dash.publishStats(row)
+		tracker.record(env)
+
+		// Save the state every checkpointInterval days, for -resume
+		if config.checkpointDays > 0 && day%config.checkpointDays == 0 {
+			path := outputDir + "/" + env.outputName(stateFileName(day))
+			if err := SaveState(path, env, src, recorder.rows); err != nil {
+				fmt.Fprintln(msgOut, "failed to save state:", err)
+			} else {
+				fmt.Fprintln(msgOut, "State saved to:", path)
+			}
 		}
+
+		// Capture today's frames, with a burst after a policy tightening
+		if tightened {
+			render.policyTightened(day)
+		}
+		render.run(env)
+
+		// In window mode, frames older than the window are thinned to one per week
+		if config.statsWindowDays > 0 {
+			cutoff := day - config.statsWindowDays + 1
+			render.after(func() { frames.thin(cutoff) })
+		}
+
+		// Every flushEveryDays days, write out buffered rows and frames
+		if stats.out.due(day) {
+			render.wait()
+		}
+		if err := stats.out.maybeFlush(day, frames, tracker); err != nil {
+			fmt.Fprintln(msgOut, "failed to flush outputs:", err)
+		}
+	}
+	if !aborted {
+		shell.last(env)
+	}
+	dash.last(env)
+	// Always flush what we have, even if the run was cut short
+	render.finish(env)
+	if err := snapshots.close(); err != nil {
+		fmt.Fprintln(msgOut, "failed to save snapshots:", err)
+	} else if snapshots != nil {
+		fmt.Fprintln(msgOut, "Snapshots saved to:", snapshots.path)
+	}
+	stats.flush()
+	if err := stats.out.close(); err != nil {
+		fmt.Fprintln(msgOut, "failed to write stats.csv:", err)
+	} else if stats.out != nil {
+		fmt.Fprintln(msgOut, "Stats saved to:", stats.out.statsPath)
+	}
+	if err := tracker.close(); err != nil {
+		fmt.Fprintln(msgOut, "failed to write tracked agent log:", err)
+	} else if tracker != nil {
+		fmt.Fprintln(msgOut, "Tracked agent log saved to:", tracker.path)
+	}
+	// The summary is also kept for the run report
+	var summary strings.Builder
+	console := msgOut
+	if config.report.enabled() {
+		msgOut = io.MultiWriter(console, &summary)
+	}
+	if aborted {
+		fmt.Fprintf(msgOut, "run aborted on day %d; saving partial outputs\n", env.day)
 	}
+	printBurdenSummary(env)
+	printWaveSummary(env, series, config.waveProminence)
+	printFinalSizeSummary(env, series)
+	printHospitalQueueSummary(env)
+	printSideEffectsSummary(env)
+	printExcessMortality(env, config, runID)
+	printPathogenSummary(env)
+	printHygieneSupplySummary(env)
+	printQuarantineSummary(env)
+	printTestingSummary(env)
+	printTracingSummary(env)
+	printHouseholdVaccinationSummary(env)
+	printExposureRiskSummary(env)
+	printNPISummary(env)
+	printProfileSummary(env)
+	printChainSummary(env)
+	printSuperspreadingSummary(env)
+	if config.stockpile.enabled() {
+		printStockpileSummary(env, config, runID, recorder.rows)
+	}
+	printReffSummary(env)
+	printLockdownSummary(env)
+	printRegionSummary(env)
+	printAssimilationSummary(env)
+	printFastForwardSummary(env)
+	msgOut = console
 
 	// Create output_gif folder if it doesn't exist
-	outputDir := "output_gif"
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		fmt.Printf("failed to create output directory '%s': %v\n", outputDir, err)
+		fmt.Fprintf(msgOut, "failed to create output directory '%s': %v\n", outputDir, err)
+		return
+	}
+
+	// Save the full stats table, if requested
+	if config.statsFilename != "" {
+		fillReff(env, recorder.rows)
+		statsPath := outputDir + "/" + config.statsFilename
+		if err := recorder.save(statsPath, config.statsFormat); err != nil {
+			fmt.Fprintln(msgOut, "failed to save stats:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Stats saved to:", statsPath)
+		}
+	}
+
+	// Frames already flushed to chunks are read back and put in front
+	spatialFrames, err := stats.out.allFrames("spatial", frames.spatial)
+	if err != nil {
+		fmt.Fprintln(msgOut, "failed to read spatial frame chunks:", err)
+		return
+	}
+	pieFrames, err := stats.out.allFrames("pie", frames.pie)
+	if err != nil {
+		fmt.Fprintln(msgOut, "failed to read pie frame chunks:", err)
 		return
 	}
 
 	// 1) Save spatial distribution GIF
+	saved := true
 	spatialPath := outputDir + "/" + config.gifFilename
-	if err := SaveEnvironmentGIF(spatialPath, framesSpatial, config.gifDelay); err != nil {
-		fmt.Println("failed to save spatial gif:", err)
+	if err := SaveEnvironmentGIF(spatialPath, spatialFrames, config.gifDelay); err != nil {
+		fmt.Fprintln(msgOut, "failed to save spatial gif:", err)
+		saved = false
 	} else {
-		fmt.Println("Spatial GIF saved to:", spatialPath)
+		fmt.Fprintln(msgOut, "Spatial GIF saved to:", spatialPath)
 	}
 
 	// 2) Save pie chart GIF (prefix the filename)
 	piePath := outputDir + "/pie_" + config.gifFilename
-	if err := SaveEnvironmentGIF(piePath, framesPie, config.gifDelay); err != nil {
-		fmt.Println("failed to save pie gif:", err)
+	if err := SaveEnvironmentGIF(piePath, pieFrames, config.gifDelay); err != nil {
+		fmt.Fprintln(msgOut, "failed to save pie gif:", err)
+		saved = false
 	} else {
-		fmt.Println("Pie GIF saved to:", piePath)
+		fmt.Fprintln(msgOut, "Pie GIF saved to:", piePath)
+	}
+
+	// 3) Save vaccination coverage map GIF, if enabled
+	if coverage != nil {
+		coveragePath := outputDir + "/coverage_" + config.gifFilename
+		if err := SaveEnvironmentGIF(coveragePath, coverage.frames, config.gifDelay); err != nil {
+			fmt.Fprintln(msgOut, "failed to save coverage map gif:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Coverage map GIF saved to:", coveragePath)
+		}
+	}
+
+	// 4) Save the age pyramid and age-specific outcomes figure, if enabled
+	if config.ageSummary {
+		agePath := outputDir + "/age_summary.png"
+		if err := SaveAgeSummary(agePath, env); err != nil {
+			fmt.Fprintln(msgOut, "failed to save age summary:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Age summary saved to:", agePath)
+		}
+	}
+
+	// 5) Save the epidemic curve as a PNG and as a GIF that grows frame by frame, if enabled
+	if config.epidemicCurve {
+		width, height := config.canvasWidth, config.canvasWidth*3/5
+		curvePath := outputDir + "/epidemic_curve.png"
+		if err := SaveEpidemicCurve(curvePath, recorder.rows, width, height); err != nil {
+			fmt.Fprintln(msgOut, "failed to save epidemic curve:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Epidemic curve saved to:", curvePath)
+		}
+		curveGIF := outputDir + "/curve_" + config.gifFilename
+		curveFrames := EpidemicCurveFrames(recorder.rows, width, height, config.frameFrequency)
+		if err := SaveEnvironmentGIF(curveGIF, curveFrames, config.gifDelay); err != nil {
+			fmt.Fprintln(msgOut, "failed to save epidemic curve gif:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Epidemic curve GIF saved to:", curveGIF)
+		}
+	}
+
+	// 6) Save every individual's exposure risk, if enabled
+	if config.exposureRisk {
+		riskPath := outputDir + "/" + env.outputName(exposureRiskFileName)
+		if err := SaveExposureRisk(riskPath, env); err != nil {
+			fmt.Fprintln(msgOut, "failed to save exposure risk:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Exposure risk saved to:", riskPath)
+		}
+	}
+
+	// 7) Save the transmission log, if enabled
+	if config.chainStats {
+		chainsPath := outputDir + "/" + env.outputName(transmissionsFileName)
+		if err := SaveTransmissions(chainsPath, env); err != nil {
+			fmt.Fprintln(msgOut, "failed to save transmissions:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Transmissions saved to:", chainsPath)
+		}
+	}
+
+	// 8) Save the moves between health states as JSON and a Sankey diagram, if enabled
+	if config.flowDiagram {
+		jsonPath := outputDir + "/" + flowJSONFileName
+		if err := SaveFlowsJSON(jsonPath, env); err != nil {
+			fmt.Fprintln(msgOut, "failed to save flows:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Flows saved to:", jsonPath)
+		}
+		diagramPath := outputDir + "/" + flowImageFileName
+		if err := SaveFlowDiagram(diagramPath, env); err != nil {
+			fmt.Fprintln(msgOut, "failed to save flow diagram:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Flow diagram saved to:", diagramPath)
+		}
+	}
+
+	// 9) Assemble the summary, charts, GIFs and settings into one document, if enabled
+	if config.report.enabled() {
+		title := "Simulation report"
+		if *configFile != "" {
+			title += ": " + *configFile
+		} else if *preset != "" {
+			title += ": preset " + *preset
+		}
+		rep := &runReport{
+			title:    title,
+			seed:     runID.seed,
+			model:    modelTopology(env.seir, env.immunityWaning),
+			rows:     recorder.rows,
+			summary:  summary.String(),
+			settings: config.settings,
+		}
+		rep.addPNG("Epidemic curve", DrawEpidemicCurve(recorder.rows, config.canvasWidth, config.canvasWidth*3/5))
+		rep.addPNG("Outcomes by age", DrawAgeSummary(env))
+		rep.addFile("Spatial spread", spatialPath)
+		rep.addFile("Health states", piePath)
+		if coverage != nil {
+			rep.addFile("Vaccination coverage", outputDir+"/coverage_"+config.gifFilename)
+		}
+		if config.flowDiagram {
+			rep.addFile("Moves between health states", outputDir+"/"+flowImageFileName)
+		}
+		reportPath := outputDir + "/" + reportFileName(config.report)
+		if err := SaveReport(reportPath, config.report, rep, env); err != nil {
+			fmt.Fprintln(msgOut, "failed to save report:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Report saved to:", reportPath)
+		}
+	}
+
+	// 10) Run the other replicates and save their aggregate stats, if requested
+	if config.numReplicates > 1 {
+		first := scenarioResult{name: "replicate 1", rng: runID, rows: recorder.rows}
+		first.countInfected(env)
+		runReplicates(config, first, outputDir)
+	}
+
+	// The chunks are only needed if the full GIFs could not be written
+	if saved {
+		stats.out.removeChunks()
 	}
 }
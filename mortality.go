@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Background mortality and excess deaths.
+//
+// With backgroundMortality enabled, everyone alive also faces an age-specific
+// risk of dying of other causes, following the Gompertz law: the yearly
+// hazard at age x is backgroundMortalityA * exp(backgroundMortalityB * x).
+// Background deaths are kept apart from disease deaths in the stats
+// (DiseaseDeaths, BackgroundDeaths), the age summary and the life-years
+// lost, which count disease deaths only.
+//
+// Counting disease deaths alone overstates the toll when many victims would
+// have died soon anyway (mortality displacement). At the end of the run, a
+// baseline run with the same config and seed but no infections measures the
+// deaths expected without the disease. Excess mortality is the run's total
+// deaths minus the baseline's; the difference between disease deaths and
+// excess deaths is the number of disease deaths that displaced a background
+// death.
+
+// BackgroundMortality configures deaths from causes other than the disease.
+type BackgroundMortality struct {
+	enabled bool
+	a       float64 // yearly hazard at age 0
+	b       float64 // growth of the hazard per year of age
+}
+
+// dailyHazard returns the chance of dying of other causes on one day at age.
+func (m BackgroundMortality) dailyHazard(age int) float64 {
+	return 1 - math.Exp(-m.a*math.Exp(m.b*float64(age))/365)
+}
+
+// applyBackgroundDeaths draws today's background deaths among the living.
+func applyBackgroundDeaths(env *Environment, rng *rand.Rand) {
+	if !env.backgroundMortality.enabled {
+		return
+	}
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus == Dead {
+			continue
+		}
+		if rng.Float64() < env.backgroundMortality.dailyHazard(ind.age) {
+			ind.healthStatus = Dead
+			ind.deathDay = env.day
+			ind.backgroundDeath = true
+			ind.infection = nil // a hospital queue drops the dead on its next pass
+			env.transitions.backgroundDeaths++
+		}
+	}
+}
+
+// countDeaths returns the disease and background deaths so far.
+func countDeaths(env *Environment) (disease, background int) {
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus != Dead {
+			continue
+		}
+		if ind.backgroundDeath {
+			background++
+		} else {
+			disease++
+		}
+	}
+	return disease, background
+}
+
+// baselineDeaths runs config for days days with the random stream id, set up
+// exactly like the run but without seeding any infection (the initial,
+// regional and pathogen seeds, or those imposed by an incidence file), and
+// returns the number of deaths.
+func baselineDeaths(config *Config, id rngStream, days int) (int, error) {
+	rng := id.newRand()
+	env, err := prepareRun(config, id, rng, false)
+	if err != nil {
+		return 0, err
+	}
+	env.assimilation = nil
+	for day := 1; day <= days; day++ {
+		env.day = day
+		if _, err := stepDay(env, rng); err != nil {
+			return 0, fmt.Errorf("day %d: %v", day, err)
+		}
+	}
+	disease, background := countDeaths(env)
+	return disease + background, nil
+}
+
+// printExcessMortality compares the run's deaths with a no-disease baseline.
+func printExcessMortality(env *Environment, config *Config, id rngStream) {
+	if !env.backgroundMortality.enabled {
+		return
+	}
+	disease, background := countDeaths(env)
+	fmt.Fprintf(msgOut, "Deaths: %d from the disease, %d from other causes\n", disease, background)
+	expected, err := baselineDeaths(config, id, env.day)
+	if err != nil {
+		fmt.Fprintln(msgOut, "  No-disease baseline failed:", err)
+		return
+	}
+	excess := disease + background - expected
+	fmt.Fprintf(msgOut, "  Excess mortality: %d deaths over a no-disease baseline of %d (same seed, %d days)\n",
+		excess, expected, env.day)
+	if displaced := disease - excess; displaced > 0 {
+		fmt.Fprintf(msgOut, "  About %d disease deaths (%.0f%%) displaced deaths from other causes\n",
+			displaced, 100*float64(displaced)/float64(max(disease, 1)))
+	}
+}
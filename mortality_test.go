@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+// TestBaselineWithPathogen runs the no-disease baseline of a config with a
+// [disease.NAME] block. The baseline seeds no infections, so the pathogen
+// must still have its per-person state and must never spread.
+func TestBaselineWithPathogen(t *testing.T) {
+	config := getDefaultConfig()
+	config.popSize = 1500
+	config.numDays = 40
+	config.backgroundMortality.enabled = true
+	config.pathogen("flu").startDay = 10
+	config, err := validateConfig(config, NewConfigValidator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := baselineDeaths(config, rngStream{alg: config.rngAlgorithm, seed: 11}, config.numDays); err != nil {
+		t.Fatal(err)
+	}
+}
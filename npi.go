@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Effectiveness of non-pharmaceutical interventions (and the vaccine).
+//
+// computeB scales every contact's transmission probability by three
+// factors: the vaccine factor, the hygiene factor and the compliance
+// (distancing) factor. With npiReport = true, each time it runs it also
+// computes the infection probability the individual would have had with one
+// factor left out (set to 1), and with all three left out. Summed over every
+// Susceptible individual and day, these give the infection pressure, i.e. the
+// expected number of infections, with and without each mechanism. At the end
+// of the run a table gives each factor's mean value and the share of the
+// pressure it removed with the others kept in place. The comparison is
+// first-order: it counts the infections a mechanism prevented directly, not
+// the onward infections those would have caused.
+
+// npiVariant is a set of factors left out of the infection probability.
+type npiVariant int
+
+const (
+	npiActual       npiVariant = iota // every factor applied
+	npiNoVaccine                      // vaccine factor left out
+	npiNoHygiene                      // hygiene factor left out
+	npiNoCompliance                   // compliance factor left out
+	npiNone                           // all three left out
+	numNPIVariants
+)
+
+// npiFactors are the factor values of one variant.
+type npiFactors struct{ vax, hygiene, compliance float64 }
+
+// npiTally accumulates infection pressure and factor values.
+type npiTally struct {
+	pressure [numNPIVariants]float64 // summed infection probability per variant
+	factors  npiFactors              // summed factor values actually applied
+	evals    int                     // infection probabilities computed
+}
+
+// npiContacts is one individual's infection probability per variant, built
+// up contact by contact.
+type npiContacts struct {
+	factors [numNPIVariants]npiFactors
+	fail    [numNPIVariants]float64 // probability of escaping every contact so far
+}
+
+// newNPIContacts starts an individual's variants from its actual factors.
+func newNPIContacts(vax, hygiene, compliance float64) npiContacts {
+	var c npiContacts
+	for v := range c.factors {
+		f := npiFactors{vax, hygiene, compliance}
+		switch npiVariant(v) {
+		case npiNoVaccine:
+			f.vax = 1
+		case npiNoHygiene:
+			f.hygiene = 1
+		case npiNoCompliance:
+			f.compliance = 1
+		case npiNone:
+			f = npiFactors{1, 1, 1}
+		}
+		c.factors[v] = f
+		c.fail[v] = 1
+	}
+	return c
+}
+
+// add records n contacts whose transmission probability, before the three
+// factors, is base. Distancing does not apply at home (distancing false).
+func (c *npiContacts) add(base float64, n int, distancing bool) {
+	for v, f := range c.factors {
+		p := base * f.vax * f.hygiene
+		if distancing {
+			p *= f.compliance
+		}
+		c.fail[v] *= math.Pow(1-clamp01(p), float64(n))
+	}
+}
+
+// record adds the individual's variants, scaled by protection (boosting), and
+// its actual factors to t.
+func (t *npiTally) record(c *npiContacts, protection float64) {
+	for v := range t.pressure {
+		t.pressure[v] += clamp01(1-c.fail[v]) * protection
+	}
+	f := c.factors[npiActual]
+	t.factors.vax += f.vax
+	t.factors.hygiene += f.hygiene
+	t.factors.compliance += f.compliance
+	t.evals++
+}
+
+// merge adds o to t.
+func (t *npiTally) merge(o *npiTally) {
+	for v := range t.pressure {
+		t.pressure[v] += o.pressure[v]
+	}
+	t.factors.vax += o.factors.vax
+	t.factors.hygiene += o.factors.hygiene
+	t.factors.compliance += o.factors.compliance
+	t.evals += o.evals
+}
+
+// printNPISummary reports the contribution of each mechanism to reducing
+// transmission over the run.
+func printNPISummary(env *Environment) {
+	t := env.npi
+	if !env.npiReport || t.evals == 0 {
+		return
+	}
+	actual := t.pressure[npiActual]
+	n := float64(t.evals)
+	fmt.Fprintf(msgOut, "Transmission reduction by mechanism (first-order, over %d susceptible-days):\n", t.evals)
+	fmt.Fprintf(msgOut, "  %-12s %11s %17s %12s\n", "Mechanism", "Mean factor", "Pressure removed", "Averted")
+	row := func(name string, factor float64, v npiVariant) {
+		without := t.pressure[v]
+		share := 0.0
+		if without > 0 {
+			share = 100 * (without - actual) / without
+		}
+		factorText := "-"
+		if factor >= 0 {
+			factorText = fmt.Sprintf("%.3f", factor)
+		}
+		fmt.Fprintf(msgOut, "  %-12s %11s %16.1f%% %12.1f\n", name, factorText, share, without-actual)
+	}
+	row("Vaccine", t.factors.vax/n, npiNoVaccine)
+	row("Hygiene", t.factors.hygiene/n, npiNoHygiene)
+	row("Compliance", t.factors.compliance/n, npiNoCompliance)
+	row("Combined", -1, npiNone)
+	fmt.Fprintf(msgOut, "  Expected infections with every mechanism: %.1f (without any: %.1f)\n", actual, t.pressure[npiNone])
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// msgOut receives everything that is not part of the stats CSV: warnings,
+// errors and progress messages. It is stdout by default; in -machine mode it
+// is switched to stderr so that stdout carries nothing but the CSV.
+var msgOut io.Writer = os.Stdout
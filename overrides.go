@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Command-line overrides. Every top-level config parameter is also a flag of
+// the same name, which overrides the config file, preset or default:
+//
+//	go run . -config base.txt -popSize 20000 -transmissionRate 0.5 -seir
+//
+// Parameters written Name.SUFFIX are set with -set, which can be repeated:
+//
+//	go run . -config base.txt -set tag.elderly=0.3 -set annotation.40="second wave"
+//
+// Overrides are applied after the whole file has been read, in command-line
+// order, and are checked like the file's own settings, including the checks
+// that involve several parameters. They apply to every config the run loads,
+// so with -scenarios they change all the scenarios alike, and -resume needs
+// the same overrides as the saved run.
+
+// configOverride is one parameter set on the command line.
+type configOverride struct {
+	key, value string
+}
+
+// configOverrides holds the command-line overrides, in order.
+var configOverrides []configOverride
+
+// overrideFlag is the flag of one config parameter.
+type overrideFlag struct {
+	name   string
+	isBool bool
+}
+
+func (f *overrideFlag) String() string { return "" }
+
+func (f *overrideFlag) Set(value string) error {
+	configOverrides = append(configOverrides, configOverride{f.name, value})
+	return nil
+}
+
+// IsBoolFlag lets a bool parameter be given without a value (-seir).
+func (f *overrideFlag) IsBoolFlag() bool { return f.isBool }
+
+// setFlag is the -set flag: key=value for a Name.SUFFIX parameter.
+type setFlag struct{}
+
+func (setFlag) String() string { return "" }
+
+func (setFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	configOverrides = append(configOverrides, configOverride{strings.TrimSpace(key), strings.TrimSpace(value)})
+	return nil
+}
+
+// registerOverrideFlags adds a flag to fs for every top-level config parameter
+// without a flag of that name already, and -set.
+func registerOverrideFlags(fs *flag.FlagSet) {
+	for _, p := range configSchema {
+		if p.Block != "" || p.Suffix != "" || fs.Lookup(p.Name) != nil {
+			continue
+		}
+		fs.Var(&overrideFlag{name: p.Name, isBool: p.Kind == KindBool}, p.Name, p.Description+" (overrides the config)")
+	}
+	fs.Var(setFlag{}, "set", "Override a config parameter written Name.SUFFIX, as key=value (repeatable), e.g. -set tag.elderly=0.3")
+}
+
+// applyOverrides sets the command-line overrides on c.
+func (c *Config) applyOverrides(v *ConfigValidator) {
+	for _, o := range configOverrides {
+		if !c.setParam(v, "", "", o.key, o.value) {
+			v.AddError(o.key, o.value, "unknown parameter given on the command line")
+		}
+	}
+}
+
+// overridesText lists the command-line overrides, e.g. "popSize = 2000, seir = true".
+func overridesText() string {
+	parts := make([]string, len(configOverrides))
+	for i, o := range configOverrides {
+		parts[i] = o.key + " = " + o.value
+	}
+	return strings.Join(parts, ", ")
+}
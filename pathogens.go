@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Co-circulating pathogens.
+//
+// Each [disease.NAME] block in the config adds a pathogen that spreads
+// alongside the main disease with its own transmission, recovery, mortality
+// and immunity parameters. Every individual carries a separate state per
+// pathogen (Healthy, Infected or Recovered), so someone can have flu and the
+// main disease at the same time, or be immune to one and not the other.
+// Pathogens only interact through death: dying of any of them ends the
+// individual's infections with the others. Hospital beds, vaccination and
+// policy triggers keep following the main disease.
+//
+// A pathogen with startDay > 0 is introduced into the running epidemic: its
+// initialInfected individuals are infected at the start of that day, through
+// the event queue, so sequential epidemics (a second disease, or a variant
+// with crossImmunity against it for those who had the main disease) meet a
+// population already shaped by the first one.
+
+// Pathogen is a disease defined in a [disease.NAME] block.
+type Pathogen struct {
+	disease         *Disease
+	initialInfected int
+	startDay        int     // day the pathogen is introduced, 0 = at the start
+	crossImmunity   float64 // protection of individuals ever infected with the main disease
+}
+
+// pathogenState is an individual's state for one pathogen.
+type pathogenState struct {
+	status HealthStatus // Healthy, Infected or Recovered
+	days   int          // days in the current status
+}
+
+// pathogenTotals counts infections and deaths of one pathogen over the run.
+type pathogenTotals struct {
+	infections int
+	deaths     int
+}
+
+// PathogenCounts holds the counts of one pathogen on one day.
+type PathogenCounts struct {
+	Infected  int `json:"infected"`
+	Recovered int `json:"recovered"`
+	Dead      int `json:"dead"` // cumulative deaths caused by this pathogen
+}
+
+// pathogenColors are the map colors of the pathogens, in config order.
+var pathogenColors = [][3]uint8{
+	{255, 128, 0},   // Orange
+	{255, 0, 255},   // Magenta
+	{0, 255, 255},   // Cyan
+	{255, 255, 255}, // White
+}
+
+// pathogen returns the config's pathogen with the given name, creating it with
+// the main disease's default parameters if needed.
+func (c *Config) pathogen(name string) *Pathogen {
+	for _, p := range c.pathogens {
+		if p.disease.name == name {
+			return p
+		}
+	}
+	p := &Pathogen{disease: &Disease{name: name}}
+	c.pathogens = append(c.pathogens, p)
+	applyBlockDefaults(c, "disease", name)
+	return p
+}
+
+// seedPathogens infects the initialInfected individuals of the pathogens
+// starting on day 0 at random, and schedules the introduction of the others.
+func seedPathogens(env *Environment, rng *rand.Rand) {
+	if len(env.pathogens) == 0 {
+		return
+	}
+	rng = rngOrDefault(rng)
+	scheduled := map[int]bool{}
+	for _, p := range env.pathogens {
+		if p.startDay > 0 && !scheduled[p.startDay] {
+			scheduled[p.startDay] = true
+			scheduleEvent(env, float64(p.startDay), scheduledEvent{kind: EventPathogenStart})
+		}
+	}
+	introducePathogens(env, rng)
+}
+
+// initPathogens gives every individual a Healthy state for each pathogen and
+// starts the pathogens' totals. It is part of building the population, so a
+// run that never seeds the pathogens, like the no-disease baseline, still has
+// them.
+func initPathogens(env *Environment) {
+	if len(env.pathogens) == 0 {
+		return
+	}
+	env.pathogenTotals = make([]pathogenTotals, len(env.pathogens))
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		ind.pathogens = make([]pathogenState, len(env.pathogens))
+		for k := range ind.pathogens {
+			ind.pathogens[k].status = Healthy
+		}
+	}
+}
+
+// introducePathogens infects the initialInfected individuals of every
+// pathogen starting today, chosen at random among the living.
+func introducePathogens(env *Environment, rng *rand.Rand) {
+	var alive []*Individual
+	for k, p := range env.pathogens {
+		if p.startDay != env.day {
+			continue
+		}
+		if alive == nil {
+			for _, ind := range env.population {
+				if ind != nil && ind.healthStatus != Dead {
+					alive = append(alive, ind)
+				}
+			}
+		}
+		n := min(p.initialInfected, len(alive))
+		for _, i := range rng.Perm(len(alive))[:n] {
+			alive[i].pathogens[k] = pathogenState{status: Infected}
+			env.pathogenTotals[k].infections++
+		}
+	}
+}
+
+// updatePathogens runs one day of every pathogen: infection of Healthy
+// individuals, death or recovery of the Infected, and waning immunity of the
+// Recovered. Like the main disease, all probabilities are computed before any
+// state changes.
+func updatePathogens(env *Environment, rng *rand.Rand) {
+	if len(env.pathogens) == 0 || env.pathogenTotals == nil {
+		return // no pathogen state was set up
+	}
+	if cap(env.pathogenProbs) < len(env.population) {
+		env.pathogenProbs = make([]float64, len(env.population))
+	}
+	probs := env.pathogenProbs[:len(env.population)]
+
+	for k, p := range env.pathogens {
+		quiet := pathogenQuiet(env, k)
+		for i, ind := range env.population {
+			probs[i] = 0
+			if !quiet && ind != nil && ind.healthStatus != Dead && ind.pathogens[k].status == Healthy {
+				probs[i] = pathogenInfectionProb(env, ind, k)
+			}
+		}
+		for i, ind := range env.population {
+			if ind == nil || ind.healthStatus == Dead {
+				continue
+			}
+			st := &ind.pathogens[k]
+			switch st.status {
+			case Healthy:
+				if drawFloat(rng) < probs[i] {
+					*st = pathogenState{status: Infected}
+					env.pathogenTotals[k].infections++
+				}
+			case Infected:
+				st.days++
+				c, d := pathogenOutcome(env, ind, p.disease)
+				r := drawFloat(rng)
+				switch {
+				case r < c:
+					killByPathogen(env, ind, k)
+				case r < c+d || st.days >= p.disease.infectiousPeriod:
+					*st = pathogenState{status: Recovered}
+				}
+			case Recovered:
+				st.days++
+				if st.days >= p.disease.immunityDuration {
+					*st = pathogenState{status: Healthy}
+				}
+			}
+		}
+	}
+}
+
+// pathogenInfectionProb is computeB for pathogen k: independent exposures to
+// every neighbor infected with it, decaying with the transmission kernel.
+// Vaccination does not protect against other pathogens; quarantine does.
+func pathogenInfectionProb(env *Environment, ind *Individual, k int) float64 {
+	dis := env.pathogens[k].disease
+	D0 := dis.transmissionDistance
+	if D0 <= 0 {
+		D0 = 1.0
+	}
+	kernel := transmissionKernel(env)
+	reach := kernel.reach(D0)
+	beta := clamp01(dis.transmissionRate*weatherTransmission(env)) *
+		(1.0 - 0.4*clamp01(env.hygieneLevel)) *
+		(1.0 - 0.4*effectiveCompliance(env, ind)) *
+		tagExposureMult(env, ind)
+
+	fail := 1.0
+	expose := func(other *Individual, d float64) {
+		if other != ind && other.healthStatus != Dead && other.pathogens[k].status == Infected && sameRegion(ind, other) {
+			fail *= 1 - clamp01(beta*kernel.weight(d, D0)*quarantineTransmission(env, other))
+		}
+	}
+	if env.index != nil {
+		env.index.within(ind.position, reach, expose)
+	} else {
+		for _, other := range env.population {
+			if other == nil {
+				continue
+			}
+			if d := dist(ind.position, other.position); d <= reach {
+				expose(other, d)
+			}
+		}
+	}
+
+	// Co-passengers on a train/flight are contacts regardless of distance
+	if ind.vehicle != nil {
+		pi := clamp01(beta * env.transit.contactFactor)
+		for _, other := range ind.vehicle.passengers {
+			if other != ind && other.healthStatus != Dead && other.pathogens[k].status == Infected {
+				fail *= 1 - pi
+			}
+		}
+	}
+
+	// Housemates are contacts every night, without distancing
+	if ind.home != nil {
+		pi := clamp01(clamp01(dis.transmissionRate*weatherTransmission(env)) *
+			(1.0 - 0.4*clamp01(env.hygieneLevel)) * tagExposureMult(env, ind) * env.households.transmission)
+		for _, other := range ind.home.members {
+			if other != ind && other.healthStatus != Dead && !other.inHospital && other.pathogens[k].status == Infected {
+				fail *= 1 - pi
+			}
+		}
+	}
+
+	// Past infection with the main disease protects against a variant
+	if ind.timesInfected > 0 {
+		return clamp01(1-fail) * (1 - env.pathogens[k].crossImmunity)
+	}
+	return clamp01(1 - fail)
+}
+
+// pathogenOutcome returns the daily death and recovery probabilities of an
+// individual infected with dis, using the age bands (or age curves) and care
+// level of computeC and computeD.
+func pathogenOutcome(env *Environment, ind *Individual, dis *Disease) (float64, float64) {
+	deathMult, recoveryMult := 1.0, 1.0
+	switch {
+	case ind.age < 40:
+		deathMult, recoveryMult = 0.6, 1.4
+	case ind.age > 60:
+		deathMult, recoveryMult = 1.6, 0.7
+	}
+	deathMult = ageFactor(env, AgeMortality, ind, deathMult)
+	recoveryMult = ageFactor(env, AgeRecovery, ind, recoveryMult)
+	careLevel := clamp01(env.medicalCareLevel)
+	c := math.Min(clamp01(dis.mortalityRate)*deathMult*(1.0-0.6*careLevel), 0.95)
+	d := clamp01(dis.recoveryRate * recoveryMult * (1.0 + 0.5*careLevel))
+	if c+d > 1.0 {
+		total := c + d
+		c, d = c/total, d/total
+	}
+	return c, d
+}
+
+// killByPathogen records the death of ind from pathogen k, ending any
+// infection with the main disease.
+func killByPathogen(env *Environment, ind *Individual, k int) {
+	ind.healthStatus = Dead
+	ind.deathDay = env.day
+	env.transitions.newDeaths++
+	env.pathogenTotals[k].deaths++
+	recordDeath(env, ind)
+	recordWaitingDeath(env, ind)
+	ind.infection = nil
+}
+
+// infectedPathogen returns the index of the first pathogen ind is infected
+// with, or -1 if none.
+func infectedPathogen(ind *Individual) int {
+	for k, st := range ind.pathogens {
+		if st.status == Infected {
+			return k
+		}
+	}
+	return -1
+}
+
+// pathogenColor returns the map color of an individual infected with a
+// pathogen but not with the main disease. The main disease keeps its red.
+func pathogenColor(ind *Individual) (uint8, uint8, uint8, bool) {
+	if ind.healthStatus == Infected || ind.healthStatus == Dead {
+		return 0, 0, 0, false
+	}
+	k := infectedPathogen(ind)
+	if k < 0 {
+		return 0, 0, 0, false
+	}
+	c := pathogenColors[k%len(pathogenColors)]
+	return c[0], c[1], c[2], true
+}
+
+// collectPathogenCounts returns today's counts per pathogen, or nil if there
+// are no pathogens.
+func collectPathogenCounts(env *Environment) []PathogenCounts {
+	if len(env.pathogens) == 0 {
+		return nil
+	}
+	out := make([]PathogenCounts, len(env.pathogens))
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus == Dead {
+			continue
+		}
+		for k, st := range ind.pathogens {
+			switch st.status {
+			case Infected:
+				out[k].Infected++
+			case Recovered:
+				out[k].Recovered++
+			}
+		}
+	}
+	for k := range out {
+		out[k].Dead = env.pathogenTotals[k].deaths
+	}
+	return out
+}
+
+// pathogenStatsHeader returns the per-pathogen stats columns.
+func pathogenStatsHeader(env *Environment) string {
+	out := ""
+	for _, p := range env.pathogens {
+		n := p.disease.name
+		out += fmt.Sprintf(", %s_Infected, %s_Recovered, %s_Dead", n, n, n)
+	}
+	return out
+}
+
+// pathogenLabel returns the per-pathogen part of the frame label.
+func pathogenLabel(env *Environment, counts []PathogenCounts) string {
+	out := ""
+	for k, p := range env.pathogens {
+		out += fmt.Sprintf(" | %s I:%d", p.disease.name, counts[k].Infected)
+	}
+	return out
+}
+
+// printPathogenSummary prints each pathogen's infections and deaths over the run.
+func printPathogenSummary(env *Environment) {
+	if len(env.pathogens) == 0 {
+		return
+	}
+	n := max(len(env.population), 1)
+	fmt.Fprintln(msgOut, "Co-circulating pathogens:")
+	for k, p := range env.pathogens {
+		t := env.pathogenTotals[k]
+		introduced := ""
+		if p.startDay > 0 {
+			introduced = fmt.Sprintf(", introduced on day %d", p.startDay)
+		}
+		fmt.Fprintf(msgOut, "  %s: %d infections (%.1f per 100 people), %d deaths%s\n",
+			p.disease.name, t.infections, 100*float64(t.infections)/float64(n), t.deaths, introduced)
+	}
+}
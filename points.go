@@ -0,0 +1,92 @@
+package main
+
+import (
+	"image/color"
+	"math"
+)
+
+// Point rendering for dense populations. At tens of thousands of individuals
+// solid dots merge into blobs on the spatial map. Three options keep the map
+// readable:
+//   - pointAlpha < 1 draws dots translucent, so overlapping dots add up and
+//     crowded areas show as denser color instead of a flat blob;
+//   - densityPointSize shrinks dots where the map is crowded, so the ink per
+//     area stays bounded: a dot in a grid cell holding n dots gets
+//     radius * sqrt(fit / n) when n exceeds the fit number of dots that fill
+//     the cell at full size without overlapping;
+//   - pointJitter moves each dot by up to that many pixels, so individuals at
+//     the same spot (road junctions, quarantine) do not hide each other. The
+//     offset is derived from the individual's ID, not the random generator,
+//     so it is stable from frame to frame and never changes a seeded run.
+
+// pointStyle controls how individuals are drawn on the spatial map.
+type pointStyle struct {
+	alpha   float64 // fill opacity in (0, 1], 1 = opaque
+	density bool    // shrink dots where the map is crowded
+	jitter  float64 // largest offset in pixels, 0 = exact positions
+}
+
+// minPointRadius is the smallest radius densityPointSize shrinks dots to.
+const minPointRadius = 0.5
+
+// pointFill returns the fill color of a dot with the style's opacity.
+func pointFill(s pointStyle, r, g, b uint8) color.Color {
+	if s.alpha <= 0 || s.alpha >= 1 {
+		return color.RGBA{r, g, b, 255}
+	}
+	return color.NRGBA{r, g, b, uint8(math.Round(255 * s.alpha))}
+}
+
+// pointRadii returns the radius of each dot in drawn, given the full radius,
+// or nil if all dots are drawn at full size.
+func pointRadii(s pointStyle, env *Environment, drawn []*Individual, canvasWidth int, radius float64) []float64 {
+	if !s.density || len(drawn) == 0 {
+		return nil
+	}
+	// Cells four dot radii wide, within each region's panel if there are
+	// regions; a cell is filled by fit non-overlapping dots
+	cellPx := 4 * radius
+	_, panel := regionPanels(env, canvasWidth)
+	grid := max(1, int(panel/cellPx))
+	fit := cellPx * cellPx / (math.Pi * radius * radius) / 2
+	cell := func(ind *Individual) int {
+		index := func(v float64) int {
+			return min(max(int(v/env.areaSize*float64(grid)), 0), grid-1)
+		}
+		return (ind.region*grid+index(ind.position.y))*grid + index(ind.position.x)
+	}
+
+	counts := make([]int, grid*grid*max(len(env.regions), 1))
+	for _, ind := range drawn {
+		if ind != nil {
+			counts[cell(ind)]++
+		}
+	}
+	radii := make([]float64, len(drawn))
+	for i, ind := range drawn {
+		radii[i] = radius
+		if ind == nil {
+			continue
+		}
+		if n := float64(counts[cell(ind)]); n > fit {
+			radii[i] = max(radius*math.Sqrt(fit/n), minPointRadius)
+		}
+	}
+	return radii
+}
+
+// pointJitter returns the pixel offset of ind's dot, at most s.jitter in
+// each direction.
+func pointJitter(s pointStyle, ind *Individual) (float64, float64) {
+	if s.jitter <= 0 {
+		return 0, 0
+	}
+	// splitmix64 of the ID: two well-mixed uniforms in [-1, 1)
+	z := uint64(ind.id) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	u := float64(z>>32)/(1<<31) - 1
+	v := float64(z&0xffffffff)/(1<<31) - 1
+	return u * s.jitter, v * s.jitter
+}
@@ -0,0 +1,71 @@
+package main
+
+// triggerMetric selects which epidemic signal a policy controller reacts to.
+type triggerMetric string
+
+const (
+	TriggerPrevalence triggerMetric = "prevalence" // infected fraction of the population
+	TriggerHospital   triggerMetric = "hospital"   // ward+ICU demand / staffed beds
+	TriggerICU        triggerMetric = "icu"        // ICU demand / staffed ICU beds
+)
+
+// triggerValue returns the current value of a trigger metric.
+// Prevalence is a fraction of the population; occupancy metrics are demand
+// relative to staffed capacity, so 1.0 means beds are exactly full and values
+// above 1 mean patients are going without a bed.
+func triggerValue(env *Environment, metric triggerMetric, infectedFraction float64) float64 {
+	switch metric {
+	case TriggerHospital:
+		load := computeCareLoad(env)
+		return occupancyRatio(load.wardDemand+load.icuDemand, load.wardBeds+load.icuBeds)
+	case TriggerICU:
+		load := computeCareLoad(env)
+		return occupancyRatio(load.icuDemand, load.icuBeds)
+	default:
+		return infectedFraction
+	}
+}
+
+// occupancyRatio returns demand/capacity; with no capacity, any demand counts as over capacity (2.0).
+func occupancyRatio(demand, capacity int) float64 {
+	if capacity <= 0 {
+		if demand > 0 {
+			return 2.0
+		}
+		return 0
+	}
+	return float64(demand) / float64(capacity)
+}
+
+// distancingFactor maps the distancing trigger value to the social distance
+// threshold multiplier (larger = more permissive). Prevalence uses the original
+// buckets; occupancy triggers tighten as beds fill up.
+func distancingFactor(metric triggerMetric, value float64) float64 {
+	if metric == TriggerHospital || metric == TriggerICU {
+		switch {
+		case value < 0.25:
+			return 2.0
+		case value < 0.50:
+			return 1.5
+		case value < 0.75:
+			return 1.0
+		case value < 1.00:
+			return 0.7
+		default:
+			return 0.4
+		}
+	}
+
+	switch {
+	case value < 0.01:
+		return 2.0
+	case value < 0.05:
+		return 1.5
+	case value < 0.10:
+		return 1.0
+	case value < 0.20:
+		return 0.7
+	default:
+		return 0.4
+	}
+}
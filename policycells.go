@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Policy cells (local authorities). With policyCells = N > 0, the area is
+// divided into N x N square cells (in every region, with regions), and each
+// cell runs its own copy of the policy controller instead of the central one:
+// a social distance threshold that reacts to the infected fraction among
+// those in the cell, and, with lockdownThreshold set, lockdowns started and
+// lifted by that same local fraction. An individual follows the policy of
+// the cell it is in. Cells always react to prevalence (as the
+// informationSource reports it), whatever the distancingTrigger, but
+// hospitals are shared, so overload tightens every cell. The central
+// threshold is still computed and reported as SDThreshold, but only drives
+// the hygiene campaign. With one cell, no regions and the default trigger,
+// the run is the same as with the central controller, which makes it the
+// baseline to compare decentralized responses against.
+
+// PolicyCell is the policy state of one cell.
+type PolicyCell struct {
+	sdThreshold float64        // local social distance threshold
+	lockdown    LockdownConfig // local lockdown; the rules are copied from env.lockdown daily
+	infected    int            // today's infected in the cell
+	detected    int            // of them, those with a detected infection
+	present     int            // today's individuals in the cell
+	seen        float64        // the local infected fraction the cell reacted to today
+	pending     []float64      // local fractions not yet reported, see information.go
+}
+
+// setupPolicyCells creates the cells, all starting from the central policy.
+// Regions must be set up first.
+func setupPolicyCells(env *Environment, perSide int) {
+	env.cellsPerSide = perSide
+	env.cells = nil
+	if perSide <= 0 {
+		return
+	}
+	env.cells = make([]PolicyCell, max(len(env.regions), 1)*perSide*perSide)
+	for k := range env.cells {
+		env.cells[k].sdThreshold = env.socialDistanceThreshold
+		env.cells[k].lockdown = env.lockdown
+	}
+}
+
+// cellIndex returns the index of the cell ind is in.
+func cellIndex(env *Environment, ind *Individual) int {
+	n := env.cellsPerSide
+	at := func(v float64) int {
+		return min(max(int(v/env.areaSize*float64(n)), 0), n-1)
+	}
+	return ind.region*n*n + at(ind.position.y)*n + at(ind.position.x)
+}
+
+// policyCell returns the cell ind is in, or nil without policy cells.
+func policyCell(env *Environment, ind *Individual) *PolicyCell {
+	if len(env.cells) == 0 {
+		return nil
+	}
+	return &env.cells[cellIndex(env, ind)]
+}
+
+// distanceThreshold returns the social distance threshold that applies to ind.
+func distanceThreshold(env *Environment, ind *Individual) float64 {
+	if c := policyCell(env, ind); c != nil {
+		return c.sdThreshold
+	}
+	return env.socialDistanceThreshold
+}
+
+// cellName labels cell k for logs: column and row from the top left, after
+// the region name if there are regions.
+func cellName(env *Environment, k int) string {
+	n := env.cellsPerSide
+	name := fmt.Sprintf("%d,%d", k%n, k/n%n)
+	if len(env.regions) > 0 {
+		name = env.regions[k/(n*n)].name + " " + name
+	}
+	return name
+}
+
+// updatePolicyCells updates each cell's threshold and lockdown from its local
+// infected fraction, as reported. infectedFraction is the global one, for
+// hospital overload.
+func updatePolicyCells(env *Environment, infectedFraction, avgTransDist float64) {
+	for k := range env.cells {
+		env.cells[k].infected, env.cells[k].detected, env.cells[k].present = 0, 0, 0
+	}
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		c := policyCell(env, ind)
+		c.present++
+		if ind.healthStatus == Infected {
+			c.infected++
+			if ind.infection != nil && ind.infection.detected {
+				c.detected++
+			}
+		}
+	}
+	overload := overloadTightening(env, infectedFraction)
+	for k := range env.cells {
+		c := &env.cells[k]
+		info := env.information
+		c.seen = info.report(&c.pending, info.observed(c.infected, c.detected, c.present))
+		factor := distancingFactor(TriggerPrevalence, c.seen) * overload
+		c.sdThreshold, _ = nextDistanceThreshold(c.sdThreshold, factor, avgTransDist)
+
+		l := &c.lockdown
+		l.threshold, l.release = env.lockdown.threshold, env.lockdown.release
+		l.mobility, l.minDays = env.lockdown.mobility, env.lockdown.minDays
+		l.update(env.day, c.seen)
+	}
+}
+
+// cellThresholdRange returns the lowest and highest local threshold today.
+func cellThresholdRange(env *Environment) (lo, hi float64) {
+	if len(env.cells) == 0 {
+		return 0, 0
+	}
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, c := range env.cells {
+		lo, hi = math.Min(lo, c.sdThreshold), math.Max(hi, c.sdThreshold)
+	}
+	return lo, hi
+}
+
+// lockedCells returns the number of cells in lockdown today.
+func lockedCells(env *Environment) int {
+	n := 0
+	for _, c := range env.cells {
+		if c.lockdown.active {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+package main
+
+import "sync"
+
+// Reusable scratch buffers for the daily update.
+//
+// Every individual runs several neighbor queries per day (computeA/computeB,
+// hygiene and compliance updates). Allocating a fresh slice for each query
+// dominates GC work at large population sizes, so queries append into pooled
+// buffers instead and hand them back when done.
+
+// neighbor is an infected individual found within some radius, with its distance.
+type neighbor struct {
+	infected *Individual
+	d        float64
+}
+
+// transitionProbs holds the probabilities a..e computed for one individual.
+type transitionProbs struct {
+	a, b, c, d, e float64
+}
+
+var neighborBufPool = sync.Pool{
+	New: func() any {
+		b := make([]neighbor, 0, 16)
+		return &b
+	},
+}
+
+var individualBufPool = sync.Pool{
+	New: func() any {
+		b := make([]*Individual, 0, 32)
+		return &b
+	},
+}
+
+// getNeighborBuf returns an empty neighbor buffer from the pool.
+func getNeighborBuf() *[]neighbor {
+	return neighborBufPool.Get().(*[]neighbor)
+}
+
+// putNeighborBuf clears a neighbor buffer and returns it to the pool.
+func putNeighborBuf(b *[]neighbor) {
+	clear(*b)
+	*b = (*b)[:0]
+	neighborBufPool.Put(b)
+}
+
+// getIndividualBuf returns an empty individual buffer from the pool.
+func getIndividualBuf() *[]*Individual {
+	return individualBufPool.Get().(*[]*Individual)
+}
+
+// putIndividualBuf clears an individual buffer and returns it to the pool.
+func putIndividualBuf(b *[]*Individual) {
+	clear(*b)
+	*b = (*b)[:0]
+	individualBufPool.Put(b)
+}
+
+// probsBuffer returns a transition probability slice sized to the population,
+// reusing the environment's buffer from previous days when it is large enough.
+func (env *Environment) probsBuffer() []transitionProbs {
+	n := len(env.population)
+	if cap(env.probsBuf) < n {
+		env.probsBuf = make([]transitionProbs, n)
+	}
+	env.probsBuf = env.probsBuf[:n]
+	return env.probsBuf
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// benchmarkEnv sets up the default config with popSize individuals for
+// benchmarks of the daily step: the area grows with the population so the
+// density stays that of the default 1000 in 100 x 100, 1% start infected and
+// neighbors are found through the hash grid. adjust, if not nil, changes the
+// config before it is checked.
+func benchmarkEnv(b *testing.B, popSize int, adjust func(*Config)) (*Environment, *rand.Rand) {
+	b.Helper()
+	config := getDefaultConfig()
+	config.areaSize *= math.Sqrt(float64(popSize) / float64(config.popSize))
+	config.popSize = popSize
+	config.initialInfected = popSize / 100
+	config.spatialIndex = IndexGrid
+	config.sanityCheckDays = 0
+	if adjust != nil {
+		adjust(config)
+	}
+	config, err := validateConfig(config, NewConfigValidator())
+	if err != nil {
+		b.Fatal(err)
+	}
+	id := rngStream{alg: config.rngAlgorithm, seed: 1}
+	rng := id.newRand()
+	env, err := prepareRun(config, id, rng, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	seedInfections(env, config, rng)
+	return env, rng
+}
+
+// benchmarkStep runs one day of env per iteration.
+func benchmarkStep(b *testing.B, env *Environment, rng *rand.Rand) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		env.day = i + 1
+		if _, err := stepDay(env, rng); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDailyUpdate100k is a day of 100,000 individuals.
+func BenchmarkDailyUpdate100k(b *testing.B) {
+	env, rng := benchmarkEnv(b, 100000, nil)
+	benchmarkStep(b, env, rng)
+}
+
+// poolBenchQueries is the number of neighbor queries per iteration of the
+// buffer benchmarks, and poolBenchFound the neighbors each one finds.
+const (
+	poolBenchQueries = 1000
+	poolBenchFound   = 12
+)
+
+// neighborBufSink keeps the fresh buffers on the heap, as they are in the
+// daily update, where they outlive the function that allocates them.
+var neighborBufSink *[]neighbor
+
+// BenchmarkNeighborBufPooled runs neighbor queries that append into buffers
+// from the pool.
+func BenchmarkNeighborBufPooled(b *testing.B) {
+	ind := &Individual{}
+	b.ReportAllocs()
+	for b.Loop() {
+		for range poolBenchQueries {
+			buf := getNeighborBuf()
+			for k := range poolBenchFound {
+				*buf = append(*buf, neighbor{ind, float64(k)})
+			}
+			putNeighborBuf(buf)
+		}
+	}
+}
+
+// BenchmarkNeighborBufFresh runs the same queries, each into a newly
+// allocated buffer.
+func BenchmarkNeighborBufFresh(b *testing.B) {
+	ind := &Individual{}
+	b.ReportAllocs()
+	for b.Loop() {
+		for range poolBenchQueries {
+			buf := make([]neighbor, 0, 16)
+			for k := range poolBenchFound {
+				buf = append(buf, neighbor{ind, float64(k)})
+			}
+			neighborBufSink = &buf
+		}
+	}
+}
+
+// BenchmarkProbsBufferReused takes a day's transition probability slice for
+// 100,000 individuals from the environment.
+func BenchmarkProbsBufferReused(b *testing.B) {
+	env := &Environment{population: make([]*Individual, 100000)}
+	b.ReportAllocs()
+	for b.Loop() {
+		ps := env.probsBuffer()
+		ps[0].a = 1
+	}
+}
+
+// BenchmarkProbsBufferFresh allocates the same slice every day.
+func BenchmarkProbsBufferFresh(b *testing.B) {
+	n := 100000
+	b.ReportAllocs()
+	for b.Loop() {
+		ps := make([]transitionProbs, n)
+		ps[0].a = 1
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The example configs in config/ are compiled into the binary, so a single
+// executable can run them (-preset NAME) or write them out as a starting
+// point (-exportDefaults DIR) without the source tree. Frame text uses the
+// built-in basicfont face, so no font files are needed either.
+
+//go:embed config/*.txt
+var presetFS embed.FS
+
+// presetNames returns the names of the embedded presets (file names without .txt).
+func presetNames() []string {
+	entries, _ := fs.ReadDir(presetFS, "config")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadPreset loads and validates an embedded preset config by name.
+func loadPreset(name string) (*Config, error) {
+	f, err := presetFS.Open(path.Join("config", name+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(presetNames(), ", "))
+	}
+	defer f.Close()
+	return loadConfig(f)
+}
+
+// exportDefaults writes the embedded presets to dir, refusing to overwrite existing files.
+func exportDefaults(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, name := range presetNames() {
+		data, err := presetFS.ReadFile(path.Join("config", name+".txt"))
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, name+".txt")
+		if _, err := os.Stat(dst); err == nil {
+			fmt.Fprintf(msgOut, "skipping %s: file exists\n", dst)
+			continue
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintln(msgOut, "wrote", dst)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Behavior profiles. Without them everyone follows the same behavioral model,
+// starting from the hygieneDistribution and complianceDistribution draws.
+// profile.NAME = fraction puts that share of the population in profile NAME
+// (e.g. cautious, reckless); profiles are exclusive, and whoever is left over
+// keeps the default behavior. Each profile can set
+//
+//	profileHygiene.NAME            hygiene baseline (0-1)
+//	profileCompliance.NAME         distancing compliance baseline (0-1)
+//	profileMobility.NAME           multiplier on step lengths and on the
+//	                               chances of taking a train or a flight
+//	profileVaccineAcceptance.NAME  base vaccine acceptance, in place of 0.55
+//
+// A baseline is where the member starts, and every day the social and policy
+// updates are pulled profilePull of the way back towards it, so a cautious
+// crowd stays more careful than its neighbors. A profile without a hygiene or
+// compliance baseline uses the population draw and the default update. The
+// stats get per-profile counts, named like the per-tag ones
+// (cautious_Infected), and the summary compares the profiles.
+
+// profilePull is the share of the gap to its baseline a profile's hygiene and
+// compliance close every day.
+const profilePull = 0.1
+
+// ProfileSpec describes one behavior profile.
+type ProfileSpec struct {
+	name              string
+	fraction          float64 // share of the population with this profile
+	hygiene           float64 // hygiene baseline, -1 for the population draw
+	compliance        float64 // compliance baseline, -1 for the population draw
+	mobility          float64 // multiplier on step lengths and transit chances
+	vaccineAcceptance float64 // base vaccine acceptance probability
+}
+
+// assignProfiles gives each individual at most one profile, by cumulative
+// fraction, and starts it at the profile's baselines. It draws nothing
+// without profiles.
+func assignProfiles(env *Environment, specs []*ProfileSpec, rng *rand.Rand) {
+	env.profiles = specs
+	if len(specs) == 0 {
+		return
+	}
+	rng = rngOrDefault(rng)
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		u := rng.Float64()
+		for _, spec := range specs {
+			if u < spec.fraction {
+				ind.profile = spec
+				break
+			}
+			u -= spec.fraction
+		}
+		if p := ind.profile; p != nil {
+			if p.hygiene >= 0 {
+				ind.hygieneLevel = p.hygiene
+			}
+			if p.compliance >= 0 {
+				ind.socialDistanceCompliance = p.compliance
+			}
+		}
+	}
+}
+
+// profileByName returns the environment's profile of that name, or nil.
+func (env *Environment) profileByName(name string) *ProfileSpec {
+	for _, spec := range env.profiles {
+		if spec.name == name {
+			return spec
+		}
+	}
+	return nil
+}
+
+// anchor pulls an updated level back towards a baseline (if set).
+func anchor(level, baseline float64) float64 {
+	if baseline < 0 {
+		return level
+	}
+	return level + profilePull*(baseline-level)
+}
+
+// profileHygiene returns today's updated hygiene of ind after its profile's pull.
+func profileHygiene(ind *Individual, level float64) float64 {
+	if ind.profile == nil {
+		return level
+	}
+	return anchor(level, ind.profile.hygiene)
+}
+
+// profileCompliance returns today's updated compliance of ind after its profile's pull.
+func profileCompliance(ind *Individual, level float64) float64 {
+	if ind.profile == nil {
+		return level
+	}
+	return anchor(level, ind.profile.compliance)
+}
+
+// profileMobility returns the multiplier of ind's profile on its step lengths
+// and transit chances.
+func profileMobility(ind *Individual) float64 {
+	if ind.profile == nil {
+		return 1
+	}
+	return ind.profile.mobility
+}
+
+// profileAcceptance returns the base vaccine acceptance of ind, given the
+// default one.
+func profileAcceptance(ind *Individual, base float64) float64 {
+	if ind.profile == nil {
+		return base
+	}
+	return ind.profile.vaccineAcceptance
+}
+
+// profileStatsHeader returns the per-profile stats columns.
+func profileStatsHeader(env *Environment) string {
+	out := ""
+	for _, spec := range env.profiles {
+		n := spec.name
+		out += fmt.Sprintf(", %s_Healthy, %s_Susceptible, %s_Infected, %s_Recovered, %s_Dead, %s_Vaccinated", n, n, n, n, n, n)
+	}
+	return out
+}
+
+// collectProfileCounts returns the status counts per profile, in
+// env.profiles order, or nil without profiles.
+func collectProfileCounts(env *Environment) []TagCounts {
+	if len(env.profiles) == 0 {
+		return nil
+	}
+	out := make([]TagCounts, len(env.profiles))
+	for _, ind := range env.population {
+		if ind == nil || ind.profile == nil {
+			continue
+		}
+		i := profileIndex(env, ind.profile)
+		if i < 0 {
+			continue
+		}
+		c := &out[i]
+		if ind.vaccinated && ind.healthStatus != Dead {
+			c.Vaccinated++
+		}
+		switch ind.healthStatus {
+		case Healthy:
+			c.Healthy++
+		case Susceptible:
+			c.Susceptible++
+		case Infected:
+			c.Infected++
+		case Recovered:
+			c.Recovered++
+		case Dead:
+			c.Dead++
+		}
+	}
+	return out
+}
+
+// profileIndex returns the index of spec in env.profiles, or -1.
+func profileIndex(env *Environment, spec *ProfileSpec) int {
+	for i, p := range env.profiles {
+		if p == spec {
+			return i
+		}
+	}
+	return -1
+}
+
+// printProfileSummary compares the behavior profiles at the end of the run:
+// size, share ever infected, deaths, vaccination and today's mean behavior.
+func printProfileSummary(env *Environment) {
+	if len(env.profiles) == 0 {
+		return
+	}
+	type tally struct {
+		n, infected, dead, vaccinated int
+		hygiene, compliance           float64
+	}
+	rows := make([]tally, len(env.profiles)+1) // the last row is the default behavior
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		i := len(env.profiles)
+		if ind.profile != nil {
+			if i = profileIndex(env, ind.profile); i < 0 {
+				continue
+			}
+		}
+		r := &rows[i]
+		r.n++
+		if ind.timesInfected > 0 {
+			r.infected++
+		}
+		if ind.healthStatus == Dead {
+			r.dead++
+			continue
+		}
+		if ind.vaccinated {
+			r.vaccinated++
+		}
+		r.hygiene += ind.hygieneLevel
+		r.compliance += ind.socialDistanceCompliance
+	}
+	fmt.Fprintln(msgOut, "Behavior profiles:")
+	fmt.Fprintf(msgOut, "  %-16s %7s %9s %7s %11s %8s %11s\n", "Profile", "People", "Infected", "Dead", "Vaccinated", "Hygiene", "Compliance")
+	for i, r := range rows {
+		name := "(default)"
+		if i < len(env.profiles) {
+			name = env.profiles[i].name
+		}
+		if r.n == 0 {
+			fmt.Fprintf(msgOut, "  %-16s %7d\n", name, 0)
+			continue
+		}
+		alive := float64(max(r.n-r.dead, 1))
+		fmt.Fprintf(msgOut, "  %-16s %7d %8.1f%% %6.1f%% %10.1f%% %8.3f %11.3f\n", name, r.n,
+			100*float64(r.infected)/float64(r.n), 100*float64(r.dead)/float64(r.n),
+			100*float64(r.vaccinated)/alive, r.hygiene/alive, r.compliance/alive)
+	}
+}
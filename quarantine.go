@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Quarantine. With detectionRate > 0, each new infection is detected with
+// that probability detectionDelay days after it starts (an EventDetection on
+// the event queue; it is dropped if the infection ends first). A detected
+// individual goes into isolation with probability quarantineComplianceRate.
+// Isolated individuals do not move or board trains and flights, and everyone
+// they could infect is exposed to them with only quarantineTransmission of
+// the usual per-contact probability. Isolation lasts quarantineDays days
+// (an EventQuarantineEnd), even if the individual recovers sooner. Infections
+// can also be detected by daily testing (see testing.go); each infection is
+// detected at most once.
+
+// QuarantineConfig configures detection and isolation.
+type QuarantineConfig struct {
+	detectionRate  float64 // share of infections that are detected
+	detectionDelay int     // days from infection to detection
+	complianceRate float64 // share of detected individuals who isolate
+	days           int     // days in isolation
+	transmission   float64 // per-contact transmission multiplier from isolated individuals
+	testRate       float64 // share of the living population tested each day, 0 = no testing
+	sensitivity    float64 // chance that a test of an infected individual is positive
+
+	detected    int // infections detected so far
+	quarantined int // isolation periods started so far
+	personDays  int // days spent in isolation so far
+
+	tested         int // tests so far
+	testedInfected int // of them, tests of infected individuals not yet detected
+	falseNegatives int // of those, tests that missed the infection
+
+	today         int // day the daily counts below belong to
+	detectedToday int
+	testedToday   int
+}
+
+// enabled reports whether infections are detected at all.
+func (q QuarantineConfig) enabled() bool { return q.detectionRate > 0 || q.testing() }
+
+// testing reports whether the population is tested every day.
+func (q QuarantineConfig) testing() bool { return q.testRate > 0 }
+
+// startDay resets the daily counts if they belong to an earlier day.
+func (q *QuarantineConfig) startDay(day int) {
+	if q.today != day {
+		q.today, q.detectedToday, q.testedToday = day, 0, 0
+	}
+}
+
+// scheduleDetection decides whether a new infection of ind will be detected
+// and, if so, when.
+func scheduleDetection(env *Environment, ind *Individual, rng *rand.Rand) {
+	q := &env.quarantine
+	if q.detectionRate <= 0 || drawFloat(rng) >= q.detectionRate {
+		return
+	}
+	scheduleEvent(env, float64(env.day+q.detectionDelay), scheduledEvent{
+		kind: EventDetection, ind: ind, infection: ind.infection,
+	})
+}
+
+// detect records the detection of ind's infection on day, traces its
+// contacts (see tracing.go) and, if ind complies, starts (or extends) its
+// isolation. An infection already detected is not detected again.
+func detect(env *Environment, ind *Individual, day int, rng *rand.Rand) {
+	q := &env.quarantine
+	if ind.infection != nil {
+		if ind.infection.detected {
+			return
+		}
+		ind.infection.detected = true
+	}
+	q.startDay(day)
+	q.detected++
+	q.detectedToday++
+	traceContacts(env, ind, day, rng)
+	if decisionDraw(env, ind, DecisionQuarantine, rng) >= q.complianceRate {
+		return
+	}
+	startQuarantine(env, ind, day)
+}
+
+// startQuarantine isolates ind from day for quarantineDays days, extending
+// any quarantine it is already in.
+func startQuarantine(env *Environment, ind *Individual, day int) {
+	q := &env.quarantine
+	q.quarantined++
+	ind.quarantinedUntil = day + q.days
+	scheduleEvent(env, float64(ind.quarantinedUntil), scheduledEvent{kind: EventQuarantineEnd, ind: ind})
+}
+
+// endQuarantine releases ind from isolation, unless a later detection
+// extended it.
+func endQuarantine(ind *Individual, day int) {
+	if ind.quarantinedUntil <= day {
+		ind.quarantinedUntil = 0
+	}
+}
+
+// quarantined reports whether ind is in isolation today.
+func quarantined(ind *Individual) bool {
+	return ind.quarantinedUntil != 0
+}
+
+// quarantineTransmission returns the per-contact transmission multiplier for
+// exposure to source.
+func quarantineTransmission(env *Environment, source *Individual) float64 {
+	if !quarantined(source) {
+		return 1
+	}
+	return env.quarantine.transmission
+}
+
+// countQuarantined returns the number of living individuals in isolation today.
+func countQuarantined(env *Environment) int {
+	if !env.quarantine.enabled() {
+		return 0
+	}
+	n := 0
+	for _, ind := range env.population {
+		if ind != nil && ind.healthStatus != Dead && quarantined(ind) {
+			n++
+		}
+	}
+	return n
+}
+
+// tallyQuarantine adds today's isolated individuals to the person-days total.
+func tallyQuarantine(env *Environment) {
+	env.quarantine.personDays += countQuarantined(env)
+}
+
+// printQuarantineSummary reports detections and isolation at the end of the run.
+func printQuarantineSummary(env *Environment) {
+	q := env.quarantine
+	if !q.enabled() {
+		return
+	}
+	fmt.Fprintf(msgOut, "Quarantine: %d infections detected, %d isolated, %d person-days in isolation\n",
+		q.detected, q.quarantined, q.personDays)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// errorPolicy decides what happens when a daily update returns an error.
+type errorPolicy string
+
+const (
+	PolicyAbort      errorPolicy = "abort"      // stop the run, keep outputs gathered so far
+	PolicySkipDay    errorPolicy = "skip"       // log the error, skip the rest of that day, continue
+	PolicyCheckpoint errorPolicy = "checkpoint" // dump agent state to disk, then abort
+)
+
+// handleDayError logs a failed daily update and applies the error policy.
+// It returns true if the run should stop. Partial outputs are flushed by the caller.
+func handleDayError(policy errorPolicy, env *Environment, outputDir string, day int, stage string, err error) bool {
+	fmt.Fprintf(msgOut, "error in %s on day %d: %v\n", stage, day, err)
+
+	switch policy {
+	case PolicySkipDay:
+		fmt.Fprintf(msgOut, "skipping the rest of day %d\n", day)
+		return false
+	case PolicyCheckpoint:
+		path, cpErr := writeCheckpointCSV(env, outputDir, day)
+		if cpErr != nil {
+			fmt.Fprintf(msgOut, "failed to write checkpoint: %v\n", cpErr)
+		} else {
+			fmt.Fprintln(msgOut, "Checkpoint saved to:", path)
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// writeCheckpointCSV dumps the state of every individual to
+// outputDir/checkpoint_day<day>.csv, for inspecting a failed run (.gz with
+// compressOutputs).
+func writeCheckpointCSV(env *Environment, outputDir string, day int) (string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(outputDir, env.outputName(fmt.Sprintf("checkpoint_day%d.csv", day)))
+	w, err := createOutput(path)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintln(w, "ID, Age, Gender, HealthStatus, Severity, DaysInfected, DaysSinceRecovery, Vaccinated, DaysSinceVaccination, Hygiene, Compliance, MoveType, X, Y")
+	for i, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		mt := moveType("")
+		if ind.movementPattern != nil {
+			mt = ind.movementPattern.moveType
+		}
+		severity, daysInfected := Severity(""), 0
+		if ind.infection != nil {
+			severity, daysInfected = ind.infection.severity, ind.infection.daysInfected
+		}
+		fmt.Fprintf(w, "%d, %d, %s, %s, %s, %d, %d, %v, %d, %.4f, %.4f, %s, %.4f, %.4f\n",
+			i, ind.age, ind.gender, ind.healthStatus, severity, daysInfected, ind.daysSinceRecovery,
+			ind.vaccinated, ind.daysSinceVacination, ind.hygieneLevel, ind.socialDistanceCompliance,
+			mt, ind.position.x, ind.position.y)
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Effective reproduction number. With reportReff = true, the transmission log
+// of chains.go is kept (whether or not chainStats is set) and R_effective is
+// computed by date of infection: R_eff(t) is the mean number of secondary
+// cases caused by the infections that started on day t. This is the case
+// reproduction number; it tells how many people someone infected on day t
+// went on to infect, so it is only final once those infections have ended.
+// The values are printed at the end of the run, with the first day whose
+// cohort still has ongoing infections, and added to the stats file as the
+// Reff column (empty on days without infections).
+
+// reffDay is R_effective for the infections that started on one day.
+type reffDay struct {
+	infections int
+	secondary  int
+}
+
+// value returns the mean secondary cases, or false without infections.
+func (r reffDay) value() (float64, bool) {
+	if r.infections == 0 {
+		return 0, false
+	}
+	return float64(r.secondary) / float64(r.infections), true
+}
+
+// reffByDay returns R_effective for days 0 to lastDay, and the first day
+// whose infections are not all over (lastDay+1 if none).
+func reffByDay(env *Environment, lastDay int) ([]reffDay, int) {
+	out := make([]reffDay, lastDay+1)
+	events := env.chains.events
+	for _, t := range events {
+		if t.day >= 0 && t.day <= lastDay {
+			out[t.day].infections++
+			out[t.day].secondary += t.offspring
+		}
+	}
+	provisional := lastDay + 1
+	for _, ind := range env.population {
+		if ind == nil || ind.infection == nil || ind.healthStatus == Dead {
+			continue
+		}
+		if k := ind.infection.chain; k >= 0 && k < len(events) {
+			provisional = min(provisional, events[k].day)
+		}
+	}
+	return out, provisional
+}
+
+// fillReff sets the Reff field of the stats rows.
+func fillReff(env *Environment, rows []DayStats) {
+	if !env.reportReff || len(rows) == 0 {
+		return
+	}
+	byDay, _ := reffByDay(env, rows[len(rows)-1].Day)
+	for i := range rows {
+		if d := rows[i].Day; d >= 0 && d < len(byDay) {
+			if v, ok := byDay[d].value(); ok {
+				rows[i].Reff = &v
+			}
+		}
+	}
+}
+
+// reffHeader returns the Reff column of the stats file header, if enabled.
+func reffHeader(env *Environment) string {
+	if !env.reportReff {
+		return ""
+	}
+	return ", Reff"
+}
+
+// reffColumn returns the row's Reff column for the stats file, if enabled.
+func reffColumn(env *Environment, s DayStats) string {
+	if !env.reportReff {
+		return ""
+	}
+	if s.Reff == nil {
+		return ", "
+	}
+	return fmt.Sprintf(", %.3f", *s.Reff)
+}
+
+// printReffSummary prints R_effective by date of infection, ten days a line.
+func printReffSummary(env *Environment) {
+	if !env.reportReff {
+		return
+	}
+	byDay, provisional := reffByDay(env, env.day)
+	fmt.Fprintln(msgOut, "R_effective by date of infection (mean secondary cases of the infections that started each day):")
+	for start := 0; start < len(byDay); start += 10 {
+		var line strings.Builder
+		fmt.Fprintf(&line, "  Day %4d:", start)
+		for d := start; d < min(start+10, len(byDay)); d++ {
+			if v, ok := byDay[d].value(); ok {
+				fmt.Fprintf(&line, " %5.2f", v)
+			} else {
+				line.WriteString("     -")
+			}
+		}
+		fmt.Fprintln(msgOut, line.String())
+	}
+	if provisional <= env.day {
+		fmt.Fprintf(msgOut, "  From day %d on, some infections are still ongoing, so R_effective is provisional (too low)\n", provisional)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// Regions.
+//
+// Each [region.NAME] block in the config adds a region, such as a city.
+// Together the regions split the population: popSize is divided over them in
+// proportion to their population weights, in config order. Every region has
+// its own copy of the area, so individuals only meet, infect and trace those
+// in the same region (housemates and co-passengers on a train or flight
+// excepted). Train and flight trips connect the regions: a trip from region A
+// ends in region B with probability travel.B of A's block, so the travel
+// lines of all blocks make up the travel matrix. Individuals without a home
+// settle where they land. Those with a home (see households.go) are back in
+// its region on their next move, so their trips are day trips.
+//
+// A region's initialInfected individuals are infected among its residents at
+// the start, on top of the global initialInfected, which are spread over all
+// regions. The stats gain Population (the living present), Infected,
+// Recovered and Dead (those who died there) columns per region, the spatial
+// map shows one panel per region, and the final summary gives each region's
+// infections and deaths.
+
+// Region is a region defined in a [region.NAME] block.
+type Region struct {
+	name            string
+	weight          float64            // relative size of its population
+	initialInfected int                // infections seeded among its residents
+	travel          map[string]float64 // destination -> chance a train/flight trip ends there
+	trips           []regionTrip       // travel resolved to region indices, see resolveTravel
+}
+
+// regionTrip is one entry of a region's row of the travel matrix.
+type regionTrip struct {
+	to int
+	p  float64
+}
+
+// RegionCounts holds the counts of one region on one day.
+type RegionCounts struct {
+	Population int `json:"population"` // living individuals present
+	Infected   int `json:"infected"`
+	Recovered  int `json:"recovered"`
+	Dead       int `json:"dead"` // cumulative deaths in the region
+}
+
+// region returns the config's region with the given name, creating it if
+// needed.
+func (c *Config) region(name string) *Region {
+	for _, r := range c.regions {
+		if r.name == name {
+			return r
+		}
+	}
+	r := &Region{name: name, weight: 1, travel: map[string]float64{}}
+	c.regions = append(c.regions, r)
+	return r
+}
+
+// setTravel stores the travel chance of a "FROM.TO" pair of region names.
+func (c *Config) setTravel(names string, p float64) {
+	from, to, _ := strings.Cut(names, ".")
+	c.region(from).travel[to] = p
+}
+
+// resolveTravel checks the travel matrix and resolves its destinations to
+// region indices, in config order.
+func resolveTravel(config *Config, v *ConfigValidator) {
+	index := map[string]int{}
+	for k, r := range config.regions {
+		index[r.name] = k
+	}
+	for _, r := range config.regions {
+		key := "[region." + r.name + "] travel."
+		r.trips = nil
+		total := 0.0
+		for _, to := range config.regions {
+			p, ok := r.travel[to.name]
+			if !ok {
+				continue
+			}
+			if to == r {
+				v.AddError(key+to.name, fmt.Sprintf("%g", p), "a region cannot travel to itself")
+				continue
+			}
+			r.trips = append(r.trips, regionTrip{to: index[to.name], p: p})
+			total += p
+		}
+		for _, name := range slices.Sorted(maps.Keys(r.travel)) {
+			if _, ok := index[name]; !ok {
+				v.AddError(key+name, fmt.Sprintf("%g", r.travel[name]), "is not a region (add a [region."+name+"] block)")
+			}
+		}
+		if total > 1 {
+			v.AddError("[region."+r.name+"] travel", fmt.Sprintf("%g", total), "travel chances must add up to at most 1")
+		}
+	}
+}
+
+// assignRegions splits the population over the regions in proportion to
+// their weights, in population order.
+func assignRegions(env *Environment) {
+	if len(env.regions) == 0 {
+		return
+	}
+	env.regionInfections = make([]int, len(env.regions))
+	total := 0.0
+	for _, r := range env.regions {
+		total += r.weight
+	}
+	n := len(env.population)
+	lo, cum := 0, 0.0
+	for k, r := range env.regions {
+		cum += r.weight
+		hi := n
+		if k < len(env.regions)-1 {
+			hi = min(int(math.Round(cum/total*float64(n))), n)
+		}
+		for _, ind := range env.population[lo:hi] {
+			if ind != nil {
+				ind.region = k
+			}
+		}
+		lo = max(lo, hi)
+	}
+}
+
+// seedRegions infects each region's initialInfected individuals, chosen at
+// random among its residents who are not yet infected.
+func seedRegions(env *Environment, rng *rand.Rand) {
+	for k, r := range env.regions {
+		if r.initialInfected <= 0 {
+			continue
+		}
+		var residents []*Individual
+		for _, ind := range env.population {
+			if ind == nil || ind.region != k {
+				continue
+			}
+			if s := ind.healthStatus; s != Dead && s != Infected && s != Exposed {
+				residents = append(residents, ind)
+			}
+		}
+		n := min(r.initialInfected, len(residents))
+		for _, i := range rng.Perm(len(residents))[:n] {
+			infect(env, residents[i], env.disease, rng)
+			countAdmission(env, residents[i])
+		}
+	}
+}
+
+// homeRegion returns the region ind returns to: that of its home, or the
+// one it is in if it has none.
+func homeRegion(ind *Individual) int {
+	if ind.home != nil {
+		return ind.home.region
+	}
+	return ind.region
+}
+
+// changeRegion moves ind between regions at the start of a move: back to
+// the region of its home, then to the destination of today's train or
+// flight trip, if it leads to another region.
+func changeRegion(env *Environment, ind *Individual, rng *rand.Rand) {
+	if len(env.regions) < 2 {
+		return
+	}
+	ind.region = homeRegion(ind)
+	trips := env.regions[ind.region].trips
+	if len(trips) == 0 || ind.movementPattern == nil {
+		return
+	}
+	if mt := ind.movementPattern.moveType; mt != Train && mt != Flight {
+		return
+	}
+	u := rng.Float64()
+	for _, t := range trips {
+		if u < t.p {
+			ind.region = t.to
+			return
+		}
+		u -= t.p
+	}
+}
+
+// sameRegion reports whether a and b are in the same region today.
+func sameRegion(a, b *Individual) bool { return a.region == b.region }
+
+// countRegionInfection counts a new infection of ind in its region.
+func countRegionInfection(env *Environment, ind *Individual) {
+	if len(env.regionInfections) > 0 {
+		env.regionInfections[ind.region]++
+	}
+}
+
+// collectRegionCounts returns today's counts per region.
+func collectRegionCounts(env *Environment) []RegionCounts {
+	if len(env.regions) == 0 {
+		return nil
+	}
+	out := make([]RegionCounts, len(env.regions))
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		c := &out[ind.region]
+		switch ind.healthStatus {
+		case Dead:
+			c.Dead++
+			continue
+		case Infected, Exposed:
+			c.Infected++
+		case Recovered:
+			c.Recovered++
+		}
+		c.Population++
+	}
+	return out
+}
+
+// regionStatsHeader returns the per-region stats columns.
+func regionStatsHeader(env *Environment) string {
+	out := ""
+	for _, r := range env.regions {
+		n := r.name
+		out += fmt.Sprintf(", %s_Population, %s_Infected, %s_Recovered, %s_Dead", n, n, n, n)
+	}
+	return out
+}
+
+// regionPanels returns the layout of the spatial map with one square panel
+// per region: the number of columns and the panel width in pixels. Without
+// regions the whole canvas is one panel.
+func regionPanels(env *Environment, canvasWidth int) (cols int, panel float64) {
+	if len(env.regions) < 2 {
+		return 1, float64(canvasWidth)
+	}
+	cols = int(math.Ceil(math.Sqrt(float64(len(env.regions)))))
+	return cols, float64(canvasWidth) / float64(cols)
+}
+
+// regionPixel maps a position in region k to canvas pixels.
+func regionPixel(env *Environment, canvasWidth, k int, p OrderedPair) (float64, float64) {
+	cols, panel := regionPanels(env, canvasWidth)
+	ox, oy := float64(k%cols)*panel, float64(k/cols)*panel
+	return ox + p.x/env.areaSize*panel, oy + p.y/env.areaSize*panel
+}
+
+// printRegionSummary reports each region's infections and deaths over the
+// run.
+func printRegionSummary(env *Environment) {
+	if len(env.regions) == 0 {
+		return
+	}
+	counts := collectRegionCounts(env)
+	fmt.Fprintln(msgOut, "Regions:")
+	fmt.Fprintf(msgOut, "  %-16s %10s %10s %7s\n", "Region", "Population", "Infections", "Deaths")
+	for k, r := range env.regions {
+		fmt.Fprintf(msgOut, "  %-16s %10d %10d %7d\n", r.name, counts[k].Population, env.regionInfections[k], counts[k].Dead)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+// Rendering schedule. Every renderer (the spatial/pie frames, the coverage
+// map, ...) is registered with a RenderScheduler together with how often it
+// runs, instead of each having its own day%N check in the main loop. Besides
+// the regular frequency the scheduler supports:
+//   - bursts: after a policy tightening, burst renderers capture every day
+//     for burstFrames days, so the response is visible frame by frame;
+//   - final-frame forcing: with finalFrame, every renderer captures the last
+//     simulated day even if it is not on its schedule.
+// A renderer never runs twice for the same day. Renderers run on a snapshot
+// of the day in the background (see snapshot.go); which renderers are due is
+// decided on the simulation's goroutine.
+
+// scheduledRenderer is one renderer and its schedule.
+type scheduledRenderer struct {
+	every   int  // capture every N days
+	burst   bool // also capture during policy bursts
+	capture func(env *Environment)
+	last    int // last day captured, -1 if none
+}
+
+// RenderScheduler owns which renderers run on which days.
+type RenderScheduler struct {
+	renderers  []*scheduledRenderer
+	burstDays  int  // days of daily capture after a policy tightening, 0 = off
+	burstUntil int  // last day of the current burst, -1 if none
+	forceFinal bool // capture the final day with every renderer
+	pipeline   *renderPipeline
+}
+
+// newRenderScheduler returns a scheduler with no renderers.
+func newRenderScheduler(burstDays int, forceFinal bool) *RenderScheduler {
+	return &RenderScheduler{burstDays: burstDays, burstUntil: -1, forceFinal: forceFinal, pipeline: newRenderPipeline()}
+}
+
+// add registers a renderer that runs every `every` days (day 0 included). If
+// burst is set it also runs every day of a policy burst.
+func (s *RenderScheduler) add(every int, burst bool, capture func(env *Environment)) {
+	if every <= 0 {
+		every = 1
+	}
+	s.renderers = append(s.renderers, &scheduledRenderer{every: every, burst: burst, capture: capture, last: -1})
+}
+
+// policyTightened starts (or extends) a burst on day.
+func (s *RenderScheduler) policyTightened(day int) {
+	if s.burstDays > 0 {
+		s.burstUntil = max(s.burstUntil, day+s.burstDays-1)
+	}
+}
+
+// run captures with every renderer due on env.day.
+func (s *RenderScheduler) run(env *Environment) {
+	day := env.day
+	var due []*scheduledRenderer
+	for _, r := range s.renderers {
+		if day%r.every == 0 || r.burst && day <= s.burstUntil {
+			due = s.claim(due, r, day)
+		}
+	}
+	s.capture(due, env)
+}
+
+// finish forces a final capture of env.day, if finalFrame is set, and waits
+// until every capture has been drawn.
+func (s *RenderScheduler) finish(env *Environment) {
+	if s.forceFinal {
+		var due []*scheduledRenderer
+		for _, r := range s.renderers {
+			due = s.claim(due, r, env.day)
+		}
+		s.capture(due, env)
+	}
+	s.pipeline.close()
+}
+
+// claim adds r to due unless it already captured day.
+func (s *RenderScheduler) claim(due []*scheduledRenderer, r *scheduledRenderer, day int) []*scheduledRenderer {
+	if r.last == day {
+		return due
+	}
+	r.last = day
+	return append(due, r)
+}
+
+// capture snapshots env and runs the due renderers on it in the background.
+func (s *RenderScheduler) capture(due []*scheduledRenderer, env *Environment) {
+	if len(due) == 0 {
+		return
+	}
+	snap := takeSnapshot(env)
+	s.pipeline.do(func() {
+		for _, r := range due {
+			r.capture(snap.env)
+		}
+	})
+}
+
+// after queues job to run once the captures queued so far are drawn, for
+// work on the renderers' output such as thinning old frames.
+func (s *RenderScheduler) after(job func()) {
+	s.pipeline.do(job)
+}
+
+// wait blocks until every queued capture has been drawn, before the
+// renderers' output is read on the simulation's goroutine.
+func (s *RenderScheduler) wait() {
+	s.pipeline.wait()
+}
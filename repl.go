@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Interactive inspection. With -repl, the run stops before day 1 (or the day
+// after a resumed state), between days and after the last day to read
+// commands from the terminal:
+//
+//	count [STATUS] [FILTER...]   count individuals, e.g. count infected age>65
+//	show agent ID                print the full state of one individual
+//	set [KEY VALUE]              change a parameter, or list the ones that can be changed
+//	run [N]                      simulate N more days (default 1)
+//	continue                     run to the end without stopping
+//	quit                         end the run here and write the outputs
+//
+// STATUS is a health status, vaccinated, quarantined, hospitalized or all.
+// A FILTER compares a field (age, id, x, y, hygiene, compliance,
+// daysInfected, timesInfected) with <, <=, >, >=, = or !=, or matches
+// tag=NAME or gender=VALUE; all filters must hold. The end of input works
+// like continue.
+
+// replSettable lists the parameters set can change, and how a value parsed
+// into a Config is carried over to the running environment. Parameters that
+// add or remove output columns cannot be changed mid-run.
+var replSettable = map[string]func(env *Environment, c *Config){
+	"transmissionRate":        func(env *Environment, c *Config) { env.disease.transmissionRate = c.transmissionRate },
+	"socialDistanceThreshold": func(env *Environment, c *Config) { env.socialDistanceThreshold = c.socialDistanceThreshold },
+	"hygieneLevel":            func(env *Environment, c *Config) { env.hygieneLevel = c.hygieneLevel },
+	"mobilityRate":            func(env *Environment, c *Config) { env.mobilityRate = c.mobilityRate },
+	"vaccinationRate":         func(env *Environment, c *Config) { env.vaccinationRate = c.vaccinationRate },
+	"medicalCareLevel":        func(env *Environment, c *Config) { env.medicalCareLevel = c.medicalCareLevel },
+	"lockdownRelease":         func(env *Environment, c *Config) { env.lockdown.release = c.lockdown.release },
+	"lockdownMobility":        func(env *Environment, c *Config) { env.lockdown.mobility = c.lockdown.mobility },
+	"lockdownMinDays":         func(env *Environment, c *Config) { env.lockdown.minDays = c.lockdown.minDays },
+}
+
+// replSettings returns the current value of each settable parameter.
+func replSettings(env *Environment) map[string]string {
+	return map[string]string{
+		"transmissionRate":        fmt.Sprintf("%g", env.disease.transmissionRate),
+		"socialDistanceThreshold": fmt.Sprintf("%g", env.socialDistanceThreshold),
+		"hygieneLevel":            fmt.Sprintf("%g", env.hygieneLevel),
+		"mobilityRate":            fmt.Sprintf("%g", env.mobilityRate),
+		"vaccinationRate":         fmt.Sprintf("%g", env.vaccinationRate),
+		"medicalCareLevel":        fmt.Sprintf("%g", env.medicalCareLevel),
+		"lockdownRelease":         fmt.Sprintf("%g", env.lockdown.release),
+		"lockdownMobility":        fmt.Sprintf("%g", env.lockdown.mobility),
+		"lockdownMinDays":         strconv.Itoa(env.lockdown.minDays),
+	}
+}
+
+// repl reads commands between days. A nil *repl never stops the run.
+type repl struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	until   int  // the next stop is at the end of this day
+	running bool // continue was given, or the input ended
+}
+
+// newRepl returns a repl reading commands from in and answering on out.
+func newRepl(in io.Reader, out io.Writer) *repl {
+	return &repl{in: bufio.NewScanner(in), out: out}
+}
+
+// pause reads commands if the run should stop at the end of env.day. It
+// returns false if the run should end here.
+func (r *repl) pause(env *Environment) bool {
+	if r == nil || r.running || env.day < r.until {
+		return true
+	}
+	for {
+		fmt.Fprintf(r.out, "day %d> ", env.day)
+		if !r.in.Scan() {
+			fmt.Fprintln(r.out)
+			r.running = true
+			return true
+		}
+		fields := strings.Fields(r.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch cmd, args := fields[0], fields[1:]; cmd {
+		case "count":
+			r.count(env, args)
+		case "show":
+			r.show(env, args)
+		case "set":
+			r.set(env, args)
+		case "run":
+			days := 1
+			if len(args) > 0 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					fmt.Fprintf(r.out, "run: invalid number of days %q\n", args[0])
+					continue
+				}
+				days = n
+			}
+			r.until = env.day + days
+			return true
+		case "continue":
+			r.running = true
+			return true
+		case "quit", "exit":
+			r.running = true
+			return false
+		case "help":
+			fmt.Fprintln(r.out, "commands: count [STATUS] [FILTER...], show agent ID, set [KEY VALUE], run [N], continue, quit")
+		default:
+			fmt.Fprintf(r.out, "unknown command %q (try help)\n", cmd)
+		}
+	}
+}
+
+// last stops once more at the end of the run, unless the run was continued
+// or quit; any of run, continue and quit then writes the outputs.
+func (r *repl) last(env *Environment) {
+	if r == nil || r.running {
+		return
+	}
+	fmt.Fprintf(r.out, "end of the run on day %d\n", env.day)
+	r.until = env.day
+	r.pause(env)
+}
+
+// count prints the number of individuals matching a status and filters.
+func (r *repl) count(env *Environment, args []string) {
+	status := "all"
+	if len(args) > 0 && !strings.ContainsAny(args[0], "<>=!") {
+		status, args = strings.ToLower(args[0]), args[1:]
+	}
+	match, err := replStatus(status)
+	if err != nil {
+		fmt.Fprintln(r.out, "count:", err)
+		return
+	}
+	filters := make([]func(*Individual) bool, len(args))
+	for i, arg := range args {
+		if filters[i], err = replFilter(arg); err != nil {
+			fmt.Fprintln(r.out, "count:", err)
+			return
+		}
+	}
+	n := 0
+	for _, ind := range env.population {
+		if ind == nil || !match(ind) {
+			continue
+		}
+		if !slices.ContainsFunc(filters, func(f func(*Individual) bool) bool { return !f(ind) }) {
+			n++
+		}
+	}
+	fmt.Fprintln(r.out, n)
+}
+
+// replStatus returns the test for a count STATUS.
+func replStatus(status string) (func(*Individual) bool, error) {
+	switch status {
+	case "all":
+		return func(*Individual) bool { return true }, nil
+	case "vaccinated":
+		return func(ind *Individual) bool { return ind.vaccinated && ind.healthStatus != Dead }, nil
+	case "quarantined":
+		return func(ind *Individual) bool { return quarantined(ind) && ind.healthStatus != Dead }, nil
+	case "hospitalized":
+		return func(ind *Individual) bool { return ind.inHospital && ind.healthStatus != Dead }, nil
+	}
+	for _, s := range []HealthStatus{Healthy, Susceptible, Exposed, Infected, Recovered, Dead} {
+		if strings.EqualFold(status, string(s)) {
+			return func(ind *Individual) bool { return ind.healthStatus == s }, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown status %q", status)
+}
+
+// replFields are the numeric fields a filter can compare.
+var replFields = map[string]func(*Individual) float64{
+	"age":           func(ind *Individual) float64 { return float64(ind.age) },
+	"id":            func(ind *Individual) float64 { return float64(ind.id) },
+	"x":             func(ind *Individual) float64 { return ind.position.x },
+	"y":             func(ind *Individual) float64 { return ind.position.y },
+	"hygiene":       func(ind *Individual) float64 { return ind.hygieneLevel },
+	"compliance":    func(ind *Individual) float64 { return ind.socialDistanceCompliance },
+	"timesInfected": func(ind *Individual) float64 { return float64(ind.timesInfected) },
+	"daysInfected": func(ind *Individual) float64 {
+		if ind.infection == nil {
+			return 0
+		}
+		return float64(ind.infection.daysInfected)
+	},
+}
+
+// replFilter parses a filter such as age>65 or tag=NAME.
+func replFilter(arg string) (func(*Individual) bool, error) {
+	i := strings.IndexAny(arg, "<>=!")
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid filter %q (want FIELD<VALUE, FIELD=VALUE, ...)", arg)
+	}
+	field, rest := arg[:i], arg[i:]
+	op := rest[:1]
+	if len(rest) > 1 && rest[1] == '=' {
+		op = rest[:2]
+	}
+	value := rest[len(op):]
+
+	switch field {
+	case "tag":
+		if op != "=" && op != "!=" {
+			return nil, fmt.Errorf("tag takes = or !=")
+		}
+		return func(ind *Individual) bool { return ind.hasTag(value) == (op == "=") }, nil
+	case "gender":
+		if op != "=" && op != "!=" {
+			return nil, fmt.Errorf("gender takes = or !=")
+		}
+		return func(ind *Individual) bool { return strings.EqualFold(ind.gender, value) == (op == "=") }, nil
+	}
+	get, ok := replFields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for %s", value, field)
+	}
+	switch op {
+	case "<":
+		return func(ind *Individual) bool { return get(ind) < v }, nil
+	case "<=":
+		return func(ind *Individual) bool { return get(ind) <= v }, nil
+	case ">":
+		return func(ind *Individual) bool { return get(ind) > v }, nil
+	case ">=":
+		return func(ind *Individual) bool { return get(ind) >= v }, nil
+	case "=", "==":
+		return func(ind *Individual) bool { return get(ind) == v }, nil
+	case "!=":
+		return func(ind *Individual) bool { return get(ind) != v }, nil
+	}
+	return nil, fmt.Errorf("invalid operator in %q", arg)
+}
+
+// show prints the full state of one individual.
+func (r *repl) show(env *Environment, args []string) {
+	if len(args) != 2 || args[0] != "agent" {
+		fmt.Fprintln(r.out, "usage: show agent ID")
+		return
+	}
+	id, err := strconv.Atoi(args[1])
+	if err != nil || id < 0 || id >= len(env.population) || env.population[id] == nil {
+		fmt.Fprintf(r.out, "show: no individual %q (popSize %d)\n", args[1], len(env.population))
+		return
+	}
+	ind := env.population[id]
+	fmt.Fprintf(r.out, "agent %d: %s, age %d, %s\n", id, ind.gender, ind.age, ind.healthStatus)
+	fmt.Fprintf(r.out, "  position (%.2f, %.2f)", ind.position.x, ind.position.y)
+	if ind.movementPattern != nil {
+		fmt.Fprintf(r.out, ", moving by %s", ind.movementPattern.moveType)
+	}
+	fmt.Fprintln(r.out)
+	if inf := ind.infection; inf != nil {
+		fmt.Fprintf(r.out, "  infected %d days (%s", inf.daysInfected, inf.severity)
+		if inf.detected {
+			fmt.Fprint(r.out, ", detected")
+		}
+		fmt.Fprintln(r.out, ")")
+	}
+	fmt.Fprintf(r.out, "  infections %d, days since recovery %d\n", ind.timesInfected, ind.daysSinceRecovery)
+	fmt.Fprintf(r.out, "  vaccinated %v, days since vaccination %d\n", ind.vaccinated, ind.daysSinceVacination)
+	fmt.Fprintf(r.out, "  hygiene %.3f, compliance %.3f\n", ind.hygieneLevel, ind.socialDistanceCompliance)
+	if len(ind.tags) > 0 {
+		fmt.Fprintf(r.out, "  tags %s\n", strings.Join(ind.tags, ", "))
+	}
+	if ind.profile != nil {
+		fmt.Fprintf(r.out, "  profile %s\n", ind.profile.name)
+	}
+	if ind.inHospital || ind.waitingForBed {
+		fmt.Fprintf(r.out, "  in hospital %v, waiting for a bed %v\n", ind.inHospital, ind.waitingForBed)
+	}
+	if quarantined(ind) {
+		fmt.Fprintf(r.out, "  in quarantine until day %d\n", ind.quarantinedUntil)
+	}
+	if ind.healthStatus == Dead {
+		fmt.Fprintf(r.out, "  died on day %d\n", ind.deathDay)
+	}
+}
+
+// set changes a parameter of the running simulation, checking the value
+// against the config schema. Without arguments it lists the settable
+// parameters and their current values.
+func (r *repl) set(env *Environment, args []string) {
+	if len(args) == 0 {
+		values := replSettings(env)
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			fmt.Fprintf(r.out, "  %s = %s\n", key, values[key])
+		}
+		return
+	}
+	if len(args) != 2 {
+		fmt.Fprintln(r.out, "usage: set KEY VALUE")
+		return
+	}
+	key, value := args[0], args[1]
+	if _, ok := replSettable[key]; !ok {
+		fmt.Fprintf(r.out, "set: %s cannot be changed during a run (set without arguments lists the ones that can)\n", key)
+		return
+	}
+	if err := setParameter(env, key, value); err != nil {
+		fmt.Fprintln(r.out, "set:", err)
+		return
+	}
+	fmt.Fprintf(r.out, "%s = %s\n", key, replSettings(env)[key])
+}
+
+// setParameter changes a parameter of the running simulation (one of
+// replSettable), checking the value against the config schema. It is shared
+// by -repl and the -serve dashboard.
+func setParameter(env *Environment, key, value string) error {
+	carry, ok := replSettable[key]
+	p, _ := lookupParam(key)
+	if !ok || p == nil {
+		return fmt.Errorf("%s cannot be changed during a run", key)
+	}
+	c := getDefaultConfig()
+	v := NewConfigValidator()
+	p.apply(c, v, key, "", value)
+	if v.HasErrors() {
+		return fmt.Errorf("invalid value '%s' for %s: %s", value, key, v.errors[0].Message)
+	}
+	carry(env, c)
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+)
+
+// Monte Carlo replicates. A single stochastic run can be far from typical, so
+// with numReplicates = R > 1 the same config is run R times: the main run
+// with the run's stream, then R-1 more with the next streams of its seed
+// (rerun replicate k alone with -stream k-1; see rng.go). The extra runs are simulated like
+// scenarios (see scenarios.go), without frames or per-run outputs. For every
+// day and every main statistic, output_gif/replicates.csv gives the mean, the
+// median and the central 95% interval over the replicates, and the console
+// shows the spread of the main outcomes. With epidemicCurve on,
+// output_gif/replicates.png also plots every replicate's infected curve with
+// the mean and the 95% band.
+
+// replicateColumn is a daily statistic summarized over replicates.
+type replicateColumn struct {
+	name string
+	get  func(DayStats) float64
+}
+
+var replicateColumns = []replicateColumn{
+	{"Healthy", func(r DayStats) float64 { return float64(r.Healthy) }},
+	{"Susceptible", func(r DayStats) float64 { return float64(r.Susceptible) }},
+	{"Exposed", func(r DayStats) float64 { return float64(r.Exposed) }},
+	{"Infected", func(r DayStats) float64 { return float64(r.Infected) }},
+	{"Recovered", func(r DayStats) float64 { return float64(r.Recovered) }},
+	{"Dead", func(r DayStats) float64 { return float64(r.Dead) }},
+	{"Vaccinated", func(r DayStats) float64 { return float64(r.Vaccinated) }},
+	{"NewInfections", func(r DayStats) float64 { return float64(r.NewInfections) }},
+	{"NewDeaths", func(r DayStats) float64 { return float64(r.NewDeaths) }},
+	{"WardOccupied", func(r DayStats) float64 { return float64(r.WardOccupied) }},
+	{"ICUOccupied", func(r DayStats) float64 { return float64(r.ICUOccupied) }},
+}
+
+// band is the distribution of a value over replicates.
+type band struct {
+	mean, median, lo, hi float64 // lo and hi bound the central 95%
+}
+
+// bandOf returns the band of values; it sorts values.
+func bandOf(values []float64) band {
+	if len(values) == 0 {
+		return band{}
+	}
+	slices.Sort(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return band{
+		mean:   sum / float64(len(values)),
+		median: quantile(values, 0.5),
+		lo:     quantile(values, 0.025),
+		hi:     quantile(values, 0.975),
+	}
+}
+
+// quantile returns the q-quantile of sorted values, interpolating linearly
+// between neighbors.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	f := pos - float64(i)
+	return sorted[i]*(1-f) + sorted[i+1]*f
+}
+
+// dayBands returns, for every day any replicate reached, the number of
+// replicates with that day and the band of each replicateColumns statistic.
+func dayBands(results []scenarioResult) (days []int, counts []int, bands [][]band) {
+	byDay := map[int][]DayStats{}
+	for _, res := range results {
+		for _, r := range res.rows {
+			if _, ok := byDay[r.Day]; !ok {
+				days = append(days, r.Day)
+			}
+			byDay[r.Day] = append(byDay[r.Day], r)
+		}
+	}
+	slices.Sort(days)
+	for _, day := range days {
+		rows := byDay[day]
+		bs := make([]band, len(replicateColumns))
+		for c, col := range replicateColumns {
+			values := make([]float64, len(rows))
+			for i, r := range rows {
+				values[i] = col.get(r)
+			}
+			bs[c] = bandOf(values)
+		}
+		counts = append(counts, len(rows))
+		bands = append(bands, bs)
+	}
+	return days, counts, bands
+}
+
+// runReplicates runs the replicates after the main run, whose result is
+// first, then summarizes them all and saves the aggregate to outputDir.
+func runReplicates(config *Config, first scenarioResult, outputDir string) {
+	results := []scenarioResult{first}
+	for k := 2; k <= config.numReplicates; k++ {
+		id := first.rng
+		id.stream += k - 1
+		fmt.Fprintf(msgOut, "Running replicate %d of %d (%v)\n", k, config.numReplicates, id)
+		res := simulateConfig(fmt.Sprintf("replicate %d", k), config, id)
+		if res.err != nil {
+			fmt.Fprintf(msgOut, "Replicate %d failed: %v\n", k, res.err)
+		}
+		if len(res.rows) > 0 {
+			results = append(results, res)
+		}
+	}
+	printReplicateSummary(results)
+
+	path := outputDir + "/replicates.csv"
+	if err := saveReplicateBands(path, results); err != nil {
+		fmt.Fprintln(msgOut, "failed to save replicate stats:", err)
+	} else {
+		fmt.Fprintln(msgOut, "Replicate stats saved to:", path)
+	}
+	if config.epidemicCurve {
+		chartPath := outputDir + "/replicates.png"
+		if err := SaveReplicateChart(chartPath, results, config.canvasWidth, config.canvasWidth*3/5); err != nil {
+			fmt.Fprintln(msgOut, "failed to save replicate chart:", err)
+		} else {
+			fmt.Fprintln(msgOut, "Replicate chart saved to:", chartPath)
+		}
+	}
+}
+
+// printReplicateSummary reports the spread of the main outcomes over the
+// replicates.
+func printReplicateSummary(results []scenarioResult) {
+	var peak, peakDay, attack, deaths []float64
+	for _, res := range results {
+		o := res.outcome()
+		peak = append(peak, float64(o.peakInfected))
+		peakDay = append(peakDay, float64(o.peakDay))
+		attack = append(attack, 100*o.attackRate)
+		deaths = append(deaths, float64(o.deaths))
+	}
+	fmt.Fprintf(msgOut, "Replicates: %d runs; mean (median) [95%% interval]\n", len(results))
+	for _, m := range []struct {
+		name   string
+		values []float64
+		format string
+	}{
+		{"Peak infected", peak, "%.0f"},
+		{"Peak day", peakDay, "%.0f"},
+		{"Attack rate %", attack, "%.1f"},
+		{"Deaths", deaths, "%.0f"},
+	} {
+		b := bandOf(m.values)
+		f := m.format
+		fmt.Fprintf(msgOut, "  %-14s "+f+" ("+f+") ["+f+", "+f+"]\n", m.name+":", b.mean, b.median, b.lo, b.hi)
+	}
+}
+
+// saveReplicateBands writes the band of every daily statistic over the
+// replicates, one row per day.
+func saveReplicateBands(path string, results []scenarioResult) error {
+	days, counts, bands := dayBands(results)
+	return writeCSV(path, func(w *bufio.Writer) {
+		fmt.Fprint(w, "Day, Replicates")
+		for _, col := range replicateColumns {
+			n := col.name
+			fmt.Fprintf(w, ", %s_Mean, %s_Median, %s_Lo95, %s_Hi95", n, n, n, n)
+		}
+		fmt.Fprintln(w)
+		for i, day := range days {
+			fmt.Fprintf(w, "%d, %d", day, counts[i])
+			for _, b := range bands[i] {
+				fmt.Fprintf(w, ", %.2f, %.2f, %.2f, %.2f", b.mean, b.median, b.lo, b.hi)
+			}
+			fmt.Fprintln(w)
+		}
+	})
+}
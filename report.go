@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Run reports. With report = markdown or html, the end of a run writes
+// output_gif/report.md or report.html: one self-contained document with the
+// key figures, the summary printed on the console, the epidemic curve, the
+// age-specific outcomes, the GIFs and charts the run saved, and the settings
+// of the config file. Images are embedded as base64 data URIs, so the file can
+// be attached to a write-up on its own. (Some Markdown viewers, GitHub's
+// among them, do not show data URIs; the HTML report shows them everywhere.)
+
+// reportFormat selects the run report's document format.
+type reportFormat string
+
+const (
+	ReportNone     reportFormat = "none"
+	ReportMarkdown reportFormat = "markdown"
+	ReportHTML     reportFormat = "html"
+)
+
+// enabled reports whether a report is written.
+func (f reportFormat) enabled() bool { return f == ReportMarkdown || f == ReportHTML }
+
+// reportFileName returns the report's file name in the output directory.
+func reportFileName(f reportFormat) string {
+	if f == ReportHTML {
+		return "report.html"
+	}
+	return "report.md"
+}
+
+// runReport holds everything that goes into a run report.
+type runReport struct {
+	title    string
+	seed     int64
+	model    string
+	rows     []DayStats
+	summary  string      // the end-of-run summary as printed on the console
+	settings [][2]string // config file keys and values, in file order
+	images   []reportImage
+}
+
+// reportImage is an image embedded in the report.
+type reportImage struct {
+	caption string
+	mime    string
+	data    []byte
+}
+
+// addPNG encodes img and adds it to the report.
+func (r *runReport) addPNG(caption string, img image.Image) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err == nil {
+		r.images = append(r.images, reportImage{caption, "image/png", buf.Bytes()})
+	}
+}
+
+// addFile adds the image file at path, if the run wrote it.
+func (r *runReport) addFile(caption, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	mime := "image/png"
+	if strings.EqualFold(filepath.Ext(path), ".gif") {
+		mime = "image/gif"
+	}
+	r.images = append(r.images, reportImage{caption, mime, data})
+}
+
+// keyFigures returns the label and value of each line of the key figures table.
+func (r *runReport) keyFigures(env *Environment) [][2]string {
+	out := [][2]string{
+		{"Seed", fmt.Sprint(r.seed)},
+		{"Model", r.model},
+		{"Population", fmt.Sprint(len(env.population))},
+	}
+	if len(r.rows) == 0 {
+		return out
+	}
+	last := r.rows[len(r.rows)-1]
+	peak := r.rows[0]
+	for _, s := range r.rows {
+		if s.Infected > peak.Infected {
+			peak = s
+		}
+	}
+	infections := 0
+	for _, ind := range env.population {
+		if ind != nil {
+			infections += ind.timesInfected
+		}
+	}
+	return append(out,
+		[2]string{"Days simulated", fmt.Sprint(last.Day)},
+		[2]string{"Peak infected", fmt.Sprintf("%d on day %d", peak.Infected, peak.Day)},
+		[2]string{"Infections", fmt.Sprint(infections)},
+		[2]string{"Deaths", fmt.Sprint(last.Dead)},
+		[2]string{"Vaccinated at the end", fmt.Sprint(last.Vaccinated)},
+		[2]string{"At the end (H / S / I / R / D)", fmt.Sprintf("%d / %d / %d / %d / %d",
+			last.Healthy, last.Susceptible+last.Exposed, last.Infected, last.Recovered, last.Dead)},
+	)
+}
+
+// SaveReport writes the report to path in the given format.
+func SaveReport(path string, format reportFormat, r *runReport, env *Environment) error {
+	var buf bytes.Buffer
+	if format == ReportHTML {
+		r.writeHTML(&buf, env)
+	} else {
+		r.writeMarkdown(&buf, env)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// dataURI returns img as a data URI.
+func (img reportImage) dataURI() string {
+	return "data:" + img.mime + ";base64," + base64.StdEncoding.EncodeToString(img.data)
+}
+
+func (r *runReport) writeMarkdown(w *bytes.Buffer, env *Environment) {
+	cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+	fmt.Fprintf(w, "# %s\n\n", r.title)
+	fmt.Fprintf(w, "## Key figures\n\n| | |\n|---|---|\n")
+	for _, kv := range r.keyFigures(env) {
+		fmt.Fprintf(w, "| %s | %s |\n", kv[0], cell(kv[1]))
+	}
+	fmt.Fprintf(w, "\n## Summary\n\n```\n%s```\n", r.summary)
+	if len(r.images) > 0 {
+		fmt.Fprintf(w, "\n## Charts\n")
+		for _, img := range r.images {
+			fmt.Fprintf(w, "\n### %s\n\n![%s](%s)\n", img.caption, img.caption, img.dataURI())
+		}
+	}
+	fmt.Fprintf(w, "\n## Configuration\n\n")
+	if len(r.settings) == 0 {
+		fmt.Fprintf(w, "All parameters at their defaults.\n")
+		return
+	}
+	fmt.Fprintf(w, "Parameters not listed are at their defaults (see `-help-config`).\n\n| Parameter | Value |\n|---|---|\n")
+	for _, kv := range r.settings {
+		fmt.Fprintf(w, "| `%s` | %s |\n", kv[0], cell(kv[1]))
+	}
+}
+
+func (r *runReport) writeHTML(w *bytes.Buffer, env *Environment) {
+	e := html.EscapeString
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", e(r.title))
+	fmt.Fprintf(w, "<style>body{font-family:sans-serif;max-width:1000px;margin:2em auto}"+
+		"table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:2px 8px;text-align:left}"+
+		"img{max-width:100%%}pre{background:#f4f4f4;padding:1em;overflow-x:auto}</style>\n</head>\n<body>\n")
+	fmt.Fprintf(w, "<h1>%s</h1>\n<h2>Key figures</h2>\n<table>\n", e(r.title))
+	for _, kv := range r.keyFigures(env) {
+		fmt.Fprintf(w, "<tr><th>%s</th><td>%s</td></tr>\n", e(kv[0]), e(kv[1]))
+	}
+	fmt.Fprintf(w, "</table>\n<h2>Summary</h2>\n<pre>%s</pre>\n", e(r.summary))
+	if len(r.images) > 0 {
+		fmt.Fprintf(w, "<h2>Charts</h2>\n")
+		for _, img := range r.images {
+			fmt.Fprintf(w, "<h3>%s</h3>\n<img src=\"%s\" alt=\"%s\">\n", e(img.caption), img.dataURI(), e(img.caption))
+		}
+	}
+	fmt.Fprintf(w, "<h2>Configuration</h2>\n")
+	if len(r.settings) == 0 {
+		fmt.Fprintf(w, "<p>All parameters at their defaults.</p>\n")
+	} else {
+		fmt.Fprintf(w, "<p>Parameters not listed are at their defaults (see <code>-help-config</code>).</p>\n<table>\n<tr><th>Parameter</th><th>Value</th></tr>\n")
+		for _, kv := range r.settings {
+			fmt.Fprintf(w, "<tr><td><code>%s</code></td><td>%s</td></tr>\n", e(kv[0]), e(kv[1]))
+		}
+		fmt.Fprintf(w, "</table>\n")
+	}
+	fmt.Fprintf(w, "</body>\n</html>\n")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+)
+
+// Random number generators.
+//
+// Every draw of a run goes through a *rand.Rand whose source is picked with
+// rngAlgorithm:
+//   - mathrand (default): math/rand's generator. Independent runs and the
+//     parallel chunks (see workers.go) are seeded with different integers,
+//     which in practice gives unrelated sequences but guarantees nothing.
+//   - xoshiro: xoshiro256** (Blackman and Vigna), a 256-bit generator that
+//     can jump ahead 2^128 or 2^192 draws in one step. Its sequence is cut
+//     into non-overlapping streams: a run with -stream k starts 2^192*k
+//     draws into the seed's sequence, its own draws use the first 2^128 of
+//     that block, and each day's chunk streams take the next 2^128-draw
+//     pieces in turn. No two runs of the same seed, and no two streams within
+//     a run, can ever overlap.
+//
+// Replicate k of a run uses stream k-1 of its seed. With mathrand, stream k
+// of seed S is simply seed S+k.
+
+// rngAlgorithm selects the generator of a run.
+type rngAlgorithm string
+
+const (
+	RNGMathRand rngAlgorithm = "mathrand" // math/rand's generator (default)
+	RNGXoshiro  rngAlgorithm = "xoshiro"  // xoshiro256**, with jumpable streams
+)
+
+var rngAlgorithmChoices = []string{string(RNGMathRand), string(RNGXoshiro)}
+
+// jumpSource is a random source whose sequence can be split into
+// non-overlapping streams by jumping ahead.
+type jumpSource interface {
+	rand.Source64
+	jump()             // skip the next 2^128 draws
+	longJump()         // skip the next 2^192 draws
+	clone() jumpSource // an independent copy at the same position
+}
+
+// rngStream identifies the random sequence of a run.
+type rngStream struct {
+	alg    rngAlgorithm
+	seed   int64
+	stream int
+}
+
+// source returns a new source at the start of the stream.
+func (id rngStream) source() rand.Source64 {
+	if id.alg == RNGXoshiro {
+		x := newXoshiro(id.seed)
+		for range id.stream {
+			x.longJump()
+		}
+		return x
+	}
+	return rand.NewSource(id.seed + int64(id.stream)).(rand.Source64)
+}
+
+// newRand returns a generator at the start of the stream.
+func (id rngStream) newRand() *rand.Rand { return rand.New(id.source()) }
+
+// splitter returns the chunk streams of the stream, nil if its generator
+// cannot jump.
+func (id rngStream) splitter() *streamSplitter {
+	x, ok := id.source().(jumpSource)
+	if !ok {
+		return nil
+	}
+	x.jump() // past the run's own draws
+	return &streamSplitter{next: x}
+}
+
+// String describes the stream for messages, e.g. "seed 7, stream 2".
+func (id rngStream) String() string {
+	if id.alg != RNGXoshiro {
+		return fmt.Sprintf("seed %d", id.seed+int64(id.stream))
+	}
+	if id.stream == 0 {
+		return fmt.Sprintf("seed %d", id.seed)
+	}
+	return fmt.Sprintf("seed %d, stream %d", id.seed, id.stream)
+}
+
+// streamSplitter hands out consecutive non-overlapping streams of a
+// jumpable source.
+type streamSplitter struct {
+	next jumpSource
+	used uint64 // streams handed out so far
+}
+
+// take returns the next stream.
+func (s *streamSplitter) take() jumpSource {
+	src := s.next.clone()
+	s.next.jump()
+	s.used++
+	return src
+}
+
+// skip discards n streams.
+func (s *streamSplitter) skip(n uint64) {
+	for range n {
+		s.next.jump()
+	}
+	s.used += n
+}
+
+// xoshiro is the xoshiro256** generator.
+type xoshiro struct{ s [4]uint64 }
+
+// newXoshiro returns a generator seeded from seed with splitmix64, as its
+// authors recommend.
+func newXoshiro(seed int64) *xoshiro {
+	x := &xoshiro{}
+	x.Seed(seed)
+	return x
+}
+
+func (x *xoshiro) Seed(seed int64) {
+	z := uint64(seed)
+	for i := range x.s {
+		z += 0x9e3779b97f4a7c15
+		v := z
+		v = (v ^ (v >> 30)) * 0xbf58476d1ce4e5b9
+		v = (v ^ (v >> 27)) * 0x94d049bb133111eb
+		x.s[i] = v ^ (v >> 31)
+	}
+}
+
+func (x *xoshiro) Uint64() uint64 {
+	s := &x.s
+	result := bits.RotateLeft64(s[1]*5, 7) * 9
+	t := s[1] << 17
+	s[2] ^= s[0]
+	s[3] ^= s[1]
+	s[1] ^= s[2]
+	s[0] ^= s[3]
+	s[2] ^= t
+	s[3] = bits.RotateLeft64(s[3], 45)
+	return result
+}
+
+func (x *xoshiro) Int63() int64 { return int64(x.Uint64() >> 1) }
+
+func (x *xoshiro) clone() jumpSource {
+	c := *x
+	return &c
+}
+
+// The jump polynomials of xoshiro256**: x^(2^128) and x^(2^192) modulo the
+// characteristic polynomial of its state transition.
+var (
+	xoshiroJump     = [4]uint64{0x180ec6d33cfd0aba, 0xd5a61266f0c9392c, 0xa9582618e03fc9aa, 0x39abdc4529b1661c}
+	xoshiroLongJump = [4]uint64{0x76e15d3efefdcbbf, 0xc5004e441c522fb3, 0x77710069854ee241, 0x39109bb02acbe635}
+)
+
+func (x *xoshiro) jump()     { x.jumpBy(xoshiroJump) }
+func (x *xoshiro) longJump() { x.jumpBy(xoshiroLongJump) }
+
+// jumpBy advances the state by the jump polynomial poly.
+func (x *xoshiro) jumpBy(poly [4]uint64) {
+	var s [4]uint64
+	for _, word := range poly {
+		for b := range 64 {
+			if word&(1<<b) != 0 {
+				for i := range s {
+					s[i] ^= x.s[i]
+				}
+			}
+			x.Uint64()
+		}
+	}
+	x.s = s
+}
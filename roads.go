@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Road network movement.
+//
+// Instead of moving freely in the 2D area, individuals can be constrained to a
+// road/path network loaded from an edge list. Each line of the file is one
+// edge given by its end coordinates, "x1, y1, x2, y2" (commas or spaces;
+// '#' starts a comment). Edges sharing an end point are connected. A move of
+// length L walks L along the network, choosing a random onward edge at every
+// junction (turning back only at dead ends).
+
+// roadNetwork is an undirected graph of road junctions.
+type roadNetwork struct {
+	nodes []OrderedPair
+	adj   [][]int // adj[i] = nodes connected to node i
+	edges [][2]int
+	total float64 // total road length
+}
+
+// roadPosition is where an individual is on the network: a fraction t of the
+// way along the edge from node `from` to node `to`.
+type roadPosition struct {
+	from, to int
+	t        float64
+}
+
+// loadRoadNetwork reads an edge list and checks that every coordinate lies in [0, areaSize].
+func loadRoadNetwork(path string, areaSize float64) (*roadNetwork, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	net := &roadNetwork{}
+	index := map[OrderedPair]int{}
+	node := func(p OrderedPair) int {
+		if i, ok := index[p]; ok {
+			return i
+		}
+		index[p] = len(net.nodes)
+		net.nodes = append(net.nodes, p)
+		net.adj = append(net.adj, nil)
+		return index[p]
+	}
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s line %d: expected x1, y1, x2, y2", path, lineNum)
+		}
+		var v [4]float64
+		for i, s := range fields {
+			v[i], err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid number %q", path, lineNum, s)
+			}
+			if v[i] < 0 || v[i] > areaSize {
+				return nil, fmt.Errorf("%s line %d: coordinate %g outside the area [0, %g]", path, lineNum, v[i], areaSize)
+			}
+		}
+		a, b := node(OrderedPair{v[0], v[1]}), node(OrderedPair{v[2], v[3]})
+		if a == b {
+			continue // zero-length edge
+		}
+		net.adj[a] = append(net.adj[a], b)
+		net.adj[b] = append(net.adj[b], a)
+		net.edges = append(net.edges, [2]int{a, b})
+		net.total += dist(net.nodes[a], net.nodes[b])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(net.edges) == 0 {
+		return nil, fmt.Errorf("%s: no edges", path)
+	}
+	return net, nil
+}
+
+// point returns the coordinates of a road position.
+func (net *roadNetwork) point(p roadPosition) OrderedPair {
+	a, b := net.nodes[p.from], net.nodes[p.to]
+	return OrderedPair{x: a.x + p.t*(b.x-a.x), y: a.y + p.t*(b.y-a.y)}
+}
+
+// randomPosition picks a point uniformly along the whole network.
+func (net *roadNetwork) randomPosition(rng *rand.Rand) roadPosition {
+	target := rng.Float64() * net.total
+	for _, e := range net.edges {
+		l := dist(net.nodes[e[0]], net.nodes[e[1]])
+		if target <= l {
+			return roadPosition{from: e[0], to: e[1], t: target / l}
+		}
+		target -= l
+	}
+	e := net.edges[len(net.edges)-1]
+	return roadPosition{from: e[0], to: e[1], t: 1}
+}
+
+// walk moves p a distance d along the network and returns the new position.
+func (net *roadNetwork) walk(p roadPosition, d float64, rng *rand.Rand) roadPosition {
+	// Pick a random direction along the current edge
+	if rng.Intn(2) == 0 {
+		p = roadPosition{from: p.to, to: p.from, t: 1 - p.t}
+	}
+	for d > 0 {
+		l := dist(net.nodes[p.from], net.nodes[p.to])
+		left := (1 - p.t) * l
+		if d < left {
+			p.t += d / l
+			return p
+		}
+		d -= left
+		p = roadPosition{from: p.to, to: net.nextNode(p.to, p.from, rng), t: 0}
+	}
+	return p
+}
+
+// nextNode picks a random neighbor of node, avoiding cameFrom unless it is the only way out.
+func (net *roadNetwork) nextNode(node, cameFrom int, rng *rand.Rand) int {
+	onward := 0
+	for _, n := range net.adj[node] {
+		if n != cameFrom {
+			onward++
+		}
+	}
+	if onward == 0 {
+		return cameFrom // dead end: turn back
+	}
+	k := rng.Intn(onward)
+	for _, n := range net.adj[node] {
+		if n == cameFrom {
+			continue
+		}
+		if k == 0 {
+			return n
+		}
+		k--
+	}
+	return cameFrom
+}
+
+// placeOnRoads puts every individual at a random point of env's road network.
+func placeOnRoads(env *Environment, rng *rand.Rand) {
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		ind.road = env.roads.randomPosition(rng)
+		ind.position = env.roads.point(ind.road)
+	}
+}
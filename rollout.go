@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Geographic vaccine rollout.
+//
+// By default vaccines are offered across the whole map in random order. With
+// a geographic rollout the map is split into a rolloutGrid x rolloutGrid grid
+// and cells are served one after another, as when doses travel along a supply
+// chain. People in earlier cells are offered vaccines first; doses only spill
+// over to the next cell once no one left in the earlier cells accepts that
+// day. With rolloutDaysPerCell set, a new cell only opens every N days of the
+// campaign, and people in cells that have not opened yet get no doses at all.
+
+// rolloutOrder selects the order in which grid cells are served.
+type rolloutOrder string
+
+const (
+	RolloutRandom rolloutOrder = "random" // no geography: the whole map at once
+	RolloutRows   rolloutOrder = "rows"   // row by row from the top-left cell
+	RolloutCenter rolloutOrder = "center" // from the center of the map outwards
+	RolloutCustom rolloutOrder = "custom" // the cells listed in rolloutCells
+)
+
+// VaccineRollout configures the geographic rollout order.
+type VaccineRollout struct {
+	order       rolloutOrder
+	grid        int    // cells per side
+	cellsText   string // rolloutCells as written in the config
+	cells       []int  // custom order: row-major cell indices, served first
+	daysPerCell int    // days between cell openings, 0 = all cells open
+	rank        []int  // cell index -> position in the rollout, set by rolloutRanks
+}
+
+// geographic reports whether vaccines are rolled out cell by cell.
+func (r VaccineRollout) geographic() bool { return r.order != "" && r.order != RolloutRandom }
+
+// parseRolloutCells parses a comma-separated list of row-major cell indices
+// on a grid x grid map. Each cell may only appear once.
+func parseRolloutCells(s string, grid int) ([]int, error) {
+	var cells []int
+	seen := map[int]bool{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		c, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a cell index", f)
+		}
+		if c < 0 || c >= grid*grid {
+			return nil, fmt.Errorf("cell %d is outside the %dx%d grid (0 - %d)", c, grid, grid, grid*grid-1)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("cell %d is listed twice", c)
+		}
+		seen[c] = true
+		cells = append(cells, c)
+	}
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("no cells listed")
+	}
+	return cells, nil
+}
+
+// rolloutRanks returns the position of every cell in the rollout order.
+// Cells not listed in a custom order follow the listed ones in row order.
+func rolloutRanks(r VaccineRollout) []int {
+	n := r.grid * r.grid
+	order := make([]int, 0, n)
+	switch r.order {
+	case RolloutCustom:
+		listed := map[int]bool{}
+		for _, c := range r.cells {
+			listed[c] = true
+			order = append(order, c)
+		}
+		for c := 0; c < n; c++ {
+			if !listed[c] {
+				order = append(order, c)
+			}
+		}
+	default:
+		for c := 0; c < n; c++ {
+			order = append(order, c)
+		}
+		if r.order == RolloutCenter {
+			mid := float64(r.grid) / 2
+			centerDist := func(c int) float64 {
+				return math.Hypot(float64(c%r.grid)+0.5-mid, float64(c/r.grid)+0.5-mid)
+			}
+			sort.SliceStable(order, func(a, b int) bool {
+				return centerDist(order[a]) < centerDist(order[b])
+			})
+		}
+	}
+	rank := make([]int, n)
+	for i, c := range order {
+		rank[c] = i
+	}
+	return rank
+}
+
+// rolloutRank returns the rollout position of the cell ind is in today.
+func rolloutRank(env *Environment, ind *Individual) int {
+	r := env.rollout
+	cell := func(v float64) int {
+		return min(max(int(v/env.areaSize*float64(r.grid)), 0), r.grid-1)
+	}
+	return r.rank[cell(ind.position.y)*r.grid+cell(ind.position.x)]
+}
+
+// openCells returns how many cells of the rollout are open today.
+func openCells(env *Environment) int {
+	r := env.rollout
+	if r.daysPerCell <= 0 {
+		return r.grid * r.grid
+	}
+	return 1 + max(env.day-env.vaccineSupply.startDay, 0)/r.daysPerCell
+}
+
+// applyRolloutOrder reorders population indices by the rollout position of
+// each individual's cell, keeping the existing order within a cell, and drops
+// individuals in cells that have not opened yet.
+func applyRolloutOrder(env *Environment, indices []int) []int {
+	if !env.rollout.geographic() {
+		return indices
+	}
+	open := openCells(env)
+	ranks := make([]int, len(env.population))
+	kept := indices[:0]
+	for _, idx := range indices {
+		ind := env.population[idx]
+		if ind == nil {
+			continue
+		}
+		rank := rolloutRank(env, ind)
+		if rank >= open {
+			continue
+		}
+		ranks[idx] = rank
+		kept = append(kept, idx)
+	}
+	sort.SliceStable(kept, func(a, b int) bool {
+		return ranks[kept[a]] < ranks[kept[b]]
+	})
+	return kept
+}
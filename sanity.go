@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Before a long run, a short burn-in of the same model shows whether the
+// configured parameters give an epidemic at all, or one that saturates the
+// population within days. Either is usually a configuration mistake, and it is
+// cheaper to find out from a few simulated days than from the full run.
+
+// stabilityEstimate summarizes the early growth seen in a burn-in run.
+type stabilityEstimate struct {
+	days         int
+	growthRate   float64 // per day, fitted to log prevalence (negative if shrinking)
+	doublingTime float64 // days; +Inf if infections are not growing
+	meanDuration float64 // mean infectious period in days, from the observed exit rate
+	r0           float64 // implied basic reproduction number, 1 + growthRate*meanDuration
+	attackRate   float64 // infections during the burn-in (including the seeds) per head of population
+}
+
+// estimateStability runs the model for the given number of days on a fresh
+// population built from config (without the road network; large populations
+// are scaled down at the same density) and estimates the early growth rate,
+// doubling time and implied R0. It returns false if no infections were seeded.
+func estimateStability(config *Config, days int, rng *rand.Rand) (stabilityEstimate, bool) {
+	est := stabilityEstimate{days: days}
+	config = burnInConfig(config)
+	if config.initialInfected <= 0 || days <= 0 {
+		return est, false
+	}
+	env := environmentFromConfig(config, rng)
+	env.disease = diseaseFromConfig(config)
+	seedInfections(env, config, rng)
+
+	_, seeded, _, _, _, _ := ComputePopulationStats(env)
+	series := &epidemicSeries{}
+	series.add(0, seeded, seeded)
+	for day := 1; day <= days; day++ {
+		env.day = day
+		if _, err := stepDay(env, rng); err != nil {
+			break
+		}
+
+		_, infected, _, _, _, _ := ComputePopulationStats(env)
+		series.add(day, infected, env.transitions.newInfections)
+	}
+	return earlyGrowth(series, len(env.population)), true
+}
+
+// earlyGrowth estimates the growth rate, infectious period and implied R0 from
+// a whole series (day 0 first) in a population of n. People leaving the
+// infected state on a step are those infected before plus the new infections,
+// minus those infected after.
+func earlyGrowth(s *epidemicSeries, n int) stabilityEstimate {
+	est := stabilityEstimate{days: len(s.infected) - 1}
+	if len(s.infected) == 0 {
+		return est
+	}
+	prevalence := make([]float64, len(s.infected))
+	infections := 0
+	infectedDays, exits := 0, 0
+	for i, inf := range s.infected {
+		prevalence[i] = float64(inf)
+		infections += s.newInfections[i]
+		if i > 0 {
+			infectedDays += s.infected[i-1]
+			exits += s.infected[i-1] + s.newInfections[i] - inf
+		}
+	}
+
+	// In the early phase prevalence grows (or shrinks) as exp(r*t) with r = (R0-1)/D
+	est.growthRate = logLinearSlope(prevalence)
+	est.doublingTime = math.Inf(1)
+	if est.growthRate > 0 {
+		est.doublingTime = math.Ln2 / est.growthRate
+	}
+	// With no one resolving yet, the infectious period is at least the series length
+	est.meanDuration = float64(est.days)
+	if exits > 0 {
+		est.meanDuration = float64(infectedDays) / float64(exits)
+	}
+	est.r0 = math.Max(0, 1+est.growthRate*est.meanDuration)
+	if n > 0 {
+		est.attackRate = float64(infections) / float64(n)
+	}
+	return est
+}
+
+// logLinearSlope returns the least-squares slope of ln(y) against the index.
+func logLinearSlope(y []float64) float64 {
+	n := 0.0
+	var sx, sy, sxx, sxy float64
+	for i, v := range y {
+		if v <= 0 {
+			continue
+		}
+		x, ly := float64(i), math.Log(v)
+		n++
+		sx += x
+		sy += ly
+		sxx += x * x
+		sxy += x * ly
+	}
+	den := n*sxx - sx*sx
+	if n < 2 || den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
+
+// printStabilityCheck reports the burn-in estimate and warns about parameter
+// combinations that imply no epidemic or near-instant saturation.
+func printStabilityCheck(est stabilityEstimate) {
+	td := "n/a (not growing)"
+	if !math.IsInf(est.doublingTime, 1) {
+		td = fmt.Sprintf("%.1f days", est.doublingTime)
+	}
+	fmt.Fprintf(msgOut, "Pre-run check (%d-day burn-in): growth %.3f/day, doubling time %s, infectious period ~%.1f days, implied R0 ~%.2f\n",
+		est.days, est.growthRate, td, est.meanDuration, est.r0)
+
+	switch {
+	case est.r0 < 1:
+		fmt.Fprintln(msgOut, "Warning: implied R0 is below 1, so the outbreak will most likely die out.")
+		fmt.Fprintln(msgOut, "         Consider raising transmissionRate, transmissionDistance or population density (popSize/areaSize).")
+	case est.attackRate >= 0.5 || est.doublingTime < 1:
+		fmt.Fprintf(msgOut, "Warning: %.0f%% of the population was infected within %d days; the epidemic saturates almost instantly.\n",
+			100*est.attackRate, est.days)
+		fmt.Fprintln(msgOut, "         Consider lowering transmissionRate or transmissionDistance, or spreading the population out.")
+	}
+}
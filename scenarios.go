@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scenario comparison.
+//
+//	go run . -scenarios baseline.txt,lockdown.txt,vaccinate.txt [-parallel] [-seed 1]
+//
+// Runs every config file as a scenario and compares them. All scenarios use
+// the same seed (-seed, else the first config's randomSeed, else the time),
+// so differences come from the configs rather than from chance, unless a
+// config sets its own randomSeed. Scenarios are simulated like the main run,
+// including the road network, weather and incidence files and warm-up, but
+// draw no frames and write no per-run outputs. With -parallel they run at
+// the same time.
+//
+// Three files are written to output_gif/:
+//   - scenario_comparison.csv: one row per scenario with its final outcome;
+//   - scenario_days.csv: every day's main stats for every scenario;
+//   - scenario_comparison.png: the infected count of every scenario over time.
+
+// scenarioResult is the outcome of one scenario.
+type scenarioResult struct {
+	name         string
+	rng          rngStream
+	rows         []DayStats // day 0 first
+	popSize      int
+	everInfected int // individuals infected at least once
+	err          error
+}
+
+// scenarioName returns the name of the scenario in path: its file name
+// without the extension.
+func scenarioName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// runScenario simulates the config in path, with seed unless the config sets
+// its own randomSeed, and returns its daily stats.
+func runScenario(path string, seed int64) scenarioResult {
+	config, err := loadConfigFromFile(path)
+	if err != nil {
+		return scenarioResult{name: scenarioName(path), rng: rngStream{seed: seed}, err: err}
+	}
+	if config.randomSeed != 0 {
+		seed = config.randomSeed
+	}
+	return simulateConfig(scenarioName(path), config, rngStream{alg: config.rngAlgorithm, seed: seed})
+}
+
+// prepareRun sets up config with the random stream id, drawing from rng, up
+// to the initial infections: the pre-run check, the population and its files
+// (see setUpEnvironment), the warm-up and the stockpile. The main run, the
+// scenarios, the no-disease baseline and the benchmarks all start this way,
+// then seed with seedInfections, so the same seed gives them the same state.
+// With report set, the pre-run check and the loaded files are printed; the
+// check draws from rng either way.
+func prepareRun(config *Config, id rngStream, rng *rand.Rand, report bool) (*Environment, error) {
+	if config.sanityCheckDays > 0 {
+		if est, ok := estimateStability(config, min(config.sanityCheckDays, config.numDays), rng); ok && report {
+			printStabilityCheck(est)
+		}
+	}
+	env, err := setUpEnvironment(config, id, rng, report)
+	if err != nil {
+		return nil, err
+	}
+	// Let behavior settle before the epidemic starts; warm-up days are not recorded
+	if err := warmUp(env, config.warmupDays, rng); err != nil {
+		return nil, fmt.Errorf("warm-up: %v", err)
+	}
+	allocateProphylaxis(env, id)
+	return env, nil
+}
+
+// setUpEnvironment builds the population of config with its disease, and
+// loads the road network and the weather and incidence files. A resumed run
+// does only this before its saved state replaces the population. With report
+// set, the model and the loaded files are printed.
+func setUpEnvironment(config *Config, id rngStream, rng *rand.Rand, report bool) (*Environment, error) {
+	var err error
+	env := environmentFromConfig(config, rng)
+	env.streams = id.splitter()
+	env.disease = diseaseFromConfig(config)
+	if report {
+		fmt.Fprintf(msgOut, "Model: %s\n", modelTopology(env.seir, env.immunityWaning))
+		if config.transmissionKernel != KernelExponential {
+			fmt.Fprintf(msgOut, "Transmission kernel: %s\n", config.transmissionKernel)
+		}
+	}
+
+	// Constrain movement to a road network, if one is given
+	if config.roadNetworkFile != "" {
+		if env.roads, err = loadRoadNetwork(config.roadNetworkFile, config.areaSize); err != nil {
+			return nil, fmt.Errorf("road network: %v", err)
+		}
+		placeOnRoads(env, rng)
+		if report {
+			fmt.Fprintf(msgOut, "Loaded road network: %d junctions, %d edges, total length %.1f\n",
+				len(env.roads.nodes), len(env.roads.edges), env.roads.total)
+		}
+	}
+
+	// Age-dependent parameter curves, read with the config
+	if curves := env.ageCurves; curves != nil && report {
+		fmt.Fprintf(msgOut, "Loaded age curves: %s at %d ages, %d-%d\n",
+			strings.Join(curves.names(), ", "), len(curves.ages), curves.ages[0], curves.ages[len(curves.ages)-1])
+	}
+
+	// Per-day weather modifiers of transmission and outdoor activity, if given
+	if config.weatherFile != "" {
+		if env.weather, err = loadWeather(config.weatherFile, config.startDate); err != nil {
+			return nil, fmt.Errorf("weather file: %v", err)
+		}
+		if report {
+			days := env.weather.days
+			fmt.Fprintf(msgOut, "Loaded weather: %d entries, days %d-%d\n", len(days), days[0].day, days[len(days)-1].day)
+		}
+	}
+
+	// Observed incidence curve to follow for the first days, if given
+	if config.incidenceFile != "" {
+		if env.assimilation, err = loadIncidence(config.incidenceFile, config.startDate, config.incidenceScale, config.assimilationDays); err != nil {
+			return nil, fmt.Errorf("incidence file: %v", err)
+		}
+		if report {
+			fmt.Fprintf(msgOut, "Loaded incidence curve: %d entries, assimilating days 1-%d\n", len(env.assimilation.observed), env.assimilation.until)
+		}
+	}
+	return env, nil
+}
+
+// simulateConfig runs config with the random stream id, without frames or
+// outputs, and returns its daily stats. It is set up like the main run, so a
+// scenario gives the same result as running its config alone with the same
+// seed.
+func simulateConfig(name string, config *Config, id rngStream) scenarioResult {
+	res := scenarioResult{name: name, rng: id}
+	rng := id.newRand()
+	env, err := prepareRun(config, id, rng, false)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	seedInfections(env, config, rng)
+
+	res.rows = append(res.rows, collectDayStats(0, env, false))
+	for day := 1; day <= config.numDays; day++ {
+		env.day = day
+		tightened, err := stepDay(env, rng)
+		if err != nil {
+			res.err = fmt.Errorf("day %d: %v", day, err)
+			break
+		}
+		res.rows = append(res.rows, collectDayStats(day, env, tightened))
+	}
+	res.countInfected(env)
+	return res
+}
+
+// countInfected records the population size and how many individuals were
+// infected at least once by the end of the run in env.
+func (res *scenarioResult) countInfected(env *Environment) {
+	res.popSize = len(env.population)
+	res.everInfected = 0
+	for _, ind := range env.population {
+		if ind != nil && ind.timesInfected > 0 {
+			res.everInfected++
+		}
+	}
+}
+
+// scenarioOutcome summarizes a scenario's run.
+type scenarioOutcome struct {
+	peakInfected, peakDay            int
+	infections, deaths, vaccinated   int
+	peakWard, peakICU, daysTightened int
+	attackRate                       float64
+}
+
+// outcome returns the summary of the scenario's run.
+func (res scenarioResult) outcome() scenarioOutcome {
+	var o scenarioOutcome
+	for _, r := range res.rows {
+		if r.Infected > o.peakInfected {
+			o.peakInfected, o.peakDay = r.Infected, r.Day
+		}
+		o.infections += r.NewInfections
+		o.peakWard = max(o.peakWard, r.WardOccupied)
+		o.peakICU = max(o.peakICU, r.ICUOccupied)
+		if r.PolicyTightened {
+			o.daysTightened++
+		}
+	}
+	if n := len(res.rows); n > 0 {
+		o.deaths = res.rows[n-1].Dead
+		o.vaccinated = res.rows[n-1].Vaccinated
+	}
+	if res.popSize > 0 {
+		o.attackRate = float64(res.everInfected) / float64(res.popSize)
+	}
+	return o
+}
+
+// runScenarios implements -scenarios: paths is the comma-separated list of
+// config files.
+func runScenarios(paths string, seed int64, parallel bool) {
+	var files []string
+	for _, p := range strings.Split(paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			files = append(files, p)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(msgOut, "Error: -scenarios needs at least one config file")
+		return
+	}
+	if seed == 0 {
+		if config, err := loadConfigFromFile(files[0]); err == nil {
+			seed = config.randomSeed
+		}
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	results := make([]scenarioResult, len(files))
+	var wg sync.WaitGroup
+	for i, path := range files {
+		if !parallel {
+			fmt.Fprintf(msgOut, "Running scenario %s\n", scenarioName(path))
+			results[i] = runScenario(path, seed)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runScenario(path, seed)
+		}()
+	}
+	wg.Wait()
+
+	var done []scenarioResult
+	for _, res := range results {
+		if res.err != nil {
+			fmt.Fprintf(msgOut, "Scenario %s failed: %v\n", res.name, res.err)
+		}
+		if len(res.rows) == 0 {
+			continue
+		}
+		o := res.outcome()
+		fmt.Fprintf(msgOut, "Scenario %s: peak %d infected on day %d, attack rate %.1f%%, %d deaths, %d vaccinated\n",
+			res.name, o.peakInfected, o.peakDay, 100*o.attackRate, o.deaths, o.vaccinated)
+		done = append(done, res)
+	}
+	if len(done) == 0 {
+		return
+	}
+
+	outputDir := "output_gif"
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Fprintf(msgOut, "failed to create output directory '%s': %v\n", outputDir, err)
+		return
+	}
+	save := func(path, what string, write func(string) error) {
+		if err := write(path); err != nil {
+			fmt.Fprintf(msgOut, "failed to save %s: %v\n", what, err)
+		} else {
+			fmt.Fprintf(msgOut, "Scenario %s saved to: %s\n", what, path)
+		}
+	}
+	save(outputDir+"/scenario_comparison.csv", "comparison", func(p string) error { return saveScenarioComparison(p, done) })
+	save(outputDir+"/scenario_days.csv", "daily stats", func(p string) error { return saveScenarioDays(p, done) })
+	save(outputDir+"/scenario_comparison.png", "chart", func(p string) error { return SaveScenarioChart(p, done, 800, 480) })
+}
+
+// saveScenarioComparison writes one row per scenario with its outcome.
+func saveScenarioComparison(path string, results []scenarioResult) error {
+	return writeCSV(path, func(w *bufio.Writer) {
+		fmt.Fprintln(w, "Scenario, Seed, Days, PeakInfected, PeakDay, AttackRate, Infections, Deaths, Vaccinated, PeakWard, PeakICU, DaysTightened")
+		for _, res := range results {
+			o := res.outcome()
+			fmt.Fprintf(w, "%s, %d, %d, %d, %d, %.4f, %d, %d, %d, %d, %d, %d\n",
+				res.name, res.rng.seed, res.rows[len(res.rows)-1].Day, o.peakInfected, o.peakDay, o.attackRate,
+				o.infections, o.deaths, o.vaccinated, o.peakWard, o.peakICU, o.daysTightened)
+		}
+	})
+}
+
+// saveScenarioDays writes every day's main stats for every scenario.
+func saveScenarioDays(path string, results []scenarioResult) error {
+	return writeCSV(path, func(w *bufio.Writer) {
+		fmt.Fprintln(w, "Scenario, Day, Healthy, Susceptible, Exposed, Infected, Recovered, Dead, Vaccinated, NewInfections, NewDeaths, WardOccupied, ICUOccupied, PolicyTightened")
+		for _, res := range results {
+			for _, r := range res.rows {
+				fmt.Fprintf(w, "%s, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %d, %v\n",
+					res.name, r.Day, r.Healthy, r.Susceptible, r.Exposed, r.Infected, r.Recovered, r.Dead,
+					r.Vaccinated, r.NewInfections, r.NewDeaths, r.WardOccupied, r.ICUOccupied, r.PolicyTightened)
+			}
+		}
+	})
+}
+
+// writeCSV creates path and fills it with write.
+func writeCSV(path string, write func(w *bufio.Writer)) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	write(w)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Web dashboard. With -serve ADDR (e.g. -serve :8080), the run starts an HTTP
+// server with a dashboard page and waits, paused before day 1, for a browser.
+// The page receives every day's stats and spatial and pie frames as
+// server-sent events and draws the epidemic curve as it grows. Its controls
+// are:
+//
+//	play / pause   run day after day, at the speed set by the delay slider
+//	step           run one day, then pause
+//	stop           end the run here and write the outputs
+//	sliders        change the parameters -repl's set can change
+//
+// Controls are carried out between days on the simulation's goroutine, so a
+// change applies from the next day on, exactly as with -repl. The endpoints
+// are GET / (the page), GET /events (the event stream), POST /control?cmd=
+// and POST /set?key=&value=. At the end of the run the page shows the final
+// day and the server stops with the program.
+
+// dashCommand is a control sent by the page, carried out between days.
+type dashCommand struct {
+	cmd, key, value string
+	done            chan error
+}
+
+// dashEvent is one server-sent event.
+type dashEvent struct {
+	name, data string
+}
+
+// dashParam describes a slider on the page.
+type dashParam struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Step  float64 `json:"step"`
+}
+
+// dashState is the run state shown by the page.
+type dashState struct {
+	Day     int         `json:"day"`
+	NumDays int         `json:"numDays"`
+	Playing bool        `json:"playing"`
+	DelayMs int         `json:"delayMs"`
+	Params  []dashParam `json:"params"`
+}
+
+// dashboard serves the page and relays controls. A nil *dashboard never
+// stops the run.
+type dashboard struct {
+	commands    chan dashCommand
+	ended       chan struct{}
+	numDays     int
+	canvasWidth int
+	pointRadius float64
+
+	playing bool
+	steps   int           // days to run before pausing again
+	delay   time.Duration // between days while playing
+
+	mu      sync.Mutex
+	clients map[chan dashEvent]bool
+	latest  map[string]dashEvent // last event of each kind, for new clients
+	order   []string             // kinds in latest, in the order first sent
+}
+
+// newDashboard starts serving on addr and returns the dashboard.
+func newDashboard(addr string, numDays, canvasWidth int, pointRadius float64) (*dashboard, error) {
+	d := &dashboard{
+		commands:    make(chan dashCommand),
+		ended:       make(chan struct{}),
+		numDays:     numDays,
+		canvasWidth: canvasWidth,
+		pointRadius: pointRadius,
+		delay:       200 * time.Millisecond,
+		clients:     map[chan dashEvent]bool{},
+		latest:      map[string]dashEvent{},
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.page)
+	mux.HandleFunc("/events", d.events)
+	mux.HandleFunc("/control", d.control)
+	mux.HandleFunc("/set", d.control)
+	go http.Serve(ln, mux)
+	return d, nil
+}
+
+// pause carries out controls until the run should go on to the next day. It
+// returns false if the run should end here.
+func (d *dashboard) pause(env *Environment) bool {
+	if d == nil {
+		return true
+	}
+	d.publishState(env)
+	var wait <-chan time.Time
+	for {
+		if d.steps > 0 {
+			d.steps--
+			return true
+		}
+		switch {
+		case !d.playing:
+			wait = nil
+		case wait == nil:
+			wait = time.After(d.delay)
+		}
+		select {
+		case c := <-d.commands:
+			err := d.apply(env, c)
+			c.done <- err
+			if errors.Is(err, errDashStop) {
+				return false
+			}
+			d.publishState(env)
+		case <-wait:
+			return true
+		}
+	}
+}
+
+// errDashStop is returned by apply for the stop control.
+var errDashStop = errors.New("run stopped")
+
+// apply carries out one control.
+func (d *dashboard) apply(env *Environment, c dashCommand) error {
+	switch c.cmd {
+	case "play":
+		d.playing = true
+	case "pause":
+		d.playing = false
+	case "step":
+		d.playing, d.steps = false, 1
+	case "stop":
+		d.playing = false
+		return errDashStop
+	case "delay":
+		ms, err := strconv.Atoi(c.value)
+		if err != nil || ms < 0 {
+			return fmt.Errorf("invalid delay %q", c.value)
+		}
+		d.delay = time.Duration(ms) * time.Millisecond
+	case "set":
+		return setParameter(env, c.key, c.value)
+	default:
+		return fmt.Errorf("unknown control %q", c.cmd)
+	}
+	return nil
+}
+
+// last tells the page the run is over; controls are refused from now on.
+func (d *dashboard) last(env *Environment) {
+	if d == nil {
+		return
+	}
+	d.playing = false
+	d.publishState(env)
+	close(d.ended)
+	d.publish("end", fmt.Sprintf("%d", env.day))
+}
+
+// publishStats sends a day's stats row.
+func (d *dashboard) publishStats(row DayStats) {
+	if d == nil {
+		return
+	}
+	if data, err := json.Marshal(row); err == nil {
+		d.publish("stats", string(data))
+	}
+}
+
+// capture draws the spatial and pie frames of a day and sends them. It is
+// registered as a renderer, so it runs on a snapshot in the background.
+func (d *dashboard) capture(env *Environment) {
+	frame := struct {
+		Day     int    `json:"day"`
+		Spatial string `json:"spatial"`
+		Pie     string `json:"pie"`
+	}{env.day, pngDataURI(env.DrawToCanvas(d.canvasWidth, d.pointRadius)), pngDataURI(DrawEnvironmentPie(env, d.canvasWidth))}
+	if data, err := json.Marshal(frame); err == nil {
+		d.publish("frame", string(data))
+	}
+}
+
+// pngDataURI encodes img as a PNG data URI.
+func pngDataURI(img image.Image) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return ""
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// publishState sends the day, the play state and the slider values.
+func (d *dashboard) publishState(env *Environment) {
+	s := dashState{Day: env.day, NumDays: d.numDays, Playing: d.playing, DelayMs: int(d.delay / time.Millisecond)}
+	values := replSettings(env)
+	for _, key := range slices.Sorted(maps.Keys(replSettable)) {
+		p, _ := lookupParam(key)
+		v, err := strconv.ParseFloat(values[key], 64)
+		if p == nil || err != nil {
+			continue
+		}
+		step := 1.0
+		if p.Kind == KindFloat {
+			step = math.Max((p.Max-p.Min)/200, 0.001)
+		}
+		s.Params = append(s.Params, dashParam{Name: key, Value: v, Min: p.Min, Max: p.Max, Step: step})
+	}
+	if data, err := json.Marshal(s); err == nil {
+		d.publish("state", string(data))
+	}
+}
+
+// publish sends an event to every connected page and keeps it for pages
+// that connect later ("stats" events are all kept, so a late page can draw
+// the curve so far). A page that cannot keep up misses events.
+func (d *dashboard) publish(name, data string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ev := dashEvent{name, data}
+	key := name
+	if name == "stats" {
+		key = fmt.Sprintf("stats %d", len(d.order))
+	}
+	if _, ok := d.latest[key]; !ok {
+		d.order = append(d.order, key)
+	}
+	d.latest[key] = ev
+	for ch := range d.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// events streams events to one page.
+func (d *dashboard) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	d.mu.Lock()
+	backlog := make([]dashEvent, 0, len(d.order))
+	for _, key := range d.order {
+		backlog = append(backlog, d.latest[key])
+	}
+	ch := make(chan dashEvent, 256)
+	d.clients[ch] = true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.clients, ch)
+		d.mu.Unlock()
+	}()
+
+	for _, ev := range backlog {
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+	}
+	flusher.Flush()
+	for {
+		select {
+		case ev := <-ch:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// control relays a control from the page and answers with its result.
+func (d *dashboard) control(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	c := dashCommand{cmd: q.Get("cmd"), key: q.Get("key"), value: q.Get("value"), done: make(chan error, 1)}
+	if r.URL.Path == "/set" {
+		c.cmd = "set"
+	}
+	select {
+	case d.commands <- c:
+	case <-d.ended:
+		http.Error(w, "the run is over", http.StatusConflict)
+		return
+	case <-r.Context().Done():
+		return
+	}
+	if err := <-c.done; err != nil && !errors.Is(err, errDashStop) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// page serves the dashboard.
+func (d *dashboard) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, dashboardPage)
+}
+
+// dashboardPage is the dashboard: controls, sliders, frames and the curve.
+const dashboardPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Epidemic simulation</title>
+<style>
+body{font-family:sans-serif;margin:1em 2em}
+#controls button{margin-right:.4em}
+#main{display:flex;flex-wrap:wrap;gap:1em;margin-top:1em}
+#params label{display:block;margin:.3em 0}
+#params input{width:220px;vertical-align:middle}
+#error{color:#b00}
+table{border-collapse:collapse}td{padding:1px 8px}
+</style>
+</head>
+<body>
+<h2>Epidemic simulation <span id="day"></span></h2>
+<div id="controls">
+<button onclick="control('play')">Play</button>
+<button onclick="control('pause')">Pause</button>
+<button onclick="control('step')">Step</button>
+<button onclick="control('stop')">Stop</button>
+<label>Delay <input id="delay" type="range" min="0" max="2000" step="50" onchange="control('delay', this.value)"> <span id="delayv"></span> ms</label>
+<span id="status"></span> <span id="error"></span>
+</div>
+<div id="main">
+<div><img id="spatial"><br><img id="pie"></div>
+<div><canvas id="curve" width="600" height="300"></canvas><table id="stats"></table></div>
+<div id="params"></div>
+</div>
+<script>
+const rows = [];
+const series = [["healthy","#2a9d2a"],["susceptible","#e0c000"],["exposed","#e08000"],["infected","#d02020"],["recovered","#2060d0"],["dead","#404040"]];
+let over = false;
+function post(url) {
+  return fetch(url, {method: "POST"}).then(r => r.ok ? "" : r.text()).then(t => {
+    document.getElementById("error").textContent = t;
+  });
+}
+function control(cmd, value) {
+  post("/control?cmd=" + cmd + (value === undefined ? "" : "&value=" + encodeURIComponent(value)));
+}
+function set(key, value) {
+  post("/set?key=" + encodeURIComponent(key) + "&value=" + encodeURIComponent(value));
+}
+function drawCurve() {
+  const c = document.getElementById("curve"), g = c.getContext("2d");
+  g.clearRect(0, 0, c.width, c.height);
+  if (rows.length === 0) return;
+  const last = Math.max(rows[rows.length - 1].day, 1);
+  let peak = 1;
+  for (const r of rows) for (const [k] of series) peak = Math.max(peak, r[k] || 0);
+  series.forEach(([k, col], i) => {
+    g.strokeStyle = col; g.beginPath();
+    rows.forEach((r, j) => {
+      const x = 30 + (c.width - 40) * r.day / last, y = c.height - 20 - (c.height - 30) * (r[k] || 0) / peak;
+      j ? g.lineTo(x, y) : g.moveTo(x, y);
+    });
+    g.stroke();
+    g.fillStyle = col; g.fillText(k, 40 + 80 * i, 12);
+  });
+  g.fillStyle = "#000"; g.fillText("day " + rows[rows.length - 1].day, c.width - 60, c.height - 5);
+}
+const es = new EventSource("/events");
+es.addEventListener("stats", e => {
+  const r = JSON.parse(e.data);
+  rows.push(r);
+  drawCurve();
+  const t = document.getElementById("stats");
+  t.innerHTML = "";
+  for (const k of ["day","healthy","susceptible","exposed","infected","recovered","dead","vaccinated","sdThreshold","wardOccupied","icuOccupied"]) {
+    if (r[k] === undefined) continue;
+    const v = typeof r[k] === "number" && !Number.isInteger(r[k]) ? r[k].toFixed(3) : r[k];
+    t.insertRow().innerHTML = "<td>" + k + "</td><td>" + v + "</td>";
+  }
+});
+es.addEventListener("frame", e => {
+  const f = JSON.parse(e.data);
+  document.getElementById("spatial").src = f.spatial;
+  document.getElementById("pie").src = f.pie;
+});
+es.addEventListener("state", e => {
+  const s = JSON.parse(e.data);
+  document.getElementById("day").textContent = "day " + s.day + " of " + s.numDays;
+  document.getElementById("status").textContent = over ? "finished" : s.playing ? "playing" : "paused";
+  document.getElementById("delay").value = s.delayMs;
+  document.getElementById("delayv").textContent = s.delayMs;
+  const p = document.getElementById("params");
+  for (const q of s.params) {
+    let input = document.getElementById("p-" + q.name);
+    if (!input) {
+      const l = document.createElement("label");
+      l.innerHTML = "<span></span><br><input type='range'> <output></output>";
+      l.firstChild.textContent = q.name;
+      input = l.querySelector("input");
+      input.id = "p-" + q.name;
+      input.min = q.min; input.max = q.max; input.step = q.step;
+      input.oninput = () => { l.querySelector("output").textContent = input.value; };
+      input.onchange = () => set(q.name, input.value);
+      p.appendChild(l);
+    }
+    if (document.activeElement !== input) {
+      input.value = q.value;
+      input.parentNode.querySelector("output").textContent = q.value;
+    }
+  }
+});
+es.addEventListener("end", () => {
+  over = true;
+  document.getElementById("status").textContent = "finished";
+  es.close();
+});
+</script>
+</body>
+</html>
+`
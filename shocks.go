@@ -0,0 +1,63 @@
+package main
+
+import "math/rand"
+
+// Compliance shocks. A shock (a holiday, a festival, a mass gathering) on day
+// X makes a random share of the living population travel further and keep
+// less distance for shockDays days: their step lengths are multiplied by
+// shockMobility and their social distance compliance counts for
+// shockCompliance times its usual value in the infection and exposure
+// probabilities. Shocks start and end through the event queue.
+
+// ShockConfig lists the shocks of a run and how strong they are.
+type ShockConfig struct {
+	shares     map[int]float64 // day -> share of the population affected
+	days       int             // how long each shock lasts
+	mobility   float64         // step length multiplier while shocked
+	compliance float64         // compliance multiplier while shocked
+}
+
+// scheduleShocks queues the start of every configured shock.
+func scheduleShocks(env *Environment) {
+	for day := range env.shocks.shares {
+		scheduleEvent(env, float64(day), scheduledEvent{kind: EventShockStart})
+	}
+}
+
+// startShock picks today's shocked individuals and schedules their recovery.
+func startShock(env *Environment, rng *rand.Rand) {
+	share := env.shocks.shares[env.day]
+	until := env.day + env.shocks.days
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus == Dead || rng.Float64() >= share {
+			continue
+		}
+		ind.shockedUntil = max(ind.shockedUntil, until)
+		scheduleEvent(env, float64(until), scheduledEvent{kind: EventShockEnd, ind: ind})
+	}
+}
+
+// endShock returns ind to normal behavior, unless a later shock extended it.
+func endShock(ind *Individual, day int) {
+	if ind.shockedUntil <= day {
+		ind.shockedUntil = 0
+	}
+}
+
+// shockMobility returns the step length multiplier for ind today.
+func shockMobility(env *Environment, ind *Individual) float64 {
+	if ind.shockedUntil == 0 {
+		return 1
+	}
+	return env.shocks.mobility
+}
+
+// effectiveCompliance returns ind's social distance compliance as it acts on
+// infection and exposure today, lowered while ind is shocked.
+func effectiveCompliance(env *Environment, ind *Individual) float64 {
+	c := clamp01(ind.socialDistanceCompliance)
+	if ind.shockedUntil != 0 {
+		c *= env.shocks.compliance
+	}
+	return c
+}
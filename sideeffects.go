@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Vaccine side effects. With sideEffects enabled, a share of newly vaccinated
+// individuals feel unwell for 1 to maxDays days and move less while they
+// recover: their step lengths are scaled by mobility. The end of each episode
+// is an EventSideEffectsEnd on the event queue.
+
+// VaccineSideEffects configures reduced mobility after vaccination.
+type VaccineSideEffects struct {
+	enabled  bool
+	rate     float64 // share of vaccinations that cause side effects
+	maxDays  int     // episodes last 1..maxDays days, uniformly
+	mobility float64 // step length multiplier during an episode
+	episodes int     // episodes so far in this run
+}
+
+// startSideEffects is called when ind is vaccinated on env.day. It decides
+// whether the dose causes side effects and, if so, schedules their end.
+func startSideEffects(env *Environment, ind *Individual, rng *rand.Rand) {
+	se := &env.sideEffects
+	if !se.enabled || rng.Float64() >= se.rate {
+		return
+	}
+	days := 1 + rng.Intn(se.maxDays)
+	ind.sideEffectsUntil = env.day + days
+	se.episodes++
+	scheduleEvent(env, float64(ind.sideEffectsUntil), scheduledEvent{kind: EventSideEffectsEnd, ind: ind})
+}
+
+// endSideEffects clears ind's side effects, unless a later dose extended them.
+func endSideEffects(ind *Individual, day int) {
+	if ind.sideEffectsUntil <= day {
+		ind.sideEffectsUntil = 0
+	}
+}
+
+// printSideEffectsSummary reports how many vaccinations caused side effects.
+func printSideEffectsSummary(env *Environment) {
+	if !env.sideEffects.enabled {
+		return
+	}
+	fmt.Fprintf(msgOut, "Vaccine side effects: %d episodes of reduced mobility\n", env.sideEffects.episodes)
+}
+
+// sideEffectMobility returns the step length multiplier for ind today.
+func sideEffectMobility(env *Environment, ind *Individual) float64 {
+	if ind.sideEffectsUntil == 0 {
+		return 1
+	}
+	return env.sideEffects.mobility
+}
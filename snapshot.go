@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// Per-day snapshots for renderers.
+//
+// Drawing frames takes a noticeable share of the run time at large population
+// sizes. Instead of drawing while the simulation waits, the main loop takes a
+// snapshot of the day's state and hands it to a background goroutine that runs
+// the renderers, so the next day is computed while the frames are drawn. The
+// snapshot copies everything the renderers read (positions, health and
+// vaccination status, pathogen states, counters, policy cells), so they never see the next
+// day's updates. Renderers must not draw random numbers, so rendering never
+// changes the course of a seeded run.
+
+// Snapshot is an immutable copy of one day's state.
+type Snapshot struct {
+	env *Environment // copy of the environment; population holds copies of the individuals
+}
+
+// takeSnapshot copies env for the renderers. Fields that are updated in place
+// (individuals, infections, pathogen states and totals) are copied; the rest
+// of the environment is only read during the run and is shared.
+func takeSnapshot(env *Environment) *Snapshot {
+	snap := *env
+	snap.population = make([]*Individual, len(env.population))
+	copies := make([]Individual, len(env.population))
+	for i, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		c := &copies[i]
+		*c = *ind
+		if ind.infection != nil {
+			inf := *ind.infection
+			c.infection = &inf
+		}
+		c.pathogens = slices.Clone(ind.pathogens)
+		snap.population[i] = c
+	}
+	snap.pathogenTotals = slices.Clone(env.pathogenTotals)
+	snap.cells = slices.Clone(env.cells)
+	snap.renderSet = nil // picked again from the copies
+	return &Snapshot{env: &snap}
+}
+
+// renderPipeline runs jobs one at a time, in order, on a background
+// goroutine. At most one job waits in the queue, which bounds the number of
+// snapshots held in memory.
+type renderPipeline struct {
+	jobs    chan func()
+	pending sync.WaitGroup
+}
+
+// newRenderPipeline starts the pipeline's goroutine.
+func newRenderPipeline() *renderPipeline {
+	p := &renderPipeline{jobs: make(chan func(), 1)}
+	go func() {
+		for job := range p.jobs {
+			job()
+			p.pending.Done()
+		}
+	}()
+	return p
+}
+
+// do queues job, blocking while another job is already waiting.
+func (p *renderPipeline) do(job func()) {
+	p.pending.Add(1)
+	p.jobs <- job
+}
+
+// wait blocks until every queued job has run.
+func (p *renderPipeline) wait() {
+	p.pending.Wait()
+}
+
+// close waits for the queued jobs and stops the goroutine.
+func (p *renderPipeline) close() {
+	p.wait()
+	close(p.jobs)
+}
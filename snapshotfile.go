@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Saved snapshots and the render subcommand.
+//
+// With snapshotEvery = N, every N days the run's snapshot (see snapshot.go)
+// is also appended to output_gif/snapshots.gob: the positions, health and
+// vaccination status and pathogen states of every individual, plus the
+// settings the renderers use. The render subcommand redraws any of the GIFs
+// from that file without simulating again:
+//
+//	go run . render [-snapshots output_gif/snapshots.gob] [-renderer spatial|pie|coverage]
+//	    [-width 800] [-pointRadius 3] [-palette colorblind] [-deadRendering fade] [-every 2] [-out my.gif]
+//
+// Flags that are not given keep the values of the run that saved the file.
+
+// snapshotFileName is the name of the snapshot file in the output directory.
+const snapshotFileName = "snapshots.gob"
+
+// snapshotHeader is the first record of a snapshot file: the run's settings
+// that do not change from day to day.
+type snapshotHeader struct {
+	AreaSize        float64
+	StartDate       time.Time
+	SEIR            bool
+	Pathogens       []string
+	Annotations     map[int]string
+	SnapshotEvery   int
+	CanvasWidth     int
+	PointRadius     float64
+	GIFDelay        int
+	CoverageGrid    int
+	DeadRendering   deadRenderMode
+	DeadFadeFrames  int
+	DeathCounter    bool
+	Palette         string
+	PointAlpha      float64
+	DensityPoints   bool
+	PointJitter     float64
+	LargePopulation bool
+	RenderSample    int
+}
+
+// snapshotDay is one day's record in a snapshot file.
+type snapshotDay struct {
+	Day            int
+	Individuals    []snapshotIndividual
+	PathogenDeaths []int
+}
+
+// snapshotIndividual is the state of one individual the renderers read.
+type snapshotIndividual struct {
+	X, Y                 float64
+	Status               HealthStatus
+	Vaccinated           bool
+	DaysSinceVaccination int
+	DeathDay             int
+	Pathogens            []HealthStatus
+}
+
+// snapshotWriter appends snapshots to a snapshot file. It runs as a renderer,
+// so it writes on the render goroutine; the first error stops all writing and
+// is returned by close.
+type snapshotWriter struct {
+	path string
+	w    *outputFile
+	enc  *gob.Encoder
+	err  error
+}
+
+// newSnapshotWriter creates the snapshot file in dir and writes its header.
+func newSnapshotWriter(dir string, config *Config, env *Environment) (*snapshotWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := dir + "/" + env.outputName(snapshotFileName)
+	w, err := createOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	sw := &snapshotWriter{path: path, w: w, enc: gob.NewEncoder(w)}
+	names := make([]string, len(env.pathogens))
+	for k, p := range env.pathogens {
+		names[k] = p.disease.name
+	}
+	sw.err = sw.enc.Encode(snapshotHeader{
+		AreaSize:        env.areaSize,
+		StartDate:       env.startDate,
+		SEIR:            env.seir,
+		Pathogens:       names,
+		Annotations:     env.annotations,
+		SnapshotEvery:   config.snapshotEvery,
+		CanvasWidth:     config.canvasWidth,
+		PointRadius:     config.pointRadius,
+		GIFDelay:        config.gifDelay,
+		CoverageGrid:    config.coverageGrid,
+		DeadRendering:   env.deadRender.mode,
+		DeadFadeFrames:  env.deadRender.fadeFrames,
+		DeathCounter:    env.deadRender.counter,
+		Palette:         env.palette,
+		PointAlpha:      env.points.alpha,
+		DensityPoints:   env.points.density,
+		PointJitter:     env.points.jitter,
+		LargePopulation: env.largePopulation,
+		RenderSample:    env.renderSample,
+	})
+	return sw, nil
+}
+
+// write appends the day of the snapshot env.
+func (sw *snapshotWriter) write(env *Environment) {
+	if sw.err != nil {
+		return
+	}
+	rec := snapshotDay{Day: env.day, Individuals: make([]snapshotIndividual, 0, len(env.population))}
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		si := snapshotIndividual{
+			X:                    ind.position.x,
+			Y:                    ind.position.y,
+			Status:               ind.healthStatus,
+			Vaccinated:           ind.vaccinated,
+			DaysSinceVaccination: ind.daysSinceVacination,
+			DeathDay:             ind.deathDay,
+		}
+		for _, st := range ind.pathogens {
+			si.Pathogens = append(si.Pathogens, st.status)
+		}
+		rec.Individuals = append(rec.Individuals, si)
+	}
+	for _, t := range env.pathogenTotals {
+		rec.PathogenDeaths = append(rec.PathogenDeaths, t.deaths)
+	}
+	sw.err = sw.enc.Encode(rec)
+}
+
+// close flushes and closes the file, returning the first error.
+func (sw *snapshotWriter) close() error {
+	if sw == nil {
+		return nil
+	}
+	err := sw.err
+	if cerr := sw.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// replayEnvironment returns an environment with the settings in h, for
+// drawing saved days.
+func replayEnvironment(h snapshotHeader) *Environment {
+	env := &Environment{
+		areaSize:        h.AreaSize,
+		startDate:       h.StartDate,
+		seir:            h.SEIR,
+		annotations:     h.Annotations,
+		palette:         h.Palette,
+		points:          pointStyle{alpha: h.PointAlpha, density: h.DensityPoints, jitter: h.PointJitter},
+		largePopulation: h.LargePopulation,
+		renderSample:    h.RenderSample,
+		deadRender: deadRenderOptions{
+			mode:           h.DeadRendering,
+			fadeFrames:     h.DeadFadeFrames,
+			frameFrequency: h.SnapshotEvery,
+			counter:        h.DeathCounter,
+		},
+	}
+	for _, name := range h.Pathogens {
+		env.pathogens = append(env.pathogens, &Pathogen{disease: &Disease{name: name}})
+	}
+	return env
+}
+
+// load replaces env's day and population with those of rec.
+func (rec snapshotDay) load(env *Environment) {
+	env.day = rec.Day
+	env.population = make([]*Individual, len(rec.Individuals))
+	for i, si := range rec.Individuals {
+		ind := &Individual{
+			id:                  i,
+			position:            OrderedPair{x: si.X, y: si.Y},
+			healthStatus:        si.Status,
+			vaccinated:          si.Vaccinated,
+			daysSinceVacination: si.DaysSinceVaccination,
+			deathDay:            si.DeathDay,
+		}
+		for _, st := range si.Pathogens {
+			ind.pathogens = append(ind.pathogens, pathogenState{status: st})
+		}
+		env.population[i] = ind
+	}
+	env.pathogenTotals = make([]pathogenTotals, len(rec.PathogenDeaths))
+	for k, d := range rec.PathogenDeaths {
+		env.pathogenTotals[k].deaths = d
+	}
+	env.renderSet = nil
+}
+
+// runRender implements the render subcommand; args exclude its name.
+func runRender(args []string) {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	in := fs.String("snapshots", "output_gif/"+snapshotFileName, "Snapshot file saved by a run with snapshotEvery")
+	renderer := fs.String("renderer", "spatial", "What to draw: spatial, pie or coverage")
+	out := fs.String("out", "", "Output GIF (default: render_<renderer>.gif next to the snapshot file)")
+	width := fs.Int("width", 0, "Canvas width in pixels (0 = the run's canvasWidth)")
+	radius := fs.Float64("pointRadius", 0, "Radius of each individual in pixels (0 = the run's pointRadius)")
+	grid := fs.Int("coverageGrid", 0, "Coverage map cells per side (0 = the run's coverageGrid)")
+	alpha := fs.Float64("pointAlpha", 0, "Opacity of individual dots (0 = the run's pointAlpha)")
+	density := fs.Bool("densityPointSize", false, "Shrink dots where the map is crowded")
+	jitter := fs.Float64("pointJitter", 0, "Move each dot by up to this many pixels (0 = the run's pointJitter)")
+	palette := fs.String("palette", "", "Color palette: default or colorblind (empty = the run's palette)")
+	dead := fs.String("deadRendering", "", "How dead individuals are drawn: grey, fade, cross or remove (empty = the run's setting)")
+	every := fs.Int("every", 1, "Draw every Nth saved snapshot")
+	delay := fs.Int("delay", 0, "Delay between GIF frames in centiseconds (0 = the run's gifDelay)")
+	fs.Parse(args)
+
+	fail := func(msg string) {
+		fmt.Fprintln(msgOut, "Error:", msg)
+		os.Exit(2)
+	}
+	if *renderer != "spatial" && *renderer != "pie" && *renderer != "coverage" {
+		fail("-renderer must be spatial, pie or coverage")
+	}
+	if *palette != "" && !slices.Contains(paletteNames, *palette) {
+		fail("-palette must be one of " + fmt.Sprint(paletteNames))
+	}
+	switch deadRenderMode(*dead) {
+	case "", DeadGrey, DeadFade, DeadCross, DeadRemove:
+	default:
+		fail("-deadRendering must be grey, fade, cross or remove")
+	}
+	if *every < 1 {
+		fail("-every must be at least 1")
+	}
+	if *alpha < 0 || *alpha > 1 {
+		fail("-pointAlpha must be between 0 and 1")
+	}
+
+	// A run with compressOutputs saved snapshots.gob.gz
+	if _, err := os.Stat(*in); os.IsNotExist(err) {
+		if _, gzErr := os.Stat(*in + gzipSuffix); gzErr == nil {
+			*in += gzipSuffix
+		}
+	}
+	f, err := openInput(*in)
+	if err != nil {
+		fail(err.Error())
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(f)
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		fail(fmt.Sprintf("%s is not a snapshot file: %v", *in, err))
+	}
+
+	// Flags override the run's settings
+	if *width > 0 {
+		h.CanvasWidth = *width
+	}
+	if *radius > 0 {
+		h.PointRadius = *radius
+	}
+	if *grid > 0 {
+		h.CoverageGrid = *grid
+	}
+	if *palette != "" {
+		h.Palette = *palette
+	}
+	if *alpha > 0 {
+		h.PointAlpha = *alpha
+	}
+	if *density {
+		h.DensityPoints = true
+	}
+	if *jitter > 0 {
+		h.PointJitter = *jitter
+	}
+	if *dead != "" {
+		h.DeadRendering = deadRenderMode(*dead)
+	}
+	if *delay > 0 {
+		h.GIFDelay = *delay
+	}
+	h.SnapshotEvery *= *every // days between drawn frames, for fading the dead
+	if *out == "" {
+		*out = filepath.Join(filepath.Dir(*in), "render_"+*renderer+".gif")
+	}
+
+	env := replayEnvironment(h)
+	var frames []image.Image
+	for i := 0; ; i++ {
+		var rec snapshotDay
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			fail(fmt.Sprintf("reading %s: %v", *in, err))
+		}
+		if i%*every != 0 {
+			continue
+		}
+		rec.load(env)
+		switch *renderer {
+		case "spatial":
+			frames = append(frames, env.DrawToCanvas(h.CanvasWidth, h.PointRadius))
+		case "pie":
+			frames = append(frames, DrawEnvironmentPie(env, h.CanvasWidth))
+		case "coverage":
+			frames = append(frames, DrawCoverageMap(env, h.CanvasWidth, h.CoverageGrid))
+		}
+	}
+	if err := SaveEnvironmentGIF(*out, frames, h.GIFDelay); err != nil {
+		fail(fmt.Sprintf("saving %s: %v", *out, err))
+	}
+	fmt.Fprintf(msgOut, "Rendered %d frames to %s\n", len(frames), *out)
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DayStats is one row of the daily statistics output.
+type DayStats struct {
+	Day              int              `json:"day"`
+	Healthy          int              `json:"healthy"`
+	Susceptible      int              `json:"susceptible"`
+	Exposed          int              `json:"exposed,omitempty"` // infected but not yet infectious, only with seir
+	Infected         int              `json:"infected"`
+	Recovered        int              `json:"recovered"`
+	Dead             int              `json:"dead"`
+	InfectedFrac     float64          `json:"infectedFrac"`
+	Vaccinated       int              `json:"vaccinated"`
+	EnvHygiene       float64          `json:"envHygiene"`
+	EnvVaxRate       float64          `json:"envVaxRate"`
+	SDThreshold      float64          `json:"sdThreshold"`
+	PolicyTightened  bool             `json:"policyTightened"`
+	WardOccupied     int              `json:"wardOccupied"`
+	ICUOccupied      int              `json:"icuOccupied"`
+	StaffedBeds      int              `json:"staffedBeds"`
+	DiseaseDeaths    int              `json:"diseaseDeaths,omitempty"`    // cumulative, only with backgroundMortality
+	BackgroundDeaths int              `json:"backgroundDeaths,omitempty"` // cumulative deaths from other causes, only with backgroundMortality
+	NewInfections    int              `json:"newInfections"`              // incidence: infections that started today
+	NewDeaths        int              `json:"newDeaths"`                  // deaths from the simulated diseases today
+	NewRecoveries    int              `json:"newRecoveries"`              // recoveries today
+	NewAdmissions    int              `json:"newAdmissions"`              // hospital admissions today
+	DosesInStock     int              `json:"dosesInStock,omitempty"`     // vaccine doses on hand, only with a supply schedule
+	DosesWasted      int              `json:"dosesWasted,omitempty"`      // doses expired unused so far, only with a supply schedule
+	HygieneStock     int              `json:"hygieneStock,omitempty"`     // hygiene supplies on hand in person-days, only with hygieneSupply
+	HygieneSupply    float64          `json:"hygieneSupply,omitempty"`    // share of today's hygiene use covered by the stock, only with hygieneSupply
+	WardQueue        int              `json:"wardQueue,omitempty"`        // patients waiting for a ward bed, only with hospitalQueue
+	ICUQueue         int              `json:"icuQueue,omitempty"`         // patients waiting for an ICU bed, only with hospitalQueue
+	Quarantined      int              `json:"quarantined,omitempty"`      // individuals in isolation, only with detectionRate or testingRate
+	Traced           int              `json:"traced,omitempty"`           // contacts notified today, only with tracingFraction
+	TracedIsolated   int              `json:"tracedIsolated,omitempty"`   // of them, those who went into quarantine
+	Tested           int              `json:"tested,omitempty"`           // tests made today, only with testingRate
+	ReportedCases    int              `json:"reportedCases,omitempty"`    // infections detected today, only with testingRate
+	Lockdown         bool             `json:"lockdown,omitempty"`         // a lockdown is in force, only with lockdownThreshold
+	LockedCells      int              `json:"lockedCells,omitempty"`      // policy cells in lockdown, only with policyCells and lockdownThreshold
+	CellSDMin        float64          `json:"cellSDMin,omitempty"`        // lowest local threshold, only with policyCells
+	CellSDMax        float64          `json:"cellSDMax,omitempty"`        // highest local threshold, only with policyCells
+	InfectedNNDist   float64          `json:"infectedNNDist"`             // mean nearest-infected-neighbor distance
+	ClusterIndex     float64          `json:"clusterIndex"`               // Clark-Evans ratio of infected positions (<1 clustered)
+	Tags             []TagCounts      `json:"tags,omitempty"`             // per-tag counts, only when stratifyByTag is set
+	Profiles         []TagCounts      `json:"profiles,omitempty"`         // per-profile counts, only with profile.NAME
+	Pathogens        []PathogenCounts `json:"pathogens,omitempty"`        // per-pathogen counts, only with [disease.NAME] blocks
+	Regions          []RegionCounts   `json:"regions,omitempty"`          // per-region counts, only with [region.NAME] blocks
+	Annotation       string           `json:"annotation,omitempty"`       // scenario note for this day, if any
+	Reff             *float64         `json:"reff,omitempty"`             // R_effective of the day's infections, only with reportReff (stats file only, set at the end)
+}
+
+// TagCounts holds the status counts of one tagged subgroup.
+type TagCounts struct {
+	Healthy     int `json:"healthy"`
+	Susceptible int `json:"susceptible"`
+	Infected    int `json:"infected"`
+	Recovered   int `json:"recovered"`
+	Dead        int `json:"dead"`
+	Vaccinated  int `json:"vaccinated"`
+}
+
+// statsHeader returns the CSV header line matching DayStats.csvRow.
+func statsHeader(env *Environment) string {
+	incidence := ""
+	if env.reportIncidence {
+		incidence = ", NewInfections, NewDeaths, NewRecoveries, NewAdmissions"
+	}
+	supply := ""
+	if env.vaccineSupply.limited() {
+		supply = ", DosesInStock, DosesWasted"
+	}
+	if env.hygieneSupply.enabled {
+		supply += ", HygieneStock, HygieneSupply"
+	}
+	deaths := ""
+	if env.backgroundMortality.enabled {
+		deaths = ", DiseaseDeaths, BackgroundDeaths"
+	}
+	exposed := ""
+	if env.seir {
+		exposed = ", Exposed"
+	}
+	queue := ""
+	if env.hospitalQueue.enabled {
+		queue = ", WardQueue, ICUQueue"
+	}
+	if env.quarantine.enabled() {
+		queue += ", Quarantined"
+	}
+	if env.tracing.enabled() {
+		queue += ", Traced, TracedIsolated"
+	}
+	if env.quarantine.testing() {
+		queue += ", Tested, ReportedCases"
+	}
+	if env.lockdown.enabled() {
+		queue += ", Lockdown"
+		if len(env.cells) > 0 {
+			queue += ", LockedCells"
+		}
+	}
+	if len(env.cells) > 0 {
+		queue += ", CellSDMin, CellSDMax"
+	}
+	return fmt.Sprintf("Day%s, Healthy, Susceptible%s, Infected, Recovered, Dead, InfectedFrac, Vaccinated, EnvHygiene, EnvVaxRate, SDThreshold, PolicyTightened, WardOccupied, ICUOccupied, StaffedBeds%s%s%s%s, InfectedNNDist, ClusterIndex%s%s%s",
+		calendarHeader(env), exposed, deaths, queue, incidence, supply, tagStatsHeader(env)+profileStatsHeader(env), pathogenStatsHeader(env)+regionStatsHeader(env), annotationHeader(env))
+}
+
+// csvRow formats the row as a line of the stats CSV (without trailing newline).
+// When env.statsPer100k is set, every count column is printed per 100,000 population.
+func (s DayStats) csvRow(env *Environment) string {
+	count := countFormatter(env)
+
+	exposed := ""
+	if env.seir {
+		exposed = ", " + count(s.Exposed)
+	}
+	row := fmt.Sprintf(
+		"%d%s, %s, %s%s, %s, %s, %s, %.4f, %s, %.3f, %.3f, %.3f, %v, %s, %s, %s",
+		s.Day,
+		calendarColumns(env, s.Day),
+		count(s.Healthy),
+		count(s.Susceptible),
+		exposed,
+		count(s.Infected),
+		count(s.Recovered),
+		count(s.Dead),
+		s.InfectedFrac,
+		count(s.Vaccinated),
+		s.EnvHygiene,
+		s.EnvVaxRate,
+		s.SDThreshold,
+		s.PolicyTightened,
+		count(s.WardOccupied),
+		count(s.ICUOccupied),
+		count(s.StaffedBeds),
+	)
+	if env.backgroundMortality.enabled {
+		row += ", " + count(s.DiseaseDeaths) + ", " + count(s.BackgroundDeaths)
+	}
+	if env.hospitalQueue.enabled {
+		row += ", " + count(s.WardQueue) + ", " + count(s.ICUQueue)
+	}
+	if env.quarantine.enabled() {
+		row += ", " + count(s.Quarantined)
+	}
+	if env.tracing.enabled() {
+		row += ", " + count(s.Traced) + ", " + count(s.TracedIsolated)
+	}
+	if env.quarantine.testing() {
+		row += ", " + count(s.Tested) + ", " + count(s.ReportedCases)
+	}
+	if env.lockdown.enabled() {
+		row += fmt.Sprintf(", %v", s.Lockdown)
+		if len(env.cells) > 0 {
+			row += fmt.Sprintf(", %d", s.LockedCells)
+		}
+	}
+	if len(env.cells) > 0 {
+		row += fmt.Sprintf(", %.3f, %.3f", s.CellSDMin, s.CellSDMax)
+	}
+	if env.reportIncidence {
+		row += ", " + count(s.NewInfections) + ", " + count(s.NewDeaths) + ", " +
+			count(s.NewRecoveries) + ", " + count(s.NewAdmissions)
+	}
+	if env.vaccineSupply.limited() {
+		row += fmt.Sprintf(", %d, %d", s.DosesInStock, s.DosesWasted)
+	}
+	if env.hygieneSupply.enabled {
+		row += fmt.Sprintf(", %d, %.3f", s.HygieneStock, s.HygieneSupply)
+	}
+	row += fmt.Sprintf(", %.3f, %.3f", s.InfectedNNDist, s.ClusterIndex)
+	for _, t := range s.Tags {
+		row += fmt.Sprintf(", %s, %s, %s, %s, %s, %s",
+			count(t.Healthy), count(t.Susceptible), count(t.Infected), count(t.Recovered), count(t.Dead), count(t.Vaccinated))
+	}
+	for _, t := range s.Profiles {
+		row += fmt.Sprintf(", %s, %s, %s, %s, %s, %s",
+			count(t.Healthy), count(t.Susceptible), count(t.Infected), count(t.Recovered), count(t.Dead), count(t.Vaccinated))
+	}
+	for _, p := range s.Pathogens {
+		row += ", " + count(p.Infected) + ", " + count(p.Recovered) + ", " + count(p.Dead)
+	}
+	for _, r := range s.Regions {
+		row += ", " + count(r.Population) + ", " + count(r.Infected) + ", " + count(r.Recovered) + ", " + count(r.Dead)
+	}
+	row += annotationColumn(env, s.Annotation)
+	return row
+}
+
+// countFormatter returns the function used to print count columns: plain integers,
+// or values per 100,000 population when env.statsPer100k is set.
+func countFormatter(env *Environment) func(int) string {
+	n := len(env.population)
+	if !env.statsPer100k || n == 0 {
+		return func(c int) string { return fmt.Sprintf("%d", c) }
+	}
+	scale := 100000.0 / float64(n)
+	return func(c int) string { return fmt.Sprintf("%.1f", float64(c)*scale) }
+}
+
+// printStats prints one stats row to stdout.
+func printStats(env *Environment, s DayStats) {
+	fmt.Println(s.csvRow(env))
+}
+
+// aggregateStats averages a run of consecutive days into one row labeled with the last day.
+// Counts are rounded means, PolicyTightened and Lockdown are true if they were true on any of the days.
+func aggregateStats(rows []DayStats) DayStats {
+	if len(rows) == 0 {
+		return DayStats{}
+	}
+	n := float64(len(rows))
+	mean := func(get func(DayStats) float64) float64 {
+		sum := 0.0
+		for _, r := range rows {
+			sum += get(r)
+		}
+		return sum / n
+	}
+	meanInt := func(get func(DayStats) int) int {
+		return int(math.Round(mean(func(r DayStats) float64 { return float64(get(r)) })))
+	}
+
+	out := DayStats{
+		Day:              rows[len(rows)-1].Day,
+		Healthy:          meanInt(func(r DayStats) int { return r.Healthy }),
+		Susceptible:      meanInt(func(r DayStats) int { return r.Susceptible }),
+		Exposed:          meanInt(func(r DayStats) int { return r.Exposed }),
+		Infected:         meanInt(func(r DayStats) int { return r.Infected }),
+		Recovered:        meanInt(func(r DayStats) int { return r.Recovered }),
+		Dead:             meanInt(func(r DayStats) int { return r.Dead }),
+		InfectedFrac:     mean(func(r DayStats) float64 { return r.InfectedFrac }),
+		Vaccinated:       meanInt(func(r DayStats) int { return r.Vaccinated }),
+		EnvHygiene:       mean(func(r DayStats) float64 { return r.EnvHygiene }),
+		EnvVaxRate:       mean(func(r DayStats) float64 { return r.EnvVaxRate }),
+		SDThreshold:      mean(func(r DayStats) float64 { return r.SDThreshold }),
+		WardOccupied:     meanInt(func(r DayStats) int { return r.WardOccupied }),
+		ICUOccupied:      meanInt(func(r DayStats) int { return r.ICUOccupied }),
+		StaffedBeds:      meanInt(func(r DayStats) int { return r.StaffedBeds }),
+		DiseaseDeaths:    rows[len(rows)-1].DiseaseDeaths, // cumulative
+		BackgroundDeaths: rows[len(rows)-1].BackgroundDeaths,
+		NewInfections:    meanInt(func(r DayStats) int { return r.NewInfections }),
+		NewDeaths:        meanInt(func(r DayStats) int { return r.NewDeaths }),
+		NewRecoveries:    meanInt(func(r DayStats) int { return r.NewRecoveries }),
+		NewAdmissions:    meanInt(func(r DayStats) int { return r.NewAdmissions }),
+		DosesInStock:     meanInt(func(r DayStats) int { return r.DosesInStock }),
+		DosesWasted:      rows[len(rows)-1].DosesWasted, // cumulative, so take the last day
+		HygieneStock:     meanInt(func(r DayStats) int { return r.HygieneStock }),
+		HygieneSupply:    mean(func(r DayStats) float64 { return r.HygieneSupply }),
+		WardQueue:        meanInt(func(r DayStats) int { return r.WardQueue }),
+		ICUQueue:         meanInt(func(r DayStats) int { return r.ICUQueue }),
+		Quarantined:      meanInt(func(r DayStats) int { return r.Quarantined }),
+		Traced:           meanInt(func(r DayStats) int { return r.Traced }),
+		TracedIsolated:   meanInt(func(r DayStats) int { return r.TracedIsolated }),
+		Tested:           meanInt(func(r DayStats) int { return r.Tested }),
+		ReportedCases:    meanInt(func(r DayStats) int { return r.ReportedCases }),
+		LockedCells:      meanInt(func(r DayStats) int { return r.LockedCells }),
+		CellSDMin:        mean(func(r DayStats) float64 { return r.CellSDMin }),
+		CellSDMax:        mean(func(r DayStats) float64 { return r.CellSDMax }),
+		InfectedNNDist:   mean(func(r DayStats) float64 { return r.InfectedNNDist }),
+		ClusterIndex:     mean(func(r DayStats) float64 { return r.ClusterIndex }),
+	}
+	var notes []string
+	for _, r := range rows {
+		out.PolicyTightened = out.PolicyTightened || r.PolicyTightened
+		out.Lockdown = out.Lockdown || r.Lockdown
+		if r.Annotation != "" {
+			notes = append(notes, r.Annotation)
+		}
+	}
+	out.Annotation = strings.Join(notes, "; ")
+
+	if len(rows[0].Tags) > 0 {
+		out.Tags = make([]TagCounts, len(rows[0].Tags))
+		for i := range out.Tags {
+			out.Tags[i] = TagCounts{
+				Healthy:     meanInt(func(r DayStats) int { return r.Tags[i].Healthy }),
+				Susceptible: meanInt(func(r DayStats) int { return r.Tags[i].Susceptible }),
+				Infected:    meanInt(func(r DayStats) int { return r.Tags[i].Infected }),
+				Recovered:   meanInt(func(r DayStats) int { return r.Tags[i].Recovered }),
+				Dead:        meanInt(func(r DayStats) int { return r.Tags[i].Dead }),
+				Vaccinated:  meanInt(func(r DayStats) int { return r.Tags[i].Vaccinated }),
+			}
+		}
+	}
+	if len(rows[0].Profiles) > 0 {
+		out.Profiles = make([]TagCounts, len(rows[0].Profiles))
+		for i := range out.Profiles {
+			out.Profiles[i] = TagCounts{
+				Healthy:     meanInt(func(r DayStats) int { return r.Profiles[i].Healthy }),
+				Susceptible: meanInt(func(r DayStats) int { return r.Profiles[i].Susceptible }),
+				Infected:    meanInt(func(r DayStats) int { return r.Profiles[i].Infected }),
+				Recovered:   meanInt(func(r DayStats) int { return r.Profiles[i].Recovered }),
+				Dead:        meanInt(func(r DayStats) int { return r.Profiles[i].Dead }),
+				Vaccinated:  meanInt(func(r DayStats) int { return r.Profiles[i].Vaccinated }),
+			}
+		}
+	}
+	if len(rows[0].Pathogens) > 0 {
+		out.Pathogens = make([]PathogenCounts, len(rows[0].Pathogens))
+		for i := range out.Pathogens {
+			out.Pathogens[i] = PathogenCounts{
+				Infected:  meanInt(func(r DayStats) int { return r.Pathogens[i].Infected }),
+				Recovered: meanInt(func(r DayStats) int { return r.Pathogens[i].Recovered }),
+				Dead:      rows[len(rows)-1].Pathogens[i].Dead, // cumulative
+			}
+		}
+	}
+	if len(rows[0].Regions) > 0 {
+		out.Regions = make([]RegionCounts, len(rows[0].Regions))
+		for i := range out.Regions {
+			out.Regions[i] = RegionCounts{
+				Population: meanInt(func(r DayStats) int { return r.Regions[i].Population }),
+				Infected:   meanInt(func(r DayStats) int { return r.Regions[i].Infected }),
+				Recovered:  meanInt(func(r DayStats) int { return r.Regions[i].Recovered }),
+				Dead:       rows[len(rows)-1].Regions[i].Dead, // cumulative
+			}
+		}
+	}
+	return out
+}
+
+// statsWindow prints stats rows, optionally in rolling window mode.
+// With size == 0 every row is printed as soon as it is added. With size = N,
+// only the last N days are held back at full detail; older days are folded
+// into weekly averages (one row per 7 days) which are printed as soon as the
+// week is complete, so memory stays bounded no matter how long the run is.
+type statsWindow struct {
+	size   int
+	env    *Environment
+	out    *outputStream // also writes rows to stats.csv, if set
+	recent []DayStats    // last size days, full detail
+	week   []DayStats    // days evicted from recent, waiting to form a week
+}
+
+// print prints a row and writes it to the output stream, if any.
+func (w *statsWindow) print(s DayStats) {
+	printStats(w.env, s)
+	w.out.writeStats(s.csvRow(w.env))
+}
+
+// add records a new day of stats.
+func (w *statsWindow) add(s DayStats) {
+	if w.size <= 0 {
+		w.print(s)
+		return
+	}
+	w.recent = append(w.recent, s)
+	if len(w.recent) <= w.size {
+		return
+	}
+	w.week = append(w.week, w.recent[0])
+	w.recent = w.recent[1:]
+	if len(w.week) == 7 {
+		w.print(aggregateStats(w.week))
+		w.week = w.week[:0]
+	}
+}
+
+// flush prints any partial week followed by the detailed window, at the end of the run.
+func (w *statsWindow) flush() {
+	if len(w.week) > 0 {
+		w.print(aggregateStats(w.week))
+		w.week = w.week[:0]
+	}
+	for _, s := range w.recent {
+		w.print(s)
+	}
+	w.recent = nil
+}
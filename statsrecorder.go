@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// StatsRecorder keeps every day's stats row so they can be written to a file
+// at the end of the run, in addition to the console output. Rows are kept at
+// full detail even in statsWindowDays mode.
+type StatsRecorder struct {
+	env  *Environment
+	rows []DayStats
+}
+
+// statsFormat selects the file format written by a StatsRecorder.
+type statsFormat string
+
+const (
+	StatsCSV  statsFormat = "csv"  // the same columns as the console output
+	StatsJSON statsFormat = "json" // the model topology and an array with one object per day
+)
+
+// statsFile is the layout of a JSON stats file.
+type statsFile struct {
+	Model string     `json:"model"` // health states and waning edge, see modelTopology
+	Days  []statsRow `json:"days"`
+}
+
+// statsRow is a DayStats in a JSON stats file. Optional fields are left out
+// when they are zero only if the run does not report them, so every object
+// has the columns of the CSV header.
+type statsRow struct {
+	DayStats
+	columns map[string]bool // DayStats fields in the CSV header
+}
+
+// statsColumns returns the names of the stats file's CSV columns.
+func statsColumns(env *Environment) map[string]bool {
+	columns := make(map[string]bool)
+	for _, name := range strings.Split(statsHeader(env)+reffHeader(env), ", ") {
+		columns[name] = true
+	}
+	return columns
+}
+
+// MarshalJSON writes the fields of the row in DayStats order under their
+// json names, keeping zero omitempty fields whose column is enabled.
+func (r statsRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	v := reflect.ValueOf(r.DayStats)
+	t := v.Type()
+	buf.WriteByte('{')
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		value := v.Field(i)
+		if opts == "omitempty" && value.IsZero() && !r.columns[f.Name] {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "%q:", name)
+		if err := enc.Encode(value.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// add records one day.
+func (r *StatsRecorder) add(s DayStats) {
+	if r != nil {
+		r.rows = append(r.rows, s)
+	}
+}
+
+// save writes all recorded rows to path in the given format, gzipped if
+// path ends in .gz.
+func (r *StatsRecorder) save(path string, format statsFormat) error {
+	w, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case StatsJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		columns := statsColumns(r.env)
+		days := make([]statsRow, len(r.rows))
+		for i, s := range r.rows {
+			days[i] = statsRow{s, columns}
+		}
+		err = enc.Encode(statsFile{Model: modelTopology(r.env.seir, r.env.immunityWaning), Days: days})
+	default:
+		fmt.Fprintln(w, statsHeader(r.env)+reffHeader(r.env))
+		for _, s := range r.rows {
+			fmt.Fprintln(w, s.csvRow(r.env)+reffColumn(r.env, s))
+		}
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestStatsRowKeepsEnabledColumns checks that a JSON stats row has every
+// column the run reports, even on a day it is zero, and none of the others.
+func TestStatsRowKeepsEnabledColumns(t *testing.T) {
+	env := &Environment{seir: true, reportReff: true}
+	env.hospitalQueue.enabled = true
+	s := DayStats{Day: 3, Healthy: 10, WardQueue: 2}
+	data, err := json.Marshal(statsRow{s, statsColumns(env)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("%v in %s", err, data)
+	}
+	for _, name := range []string{"day", "healthy", "exposed", "wardQueue", "icuQueue", "reff", "newInfections"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s is missing from %s", name, data)
+		}
+	}
+	for _, name := range []string{"diseaseDeaths", "quarantined", "dosesInStock", "lockdown", "tags", "annotation"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("%s is not reported by the run but is in %s", name, data)
+		}
+	}
+	if got["wardQueue"] != 2.0 || got["icuQueue"] != 0.0 || got["reff"] != nil {
+		t.Errorf("wrong values in %s", data)
+	}
+}
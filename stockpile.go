@@ -0,0 +1,190 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Preparedness stockpile.
+//
+// A stockpile is a finite supply set aside before the outbreak for one
+// target group: everyone (random), the elderly (stockpileElderlyAge and
+// over) or healthcare workers (tagged healthcare_worker). At day 0,
+// prophylaxisDoses are handed out by lottery within the group, one per
+// person, and a recipient counts as vaccinated from then on. The
+// antiviralCourses are kept for the same group: every day, each member who
+// is Infected with the main disease and not yet treated gets a course, in
+// population order, until none are left. A treated infection's daily
+// mortality is multiplied by antiviralMortality and its recovery rate by
+// antiviralRecovery.
+//
+// The lottery uses a hash of the run's seed and the individual rather than
+// the run's generator. At the end, the same config is simulated again with
+// the same seed but no stockpile, and the summary reports how much of the
+// stockpile was used, when the antivirals ran out, and the infections,
+// deaths and peak it averted, also per 100 doses and courses used.
+
+// stockpileTarget selects who the stockpile is for.
+type stockpileTarget string
+
+const (
+	StockpileRandom     stockpileTarget = "random"     // everyone
+	StockpileElderly    stockpileTarget = "elderly"    // stockpileElderlyAge and over
+	StockpileHealthcare stockpileTarget = "healthcare" // tagged healthcare_worker
+)
+
+// StockpileConfig configures the preparedness stockpile.
+type StockpileConfig struct {
+	target      stockpileTarget
+	elderlyAge  int     // first age of the elderly group
+	prophylaxis int     // doses handed out at day 0
+	antivirals  int     // courses kept for the target group
+	mortality   float64 // multiplier on a treated infection's mortality
+	recovery    float64 // multiplier on a treated infection's recovery rate
+}
+
+// enabled reports whether there is a stockpile.
+func (s StockpileConfig) enabled() bool { return s.prophylaxis > 0 || s.antivirals > 0 }
+
+// stockpileState tracks the stockpile over a run.
+type stockpileState struct {
+	StockpileConfig
+	dosesGiven  int // prophylactic doses handed out
+	coursesUsed int // antiviral courses given so far
+	depletedDay int // day the last course was given, 0 while any are left
+}
+
+// newStockpile returns the state of the stockpile in s, or nil without one.
+func newStockpile(s StockpileConfig) *stockpileState {
+	if !s.enabled() {
+		return nil
+	}
+	return &stockpileState{StockpileConfig: s}
+}
+
+// inTarget reports whether ind belongs to the stockpile's target group.
+func (s *stockpileState) inTarget(ind *Individual) bool {
+	switch s.target {
+	case StockpileElderly:
+		return ind.age >= s.elderlyAge
+	case StockpileHealthcare:
+		return ind.hasTag(healthcareWorkerTag)
+	}
+	return true
+}
+
+// allocateProphylaxis hands out the prophylactic doses at day 0: the living
+// members of the target group are ordered by a lottery ticket drawn from the
+// run's random stream id and the individual, and the first ones get a dose.
+func allocateProphylaxis(env *Environment, id rngStream) {
+	s := env.stockpile
+	if s == nil || s.prophylaxis == 0 {
+		return
+	}
+	var group []*Individual
+	for _, ind := range env.population {
+		if ind != nil && ind.healthStatus != Dead && !ind.vaccinated && s.inTarget(ind) {
+			group = append(group, ind)
+		}
+	}
+	ticket := func(ind *Individual) float64 { return chainUniform(id.stream, ind.id, uint64(id.seed)) }
+	slices.SortFunc(group, func(a, b *Individual) int { return cmp.Compare(ticket(a), ticket(b)) })
+	for _, ind := range group[:min(s.prophylaxis, len(group))] {
+		ind.vaccinated = true
+		ind.daysSinceVacination = 0
+		s.dosesGiven++
+	}
+}
+
+// treatWithAntivirals gives a course to every untreated Infected member of the
+// target group, in population order, while courses last.
+func treatWithAntivirals(env *Environment) {
+	s := env.stockpile
+	if s == nil || s.coursesUsed >= s.antivirals {
+		return
+	}
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus != Infected || ind.infection == nil || ind.infection.treated {
+			continue
+		}
+		if ind.infection.disease != env.disease || !s.inTarget(ind) {
+			continue
+		}
+		ind.infection.treated = true
+		s.coursesUsed++
+		if s.coursesUsed == s.antivirals {
+			s.depletedDay = env.day
+			return
+		}
+	}
+}
+
+// antiviralMortality returns the multiplier on ind's mortality, 1 unless it
+// was treated.
+func antiviralMortality(env *Environment, ind *Individual) float64 {
+	if env.stockpile == nil || !ind.infection.treated {
+		return 1
+	}
+	return env.stockpile.mortality
+}
+
+// antiviralRecovery returns the multiplier on ind's recovery rate, 1 unless
+// it was treated.
+func antiviralRecovery(env *Environment, ind *Individual) float64 {
+	if env.stockpile == nil || !ind.infection.treated {
+		return 1
+	}
+	return env.stockpile.recovery
+}
+
+// printStockpileSummary reports the use of the stockpile and compares the
+// run, whose daily stats are rows, with a run of config with the same random
+// stream id and no stockpile.
+func printStockpileSummary(env *Environment, config *Config, id rngStream, rows []DayStats) {
+	s := env.stockpile
+	if s == nil {
+		return
+	}
+	groupSize := 0
+	for _, ind := range env.population {
+		if ind != nil && s.inTarget(ind) {
+			groupSize++
+		}
+	}
+	group := string(s.target)
+	if s.target == StockpileElderly {
+		group = fmt.Sprintf("elderly, %d and over", s.elderlyAge)
+	}
+	fmt.Fprintf(msgOut, "Stockpile (target group %s: %d individuals):\n", group, groupSize)
+	if s.prophylaxis > 0 {
+		fmt.Fprintf(msgOut, "  Prophylaxis: %d of %d doses given at day 0\n", s.dosesGiven, s.prophylaxis)
+	}
+	if s.antivirals > 0 {
+		if s.depletedDay > 0 {
+			fmt.Fprintf(msgOut, "  Antivirals: all %d courses used, the last on day %d\n", s.antivirals, s.depletedDay)
+		} else {
+			fmt.Fprintf(msgOut, "  Antivirals: %d of %d courses used, %d left\n", s.coursesUsed, s.antivirals, s.antivirals-s.coursesUsed)
+		}
+	}
+
+	with := scenarioResult{rows: rows}
+	with.countInfected(env)
+	base := *config
+	base.stockpile = StockpileConfig{}
+	without := simulateConfig("no stockpile", &base, id)
+	if without.err != nil {
+		fmt.Fprintln(msgOut, "  No-stockpile comparison failed:", without.err)
+		return
+	}
+	w, wo := with.outcome(), without.outcome()
+	fmt.Fprintf(msgOut, "  Versus no stockpile (same seed): %d infected vs %d, %d deaths vs %d, peak %d vs %d\n",
+		with.everInfected, without.everInfected, w.deaths, wo.deaths, w.peakInfected, wo.peakInfected)
+	infections, deaths := without.everInfected-with.everInfected, wo.deaths-w.deaths
+	fmt.Fprintf(msgOut, "  Averted: %d infections, %d deaths, %d at the peak", infections, deaths, wo.peakInfected-w.peakInfected)
+	if used := s.dosesGiven + s.coursesUsed; used > 0 {
+		fmt.Fprintf(msgOut, " (per 100 doses and courses used: %.1f infections, %.2f deaths)",
+			100*float64(infections)/float64(used), 100*float64(deaths)/float64(used))
+	}
+	fmt.Fprintln(msgOut)
+}
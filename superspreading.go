@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// Superspreading. By default every infectious individual is as infectious as
+// any other, so the number of people each one infects varies little around
+// R. Real epidemics are overdispersed: most cases infect no one and a few
+// infect many. With infectiousnessDispersion = k > 0, each new infection
+// draws an infectiousness multiplier from a gamma distribution with mean 1
+// and shape k, which scales every contact it has (neighbors, co-passengers
+// and housemates). Secondary cases then follow a negative binomial with
+// dispersion k; estimates for SARS-CoV-2 are about 0.1-0.5. The smaller k,
+// the more transmission comes from a few superspreaders and the more often
+// an introduction dies out on its own.
+//
+// The transmission log (see chains.go) is kept, and the summary reports the
+// distribution of secondary cases of the completed infections, with the
+// dispersion estimated from its mean and variance for comparison.
+
+// drawInfectiousness sets the infectiousness of a new infection with dis. It
+// draws nothing if dis has no dispersion.
+func drawInfectiousness(inf *Infection, dis *Disease, rng *rand.Rand) {
+	if dis == nil || dis.dispersion <= 0 {
+		return
+	}
+	k := dis.dispersion
+	inf.infectiousness = gammaSample(k, rngOrDefault(rng)) / k
+}
+
+// infectiousness returns the multiplier on the contacts of inf, 1 unless its
+// disease has a dispersion.
+func infectiousness(inf *Infection) float64 {
+	if inf == nil || inf.disease == nil || inf.disease.dispersion <= 0 {
+		return 1
+	}
+	return inf.infectiousness
+}
+
+// escapeGroup returns the probability of escaping infection by the count
+// infectious members of group, as judged by infectious. A contact with one
+// of them infects with probability clamp01(raw) times its infectiousness;
+// each is offered to source. When everyone is equally infectious the product
+// is a power.
+func escapeGroup(env *Environment, raw float64, count int, group []*Individual, infectious func(*Individual) bool, source *sourcePicker) float64 {
+	if env.disease == nil || env.disease.dispersion <= 0 {
+		pi := clamp01(raw)
+		if source != nil {
+			for _, m := range group {
+				if infectious(m) {
+					source.offer(m.infection, pi)
+				}
+			}
+		}
+		return math.Pow(1-pi, float64(count))
+	}
+	fail := 1.0
+	for _, m := range group {
+		if infectious(m) {
+			pi := clamp01(raw * infectiousness(m.infection))
+			fail *= 1 - pi
+			source.offer(m.infection, pi)
+		}
+	}
+	return fail
+}
+
+// offspringStats describes the secondary cases of completed infections.
+type offspringStats struct {
+	completed    int
+	mean, vari   float64
+	k            float64 // dispersion estimated as mean²/(variance-mean), +Inf if not overdispersed
+	top20Share   float64 // share of transmission by the 20% most infectious
+	maxOffspring int
+}
+
+// summarizeOffspring computes offspringStats from the transmission log.
+func summarizeOffspring(env *Environment) offspringStats {
+	var s offspringStats
+	events := env.chains.events
+	ongoing := make([]bool, len(events))
+	for _, ind := range env.population {
+		if ind == nil || ind.infection == nil || ind.healthStatus == Dead {
+			continue
+		}
+		if k := ind.infection.chain; k >= 0 && k < len(events) {
+			ongoing[k] = true
+		}
+	}
+	var offspring []int
+	total := 0
+	for k, t := range events {
+		if !ongoing[k] {
+			offspring = append(offspring, t.offspring)
+			total += t.offspring
+		}
+	}
+	s.completed = len(offspring)
+	if s.completed == 0 {
+		return s
+	}
+	s.mean = float64(total) / float64(s.completed)
+	for _, n := range offspring {
+		d := float64(n) - s.mean
+		s.vari += d * d
+		s.maxOffspring = max(s.maxOffspring, n)
+	}
+	if s.completed > 1 {
+		s.vari /= float64(s.completed - 1)
+	}
+	s.k = math.Inf(1)
+	if s.vari > s.mean {
+		s.k = s.mean * s.mean / (s.vari - s.mean)
+	}
+	if total > 0 {
+		slices.Sort(offspring)
+		slices.Reverse(offspring)
+		top := 0
+		for _, n := range offspring[:int(math.Ceil(0.2*float64(s.completed)))] {
+			top += n
+		}
+		s.top20Share = float64(top) / float64(total)
+	}
+	return s
+}
+
+// printSuperspreadingSummary reports the distribution of secondary cases.
+func printSuperspreadingSummary(env *Environment) {
+	if env.disease == nil || env.disease.dispersion <= 0 || env.chains == nil {
+		return
+	}
+	s := summarizeOffspring(env)
+	fmt.Fprintf(msgOut, "Superspreading (infectiousnessDispersion = %g): %d completed infections\n", env.disease.dispersion, s.completed)
+	if s.completed == 0 {
+		return
+	}
+	estimate := "not overdispersed"
+	if !math.IsInf(s.k, 1) {
+		estimate = fmt.Sprintf("estimated dispersion k ~%.2f", s.k)
+	}
+	fmt.Fprintf(msgOut, "  Secondary cases: mean %.2f, variance %.2f (%s), most %d\n", s.mean, s.vari, estimate, s.maxOffspring)
+	fmt.Fprintf(msgOut, "  The top 20%% of infectors caused %.1f%% of transmission\n", 100*s.top20Share)
+	h := summarizeChains(env).histogram
+	fmt.Fprintf(msgOut, "  Distribution: 0: %d, 1: %d, 2: %d, 3-5: %d, 6-10: %d, >10: %d\n", h[0], h[1], h[2], h[3], h[4], h[5])
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parameter sweeps. A numeric parameter written as a start:step:end range
+// declares a sweep, in the config file or on the command line:
+//
+//	vaccinationRate = 0.0:0.1:0.9
+//	popSize = 1000:1000:5000
+//
+// The run then simulates every combination of the swept values (the cross
+// product, with the first swept parameter changing slowest) instead of a
+// single run. Each combination is the config with those values set, checked
+// like any other config, so derived defaults such as medicalCapacity follow a
+// swept popSize. All combinations use the same seed (-seed, else randomSeed,
+// else the time), so differences come from the parameters rather than from
+// chance; with -parallel they run at the same time. Like scenarios, they
+// draw no frames and write no per-run outputs.
+//
+// output_gif/sweep_results.csv gets one row per combination: the swept values,
+// then the peak infected count and its day, the deaths, the duration (the
+// last day anyone was Exposed or Infected) and the attack rate. With
+// sweepHeatmap set to one of those outcomes (peak, deaths, duration or attack)
+// and at least two swept parameters, output_gif/sweep_heatmap.png shows it
+// over the first two; with more, each cell is the mean over the others.
+
+// maxSweepRuns caps the number of combinations in a sweep.
+const maxSweepRuns = 10000
+
+// Outcomes a sweep heatmap can show.
+const (
+	SweepHeatmapNone = "none"
+	SweepPeak        = "peak"
+	SweepDeaths      = "deaths"
+	SweepDuration    = "duration"
+	SweepAttackRate  = "attack"
+)
+
+var sweepHeatmapChoices = []string{SweepHeatmapNone, SweepPeak, SweepDeaths, SweepDuration, SweepAttackRate}
+
+// sweepOutcomeNames labels the heatmap outcomes.
+var sweepOutcomeNames = map[string]string{
+	SweepPeak:       "Peak infected",
+	SweepDeaths:     "Deaths",
+	SweepDuration:   "Duration (days)",
+	SweepAttackRate: "Attack rate",
+}
+
+// sweepParam is a swept parameter and its values, as written in a config.
+type sweepParam struct {
+	name   string
+	values []string
+}
+
+// sweepRange expands value if it is a start:step:end range. ok is false if
+// value is a single value or the parameter cannot be swept; a malformed range
+// is reported to v and returns no values.
+func sweepRange(v *ConfigValidator, spec *ParamSpec, key, value string) (values []string, ok bool) {
+	if spec.Suffix != "" || spec.Block != "" || spec.Kind != KindFloat && spec.Kind != KindInt {
+		return nil, false
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	var start, step, end float64
+	decimals := 0
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		x, err := strconv.ParseFloat(p, 64)
+		if err != nil || spec.Kind == KindInt && x != math.Trunc(x) {
+			v.AddError(key, value, "a sweep range is start:step:end, all numbers (whole numbers for "+key+")")
+			return nil, true
+		}
+		if _, frac, found := strings.Cut(p, "."); found {
+			decimals = max(decimals, len(frac))
+		}
+		switch i {
+		case 0:
+			start = x
+		case 1:
+			step = x
+		default:
+			end = x
+		}
+	}
+	if step <= 0 || end < start {
+		v.AddError(key, value, "a sweep range needs step > 0 and end >= start")
+		return nil, true
+	}
+	n := int(math.Floor((end-start)/step+1e-9)) + 1
+	if n > maxSweepRuns {
+		v.AddError(key, value, fmt.Sprintf("a sweep range cannot have more than %d values", maxSweepRuns))
+		return nil, true
+	}
+	for i := range n {
+		values = append(values, strconv.FormatFloat(start+float64(i)*step, 'f', decimals, 64))
+	}
+	return values, true
+}
+
+// addSweep records a swept parameter; a later range for the same parameter
+// replaces the earlier one.
+func (c *Config) addSweep(name string, values []string) {
+	c.sweep = slices.DeleteFunc(c.sweep, func(p sweepParam) bool { return p.name == name })
+	c.sweep = append(c.sweep, sweepParam{name, values})
+}
+
+// sweepRuns returns the number of combinations in c's sweep.
+func (c *Config) sweepRuns() int {
+	n := 1
+	for _, p := range c.sweep {
+		n *= len(p.values)
+		if n > maxSweepRuns {
+			return n
+		}
+	}
+	return n
+}
+
+// sweepPoints returns every combination of the swept values, the first
+// parameter changing slowest.
+func sweepPoints(params []sweepParam) [][]configOverride {
+	points := [][]configOverride{nil}
+	for _, p := range params {
+		var next [][]configOverride
+		for _, point := range points {
+			for _, value := range p.values {
+				next = append(next, append(slices.Clip(point), configOverride{p.name, value}))
+			}
+		}
+		points = next
+	}
+	return points
+}
+
+// sweepPointConfig loads the run's config (configFile, preset or the
+// defaults, with the command-line overrides) with point's values set.
+func sweepPointConfig(configFile, preset string, point []configOverride) (*Config, error) {
+	saved := configOverrides
+	configOverrides = append(slices.Clip(saved), point...)
+	defer func() { configOverrides = saved }()
+	switch {
+	case preset != "":
+		return loadPreset(preset)
+	case configFile != "":
+		return loadConfigFromFile(configFile)
+	}
+	return validateConfig(getDefaultConfig(), NewConfigValidator())
+}
+
+// sweepResult is the run of one combination.
+type sweepResult struct {
+	point []configOverride
+	scenarioResult
+}
+
+// duration returns the last day anyone was Exposed or Infected.
+func (res sweepResult) duration() int {
+	last := 0
+	for _, r := range res.rows {
+		if r.Exposed > 0 || r.Infected > 0 {
+			last = r.Day
+		}
+	}
+	return last
+}
+
+// value returns the outcome named by one of the sweepHeatmap choices.
+func (res sweepResult) value(outcome string) float64 {
+	o := res.outcome()
+	switch outcome {
+	case SweepPeak:
+		return float64(o.peakInfected)
+	case SweepDeaths:
+		return float64(o.deaths)
+	case SweepDuration:
+		return float64(res.duration())
+	}
+	return o.attackRate
+}
+
+// runSweep runs every combination of config's sweep; configFile and preset
+// are where config came from.
+func runSweep(config *Config, configFile, preset string, seed int64, parallel bool) {
+	if seed == 0 {
+		seed = config.randomSeed
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	points := sweepPoints(config.sweep)
+	names := make([]string, len(config.sweep))
+	for i, p := range config.sweep {
+		names[i] = p.name
+	}
+	fmt.Fprintf(msgOut, "Sweeping %s: %d runs, random seed %d\n", strings.Join(names, ", "), len(points), seed)
+
+	// The configs are loaded first, one at a time, since loading reads the
+	// shared command-line overrides
+	results := make([]sweepResult, len(points))
+	configs := make([]*Config, len(points))
+	for i, point := range points {
+		results[i].point = point
+		results[i].name = pointText(point)
+		if c, err := sweepPointConfig(configFile, preset, point); err != nil {
+			results[i].err = err
+		} else {
+			configs[i] = c
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range configs {
+		if c == nil {
+			continue
+		}
+		run := func() {
+			results[i].scenarioResult = simulateConfig(results[i].name, c, rngStream{alg: c.rngAlgorithm, seed: seed})
+		}
+		if !parallel {
+			fmt.Fprintf(msgOut, "Running %d of %d: %s\n", i+1, len(points), results[i].name)
+			run()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run()
+		}()
+	}
+	wg.Wait()
+
+	var done []sweepResult
+	for _, res := range results {
+		if res.err != nil {
+			fmt.Fprintf(msgOut, "Run %s failed: %v\n", res.name, res.err)
+		}
+		if len(res.rows) == 0 {
+			continue
+		}
+		o := res.outcome()
+		fmt.Fprintf(msgOut, "%s: peak %d infected on day %d, %d deaths, lasted %d days, attack rate %.1f%%\n",
+			res.name, o.peakInfected, o.peakDay, o.deaths, res.duration(), 100*o.attackRate)
+		done = append(done, res)
+	}
+	if len(done) == 0 {
+		return
+	}
+
+	outputDir := "output_gif"
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Fprintf(msgOut, "failed to create output directory '%s': %v\n", outputDir, err)
+		return
+	}
+	path := outputDir + "/sweep_results.csv"
+	if err := saveSweepResults(path, names, done); err != nil {
+		fmt.Fprintln(msgOut, "failed to save sweep results:", err)
+	} else {
+		fmt.Fprintln(msgOut, "Sweep results saved to:", path)
+	}
+	if config.sweepHeatmap == SweepHeatmapNone {
+		return
+	}
+	if len(config.sweep) < 2 {
+		fmt.Fprintln(msgOut, "Sweep heatmap skipped: it needs two swept parameters")
+		return
+	}
+	path = outputDir + "/sweep_heatmap.png"
+	if err := SaveSweepHeatmap(path, config.sweep, done, config.sweepHeatmap); err != nil {
+		fmt.Fprintln(msgOut, "failed to save sweep heatmap:", err)
+	} else {
+		fmt.Fprintln(msgOut, "Sweep heatmap saved to:", path)
+	}
+}
+
+// pointText names a combination, e.g. "vaccinationRate=0.3 popSize=2000".
+func pointText(point []configOverride) string {
+	parts := make([]string, len(point))
+	for i, o := range point {
+		parts[i] = o.key + "=" + o.value
+	}
+	return strings.Join(parts, " ")
+}
+
+// saveSweepResults writes one row per combination with its outcomes.
+func saveSweepResults(path string, names []string, results []sweepResult) error {
+	return writeCSV(path, func(w *bufio.Writer) {
+		fmt.Fprintf(w, "%s, Seed, PeakInfected, PeakDay, Deaths, Duration, AttackRate, Infections\n", strings.Join(names, ", "))
+		for _, res := range results {
+			o := res.outcome()
+			for _, p := range res.point {
+				fmt.Fprintf(w, "%s, ", p.value)
+			}
+			fmt.Fprintf(w, "%d, %d, %d, %d, %d, %.4f, %d\n",
+				res.rng.seed, o.peakInfected, o.peakDay, o.deaths, res.duration(), o.attackRate, o.infections)
+		}
+	})
+}
+
+// Layout of the sweep heatmap in pixels.
+const (
+	heatmapCell   = 56  // side of a cell
+	heatmapLeft   = 110 // room for the y values and name
+	heatmapTop    = 40  // room for the title
+	heatmapBottom = 56  // room for the x values, name and scale
+	heatmapRight  = 20
+)
+
+// DrawSweepHeatmap draws outcome over the first two swept parameters of
+// params, x across and y up, averaging over any others. Cells run from green
+// (lowest) to red (highest); combinations that failed are grey.
+func DrawSweepHeatmap(params []sweepParam, results []sweepResult, outcome string) image.Image {
+	xs, ys := params[0], params[1]
+	nx, ny := len(xs.values), len(ys.values)
+	sums := make([]float64, nx*ny)
+	counts := make([]int, nx*ny)
+	for _, res := range results {
+		i := slices.Index(xs.values, res.point[0].value)
+		j := slices.Index(ys.values, res.point[1].value)
+		sums[j*nx+i] += res.value(outcome)
+		counts[j*nx+i]++
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for k, n := range counts {
+		if n > 0 {
+			sums[k] /= float64(n)
+			lo, hi = min(lo, sums[k]), max(hi, sums[k])
+		}
+	}
+	format := func(v float64) string {
+		if outcome == SweepAttackRate {
+			return fmt.Sprintf("%.0f%%", 100*v)
+		}
+		return fmt.Sprintf("%.0f", v)
+	}
+
+	title := sweepOutcomeNames[outcome] + " by " + xs.name + " and " + ys.name
+	if len(params) > 2 {
+		title += " (mean over the other swept parameters)"
+	}
+	width := max(heatmapLeft+nx*heatmapCell+heatmapRight, 7*len(title)+20)
+	height := heatmapTop + ny*heatmapCell + heatmapBottom
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	drawLabel(img, 10, 20, color.White, title)
+	for j := range ny {
+		y0 := heatmapTop + (ny-1-j)*heatmapCell // the first value at the bottom
+		drawLabel(img, heatmapLeft-7*len(ys.values[j])-6, y0+heatmapCell/2+4, color.White, ys.values[j])
+		for i := range nx {
+			x0 := heatmapLeft + i*heatmapCell
+			col := color.RGBA{40, 40, 40, 255}
+			k := j*nx + i
+			if counts[k] > 0 {
+				level := 0.5
+				if hi > lo {
+					level = (sums[k] - lo) / (hi - lo)
+				}
+				col = coverageColor(1 - level)
+			}
+			// one pixel of black between cells
+			draw.Draw(img, image.Rect(x0, y0, x0+heatmapCell-1, y0+heatmapCell-1), &image.Uniform{col}, image.Point{}, draw.Src)
+			if text := format(sums[k]); counts[k] > 0 && 7*len(text) < heatmapCell-4 {
+				drawLabel(img, x0+(heatmapCell-7*len(text))/2, y0+heatmapCell/2+4, color.Black, text)
+			}
+		}
+	}
+	bottom := heatmapTop + ny*heatmapCell
+	for i, v := range xs.values {
+		drawLabel(img, heatmapLeft+i*heatmapCell+(heatmapCell-7*len(v))/2, bottom+16, color.White, v)
+	}
+	drawLabel(img, heatmapLeft, bottom+34, color.White, xs.name)
+	drawLabel(img, 10, heatmapTop+12, color.White, ys.name)
+	if !math.IsInf(lo, 0) {
+		drawLabel(img, heatmapLeft, bottom+50, color.White, fmt.Sprintf("green %s ... red %s", format(lo), format(hi)))
+	}
+	return img
+}
+
+// SaveSweepHeatmap writes the sweep heatmap of outcome as a PNG.
+func SaveSweepHeatmap(filename string, params []sweepParam, results []sweepResult, outcome string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, DrawSweepHeatmap(params, results, outcome)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// assignTags gives every individual each configured tag independently with
+// probability spec.fraction, and stores the specs on the environment so
+// the update functions can look up tag multipliers.
+func assignTags(env *Environment, specs []*TagSpec, rng *rand.Rand) {
+	if env == nil {
+		return
+	}
+	rng = rngOrDefault(rng)
+	env.tagSpecs = specs
+	for _, ind := range env.population {
+		if ind == nil {
+			continue
+		}
+		for _, spec := range specs {
+			if rng.Float64() < spec.fraction {
+				ind.tags = append(ind.tags, spec.name)
+			}
+		}
+	}
+}
+
+// hasTag reports whether the individual carries the given tag.
+func (ind *Individual) hasTag(name string) bool {
+	for _, t := range ind.tags {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tagSpec returns the environment's spec for a tag name, or nil if unknown.
+func (env *Environment) tagSpec(name string) *TagSpec {
+	for _, spec := range env.tagSpecs {
+		if spec.name == name {
+			return spec
+		}
+	}
+	return nil
+}
+
+// tagExposureMult returns the combined exposure multiplier of all the individual's tags (1 if untagged).
+func tagExposureMult(env *Environment, ind *Individual) float64 {
+	mult := 1.0
+	if env == nil || ind == nil {
+		return mult
+	}
+	for _, t := range ind.tags {
+		if spec := env.tagSpec(t); spec != nil {
+			mult *= spec.exposureMult
+		}
+	}
+	return mult
+}
+
+// tagVaccinationPriority returns the highest vaccination priority among the individual's tags (1 if untagged).
+func tagVaccinationPriority(env *Environment, ind *Individual) float64 {
+	priority := 1.0
+	if env == nil || ind == nil {
+		return priority
+	}
+	for _, t := range ind.tags {
+		if spec := env.tagSpec(t); spec != nil && spec.vaccinationPriority > priority {
+			priority = spec.vaccinationPriority
+		}
+	}
+	return priority
+}
+
+// hasVaccinationPriorities reports whether any tag changes the vaccination order.
+func (env *Environment) hasVaccinationPriorities() bool {
+	for _, spec := range env.tagSpecs {
+		if spec.vaccinationPriority != 1.0 {
+			return true
+		}
+	}
+	return false
+}
+
+// vaccinationOrder returns population indices in the order vaccination should be offered.
+// Without tag priorities this is a uniform random permutation. With priorities, indices
+// are drawn by weighted sampling without replacement (key = -ln(U)/w, smallest first),
+// so high-priority tags tend to be offered first without fully excluding everyone else.
+func vaccinationOrder(env *Environment, rng *rand.Rand) []int {
+	n := len(env.population)
+	if !env.hasVaccinationPriorities() {
+		return rng.Perm(n)
+	}
+
+	keys := make([]float64, n)
+	indices := make([]int, n)
+	for i, ind := range env.population {
+		indices[i] = i
+		w := tagVaccinationPriority(env, ind)
+		if w <= 0 {
+			keys[i] = math.Inf(1)
+			continue
+		}
+		keys[i] = -math.Log(1-rng.Float64()) / w
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		return keys[indices[a]] < keys[indices[b]]
+	})
+	return indices
+}
+
+// tagStatsHeader returns the extra CSV header columns for per-tag stratified stats.
+func tagStatsHeader(env *Environment) string {
+	if env == nil || !env.stratifyByTag {
+		return ""
+	}
+	out := ""
+	for _, spec := range env.tagSpecs {
+		n := spec.name
+		out += fmt.Sprintf(", %s_Healthy, %s_Susceptible, %s_Infected, %s_Recovered, %s_Dead, %s_Vaccinated", n, n, n, n, n, n)
+	}
+	return out
+}
+
+// collectTagCounts returns per-tag status counts in env.tagSpecs order,
+// or nil if stratified stats are disabled.
+func collectTagCounts(env *Environment) []TagCounts {
+	if env == nil || !env.stratifyByTag {
+		return nil
+	}
+	out := make([]TagCounts, len(env.tagSpecs))
+	for i, spec := range env.tagSpecs {
+		c := &out[i]
+		for _, ind := range env.population {
+			if ind == nil || !ind.hasTag(spec.name) {
+				continue
+			}
+			if ind.vaccinated && ind.healthStatus != Dead {
+				c.Vaccinated++
+			}
+			switch ind.healthStatus {
+			case Healthy:
+				c.Healthy++
+			case Susceptible:
+				c.Susceptible++
+			case Infected:
+				c.Infected++
+			case Recovered:
+				c.Recovered++
+			case Dead:
+				c.Dead++
+			}
+		}
+	}
+	return out
+}
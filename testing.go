@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Testing. With testingRate > 0, every day each living individual is tested
+// with that probability. A test of an infected individual (Exposed or
+// Infected with the main disease) is positive with probability
+// testSensitivity; tests of anyone else are negative. A positive test detects
+// the infection like detectionRate does (see quarantine.go): it is reported,
+// its contacts are traced and the individual may go into isolation. An
+// infection that was missed can be found by a later test, and one already
+// detected is not reported again. The stats gain the day's tests (Tested)
+// and detections (ReportedCases), so the observed epidemic can be compared
+// with the true one.
+
+// runTests tests today's sample of the population.
+func runTests(env *Environment, rng *rand.Rand) {
+	q := &env.quarantine
+	if !q.testing() {
+		return
+	}
+	q.startDay(env.day)
+	for _, ind := range env.population {
+		if ind == nil || ind.healthStatus == Dead || drawFloat(rng) >= q.testRate {
+			continue
+		}
+		q.tested++
+		q.testedToday++
+		if ind.infection == nil || ind.infection.detected {
+			continue
+		}
+		q.testedInfected++
+		if drawFloat(rng) >= q.sensitivity {
+			q.falseNegatives++
+			continue
+		}
+		detect(env, ind, env.day, rng)
+	}
+}
+
+// reportedToday returns the tests made on day and the infections detected.
+func reportedToday(env *Environment, day int) (tested, reported int) {
+	q := env.quarantine
+	if q.today != day {
+		return 0, 0
+	}
+	return q.testedToday, q.detectedToday
+}
+
+// printTestingSummary reports testing and the share of infections reported
+// at the end of the run.
+func printTestingSummary(env *Environment) {
+	q := env.quarantine
+	if !q.testing() {
+		return
+	}
+	infections := 0
+	for _, ind := range env.population {
+		if ind != nil {
+			infections += ind.timesInfected
+		}
+	}
+	fmt.Fprintf(msgOut, "Testing: %d tests, %d of infected individuals, %d false negatives\n",
+		q.tested, q.testedInfected, q.falseNegatives)
+	fmt.Fprintf(msgOut, "  Reported cases: %d of %d infections (%.1f%%)\n",
+		q.detected, infections, 100*float64(q.detected)/float64(max(infections, 1)))
+}
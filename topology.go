@@ -0,0 +1,44 @@
+package main
+
+import "strings"
+
+// Model topology.
+//
+// By default immunity wanes back to Healthy (Recovered -> Healthy), so a
+// recovered individual has to come into contact with an infected one again
+// (Healthy -> Susceptible) before it can be reinfected. With
+// immunityWaning = susceptible, waning leads straight to Susceptible as in
+// the standard SIRS/SEIRS models: the individual is at risk of infection on
+// the very next day, and drops back to Healthy if that exposure does not
+// infect it.
+
+// immunityWaning selects the state Recovered individuals return to.
+type immunityWaning string
+
+const (
+	WaningHealthy     immunityWaning = "healthy"     // Recovered -> Healthy (default)
+	WaningSusceptible immunityWaning = "susceptible" // Recovered -> Susceptible
+)
+
+// wanedStatus returns the health status of an individual whose immunity has
+// just waned.
+func wanedStatus(env *Environment) HealthStatus {
+	if env != nil && env.immunityWaning == WaningSusceptible {
+		return Susceptible
+	}
+	return Healthy
+}
+
+// modelTopology describes the run's health states and the waning edge, for
+// the console and the stats file, e.g. "H-S-E-I-R-D, R->S".
+func modelTopology(seir bool, waning immunityWaning) string {
+	states := []string{"H", "S", "I", "R", "D"}
+	if seir {
+		states = []string{"H", "S", "E", "I", "R", "D"}
+	}
+	edge := "R->H"
+	if waning == WaningSusceptible {
+		edge = "R->S"
+	}
+	return strings.Join(states, "-") + ", " + edge
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Contact tracing. With tracingFraction > 0, every detected infection (see
+// quarantine.go) is traced: the contacts the individual met over the last
+// tracingDays days, read from its contact log, are each reached with
+// probability tracingFraction. Contact memory is switched on for at least
+// tracingDays days. Reached contacts are notified tracingDelay days after
+// the detection (an EventTraceNotice) and then go into quarantine like a
+// detected case: with probability quarantineComplianceRate, for
+// quarantineDays days. Contacts are traced whether or not they are infected,
+// and whether or not the detected individual isolates itself; tracing does
+// not chain from notified contacts unless they are detected in turn.
+
+// TracingConfig configures contact tracing.
+type TracingConfig struct {
+	fraction float64 // share of contacts reached, 0 = no tracing
+	days     int     // days of contacts traced back
+	delay    int     // days from detection to notification
+
+	notified         int // contacts notified so far
+	isolated         int // notified contacts who went into quarantine
+	infectedAtNotice int // notified contacts who were infected at the time
+
+	today         int // day the daily counts below belong to
+	notifiedToday int
+	isolatedToday int
+}
+
+// enabled reports whether detected infections are traced.
+func (t TracingConfig) enabled() bool { return t.fraction > 0 }
+
+// traceContacts picks which of ind's recent contacts will be reached after
+// its infection is detected on day, and schedules their notifications.
+func traceContacts(env *Environment, ind *Individual, day int, rng *rand.Rand) {
+	t := &env.tracing
+	if !t.enabled() {
+		return
+	}
+	for _, c := range recentContacts(env, ind, t.days) {
+		if c == nil || c == ind || drawFloat(rng) >= t.fraction {
+			continue
+		}
+		scheduleEvent(env, float64(day+t.delay), scheduledEvent{kind: EventTraceNotice, ind: c})
+	}
+}
+
+// notifyContact notifies ind that it was in contact with a detected case; if
+// it complies, it starts (or extends) a quarantine.
+func notifyContact(env *Environment, ind *Individual, day int, rng *rand.Rand) {
+	t := &env.tracing
+	if t.today != day {
+		t.today, t.notifiedToday, t.isolatedToday = day, 0, 0
+	}
+	t.notified++
+	t.notifiedToday++
+	if ind.healthStatus == Exposed || ind.healthStatus == Infected {
+		t.infectedAtNotice++
+	}
+	if decisionDraw(env, ind, DecisionQuarantine, rng) >= env.quarantine.complianceRate {
+		return
+	}
+	t.isolated++
+	t.isolatedToday++
+	startQuarantine(env, ind, day)
+}
+
+// tracedToday returns the contacts notified on day and how many of them
+// went into quarantine.
+func tracedToday(env *Environment, day int) (notified, isolated int) {
+	t := env.tracing
+	if t.today != day {
+		return 0, 0
+	}
+	return t.notifiedToday, t.isolatedToday
+}
+
+// printTracingSummary reports contact tracing at the end of the run.
+func printTracingSummary(env *Environment) {
+	t := env.tracing
+	if !t.enabled() {
+		return
+	}
+	fmt.Fprintf(msgOut, "Contact tracing: %d contacts notified, %d quarantined, %d of them infected when notified\n",
+		t.notified, t.isolated, t.infectedAtNotice)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// agentIDs is a repeatable command-line flag holding individual IDs.
+// Each use may give one ID or a comma-separated list: -trackAgent 3 -trackAgent 10,42
+type agentIDs []int
+
+func (l *agentIDs) String() string {
+	parts := make([]string, len(*l))
+	for i, id := range *l {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *agentIDs) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 0 {
+			return fmt.Errorf("invalid individual ID %q", part)
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
+
+// agentTracker logs the full state of selected individuals every day, for
+// debugging why an individual behaves unexpectedly.
+type agentTracker struct {
+	ids  []int
+	w    *outputFile
+	path string
+}
+
+// trackedAgentsFileName is the name of the tracker's log in the output directory.
+const trackedAgentsFileName = "tracked_agents.csv"
+
+// newAgentTracker opens outputDir/name for the given IDs, which must be valid
+// indices into a population of size n.
+func newAgentTracker(outputDir, name string, ids []int, n int) (*agentTracker, error) {
+	for _, id := range ids {
+		if id >= n {
+			return nil, fmt.Errorf("individual %d does not exist (popSize %d)", id, n)
+		}
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(outputDir, name)
+	w, err := createOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &agentTracker{ids: ids, w: w, path: path}
+	fmt.Fprintln(t.w, "Day, ID, X, Y, MoveType, HealthStatus, Severity, DaysInfected, DaysSinceRecovery, Vaccinated, DaysSinceVaccination, Hygiene, Compliance, A, B, C, D, E")
+	return t, nil
+}
+
+// record writes one row per tracked individual for the end of the current day.
+// The probabilities a-e are the ones computed for the individual during
+// today's health update (all zero on day 0).
+func (t *agentTracker) record(env *Environment) {
+	if t == nil {
+		return
+	}
+	for _, id := range t.ids {
+		ind := env.population[id]
+		if ind == nil {
+			continue
+		}
+		var p transitionProbs
+		if env.day > 0 && id < len(env.probsBuf) {
+			p = env.probsBuf[id]
+		}
+		mt := moveType("")
+		if ind.movementPattern != nil {
+			mt = ind.movementPattern.moveType
+		}
+		severity, daysInfected := Severity(""), 0
+		if ind.infection != nil {
+			severity, daysInfected = ind.infection.severity, ind.infection.daysInfected
+		}
+		fmt.Fprintf(t.w, "%d, %d, %.4f, %.4f, %s, %s, %s, %d, %d, %v, %d, %.4f, %.4f, %.6f, %.6f, %.6f, %.6f, %.6f\n",
+			env.day, id, ind.position.x, ind.position.y, mt, ind.healthStatus, severity, daysInfected,
+			ind.daysSinceRecovery, ind.vaccinated, ind.daysSinceVacination, ind.hygieneLevel,
+			ind.socialDistanceCompliance, p.a, p.b, p.c, p.d, p.e)
+	}
+}
+
+// close flushes and closes the log.
+func (t *agentTracker) close() error {
+	if t == nil {
+		return nil
+	}
+	return t.w.Close()
+}
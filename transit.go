@@ -0,0 +1,63 @@
+package main
+
+import "math/rand"
+
+// boardVehicles builds today's train and flight manifests.
+// Every living individual whose movement pattern for today is Train or Flight
+// is assigned, in random order, to a vehicle of that type; a new vehicle is
+// started whenever the current one is full. Manifests from the previous day
+// are discarded. Does nothing unless transit vehicles are enabled.
+func boardVehicles(env *Environment, rng *rand.Rand) {
+	if env == nil {
+		return
+	}
+	for _, v := range env.vehicles {
+		for _, p := range v.passengers {
+			p.vehicle = nil
+		}
+	}
+	env.vehicles = env.vehicles[:0]
+	if !env.transit.enabled {
+		return
+	}
+	rng = rngOrDefault(rng)
+
+	capacity := map[moveType]int{
+		Train:  env.transit.trainCapacity,
+		Flight: env.transit.flightCapacity,
+	}
+	current := map[moveType]*Vehicle{}
+
+	for _, idx := range rng.Perm(len(env.population)) {
+		ind := env.population[idx]
+		if ind == nil || ind.healthStatus == Dead || ind.movementPattern == nil || quarantined(ind) {
+			continue
+		}
+		mt := ind.movementPattern.moveType
+		if mt != Train && mt != Flight {
+			continue
+		}
+		v := current[mt]
+		if v == nil || len(v.passengers) >= capacity[mt] {
+			v = &Vehicle{moveType: mt}
+			current[mt] = v
+			env.vehicles = append(env.vehicles, v)
+		}
+		v.passengers = append(v.passengers, ind)
+		ind.vehicle = v
+	}
+}
+
+// infectedCoPassengers returns the number of other infected passengers on ind's vehicle today.
+func infectedCoPassengers(ind *Individual) int {
+	if ind == nil || ind.vehicle == nil {
+		return 0
+	}
+	count := 0
+	for _, p := range ind.vehicle.passengers {
+		if p != ind && p.healthStatus == Infected {
+			count++
+		}
+	}
+	return count
+}
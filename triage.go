@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Admission criteria and ICU allocation, with hospitalQueue enabled.
+//
+// By default every Severe case may have a ward bed and every Critical case an
+// ICU bed, first come, first served. Admission criteria restrict that, as in
+// crisis standards of care:
+//   - admissionSeverity = critical: Severe cases get no ward bed;
+//   - admissionMaxAge: patients older than this get no bed at all;
+//   - icuMaxAge: patients older than this get no ICU bed;
+//   - icuExcludeComorbid: patients carrying comorbidityTag (a population tag,
+//     see tags.go) get no ICU bed.
+//
+// A patient who fails the criteria when its case first needs a bed is denied
+// care for that case: it never joins a queue and, like a waiting patient, has
+// its mortality multiplied by the waiting multiplier of its bed type and
+// recovers without the care boost. With icuAllocation = youngest, free ICU
+// beds go to the youngest waiting patients instead of the longest waiting.
+// At the end of the run a table gives, per age band, the patients who needed
+// a bed, were admitted, were denied care, and died without ever getting a bed.
+
+// icuAllocation is the order in which free ICU beds are given out.
+type icuAllocation string
+
+const (
+	ICUFirstCome icuAllocation = "fifo"     // longest waiting first (default)
+	ICUYoungest  icuAllocation = "youngest" // youngest first, then longest waiting
+)
+
+// admissionSeverities maps the admissionSeverity choices to severities.
+var admissionSeverities = map[string]Severity{"severe": Severe, "critical": Critical}
+
+// AdmissionCriteria configures who may be given a hospital bed.
+type AdmissionCriteria struct {
+	minSeverity        Severity // least severe case admitted: Severe or Critical
+	maxAge             int      // older patients get no bed, 0 = no limit
+	icuMaxAge          int      // older patients get no ICU bed, 0 = no limit
+	comorbidityTag     string   // tag marking individuals with comorbidities, "" = none
+	icuExcludeComorbid bool     // patients with comorbidities get no ICU bed
+	icuAllocation      icuAllocation
+}
+
+// restricts reports whether any criterion can deny a patient care.
+func (a AdmissionCriteria) restricts() bool {
+	return a.minSeverity == Critical || a.maxAge > 0 || a.icuMaxAge > 0 || a.icuExcludeComorbid
+}
+
+// careTally counts the patients of one age band over the run.
+type careTally struct {
+	needed      int // cases that needed a bed
+	admitted    int // of them, given a bed
+	denied      int // denied care by the admission criteria
+	diedDenied  int // died after being denied care
+	diedWaiting int // died in the queue before getting a bed
+}
+
+// admissible reports whether the admission criteria let ind have a bed of
+// the kind it needs.
+func admissible(a AdmissionCriteria, ind *Individual, icu bool) bool {
+	if !icu && a.minSeverity == Critical {
+		return false
+	}
+	if a.maxAge > 0 && ind.age > a.maxAge {
+		return false
+	}
+	if icu && a.icuMaxAge > 0 && ind.age > a.icuMaxAge {
+		return false
+	}
+	if icu && a.icuExcludeComorbid && ind.hasTag(a.comorbidityTag) {
+		return false
+	}
+	return true
+}
+
+// orderICUQueue sorts the ICU queue by the allocation rule; the sort is
+// stable, so ties keep their waiting order.
+func orderICUQueue(a AdmissionCriteria, queue []*Individual) {
+	if a.icuAllocation != ICUYoungest {
+		return
+	}
+	slices.SortStableFunc(queue, func(x, y *Individual) int { return x.age - y.age })
+}
+
+// printCareByAge reports, per age band, who needed a bed and who went
+// without one. It prints nothing unless someone was denied care or died
+// waiting.
+func printCareByAge(env *Environment) {
+	h := env.hospital
+	denied, died := 0, 0
+	for _, t := range h.byAge {
+		denied += t.denied
+		died += t.diedDenied + t.diedWaiting
+	}
+	if !env.hospitalQueue.enabled || denied+died == 0 {
+		return
+	}
+	fmt.Fprintln(msgOut, "Hospital care by age:")
+	fmt.Fprintf(msgOut, "  %-6s %8s %9s %7s %12s %12s\n", "Age", "Needed", "Admitted", "Denied", "Died denied", "Died waiting")
+	for i, t := range h.byAge {
+		if t.needed == 0 {
+			continue
+		}
+		fmt.Fprintf(msgOut, "  %-6s %8d %9d %7d %12d %12d\n", ageBandLabel(i), t.needed, t.admitted, t.denied, t.diedDenied, t.diedWaiting)
+	}
+}
@@ -13,9 +13,12 @@ import (
 
 // UpdateEnvironment performs a full environment-level update for one timestep.
 // It computes population statistics, updates policy-level social distance threshold,
-// updates environmental hygiene level, and synchronizes the environment vaccination rate.
+// updates environmental hygiene level, synchronizes the environment vaccination rate,
+// and starts or lifts a lockdown. With policy cells, each cell also updates its
+// own threshold and lockdown (see policycells.go). Policy reacts to the
+// infected fraction as the informationSource reports it (see information.go).
 // Returns:
-//   infectedFraction: current fraction of infected individuals (0..1)
+//   infectedFraction: fraction of infected individuals the policy reacted to (0..1)
 //   tightened: whether social distance policy was tightened during this update
 //   err: error, if any
 func UpdateEnvironment(env *Environment, rng *rand.Rand) (float64, bool, error) {
@@ -29,6 +32,8 @@ func UpdateEnvironment(env *Environment, rng *rand.Rand) (float64, bool, error)
 	// 1) Compute population-level statistics
 	infectedFraction, _, totalVaccinated, popHygieneMean, avgTransDist, popSize :=
 		ComputePopulationStats(env)
+	// What the policy learns: the true fraction, or reported cases (possibly late)
+	infectedFraction = reportedPrevalence(env, infectedFraction)
 
 	// 2) Update social distance threshold (policy decision)
 	tightened, err := updateSocialDistanceThreshold(env, infectedFraction, avgTransDist)
@@ -48,6 +53,14 @@ func UpdateEnvironment(env *Environment, rng *rand.Rand) (float64, bool, error)
 		return infectedFraction, tightened, err
 	}
 
+	// 5) Start or lift a lockdown (no-op unless lockdownThreshold is set),
+	// or update each policy cell's threshold and lockdown
+	if len(env.cells) > 0 {
+		updatePolicyCells(env, infectedFraction, avgTransDist)
+	} else {
+		updateLockdown(env, infectedFraction)
+	}
+
 	return infectedFraction, tightened, nil
 }
 
@@ -58,15 +71,13 @@ func UpdateEnvironment(env *Environment, rng *rand.Rand) (float64, bool, error)
 //   totalInfected: integer infected count
 //   totalVaccinated: integer vaccinated count
 //   popHygieneMean: mean of individual's hygieneLevel (0..1)
-//   avgTransDist: average transmissionDistance among individuals with disease info (fallback 1.0)
+//   avgTransDist: transmissionDistance of the environment's disease (fallback 1.0)
 //   n: population size (count of non-nil individuals)
 func ComputePopulationStats(env *Environment) (infectedFraction float64, totalInfected int, totalVaccinated int, popHygieneMean float64, avgTransDist float64, n int) {
 	if env == nil || len(env.population) == 0 {
 		return 0, 0, 0, 0, 1.0, 0
 	}
 	sumHyg := 0.0
-	sumTrans := 0.0
-	transCount := 0
 	total := 0
 	infected := 0
 	vaccinated := 0
@@ -83,10 +94,6 @@ func ComputePopulationStats(env *Environment) (infectedFraction float64, totalIn
 			vaccinated++
 		}
 		sumHyg += clamp01(ind.hygieneLevel)
-		if ind.disease != nil && ind.disease.transmissionDistance > 0 {
-			sumTrans += ind.disease.transmissionDistance
-			transCount++
-		}
 	}
 
 	popHyg := 0.0
@@ -94,8 +101,8 @@ func ComputePopulationStats(env *Environment) (infectedFraction float64, totalIn
 		popHyg = sumHyg / float64(total)
 	}
 	avgTD := 1.0
-	if transCount > 0 {
-		avgTD = sumTrans / float64(transCount)
+	if env.disease != nil && env.disease.transmissionDistance > 0 {
+		avgTD = env.disease.transmissionDistance
 	}
 	infFrac := 0.0
 	if total > 0 {
@@ -106,53 +113,58 @@ func ComputePopulationStats(env *Environment) (infectedFraction float64, totalIn
 }
 
 // updateSocialDistanceThreshold updates env.socialDistanceThreshold based on
-// infectedFraction and avgTransDist. It applies smoothing and overload tightening.
+// the configured trigger metric (infectedFraction by default, or hospital/ICU
+// occupancy) and avgTransDist. It applies smoothing and overload tightening.
 // Returns true if the policy was effectively tightened (candidate < previous).
 func updateSocialDistanceThreshold(env *Environment, infectedFraction float64, avgTransDist float64) (bool, error) {
 	if env == nil {
 		return false, errors.New("nil environment")
 	}
-	if avgTransDist <= 0 {
-		avgTransDist = 1.0
-	}
 
-	// Decide factor buckets driven by infection prevalence
-	var factor float64
-	switch {
-	case infectedFraction < 0.01:
-		factor = 2.0
-	case infectedFraction < 0.05:
-		factor = 1.5
-	case infectedFraction < 0.10:
-		factor = 1.0
-	case infectedFraction < 0.20:
-		factor = 0.7
-	default:
-		factor = 0.4
-	}
+	// Decide factor buckets driven by the trigger metric (prevalence by default)
+	signal := triggerValue(env, env.distancingTrigger, infectedFraction)
+	factor := distancingFactor(env.distancingTrigger, signal)
 
 	// If medical capacity overloaded, tighten further
-	if env.medicalCapacity > 0 && infectedFraction > 0 {
+	factor *= overloadTightening(env, infectedFraction)
+
+	var tightened bool
+	env.socialDistanceThreshold, tightened = nextDistanceThreshold(env.socialDistanceThreshold, factor, avgTransDist)
+	return tightened, nil
+}
+
+// overloadTightening returns the multiplier on the threshold factor while
+// the approximate infected count exceeds the medical capacity (capacity
+// shrinks when healthcare workers are out sick), or 1.
+func overloadTightening(env *Environment, infectedFraction float64) float64 {
+	capacity, _ := effectiveCapacity(env)
+	if capacity > 0 && infectedFraction > 0 {
 		// approximate infected count from fraction and capacity
 		approxInfected := int(math.Round(infectedFraction * float64(len(env.population))))
-		if approxInfected > env.medicalCapacity {
-			ratio := float64(approxInfected-env.medicalCapacity) / float64(env.medicalCapacity)
+		if approxInfected > capacity {
+			ratio := float64(approxInfected-capacity) / float64(capacity)
 			// convert overload to multiplier in (0.75,1]
-			extraTighten := 1.0 - clamp01(ratio*0.25)
-			factor *= extraTighten
+			return 1.0 - clamp01(ratio*0.25)
 		}
 	}
+	return 1.0
+}
+
+// nextDistanceThreshold returns the threshold that follows prev given the
+// policy factor, and whether the policy was tightened.
+func nextDistanceThreshold(prev float64, factor float64, avgTransDist float64) (float64, bool) {
+	if avgTransDist <= 0 {
+		avgTransDist = 1.0
+	}
 
 	// candidate threshold derived from avgTransDist scaled by factor
 	candidate := avgTransDist * factor
 
 	// smoothing (exponential) to avoid policy oscillations
 	alpha := 0.25
-	prev := env.socialDistanceThreshold
-	if prev <= 0 {
-		env.socialDistanceThreshold = candidate
-	} else {
-		env.socialDistanceThreshold = prev*(1.0-alpha) + candidate*alpha
+	next := candidate
+	if prev > 0 {
+		next = prev*(1.0-alpha) + candidate*alpha
 	}
 
 	// enforce reasonable bounds relative to avgTransDist
@@ -161,15 +173,15 @@ func updateSocialDistanceThreshold(env *Environment, infectedFraction float64, a
 		minThresh = 0.1
 	}
 	maxThresh := 4.0 * avgTransDist
-	env.socialDistanceThreshold = math.Max(env.socialDistanceThreshold, minThresh)
-	env.socialDistanceThreshold = math.Min(env.socialDistanceThreshold, maxThresh)
+	next = math.Max(next, minThresh)
+	next = math.Min(next, maxThresh)
 
 	// tightened if candidate is less than previous (before smoothing)
 	tightened := false
 	if prev > 0 && candidate < prev {
 		tightened = true
 	}
-	return tightened, nil
+	return next, tightened
 }
 
 // updateEnvHygieneLevel updates env.hygieneLevel using population mean hygiene,
@@ -11,8 +11,10 @@ import (
 // Rules:
 // - Healthy -> Susceptible with prob a; else stays Healthy
 // - Susceptible -> Infected with prob b; else Healthy
+//   (with seir, Susceptible -> Exposed, and Exposed -> Infected after latentPeriod days)
 // - Infected -> Dead with prob c; else Recovered with prob d; else stays Infected
 // - Recovered -> Healthy with prob e; else stays Recovered
+//   (with immunityWaning = susceptible, Recovered -> Susceptible)
 // - Dead -> stays Dead
 //
 // Constraints: 0<=a,b,c,d,e<=1 and c+d<=1.
@@ -29,55 +31,79 @@ func UpdatePopulationHealthStatus(env *Environment, rng *rand.Rand) error {
 	}
 	rng = rngOrDefault(rng)
 
+	// Start counting today's transitions (new infections, deaths, recoveries).
+	env.transitions = dailyTransitions{}
+
+	// Today's hygiene supply delivery (no-op unless hygieneSupply is set)
+	restockHygiene(env)
+
+	// Days spent in isolation (no-op unless detectionRate is set)
+	tallyQuarantine(env)
+
+	// Today's tests, which may detect infections (no-op unless testingRate is set)
+	runTests(env, rng)
+
 	// 1) Perform environment-level vaccination rollout once per generation.
 	//    This avoids repeatedly attempting rollout for each individual.
 	_, _ = UpdateVaccination(env, rng)
 
-	// 2) Calculate total infected count for overload consideration.
-	infectedTotal := 0
-	for _, p := range env.population {
-		if p != nil && p.healthStatus == Infected {
-			infectedTotal++
-		}
-	}
+	// 2) Calculate ward/ICU bed demand for overload consideration,
+	//    and assign beds if patients are admitted individually.
+	admitPatients(env)
+	load := computeCareLoad(env)
 
-	// probs holds computed probabilities for each individual before applying updates.
-	type probs struct {
-		a, b, c, d, e float64
-	}
-	ps := make([]probs, len(env.population))
+	// ps holds computed probabilities for each individual before applying updates.
+	// The buffer is kept on env and reused across days to avoid reallocating it.
+	ps := env.probsBuffer()
+
+	// Skip transmission on days with no one infectious (see fastforward.go)
+	env.fastForward.startDay(env)
+
+	// With batchTransmission, copy today's infected into flat slices (see batch.go)
+	prepareBatch(env)
 
 	// 3) Compute transition probabilities for all individuals (read-only phase).
 	//    Computing first prevents within-step dependencies caused by ordering.
-	for i, ind := range env.population {
-		if ind == nil {
-			ps[i] = probs{}
-			continue
+	//    Chunks of the population are computed in parallel (see workers.go);
+	//    the first error in population order is returned.
+	errs := make([]error, (len(env.population)+workChunk-1)/workChunk)
+	npi := make([]*npiTally, len(errs)) // per-chunk tallies for the NPI report, nil if off
+	if env.npiReport {
+		for c := range npi {
+			npi[c] = &npiTally{}
 		}
-		var a, b, c, d, e float64
-		switch ind.healthStatus {
-		case Healthy:
-			a = computeA(env, ind)
-		case Susceptible:
-			b = computeB(env, ind)
-		case Infected:
-			c = computeC(env, ind, infectedTotal)
-			d = computeD(env, ind)
-			// Ensure c + d <= 1 by normalizing if necessary
-			if c+d > 1.0 {
-				total := c + d
-				c = c / total
-				d = d / total
+	}
+	forEachChunk(env, func(chunk, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			ind := env.population[i]
+			if ind == nil {
+				ps[i] = transitionProbs{}
+				continue
 			}
-		case Recovered:
-			e = computeE(env, ind)
-		case Dead:
-			// no change
-		default:
-			return errors.New("unknown health status")
+			p, err := computeProbs(env, ind, load, npi[chunk])
+			if err != nil {
+				errs[chunk] = err
+				return
+			}
+			ps[i] = p
+			markExposure(env, ind, p)
+			addExposureRisk(ind, p)
+		}
+	})
+	env.batch = nil // stale once anyone changes state
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
-		ps[i] = probs{a: a, b: b, c: c, d: d, e: e}
 	}
+	for _, t := range npi {
+		if t != nil {
+			env.npi.merge(t)
+		}
+	}
+
+	// On days assimilating an observed curve, fix who is infected (no-op without incidenceFile)
+	constrainInfections(env, ps, rng)
 
 	// 4) Update statuses for each individual using the precomputed probabilities.
 	//    Pass env into UpdateIndividualHealthStatus so it can update behavior and timers.
@@ -91,6 +117,21 @@ func UpdatePopulationHealthStatus(env *Environment, rng *rand.Rand) error {
 		}
 	}
 
+	// Too few infections to match an observed curve: seed the rest (see assimilation.go)
+	fillInfections(env, rng)
+
+	// Antivirals from the stockpile for today's infected (see stockpile.go)
+	treatWithAntivirals(env)
+
+	// Hygiene practiced today uses up supplies
+	consumeHygiene(env)
+
+	// 5) Co-circulating pathogens, each with its own state per individual
+	updatePathogens(env, rng)
+
+	// 6) Deaths from other causes (no-op unless backgroundMortality is set)
+	applyBackgroundDeaths(env, rng)
+
 	return nil
 }
 
@@ -152,31 +193,49 @@ func UpdateIndividualHealthStatus(env *Environment, ind *Individual, a, b, c, d,
 		}
 	case Susceptible:
 		if drawFloat(rng) < b {
-			ind.healthStatus = Infected
-			ind.daysInfected = 0 // reset counter on becoming infected
-			// when infected, daysSinceRecovery should reset
-			ind.daysSinceRecovery = 0
+			startInfection(env, ind, rng)
 		} else {
 			ind.healthStatus = Healthy
+			ind.exposedBy = nil
 			// if recovered before and moved to Susceptible, keep daysSinceRecovery as-is
 		}
+	case Exposed:
+		// latent period over: the individual becomes infectious
+		if ind.infection == nil || ind.infection.latencyOver {
+			ind.healthStatus = Infected
+			countAdmission(env, ind)
+		}
 	case Infected:
+		recordIllnessDay(env, ind)
 		r := drawFloat(rng)
 		if r < c {
 			ind.healthStatus = Dead
+			if env != nil {
+				ind.deathDay = env.day
+			}
 			// death: freeze counters
+			if env != nil {
+				env.transitions.newDeaths++
+				recordDeath(env, ind)
+				recordWaitingDeath(env, ind)
+			}
+			ind.infection = nil
 		} else if r < c+d {
 			ind.healthStatus = Recovered
 			ind.daysSinceRecovery = 0
-			// clear infection counter as they've recovered
-			ind.daysInfected = 0
-		} else {
+			if env != nil {
+				env.transitions.newRecoveries++
+				env.burden.recoveries++
+			}
+			// drop the infection record as they've recovered
+			ind.infection = nil
+		} else if ind.infection != nil {
 			// remains infected: increment infection duration
-			ind.daysInfected++
+			ind.infection.daysInfected++
 		}
 	case Recovered:
 		if drawFloat(rng) < e {
-			ind.healthStatus = Healthy
+			ind.healthStatus = wanedStatus(env)
 			ind.daysSinceRecovery = 0
 		} else {
 			// remain recovered -> increment days since recovery
@@ -192,6 +251,11 @@ func UpdateIndividualHealthStatus(env *Environment, ind *Individual, a, b, c, d,
 	// Post-update bookkeeping
 	// -----------------------
 
+	// Exposure without infection boosts immunity; the boost wanes otherwise
+	if env != nil {
+		updateImmunityBoost(env, ind)
+	}
+
 	// Increment vaccination timer if vaccinated
 	if ind.vaccinated {
 		ind.daysSinceVacination++ // note: uses existing field name; consider renaming to daysSinceVaccination
@@ -200,8 +264,8 @@ func UpdateIndividualHealthStatus(env *Environment, ind *Individual, a, b, c, d,
 	// If individual was recovered and now healthy (immunity lost), keep daysSinceRecovery=0 as already set.
 	// If recovered and remained recovered, we already incremented.
 
-	// If the individual just became infected this step, daysInfected was already set to 0 above.
-	// If they remained infected we incremented daysInfected above.
+	// If the individual just became infected this step, a fresh Infection was created above.
+	// If they remained infected we incremented infection.daysInfected above.
 
 	// If individual is dead, no further behavioral updates should occur.
 	if ind.healthStatus == Dead {
@@ -220,26 +284,68 @@ func validProb(p float64) bool         { return p >= 0.0 && p <= 1.0 }
 func drawFloat(rng *rand.Rand) float64 { return rng.Float64() }
 
 // find infected neighbors within radius r
-func infectedNeighbors(env *Environment, who *Individual, r float64) []struct {
-	infected *Individual
-	d        float64
-} {
-	out := make([]struct {
-		infected *Individual
-		d        float64
-	}, 0)
+func infectedNeighbors(env *Environment, who *Individual, r float64) []neighbor {
+	return appendInfectedNeighbors(make([]neighbor, 0), env, who, r)
+}
+
+// appendInfectedNeighbors appends the infected neighbors of who within radius r to dst
+// and returns the extended slice, so callers can reuse a buffer across queries.
+func appendInfectedNeighbors(dst []neighbor, env *Environment, who *Individual, r float64) []neighbor {
+	if env.index != nil {
+		env.index.within(who.position, r, func(other *Individual, d float64) {
+			if other != who && other.healthStatus == Infected && sameRegion(who, other) {
+				dst = append(dst, neighbor{infected: other, d: d})
+			}
+		})
+		return dst
+	}
 	for _, other := range env.population {
-		if other == nil || other == who || other.healthStatus != Infected {
+		if other == nil || other == who || other.healthStatus != Infected || !sameRegion(who, other) {
 			continue
 		}
 		if d := dist(who.position, other.position); d <= r {
-			out = append(out, struct {
-				infected *Individual
-				d        float64
-			}{infected: other, d: d})
+			dst = append(dst, neighbor{infected: other, d: d})
+		}
+	}
+	return dst
+}
+
+// computeProbs returns ind's transition probabilities for today. It only
+// changes ind, so it can run for many individuals in parallel.
+func computeProbs(env *Environment, ind *Individual, load careLoad, npi *npiTally) (transitionProbs, error) {
+	var a, b, c, d, e float64
+	switch ind.healthStatus {
+	case Healthy:
+		if !env.fastForward.quiet {
+			a = computeA(env, ind)
+		}
+	case Susceptible:
+		if !env.fastForward.quiet {
+			b = computeB(env, ind, npi)
 		}
+	case Exposed:
+		// not infectious yet; becomes Infected after the latent period
+	case Infected:
+		c = computeC(env, ind, load)
+		d = computeD(env, ind)
+		// Ensure c + d <= 1 by normalizing if necessary
+		if c+d > 1.0 {
+			total := c + d
+			c = c / total
+			d = d / total
+		}
+		// Past the cap the infection must end today, split by the case-fatality ratio
+		if reachedInfectionCap(ind) {
+			c, d = forcedResolution(ind, c, d)
+		}
+	case Recovered:
+		e = computeE(env, ind)
+	case Dead:
+		// no change
+	default:
+		return transitionProbs{}, errors.New("unknown health status")
 	}
-	return out
+	return transitionProbs{a: a, b: b, c: c, d: d, e: e}, nil
 }
 
 // ---------------- A/B/C/D/E calculation ----------------
@@ -259,23 +365,21 @@ func computeA(env *Environment, ind *Individual) float64 {
 		return 0
 	}
 
-	// Base threshold: environment-level distancing requirement
-	R := env.socialDistanceThreshold
-	if R <= 0 && ind.disease != nil && ind.disease.transmissionDistance > 0 {
-		R = 0.5 * ind.disease.transmissionDistance
+	// Base threshold: environment-level distancing requirement (or that of ind's policy cell)
+	R := distanceThreshold(env, ind)
+	if R <= 0 && env.disease != nil && env.disease.transmissionDistance > 0 {
+		R = 0.5 * env.disease.transmissionDistance
 	}
 	if R <= 0 {
 		R = 1.0
 	}
 
 	// Individual compliance reduces effective contact distance
-	compliance := clamp01(ind.socialDistanceCompliance)
+	compliance := effectiveCompliance(env, ind)
 	Reff := R * (1 - 0.6*compliance)
 
-	neighbors := infectedNeighbors(env, ind, Reff)
-
-	// If no infectious neighbors, no chance of becoming susceptible
-	if len(neighbors) == 0 {
+	// If no infectious neighbors (nearby, on the same vehicle or at home), no chance of becoming susceptible
+	if !anyInfectedWithin(env, ind, Reff) && infectedCoPassengers(ind) == 0 && infectiousHousemates(ind) == 0 {
 		return 0.0
 	}
 
@@ -283,9 +387,22 @@ func computeA(env *Environment, ind *Individual) float64 {
 	// Higher hygiene = lower susceptibility, scaled from 1.0 (no hygiene) to 0.1 (max hygiene)
 	hygieneFactor := 1.0 - 0.9*clamp01(env.hygieneLevel)
 
-	// ===============================
-	// Vaccination time-based immunity
-	// ===============================
+	// Effective susceptibility after accounting for vaccine protection
+	effectiveSusceptibility := 1.0 - vaccineProtection(ind)
+
+	// Subgroup tags (e.g. healthcare workers) may have higher or lower exposure
+	exposureMult := tagExposureMult(env, ind)
+
+	// Final probability that the individual becomes susceptible
+	// Incorporate hygiene factor to reduce (not block) susceptibility
+	return clamp01(effectiveSusceptibility * hygieneFactor * exposureMult)
+}
+
+// ===============================
+// Vaccination time-based immunity
+// ===============================
+// vaccineProtection returns the individual's current protection from vaccination, in [0, 1].
+func vaccineProtection(ind *Individual) float64 {
 	// delayDays: days of strong protection after vaccination
 	delayDays := 30.0
 	// waningDays: days required for protection to decrease from 100% to 0%
@@ -310,29 +427,24 @@ func computeA(env *Environment, ind *Individual) float64 {
 		}
 		protection = clamp01(protection)
 	}
-
-	// Effective susceptibility after accounting for protection
-	effectiveSusceptibility := 1.0 - protection
-
-	// Final probability that the individual becomes susceptible
-	// Incorporate hygiene factor to reduce (not block) susceptibility
-	return clamp01(effectiveSusceptibility * hygieneFactor)
+	return protection
 }
 
 // B: Susceptible→Infected
 // Basis: transmissionRate, distance to each infected individual, vaccination.
 // Multiple exposure sources use independent failure stacking: P(infection) = 1 - Π(1 - p_i)
-// Distance decay uses exp(-d / D0), where D0 = transmissionDistance (interpretable, monotonic)
+// Distance decay uses the transmission kernel, exp(-d / D0) by default, where D0 = transmissionDistance (see kernel.go)
 // Vaccination: use environment coverage or individual flag to reduce effective transmission rate.
-func computeB(env *Environment, ind *Individual) float64 {
-	if ind == nil || ind.healthStatus != Susceptible || ind.disease == nil {
+func computeB(env *Environment, ind *Individual, npi *npiTally) float64 {
+	if ind == nil || ind.healthStatus != Susceptible || env.disease == nil {
 		return 0
 	}
-	D0 := ind.disease.transmissionDistance
+	D0 := env.disease.transmissionDistance
 	if D0 <= 0 {
 		D0 = 1.0
 	}
-	baseBeta := clamp01(ind.disease.transmissionRate)
+	kernel := transmissionKernel(env)
+	baseBeta := clamp01(env.disease.transmissionRate * weatherTransmission(env))
 
 	// Vaccine effect (simple linear reduction): if individual field exists, use it;
 	// otherwise use environment coverage as expectation
@@ -349,37 +461,94 @@ func computeB(env *Environment, ind *Individual) float64 {
 	hygieneFactor := 1.0 - 0.4*clamp01(env.hygieneLevel)
 
 	// Social distancing compliance reduces effective contact rate
-	compliance := clamp01(ind.socialDistanceCompliance)
+	compliance := effectiveCompliance(env, ind)
 	complianceFactor := 1.0 - 0.4*compliance
 
-	neighbors := infectedNeighbors(env, ind, 3*D0) // Influence radius is 3*D0
+	// Subgroup tags and the age susceptibility curve scale per-contact exposure
+	exposureMult := tagExposureMult(env, ind) * ageFactor(env, AgeSusceptibility, ind, 1)
+
+	// The age contact rates scale contacts outside the household (see agecurves.go)
+	contacts := ageFactor(env, AgeContacts, ind, 1)
+
+	// With the NPI report, the same contacts are also scored without each factor (see npi.go)
+	var variants npiContacts
+	if npi != nil {
+		variants = newNPIContacts(vaxFactor, hygieneFactor, complianceFactor)
+	}
+
+	// With chainStats, every infectious contact is a candidate source (see chains.go)
+	source := newSourcePicker(env, ind)
+
 	fail := 1.0
-	for _, nb := range neighbors {
-		// The closer the distance, the closer the value is to 1
-		decay := math.Exp(-nb.d / D0)
-		pi := baseBeta * decay * vaxFactor * hygieneFactor * complianceFactor
-		pi = clamp01(pi)
-		fail *= (1 - pi)
-	}
-	return clamp01(1 - fail)
+	if env.batch != nil && npi == nil {
+		// Scored from flat slices of the day's infected (see batch.go)
+		fail = env.batch.escape(env, ind, baseBeta*vaxFactor*hygieneFactor*complianceFactor*exposureMult*contacts, kernel, D0, source)
+	} else {
+		buf := getNeighborBuf()
+		defer putNeighborBuf(buf)
+		neighbors := appendInfectedNeighbors(*buf, env, ind, kernel.reach(D0)) // Influence radius is 3*D0 for the default kernel
+		*buf = neighbors
+		for _, nb := range neighbors {
+			// The closer the distance, the closer the value is to 1
+			decay := kernel.weight(nb.d, D0) * contacts * ageFactor(env, AgeContacts, nb.infected, 1)
+			pi := baseBeta * decay * vaxFactor * hygieneFactor * complianceFactor * exposureMult *
+				quarantineTransmission(env, nb.infected) * infectiousness(nb.infected.infection)
+			pi = clamp01(pi)
+			fail *= (1 - pi)
+			source.offer(nb.infected.infection, pi)
+			if npi != nil {
+				variants.add(baseBeta*decay*exposureMult*quarantineTransmission(env, nb.infected)*infectiousness(nb.infected.infection), 1, true)
+			}
+		}
+	}
+
+	// Infected co-passengers on a train/flight are contacts regardless of distance
+	if onBoard := infectedCoPassengers(ind); onBoard > 0 {
+		raw := baseBeta * env.transit.contactFactor * contacts * vaxFactor * hygieneFactor * complianceFactor * exposureMult
+		fail *= escapeGroup(env, raw, onBoard, ind.vehicle.passengers, func(p *Individual) bool {
+			return p != ind && p.healthStatus == Infected
+		}, source)
+		if npi != nil {
+			variants.add(baseBeta*env.transit.contactFactor*contacts*exposureMult, onBoard, true)
+		}
+	}
+	// Infected housemates are met every night, without distancing
+	if atHome := infectiousHousemates(ind); atHome > 0 {
+		raw := baseBeta * env.households.transmission * vaxFactor * hygieneFactor * exposureMult
+		fail *= escapeGroup(env, raw, atHome, ind.home.members, func(m *Individual) bool {
+			return m != ind && m.healthStatus == Infected && !m.inHospital
+		}, source)
+		if npi != nil {
+			variants.add(baseBeta*env.households.transmission*exposureMult, atHome, false)
+		}
+	}
+	if npi != nil {
+		npi.record(&variants, boostProtection(ind))
+	}
+	source.done(ind)
+	// Boosted immunity from earlier exposures (1 unless boosting is enabled)
+	return clamp01(1-fail) * boostProtection(ind)
 }
 
 // C: Infected→(death/recover/remain infected) Here we only calculate "death probability c"
-// Basis: base mortality, age, overload (infectedTotal > capacity), medical care level, vaccinationStatus
+// Basis: base mortality, age, overload (bed demand > beds for the individual's severity), medical care level, vaccinationStatus
 // Interpretable approach:
 //
 //	c = baseMort * ageMult * overloadMult * (1 - 0.6*careLevel)
 //
-// Where ageMult: <40:0.6, 40-60:1.0, >60:1.6 (example)
-// overloadMult: not overloaded=1, overloaded is linearly amplified by ratio (1 + overloadRatio)
-func computeC(env *Environment, ind *Individual, infectedTotal int) float64 {
-	if ind == nil || ind.healthStatus != Infected || ind.disease == nil {
+// Where ageMult: <40:0.6, 40-60:1.0, >60:1.6 (example), or the mortality age
+// curve; with ifrByAge or an ifr age curve, baseMort * ageMult is replaced by
+// the IFR (see ifr.go and agecurves.go)
+// overloadMult: Mild=1 (no bed needed); Severe=1 + wardOverload; Critical=1 + 2*icuOverload,
+// since ICU shortfalls drive most excess mortality.
+func computeC(env *Environment, ind *Individual, load careLoad) float64 {
+	if ind == nil || ind.healthStatus != Infected || ind.infection == nil || ind.infection.disease == nil {
 		return 0
 	}
 
-	base := clamp01(ind.disease.mortalityRate)
+	base := clamp01(ind.infection.disease.mortalityRate)
 
-	// Age adjustment (example, can be replaced with a finer curve)
+	// Age adjustment (example; ageCurvesFile replaces it with a finer curve)
 	ageMult := 1.0
 	switch {
 	case ind.age < 40:
@@ -389,15 +558,24 @@ func computeC(env *Environment, ind *Individual, infectedTotal int) float64 {
 	default:
 		ageMult = 1.6
 	}
+	ageMult = ageFactor(env, AgeMortality, ind, ageMult)
+
+	// With an IFR table or curve, the IFR replaces mortalityRate and ageMult
+	if usesIFR(env) {
+		base = ifrDeathProb(env, ind, computeD(env, ind))
+		ageMult = 1.0
+	}
 
-	// Overload adjustment
+	// Overload adjustment, depending on which kind of bed this individual needs
 	overloadMult := 1.0
-	if env.medicalCapacity > 0 && infectedTotal > env.medicalCapacity {
-		ratio := float64(infectedTotal-env.medicalCapacity) / float64(env.medicalCapacity)
-		if ratio < 0 {
-			ratio = 0
-		}
-		overloadMult = 1.0 + ratio // Linear amplification
+	switch {
+	case env.hospitalQueue.enabled:
+		// Only patients still waiting for a bed are affected
+		overloadMult = waitingMortalityMult(env, ind)
+	case ind.infection.severity == Severe:
+		overloadMult = 1.0 + load.wardOverload() // Linear amplification
+	case ind.infection.severity == Critical:
+		overloadMult = 1.0 + 2.0*load.icuOverload()
 	}
 
 	// Medical care level adjustment (the higher, the lower the mortality)
@@ -412,7 +590,7 @@ func computeC(env *Environment, ind *Individual, infectedTotal int) float64 {
 		vaxFactor = 0.5
 	}
 
-	c := base * ageMult * overloadMult * careFactor * vaxFactor
+	c := base * ageMult * overloadMult * careFactor * vaxFactor * antiviralMortality(env, ind)
 	// Note: c is clamped here, but caller must ensure c+d <= 1
 	// We cap c at 0.95 to leave room for recovery probability
 	if c > 0.95 {
@@ -427,14 +605,16 @@ func computeC(env *Environment, ind *Individual, infectedTotal int) float64 {
 //
 //	d = baseRec * ageMult * (1 + 0.5*careLevel)
 //
-// Where ageMult: <40:1.4, 40-60:1.0, >60:0.7 (example)
+// Where ageMult: <40:1.4, 40-60:1.0, >60:0.7 (example) or the recovery age
+// curve, and careLevel is 0
+// for patients waiting for a bed under the hospital queue.
 func computeD(env *Environment, ind *Individual) float64 {
-	if ind == nil || ind.healthStatus != Infected || ind.disease == nil {
+	if ind == nil || ind.healthStatus != Infected || ind.infection == nil || ind.infection.disease == nil {
 		return 0
 	}
 
-	baseRec := clamp01(ind.disease.recoveryRate)
-	// Age adjustment (example, can be replaced with a finer curve)
+	baseRec := clamp01(ind.infection.disease.recoveryRate)
+	// Age adjustment (example; ageCurvesFile replaces it with a finer curve)
 	ageMult := 1.0
 	switch {
 	case ind.age < 40:
@@ -444,27 +624,50 @@ func computeD(env *Environment, ind *Individual) float64 {
 	default:
 		ageMult = 0.7
 	}
+	ageMult = ageFactor(env, AgeRecovery, ind, ageMult)
 
-	// Medical care level adjustment (the higher, the higher the recovery rate)
+	// Medical care level adjustment (the higher, the higher the recovery rate).
+	// Patients still waiting for a hospital bed recover without that care.
 	careLevel := clamp01(env.medicalCareLevel)
+	if waitingForCare(env, ind) {
+		careLevel = 0
+	}
 	careFactor := 1.0 + 0.5*careLevel
 
 	// Days infected adjustment: longer infection duration increases recovery chance
 	timeFactor := 1.0
-	if ind.daysInfected > 0 {
-		timeFactor += math.Log(float64(ind.daysInfected)+1) / 10.0 // logarithmic increase
+	if days := ind.infection.daysInfected; days > 0 {
+		timeFactor += math.Log(float64(days)+1) / 10.0 // logarithmic increase
 	}
 
-	d := baseRec * ageMult * careFactor * timeFactor
+	d := baseRec * ageMult * careFactor * timeFactor * antiviralRecovery(env, ind)
 	return clamp01(d)
 }
 
+// reachedInfectionCap reports whether an infected individual has been infected
+// for the disease's maxInfectionDays and must resolve this step. The cap is
+// flagged by an EventInfectionCap scheduled when the infection started.
+func reachedInfectionCap(ind *Individual) bool {
+	return ind.infection != nil && ind.infection.mustResolve
+}
+
+// forcedResolution rescales c and d so that c+d = 1, keeping the individual's
+// case-fatality ratio c/(c+d). If both are zero, the disease mortality rate is used.
+func forcedResolution(ind *Individual, c, d float64) (float64, float64) {
+	total := c + d
+	if total <= 0 {
+		cfr := clamp01(ind.infection.disease.mortalityRate)
+		return cfr, 1 - cfr
+	}
+	return c / total, d / total
+}
+
 // E: Recovered→Healthy
 // Basis: days since recovery, vaccination status (bool)
 // Immunity decays over time: modeled as increasing probability with days since recovery
 // Vaccination boosts immunity: vaccinated recovered individuals have lower chance of losing immunity
 func computeE(env *Environment, ind *Individual) float64 {
-	if ind == nil || ind.healthStatus != Recovered || ind.disease == nil {
+	if ind == nil || ind.healthStatus != Recovered || env.disease == nil {
 		return 0
 	}
 
@@ -480,25 +683,38 @@ func computeE(env *Environment, ind *Individual) float64 {
 		vaxFactor = 0.5 // halve the chance of losing immunity
 	}
 
-	e := baseE * vaxFactor
+	e := baseE * vaxFactor * boostProtection(ind)
 	return clamp01(e)
 }
 
 // neighborsWithin: return neighbors (including non-infected) within radius r
 func neighborsWithin(env *Environment, who *Individual, r float64) []*Individual {
-	out := make([]*Individual, 0)
+	return appendNeighborsWithin(make([]*Individual, 0), env, who, r)
+}
+
+// appendNeighborsWithin appends all neighbors of who within radius r to dst
+// and returns the extended slice, so callers can reuse a buffer across queries.
+func appendNeighborsWithin(dst []*Individual, env *Environment, who *Individual, r float64) []*Individual {
 	if env == nil || who == nil || r <= 0 {
-		return out
+		return dst
+	}
+	if env.index != nil {
+		env.index.within(who.position, r, func(other *Individual, _ float64) {
+			if other != who && sameRegion(who, other) {
+				dst = append(dst, other)
+			}
+		})
+		return dst
 	}
 	for _, other := range env.population {
-		if other == nil || other == who {
+		if other == nil || other == who || !sameRegion(who, other) {
 			continue
 		}
 		if d := dist(who.position, other.position); d <= r {
-			out = append(out, other)
+			dst = append(dst, other)
 		}
 	}
-	return out
+	return dst
 }
 
 // updateHygieneLevel updates individual's hygieneLevel (0..1) based on:
@@ -527,7 +743,10 @@ func updateHygieneLevel(env *Environment, ind *Individual, rng *rand.Rand) error
 	afterDecay := current * (1.0 - fatigueDecay)
 
 	// social influence: neighbors' mean hygiene
-	neighbors := neighborsWithin(env, ind, socialInfluenceRadius)
+	buf := getIndividualBuf()
+	defer putIndividualBuf(buf)
+	neighbors := appendNeighborsWithin(*buf, env, ind, socialInfluenceRadius)
+	*buf = neighbors
 	meanNeighbor := 0.0
 	if len(neighbors) > 0 {
 		sum := 0.0
@@ -570,6 +789,12 @@ func updateHygieneLevel(env *Environment, ind *Individual, rng *rand.Rand) error
 	noise := (rng.Float64()*2 - 1) * randomNoise // in [-randomNoise, +randomNoise]
 	combined = combined + noise
 
+	// a behavior profile pulls hygiene back towards its baseline
+	combined = profileHygiene(ind, combined)
+
+	// gains are limited during a hygiene supply shortage
+	combined = limitHygieneGain(env, current, combined)
+
 	// final clamp and writeback
 	ind.hygieneLevel = clamp01(combined)
 	return nil
@@ -601,7 +826,10 @@ func updateSocialDistanceCompliance(env *Environment, ind *Individual, rng *rand
 	current := clamp01(ind.socialDistanceCompliance)
 
 	// neighbors' mean compliance
-	neighbors := neighborsWithin(env, ind, normRadius)
+	buf := getIndividualBuf()
+	defer putIndividualBuf(buf)
+	neighbors := appendNeighborsWithin(*buf, env, ind, normRadius)
+	*buf = neighbors
 	meanNeighborCompliance := 0.0
 	if len(neighbors) > 0 {
 		sum := 0.0
@@ -613,12 +841,12 @@ func updateSocialDistanceCompliance(env *Environment, ind *Individual, rng *rand
 		// fallback to a proxy via environment threshold (scaled into [0,1])
 		// larger socialDistanceThreshold implies stronger policy expectation -> higher compliance
 		// assume threshold up to some sensible cap (e.g., 10 units). normalize:
-		threshold := env.socialDistanceThreshold
+		threshold := distanceThreshold(env, ind)
 		meanNeighborCompliance = clamp01(threshold / 10.0)
 	}
 
-	// policy signal: map env.socialDistanceThreshold to [0,1] (tunable mapping)
-	policySignal := clamp01(env.socialDistanceThreshold / 10.0)
+	// policy signal: map the threshold of ind's policy to [0,1] (tunable mapping)
+	policySignal := clamp01(distanceThreshold(env, ind) / 10.0)
 
 	// base update: blend neighbors norm and policy
 	newCompliance := current*(1.0-normWeight-policyWeight) + meanNeighborCompliance*normWeight + policySignal*policyWeight
@@ -659,6 +887,9 @@ func updateSocialDistanceCompliance(env *Environment, ind *Individual, rng *rand
 	// small randomness
 	newCompliance += (rng.Float64()*2 - 1) * randomJitter
 
+	// a behavior profile pulls compliance back towards its baseline
+	newCompliance = profileCompliance(ind, newCompliance)
+
 	// clamp and write back
 	newCompliance = clamp01(newCompliance)
 	ind.socialDistanceCompliance = newCompliance
@@ -727,16 +958,30 @@ func UpdateVaccination(env *Environment, rng *rand.Rand) (int, error) {
 
 	n := len(env.population)
 
-	// Count current vaccinated
+	// Receive today's dose deliveries (if supply is limited) and drop expired lots
+	restockVaccines(env)
+	if !campaignActive(env) {
+		return 0, nil
+	}
+
+	// Count current vaccinated, and the population the coverage target applies to
 	currentVaccinated := 0
+	targetPop := n
 	for _, ind := range env.population {
-		if ind != nil && ind.vaccinated {
+		if ind == nil {
+			continue
+		}
+		if ind.healthStatus == Recovered && env.vaccineSupply.recoveredOutsideTarget {
+			targetPop--
+			continue
+		}
+		if ind.vaccinated {
 			currentVaccinated++
 		}
 	}
 
 	// Desired total vaccinated based on env.vaccinationRate (target coverage)
-	desiredTotal := int(math.Round(clamp01(env.vaccinationRate) * float64(n)))
+	desiredTotal := int(math.Round(clamp01(env.vaccinationRate) * float64(targetPop)))
 
 	available := desiredTotal - currentVaccinated
 	if available <= 0 {
@@ -744,16 +989,21 @@ func UpdateVaccination(env *Environment, rng *rand.Rand) (int, error) {
 		return 0, nil
 	}
 
-	// Limit vaccinations per generation to simulate rollout speed.
-	// Default: allow up to 2% of population per generation (at least 1).
-	maxDaily := int(math.Max(1.0, math.Round(0.02*float64(n))))
-	slots := int(math.Min(float64(available), float64(maxDaily)))
+	// Limit vaccinations per generation to simulate rollout speed,
+	// and to the doses in stock when supply is limited.
+	slots := min(available, dailyDoseCapacity(env, n))
+	if env.vaccineSupply.limited() {
+		slots = min(slots, dosesInStock(env))
+	}
 	if slots <= 0 {
 		return 0, nil
 	}
 
 	// To avoid bias, iterate randomized order of indices
-	indices := rand.Perm(n)
+	// (weighted towards priority tags, if any are configured)
+	// (sorted by the campaign's priority, see vaccine.go)
+	// (and served cell by cell with a geographic rollout)
+	indices := applyRolloutOrder(env, applyPriority(env, vaccinationOrder(env, rng)))
 
 	newlyVaccinated := 0
 
@@ -769,12 +1019,16 @@ func UpdateVaccination(env *Environment, rng *rand.Rand) (int, error) {
 		if ind.vaccinated {
 			continue
 		}
+		// Skip recovered individuals if the policy excludes them
+		if ind.healthStatus == Recovered && env.vaccineSupply.excludeRecovered {
+			continue
+		}
 
 		// -----------------------
 		// Individual acceptance model
 		// -----------------------
 		// Base acceptance probability (tunable)
-		baseAcceptance := 0.55 // baseline willingness to vaccinate
+		baseAcceptance := profileAcceptance(ind, 0.55) // baseline willingness to vaccinate, see profiles.go
 
 		// Age modifier: older people more likely to accept (e.g., >60 +0.2, 40-60 +0.1)
 		ageMod := 0.0
@@ -810,14 +1064,16 @@ func UpdateVaccination(env *Environment, rng *rand.Rand) (int, error) {
 		acceptanceProb = clamp01(acceptanceProb)
 
 		// Draw
-		if rng.Float64() < acceptanceProb {
+		if decisionDraw(env, ind, DecisionVaccination, rng) < acceptanceProb {
 			// Vaccinate this person
 			ind.vaccinated = true
 			ind.daysSinceVacination = 0
+			startSideEffects(env, ind, rng)
 			newlyVaccinated++
 			slots--
 		}
 	}
+	useDoses(env, newlyVaccinated)
 
 	return newlyVaccinated, nil
 }
\ No newline at end of file
@@ -12,17 +12,33 @@ import (
 // updateMove updates the individual's position based on their movement pattern.
 // It randomly selects the direction to go, and randomly selects the length of movement
 // Then we perform update on individual's position
-func (ind *Individual) updateMove(env *Environment) {
+func (ind *Individual) updateMove(env *Environment, rng *rand.Rand) {
 	if ind.movementPattern == nil || ind.healthStatus == Dead {
 		return
 	}
 
-	if ind.healthStatus == Infected {
+	// Individuals in quarantine stay where they are (at home, if they have one)
+	if quarantined(ind) {
+		ind.position = movementOrigin(env, ind)
+		ind.region = homeRegion(ind)
+		return
+	}
+
+	// Bad weather keeps some people in (no-op without a weather file)
+	if staysIn(env, rng) {
+		return
+	}
+
+	// Infected individuals stay local, unless they already boarded a train/flight today
+	if ind.healthStatus == Infected && ind.vehicle == nil {
 		ind.movementPattern = &MovementPattern{
 			moveType:   Walk,
 			moveRadius: env.areaSize * 0.001,
 		}
 	}
+	// Trains and flights may lead to another region (no-op without regions)
+	changeRegion(env, ind, rng)
+
 	// Movement radius depends on environment area size
 	moveRadius := ind.movementPattern.moveRadius
 	if moveRadius <= 0 {
@@ -31,16 +47,30 @@ func (ind *Individual) updateMove(env *Environment) {
 		moveRadius = ind.movementPattern.moveRadius
 	}
 
+	//random movement length, drawn from the configured step distribution
+	dist := drawStepLength(env, ind.movementPattern.moveType, moveRadius, rng)
+
+	// Recovering from vaccine side effects or in lockdown: shorter trips; during a shock: longer ones
+	dist *= sideEffectMobility(env, ind) * shockMobility(env, ind) * lockdownMobility(env, ind) * profileMobility(ind)
+
+	// On a road network, walk that distance along the roads instead
+	if env.roads != nil {
+		ind.road = env.roads.walk(ind.road, dist, rng)
+		ind.position = env.roads.point(ind.road)
+		ind.UpdateMovementPattern(env, rng)
+		return
+	}
+
 	// Random direction (0 to 2π)
-	//random movement length
-	dist := math.Sqrt(rand.Float64()) * moveRadius
-	angle := rand.Float64() * 2 * math.Pi
+	angle := rng.Float64() * 2 * math.Pi
 
 	dx := dist * math.Cos(angle)
 	dy := dist * math.Sin(angle)
 
-	newX := ind.position.x + dx
-	newY := ind.position.y + dy
+	// Set out from home, if the individual has one (see households.go)
+	origin := movementOrigin(env, ind)
+	newX := origin.x + dx
+	newY := origin.y + dy
 
 	// Keep within environment boundaries (wrap around)
 	if newX < 0 {
@@ -58,7 +88,42 @@ func (ind *Individual) updateMove(env *Environment) {
 	// Update position
 	ind.position = OrderedPair{x: newX, y: newY}
 
-	ind.UpdateMovementPattern(env)
+	ind.UpdateMovementPattern(env, rng)
+}
+
+// drawStepLength draws the length of one move of type mt with maximum radius R.
+// The distribution is chosen per move type via env.stepDistributions; the default
+// (disk) is uniform over the disk of radius R. Human mobility is heavy-tailed,
+// which the truncated Lévy option captures: most steps are short, a few are long.
+func drawStepLength(env *Environment, mt moveType, R float64, rng *rand.Rand) float64 {
+	if R <= 0 {
+		return 0
+	}
+	switch env.stepDistributions[mt] {
+	case StepUniform:
+		return rng.Float64() * R
+	case StepExponential:
+		// mean R/3; draws beyond R are rejected and redrawn (falling back to R)
+		mean := R / 3.0
+		for i := 0; i < 10; i++ {
+			if l := rng.ExpFloat64() * mean; l <= R {
+				return l
+			}
+		}
+		return R
+	case StepLevy:
+		// inverse CDF of a Pareto law P(l) ~ l^-(1+alpha), truncated to [R/1000, R]
+		alpha := env.levyExponent
+		if alpha <= 0 {
+			alpha = 1.5
+		}
+		lo := R / 1000.0
+		a := math.Pow(lo, -alpha)
+		b := math.Pow(R, -alpha)
+		return math.Pow(a-rng.Float64()*(a-b), -1.0/alpha)
+	default:
+		return math.Sqrt(rng.Float64()) * R
+	}
 }
 
 // NewMovementPattern creates a MovementPattern based on areaSize
@@ -89,16 +154,19 @@ func NewMovementPattern(mt moveType, env *Environment) *MovementPattern {
 
 // updateMovementPattern will assign a movement pattern to an individual
 // After the individual moves, decide how it moves for next move
-// 1% chance on flight, 4% chance on train, 95% walk
-func (ind *Individual) UpdateMovementPattern(env *Environment) {
-	val := rand.Float64()
-
-	if val <= 0.01 {
+// 1% chance on flight, 4% chance on train, 95% walk; a lockdown scales down
+// the flight and train chances (see lockdown.go), and so does a behavior
+// profile's mobility (see profiles.go)
+func (ind *Individual) UpdateMovementPattern(env *Environment, rng *rand.Rand) {
+	val := rng.Float64()
+	cut := lockdownMobility(env, ind) * profileMobility(ind)
+
+	if val <= 0.01*cut {
 		ind.movementPattern = &MovementPattern{
 			moveType:   Flight,
 			moveRadius: env.areaSize,
 		}
-	} else if val <= 0.05 {
+	} else if val <= 0.05*cut {
 		ind.movementPattern = &MovementPattern{
 			moveType:   Train,
 			moveRadius: env.areaSize * 0.1,
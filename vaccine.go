@@ -0,0 +1,156 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Vaccine supply and campaign window.
+//
+// By default vaccine doses are unlimited and the rollout is only limited by the
+// daily administration capacity. If any doseDelivery.DAY entries are configured,
+// doses must be in stock before they can be given: deliveries arrive on their
+// day, are used oldest first, and lots that pass their shelf life are thrown
+// away and counted as wasted.
+//
+// vaccinationPriority decides who is offered a vaccine first. random offers
+// them in random order (weighted by tagVaccinationPriority, if set); elderly
+// goes from the oldest down; healthcare offers them to everyone tagged
+// healthcare_worker first and then to the rest from the oldest down. Within
+// an age the order stays random, and a geographic rollout still serves its
+// cells in turn, each in priority order.
+
+// vaccinationPriority selects the order in which vaccines are offered.
+type vaccinationPriority string
+
+const (
+	PriorityRandom     vaccinationPriority = "random"     // random, weighted by tag priorities (default)
+	PriorityElderly    vaccinationPriority = "elderly"    // oldest first
+	PriorityHealthcare vaccinationPriority = "healthcare" // healthcare workers, then oldest first
+)
+
+// VaccineSupply configures the vaccination campaign.
+type VaccineSupply struct {
+	deliveries    map[int]int // day -> doses arriving; empty = unlimited supply
+	dailyCapacity int         // max doses given per day (0 = 2% of population)
+	shelfLife     int         // days a delivered lot stays usable (0 = no expiry)
+	startDay      int         // first day of the campaign
+	endDay        int         // last day of the campaign (0 = open-ended)
+	priority      vaccinationPriority
+
+	// Recovered individuals are eligible and count toward the coverage target
+	// unless these are set.
+	excludeRecovered       bool // never offer vaccines to Recovered individuals
+	recoveredOutsideTarget bool // measure coverage among non-Recovered individuals only
+}
+
+// limited reports whether doses come from a delivery schedule.
+func (s VaccineSupply) limited() bool { return len(s.deliveries) > 0 }
+
+// doseLot is one delivery still (partly) in stock.
+type doseLot struct {
+	doses   int
+	expires int // first day the lot can no longer be used (0 = never)
+}
+
+// vaccineStock tracks doses on hand over the run.
+type vaccineStock struct {
+	lots             []doseLot // oldest first
+	wasted           int       // doses expired before use, cumulative
+	administered     int       // doses given, cumulative
+	restockedThrough int       // last day whose deliveries were received
+}
+
+// campaignActive reports whether vaccines may be given on the current day.
+func campaignActive(env *Environment) bool {
+	s := env.vaccineSupply
+	return env.day >= s.startDay && (s.endDay == 0 || env.day <= s.endDay)
+}
+
+// dailyDoseCapacity returns how many doses can be given today in a population of n.
+func dailyDoseCapacity(env *Environment, n int) int {
+	if c := env.vaccineSupply.dailyCapacity; c > 0 {
+		return c
+	}
+	// Default: allow up to 2% of population per generation (at least 1).
+	return int(math.Max(1.0, math.Round(0.02*float64(n))))
+}
+
+// restockVaccines receives all deliveries due up to the current day and
+// discards expired lots.
+func restockVaccines(env *Environment) {
+	s, st := env.vaccineSupply, &env.vaccineStock
+	if !s.limited() {
+		return
+	}
+	for day := st.restockedThrough + 1; day <= env.day; day++ {
+		if doses := s.deliveries[day]; doses > 0 {
+			lot := doseLot{doses: doses}
+			if s.shelfLife > 0 {
+				lot.expires = day + s.shelfLife
+			}
+			st.lots = append(st.lots, lot)
+		}
+	}
+	if env.day > st.restockedThrough {
+		st.restockedThrough = env.day
+	}
+
+	kept := st.lots[:0]
+	for _, lot := range st.lots {
+		if lot.expires > 0 && env.day >= lot.expires {
+			st.wasted += lot.doses
+			continue
+		}
+		kept = append(kept, lot)
+	}
+	st.lots = kept
+}
+
+// dosesInStock returns the number of usable doses on hand.
+func dosesInStock(env *Environment) int {
+	total := 0
+	for _, lot := range env.vaccineStock.lots {
+		total += lot.doses
+	}
+	return total
+}
+
+// useDoses takes n doses from stock, oldest lots first.
+func useDoses(env *Environment, n int) {
+	st := &env.vaccineStock
+	st.administered += n
+	for n > 0 && len(st.lots) > 0 {
+		take := min(n, st.lots[0].doses)
+		st.lots[0].doses -= take
+		n -= take
+		if st.lots[0].doses == 0 {
+			st.lots = st.lots[1:]
+		}
+	}
+}
+
+// applyPriority reorders the random offer order by the campaign's priority.
+// The sort is stable, so ties keep their random order.
+func applyPriority(env *Environment, order []int) []int {
+	p := env.vaccineSupply.priority
+	if p != PriorityElderly && p != PriorityHealthcare {
+		return order
+	}
+	// Lower ranks are offered first
+	rank := func(i int) int {
+		ind := env.population[i]
+		switch {
+		case ind == nil:
+			return math.MaxInt
+		case p == PriorityHealthcare && ind.hasTag(healthcareWorkerTag):
+			return math.MinInt
+		}
+		return -ind.age
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+	return order
+}
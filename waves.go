@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Epidemic wave detection.
+//
+// The infected count is recorded every day and, at the end of the run,
+// split into waves: the series is smoothed with a 7-day moving average and
+// scanned for peaks and troughs, where a peak only counts if the curve falls
+// from it (and a trough only if it rises from it) by at least a prominence
+// threshold, a fraction of the highest smoothed value. This keeps day-to-day
+// noise from being reported as separate waves.
+
+// epidemicSeries holds the daily infected counts and new infections of a run.
+type epidemicSeries struct {
+	days          []int
+	infected      []int
+	newInfections []int
+}
+
+// add appends one day (days must be added in order).
+func (s *epidemicSeries) add(day, infected, newInfections int) {
+	s.days = append(s.days, day)
+	s.infected = append(s.infected, infected)
+	s.newInfections = append(s.newInfections, newInfections)
+}
+
+// head returns the first days+1 entries (day 0 to day `days`) of the series.
+func (s *epidemicSeries) head(days int) *epidemicSeries {
+	k := min(days+1, len(s.days))
+	return &epidemicSeries{days: s.days[:k], infected: s.infected[:k], newInfections: s.newInfections[:k]}
+}
+
+// wave is one epidemic wave, from the trough before it to the trough after it.
+// start, peak and end are indices into the series.
+type wave struct {
+	start, peak, end int
+	peakInfected     int
+	infections       int // new infections during the wave
+}
+
+// smoothSeries returns a centered moving average of x with the given window.
+func smoothSeries(x []int, window int) []float64 {
+	out := make([]float64, len(x))
+	half := window / 2
+	for i := range x {
+		lo, hi := max(0, i-half), min(len(x)-1, i+half)
+		sum := 0
+		for j := lo; j <= hi; j++ {
+			sum += x[j]
+		}
+		out[i] = float64(sum) / float64(hi-lo+1)
+	}
+	return out
+}
+
+// detectWaves segments the series into waves using the given prominence
+// (a fraction of the highest smoothed infected count).
+func detectWaves(s *epidemicSeries, prominence float64) []wave {
+	n := len(s.infected)
+	if n == 0 {
+		return nil
+	}
+	smooth := smoothSeries(s.infected, 7)
+	top := 0.0
+	for _, v := range smooth {
+		top = math.Max(top, v)
+	}
+	if top == 0 {
+		return nil
+	}
+	threshold := math.Max(prominence*top, 1.0)
+
+	// Alternate between looking for a peak and a trough
+	var peaks, troughs []int
+	troughs = append(troughs, 0)
+	lookingForPeak := true
+	cand := 0
+	for i := 1; i < n; i++ {
+		if lookingForPeak {
+			if smooth[i] > smooth[cand] {
+				cand = i
+			} else if smooth[cand]-smooth[i] >= threshold {
+				peaks = append(peaks, cand)
+				lookingForPeak = false
+				cand = i
+			}
+		} else {
+			if smooth[i] < smooth[cand] {
+				cand = i
+			} else if smooth[i]-smooth[cand] >= threshold {
+				troughs = append(troughs, cand)
+				lookingForPeak = true
+				cand = i
+			}
+		}
+	}
+	// A peak still rising (or not yet fallen far enough) at the end of the run counts too
+	if lookingForPeak && smooth[cand]-smooth[troughs[len(troughs)-1]] >= threshold {
+		peaks = append(peaks, cand)
+	}
+
+	waves := make([]wave, 0, len(peaks))
+	for k, p := range peaks {
+		w := wave{start: troughs[k], peak: p, end: n - 1}
+		if k+1 < len(troughs) {
+			w.end = troughs[k+1]
+		}
+		// Report the raw peak height within the wave
+		for d := w.start; d <= w.end; d++ {
+			if s.infected[d] > w.peakInfected {
+				w.peakInfected = s.infected[d]
+				w.peak = d
+			}
+		}
+		// Infections are attributed to the wave up to (not including) its end trough
+		last := w.end - 1
+		if k == len(peaks)-1 {
+			last = n - 1
+		}
+		for d := w.start; d <= last; d++ {
+			w.infections += s.newInfections[d]
+		}
+		waves = append(waves, w)
+	}
+	return waves
+}
+
+// printWaveSummary prints one line per detected wave with its attack rate.
+func printWaveSummary(env *Environment, s *epidemicSeries, prominence float64) {
+	waves := detectWaves(s, prominence)
+	n := len(env.population)
+	fmt.Fprintf(msgOut, "Epidemic waves: %d\n", len(waves))
+	for i, w := range waves {
+		attack := 0.0
+		if n > 0 {
+			attack = 100 * float64(w.infections) / float64(n)
+		}
+		start, peak, end := s.days[w.start], s.days[w.peak], s.days[w.end]
+		fmt.Fprintf(msgOut, "  Wave %d: days %d-%d (%d days), peak %d infected on day %d, attack rate %.1f%%\n",
+			i+1, start, end, end-start, w.peakInfected, peak, attack)
+	}
+}
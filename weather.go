@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Weather/climate modifiers.
+//
+// A weather file gives an exogenous per-day series, e.g. derived from
+// temperature and humidity records, that scales transmission and outdoor
+// activity. Each line is "day, transmission, activity" (commas or spaces;
+// '#' starts a comment). The day is a simulation day number, or a date
+// (YYYY-MM-DD) when startDate is set. A value holds from its day until the
+// next listed day; days before the first line use 1.0.
+//
+//   - transmission multiplies transmissionRate in every contact.
+//   - activity is the chance that an individual goes out (moves) that day;
+//     1 = everyone moves as usual, 0.6 = 40% stay put.
+
+// weatherDay holds the modifiers that apply from day on.
+type weatherDay struct {
+	day          int
+	transmission float64
+	activity     float64
+}
+
+// weatherSeries is a weather file's entries sorted by day.
+type weatherSeries struct {
+	days []weatherDay
+}
+
+// loadWeather reads a weather file. startDate (zero if no calendar) is needed
+// to resolve dates in the first column.
+func loadWeather(path string, startDate time.Time) (*weatherSeries, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	w := &weatherSeries{}
+	seen := map[int]bool{}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	first := true
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s line %d: expected day, transmission, activity", path, lineNum)
+		}
+		_, numErr := strconv.ParseFloat(fields[1], 64)
+		header := first && numErr != nil
+		first = false
+		day, err := parseWeatherDay(fields[0], startDate)
+		if err != nil {
+			if header {
+				continue // e.g. "day, transmission, activity"
+			}
+			return nil, fmt.Errorf("%s line %d: %v", path, lineNum, err)
+		}
+		if seen[day] {
+			return nil, fmt.Errorf("%s line %d: day %d listed twice", path, lineNum, day)
+		}
+		seen[day] = true
+		var v [2]float64
+		for i, s := range fields[1:] {
+			v[i], err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid number %q", path, lineNum, s)
+			}
+		}
+		if v[0] < 0 || v[0] > 10 {
+			return nil, fmt.Errorf("%s line %d: transmission multiplier %g outside [0, 10]", path, lineNum, v[0])
+		}
+		if v[1] < 0 || v[1] > 1 {
+			return nil, fmt.Errorf("%s line %d: activity %g outside [0, 1]", path, lineNum, v[1])
+		}
+		w.days = append(w.days, weatherDay{day: day, transmission: v[0], activity: v[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(w.days) == 0 {
+		return nil, fmt.Errorf("%s: no entries", path)
+	}
+	sort.Slice(w.days, func(i, j int) bool { return w.days[i].day < w.days[j].day })
+	return w, nil
+}
+
+// parseWeatherDay parses a day number, or a date relative to startDate.
+func parseWeatherDay(s string, startDate time.Time) (int, error) {
+	if day, err := strconv.Atoi(s); err == nil {
+		if day < 0 {
+			return 0, fmt.Errorf("negative day %d", day)
+		}
+		return day, nil
+	}
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid day %q (expected a day number or YYYY-MM-DD)", s)
+	}
+	if startDate.IsZero() {
+		return 0, fmt.Errorf("date %s given but no startDate is set", s)
+	}
+	day := int(date.Sub(startDate).Hours() / 24)
+	if day < 0 {
+		return 0, fmt.Errorf("date %s is before startDate", s)
+	}
+	return day, nil
+}
+
+// at returns the modifiers in effect on day.
+func (w *weatherSeries) at(day int) weatherDay {
+	i := sort.Search(len(w.days), func(i int) bool { return w.days[i].day > day })
+	if i == 0 {
+		return weatherDay{day: day, transmission: 1, activity: 1}
+	}
+	return w.days[i-1]
+}
+
+// weatherTransmission returns today's transmission multiplier (1 without weather).
+func weatherTransmission(env *Environment) float64 {
+	if env.weather == nil {
+		return 1
+	}
+	return env.weather.at(env.day).transmission
+}
+
+// staysIn reports whether the weather keeps an individual from going out today.
+func staysIn(env *Environment, rng *rand.Rand) bool {
+	if env.weather == nil {
+		return false
+	}
+	return rng.Float64() >= env.weather.at(env.day).activity
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Parallel population updates.
+//
+// Computing the day's transition probabilities only reads shared state, and
+// moving an individual only changes that individual, so both are split across
+// numWorkers goroutines (default GOMAXPROCS). The population is cut into
+// fixed chunks of workChunk individuals that workers take in turn. Movement
+// draws random numbers, so each chunk gets its own random stream every day:
+// seeded from the run's generator, or with a jumpable generator the next of
+// the run's non-overlapping streams (see rng.go). Chunks and streams do not
+// depend on the number of workers, so a seeded run gives the same result with
+// any numWorkers, including 1.
+
+// workChunk is the number of individuals per chunk of parallel work.
+const workChunk = 1024
+
+// workerCount returns the number of goroutines to use for n individuals.
+func workerCount(env *Environment, n int) int {
+	w := env.numWorkers
+	if w <= 0 {
+		w = runtime.GOMAXPROCS(0)
+	}
+	return max(1, min(w, (n+workChunk-1)/workChunk))
+}
+
+// forEachChunk calls fn for every chunk of the population, on up to
+// workerCount goroutines, and returns when all chunks are done.
+func forEachChunk(env *Environment, fn func(chunk, lo, hi int)) {
+	n := len(env.population)
+	chunks := (n + workChunk - 1) / workChunk
+	workers := workerCount(env, n)
+	if workers == 1 {
+		for c := 0; c < chunks; c++ {
+			fn(c, c*workChunk, min((c+1)*workChunk, n))
+		}
+		return
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c := int(next.Add(1) - 1)
+				if c >= chunks {
+					return
+				}
+				fn(c, c*workChunk, min((c+1)*workChunk, n))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// chunkRng returns the random stream of chunk for a day whose streams are
+// seeded with seed.
+func chunkRng(seed int64, chunk int) *rand.Rand {
+	// splitmix64 of the chunk number, so neighboring chunks get unrelated seeds
+	z := uint64(seed) + uint64(chunk+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return rand.New(rand.NewSource(int64(z)))
+}
+
+// chunkRngs returns today's random stream of each chunk: taken in chunk order
+// from env.streams, or else seeded from one draw of rng.
+func chunkRngs(env *Environment, rng *rand.Rand) func(chunk int) *rand.Rand {
+	if env.streams == nil {
+		seed := rngOrDefault(rng).Int63()
+		return func(chunk int) *rand.Rand { return chunkRng(seed, chunk) }
+	}
+	sources := make([]jumpSource, (len(env.population)+workChunk-1)/workChunk)
+	for c := range sources {
+		sources[c] = env.streams.take()
+	}
+	return func(chunk int) *rand.Rand { return rand.New(sources[chunk]) }
+}
+
+// moveAll moves every living individual one step, in parallel.
+func moveAll(env *Environment, rng *rand.Rand) {
+	rngOf := chunkRngs(env, rng)
+	forEachChunk(env, func(chunk, lo, hi int) {
+		r := rngOf(chunk)
+		for _, ind := range env.population[lo:hi] {
+			if ind != nil && ind.healthStatus != Dead {
+				ind.updateMove(env, r)
+			}
+		}
+	})
+}